@@ -0,0 +1,127 @@
+package Cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// The application can be started with a profile (skipping the profile window), headless (backend without UI)
+// or with a subcommand which runs without any UI:
+//
+//	whispering-tiger --profile default.yaml
+//	whispering-tiger --profile default.yaml --headless
+//	whispering-tiger --offline /media/usb/bundle models download default.yaml
+//	whispering-tiger profiles list|validate|migrate
+//	whispering-tiger models list|download <profile>
+//	whispering-tiger translate --to deu_Latn "Hello"
+//	whispering-tiger tts --out hello.wav "Hello"
+
+const usageText = `Usage:
+  whispering-tiger [--profile <name>] [--headless] [--offline <dir|archive>]
+  whispering-tiger [--offline <dir|archive>] <command> [arguments]
+
+Options:
+  --profile <name>   load the profile and skip the profile window
+  --headless         run the backend without UI (requires --profile)
+  --offline <path>   install the platform and models from a local directory or archive (zip, tar.gz, tar.zst, 7z)
+
+Commands:
+  profiles list                          list all profiles
+  profiles validate [name...]            validate the settings of profiles
+  profiles migrate [--dry-run] [name...] migrate profiles to the current schema version
+  models list <profile>                  list the models a profile requires
+  models download <profile>              download the models a profile requires
+  translate [--from <lang>] --to <lang> <text>
+                                         translate text with a running backend
+  tts [--out <file.wav>] <text>          speak text (or save it as wav) with a running backend
+
+translate and tts connect to --addr <ip:port>, or the websocket of --profile (default 127.0.0.1:5000).
+`
+
+// Options are the command line arguments of the application.
+type Options struct {
+	Profile  string
+	Headless bool
+	// Offline is the directory or archive with the offline bundle
+	Offline string
+	// Command is the subcommand to run without UI (empty to start the UI)
+	Command string
+	Args    []string
+}
+
+func Usage(writer io.Writer) {
+	_, _ = fmt.Fprint(writer, usageText)
+}
+
+// Parse parses the command line arguments (without the program name).
+// flag.ErrHelp is returned if the usage was requested.
+func Parse(args []string) (*Options, error) {
+	options := &Options{}
+	flags := flag.NewFlagSet("whispering-tiger", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.StringVar(&options.Profile, "profile", "", "")
+	flags.BoolVar(&options.Headless, "headless", false, "")
+	flags.StringVar(&options.Offline, "offline", "", "")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		options.Command = flags.Arg(0)
+		options.Args = flags.Args()[1:]
+	}
+	if options.Headless && options.Profile == "" {
+		return nil, errors.New("--headless requires --profile")
+	}
+	if options.Headless && options.Command != "" {
+		return nil, errors.New("--headless can not be used with a command")
+	}
+	return options, nil
+}
+
+// Run runs the subcommand and returns the exit code.
+func Run(options *Options) int {
+	var err error
+	switch options.Command {
+	case "profiles":
+		err = runProfilesCommand(options.Args)
+	case "models":
+		err = runModelsCommand(options.Args)
+	case "translate":
+		err = runTranslateCommand(options, options.Args)
+	case "tts":
+		err = runTtsCommand(options, options.Args)
+	case "help":
+		Usage(os.Stdout)
+		return 0
+	default:
+		err = usageError("unknown command %q", options.Command)
+	}
+
+	var usageErr *UsageError
+	if errors.As(err, &usageErr) {
+		_, _ = fmt.Fprintln(os.Stderr, "error:", err)
+		Usage(os.Stderr)
+		return 2
+	}
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	return 0
+}
+
+// UsageError is returned for wrong arguments of a command.
+type UsageError struct {
+	Message string
+}
+
+func (e *UsageError) Error() string {
+	return e.Message
+}
+
+func usageError(format string, args ...interface{}) error {
+	return &UsageError{Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,180 @@
+package Cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"whispering-tiger-ui/Fields"
+)
+
+const commandTimeout = 2 * time.Minute
+
+// commandMessage is the part of the backend messages the commands need.
+type commandMessage struct {
+	Type            string `json:"type"`
+	Data            string `json:"data"`
+	TranslateResult string `json:"translate_result"`
+	WavData         []byte `json:"wav_data"`
+}
+
+// backendAddr returns the websocket address of the backend the commands connect to.
+func backendAddr(options *Options, addr string) (string, error) {
+	if addr != "" {
+		return addr, nil
+	}
+	if options.Profile != "" {
+		conf, err := LoadProfile(options.Profile)
+		if err != nil {
+			return "", err
+		}
+		return conf.Websocket_ip + ":" + strconv.Itoa(conf.Websocket_port), nil
+	}
+	return "127.0.0.1:5000", nil
+}
+
+func dialBackend(addr string) (*websocket.Conn, error) {
+	dialer := websocket.Dialer{
+		HandshakeTimeout: 5 * time.Second,
+	}
+	u := url.URL{Scheme: "ws", Host: addr, Path: "/"}
+	conn, _, err := dialer.Dial(u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("no backend running at %s: %w", addr, err)
+	}
+	return conn, nil
+}
+
+// sendCommand sends a message to a running backend and waits for the answer of responseType.
+func sendCommand(addr string, message Fields.SendMessageStruct, responseType string) (*commandMessage, error) {
+	conn, err := dialBackend(addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err = conn.WriteJSON(message); err != nil {
+		return nil, err
+	}
+	if err = conn.SetReadDeadline(time.Now().Add(commandTimeout)); err != nil {
+		return nil, err
+	}
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return nil, err
+		}
+		var response commandMessage
+		if json.Unmarshal(data, &response) != nil {
+			continue
+		}
+		switch response.Type {
+		case responseType:
+			return &response, nil
+		case "error":
+			return nil, errors.New(response.Data)
+		}
+	}
+}
+
+func runTranslateCommand(options *Options, args []string) error {
+	flags := flag.NewFlagSet("translate", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	fromLang := flags.String("from", "auto", "")
+	toLang := flags.String("to", "", "")
+	addrFlag := flags.String("addr", "", "")
+	romaji := flags.Bool("romaji", false, "")
+	if err := flags.Parse(args); err != nil {
+		return usageError("%v", err)
+	}
+	text := strings.Join(flags.Args(), " ")
+	if text == "" || *toLang == "" {
+		return usageError("usage: translate [--from <lang>] --to <lang> <text>")
+	}
+	addr, err := backendAddr(options, *addrFlag)
+	if err != nil {
+		return err
+	}
+
+	//goland:noinspection GoSnakeCaseUsage
+	response, err := sendCommand(addr, Fields.SendMessageStruct{
+		Type: "translate_req",
+		Value: struct {
+			Text                string `json:"text"`
+			From_lang           string `json:"from_lang"`
+			To_lang             string `json:"to_lang"`
+			To_romaji           bool   `json:"to_romaji"`
+			Ignore_send_options bool   `json:"ignore_send_options"`
+		}{
+			Text:                text,
+			From_lang:           *fromLang,
+			To_lang:             *toLang,
+			To_romaji:           *romaji,
+			Ignore_send_options: true,
+		},
+	}, "translate_result")
+	if err != nil {
+		return err
+	}
+	fmt.Println(response.TranslateResult)
+	return nil
+}
+
+func runTtsCommand(options *Options, args []string) error {
+	flags := flag.NewFlagSet("tts", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	outFile := flags.String("out", "", "")
+	addrFlag := flags.String("addr", "", "")
+	if err := flags.Parse(args); err != nil {
+		return usageError("%v", err)
+	}
+	text := strings.Join(flags.Args(), " ")
+	if text == "" {
+		return usageError("usage: tts [--out <file.wav>] <text>")
+	}
+	addr, err := backendAddr(options, *addrFlag)
+	if err != nil {
+		return err
+	}
+
+	message := Fields.SendMessageStruct{
+		Type: "tts_req",
+		Value: struct {
+			Text     string `json:"text"`
+			ToDevice bool   `json:"to_device"`
+			Download bool   `json:"download"`
+		}{
+			Text:     text,
+			ToDevice: *outFile == "",
+			Download: *outFile != "",
+		},
+	}
+	if *outFile == "" {
+		// played on the audio device of the backend, nothing to wait for
+		conn, err := dialBackend(addr)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			time.Sleep(500 * time.Millisecond) // give the backend time to read the message before closing
+			conn.Close()
+		}()
+		return conn.WriteJSON(message)
+	}
+	response, err := sendCommand(addr, message, "tts_save")
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(*outFile, response.WavData, 0644); err != nil {
+		return err
+	}
+	fmt.Println("saved", *outFile)
+	return nil
+}
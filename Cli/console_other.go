@@ -0,0 +1,6 @@
+//go:build !windows
+
+package Cli
+
+// AttachParentConsole does nothing, the output is always written to the terminal the application was started from.
+func AttachParentConsole() {}
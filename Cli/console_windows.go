@@ -0,0 +1,37 @@
+//go:build windows
+
+package Cli
+
+import (
+	"log"
+	"os"
+	"syscall"
+)
+
+var procAttachConsole = syscall.NewLazyDLL("kernel32.dll").NewProc("AttachConsole")
+
+// attachParentProcess is ATTACH_PARENT_PROCESS ((DWORD)-1)
+const attachParentProcess = ^uint32(0)
+
+// AttachParentConsole writes the output to the console the application was started from (e.g. cmd.exe).
+// Release builds are GUI applications without a console of their own, so the output of commands would be invisible.
+// Output which is redirected (e.g. into a file) is kept.
+func AttachParentConsole() {
+	if result, _, _ := procAttachConsole.Call(uintptr(attachParentProcess)); result == 0 {
+		return
+	}
+	reopen := func(file **os.File, name string, flag int) {
+		if *file != nil {
+			if _, err := (*file).Stat(); err == nil {
+				return
+			}
+		}
+		if console, err := os.OpenFile(name, flag, 0); err == nil {
+			*file = console
+		}
+	}
+	reopen(&os.Stdin, "CONIN$", os.O_RDONLY)
+	reopen(&os.Stdout, "CONOUT$", os.O_WRONLY)
+	reopen(&os.Stderr, "CONOUT$", os.O_WRONLY)
+	log.SetOutput(os.Stderr)
+}
@@ -0,0 +1,146 @@
+package Cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+	"whispering-tiger-ui/ModelDownloader"
+	"whispering-tiger-ui/Profiles"
+	"whispering-tiger-ui/RuntimeBackend"
+	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/Utilities"
+)
+
+// headlessMessage is the part of the backend messages which are printed in headless mode.
+type headlessMessage struct {
+	Type            string      `json:"type"`
+	Data            interface{} `json:"data"`
+	Text            string      `json:"text"`
+	Language        string      `json:"language"`
+	TxtTranslation  string      `json:"txt_translation"`
+	TranslateResult string      `json:"translate_result"`
+}
+
+// RunHeadless runs the backend of the profile without UI and prints the transcriptions until it is interrupted.
+// If the profile does not run its own backend, it connects to the configured websocket only.
+func RunHeadless(conf *Settings.Conf) error {
+	Settings.Config = *conf
+	addr := conf.Websocket_ip + ":" + strconv.Itoa(conf.Websocket_port)
+
+	// SIGTERM is sent by service managers (e.g. systemd) to stop the application
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var backend *exec.Cmd
+	backendExited := make(chan error, 1)
+	if conf.Run_backend {
+		if Utilities.CheckPortInUse(addr) {
+			log.Printf("a backend is already running at %s, connecting to it", addr)
+		} else {
+			settingsFile := Settings.PrepareBackendSettingsFile(filepath.Join(Settings.GetConfProfileDir(), conf.SettingsFilename))
+			var err error
+			backend, err = RuntimeBackend.NewBackendCommand(settingsFile, strconv.Itoa(deviceIndex(conf.Device_index)), strconv.Itoa(deviceIndex(conf.Device_out_index)))
+			if err != nil {
+				return err
+			}
+			backend.Stdout = os.Stdout
+			backend.Stderr = os.Stderr
+			if err = backend.Start(); err != nil {
+				return err
+			}
+			ModelDownloader.MarkModelsUsed(Profiles.RequiredModels(conf))
+			go func() {
+				backendExited <- backend.Wait()
+			}()
+		}
+	}
+
+	go monitorBackend(ctx, addr)
+
+	select {
+	case err := <-backendExited:
+		Settings.RemoveBackendSettingsFile()
+		if err != nil {
+			return fmt.Errorf("backend stopped: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	if backend == nil {
+		return nil
+	}
+	log.Printf("stopping backend")
+	if err := Utilities.SendQuitMessage(addr); err != nil {
+		_ = backend.Process.Kill()
+	}
+	select {
+	case <-backendExited:
+	case <-time.After(10 * time.Second):
+		_ = backend.Process.Kill()
+	}
+	Settings.RemoveBackendSettingsFile()
+	return nil
+}
+
+// monitorBackend connects to the websocket of the backend and prints transcriptions, translations and errors.
+func monitorBackend(ctx context.Context, addr string) {
+	for ctx.Err() == nil {
+		conn, err := dialBackend(addr)
+		if err != nil {
+			// the backend is still loading
+			select {
+			case <-ctx.Done():
+			case <-time.After(2 * time.Second):
+			}
+			continue
+		}
+		log.Printf("connected to %s", addr)
+		// the backend loaded its settings when it accepts connections
+		Settings.RemoveBackendSettingsFile()
+		go func() {
+			<-ctx.Done()
+			conn.Close()
+		}()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				break
+			}
+			var message headlessMessage
+			if json.Unmarshal(data, &message) != nil {
+				continue
+			}
+			printHeadlessMessage(message)
+		}
+		conn.Close()
+		if ctx.Err() == nil {
+			log.Printf("connection to %s lost", addr)
+		}
+	}
+}
+
+func printHeadlessMessage(message headlessMessage) {
+	switch message.Type {
+	case "transcript":
+		if message.TxtTranslation != "" {
+			fmt.Printf("[%s] %s -> %s\n", message.Language, message.Text, message.TxtTranslation)
+		} else {
+			fmt.Printf("[%s] %s\n", message.Language, message.Text)
+		}
+	case "translate_result":
+		fmt.Println(message.TranslateResult)
+	case "error", "info":
+		if text, ok := message.Data.(string); ok {
+			log.Printf("%s: %s", message.Type, text)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package Cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"whispering-tiger-ui/ModelDownloader"
+	"whispering-tiger-ui/Profiles"
+	"whispering-tiger-ui/Updater"
+)
+
+func runModelsCommand(args []string) error {
+	if len(args) != 2 {
+		return usageError("usage: models list|download <profile>")
+	}
+	conf, err := LoadProfile(args[1])
+	if err != nil {
+		return err
+	}
+	models := Profiles.RequiredModels(conf)
+
+	switch args[0] {
+	case "list":
+		for _, model := range models {
+			state := "missing"
+			if ModelDownloader.IsModelDownloaded(model) {
+				state = "downloaded"
+			}
+			fmt.Printf("%s: %s\n", model.String(), state)
+		}
+		return nil
+	case "download":
+		var errs []error
+		for _, model := range models {
+			if ModelDownloader.IsModelDownloaded(model) {
+				fmt.Printf("%s: already downloaded\n", model.String())
+				continue
+			}
+			fmt.Printf("%s: downloading\n", model.String())
+			err = ModelDownloader.DownloadModelWithoutUI(model, printDownloadStatus)
+			fmt.Println()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", model.String(), err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+	return usageError("unknown models command %q", args[0])
+}
+
+// printDownloadStatus prints the progress of a download in one line.
+func printDownloadStatus(item Updater.DownloadItem) {
+	switch item.State {
+	case Updater.DownloadRunning:
+		if item.Total < 0 {
+			fmt.Printf("\r  %s from %s (%s)   ", humanize.Bytes(item.Progress), item.Source, item.SpeedString())
+			return
+		}
+		fmt.Printf("\r  %s / %s from %s (%s)   ", humanize.Bytes(item.Progress), humanize.Bytes(uint64(item.Total)), item.Source, item.SpeedString())
+	case Updater.DownloadVerifying:
+		fmt.Print("\n  checking checksum...")
+	case Updater.DownloadExtracting:
+		if item.Progress == 0 {
+			fmt.Print("\n  extracting...")
+		} else if item.Total > 0 {
+			fmt.Printf("\r  extracting... %s / %s   ", humanize.Bytes(item.Progress), humanize.Bytes(uint64(item.Total)))
+		} else {
+			fmt.Printf("\r  extracting... %s   ", humanize.Bytes(item.Progress))
+		}
+	case Updater.DownloadFinished:
+		fmt.Print("\n  finished.")
+	}
+}
@@ -0,0 +1,145 @@
+package Cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"whispering-tiger-ui/Pages/ProfileSettings"
+	"whispering-tiger-ui/Profiles"
+	"whispering-tiger-ui/Secrets"
+	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/Utilities"
+)
+
+// profileFileName returns the file name of a profile, which can be given with or without extension.
+func profileFileName(name string) string {
+	name = filepath.Base(name)
+	if strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml") {
+		return name
+	}
+	return name + ".yaml"
+}
+
+// deviceIndex returns the audio device index of a profile setting, or -1 for the default device.
+func deviceIndex(value interface{}) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case string:
+		if index, err := strconv.Atoi(v); err == nil {
+			return index
+		}
+	}
+	return -1
+}
+
+// secretsPassphraseEnv can contain the passphrase of the encrypted secrets file, since there is no UI to ask for it.
+const secretsPassphraseEnv = "WT_SECRETS_PASSPHRASE"
+
+func unlockSecrets() {
+	if Secrets.CurrentStore() != Secrets.Store(Secrets.FileStore) || !Secrets.FileStore.Exists() || !Secrets.FileStore.IsLocked() {
+		return
+	}
+	passphrase, ok := os.LookupEnv(secretsPassphraseEnv)
+	if !ok {
+		log.Printf("secrets file is locked, set %s to unlock it", secretsPassphraseEnv)
+		return
+	}
+	if err := Secrets.FileStore.Unlock(passphrase); err != nil {
+		log.Printf("failed to unlock secrets file: %v", err)
+	}
+}
+
+// LoadProfile loads and validates a profile of the profiles directory, like the profile window does when it is started.
+func LoadProfile(name string) (*Settings.Conf, error) {
+	Utilities.MigrateProfileSettingsLocation1704429446()
+	unlockSecrets()
+
+	fileName := profileFileName(name)
+	profileFile := filepath.Join(Settings.GetConfProfileDir(), fileName)
+	if !Utilities.FileExists(profileFile) {
+		return nil, fmt.Errorf("profile %s not found in %s", fileName, Settings.GetConfProfileDir())
+	}
+
+	conf := ProfileSettings.DefaultProfileSetting
+	if err := conf.LoadYamlSettings(profileFile); err != nil {
+		return nil, err
+	}
+	if validationErrors := conf.Validate(); validationErrors != nil {
+		return nil, fmt.Errorf("profile %s is invalid: %w", fileName, validationErrors)
+	}
+	conf.SettingsFilename = fileName
+	conf.Device_index = deviceIndex(conf.Device_index)
+	conf.Device_out_index = deviceIndex(conf.Device_out_index)
+	return &conf, nil
+}
+
+// profileNames returns the profile files given as arguments, or all profiles if none are given.
+func profileNames(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return Profiles.ListProfiles(Settings.GetConfProfileDir())
+	}
+	var fileNames []string
+	for _, arg := range args {
+		fileNames = append(fileNames, profileFileName(arg))
+	}
+	return fileNames, nil
+}
+
+func runProfilesCommand(args []string) error {
+	if len(args) == 0 {
+		return usageError("missing profiles command")
+	}
+	switch args[0] {
+	case "list":
+		fileNames, err := Profiles.ListProfiles(Settings.GetConfProfileDir())
+		if err != nil {
+			return err
+		}
+		for _, fileName := range fileNames {
+			fmt.Println(fileName)
+		}
+		return nil
+	case "validate":
+		fileNames, err := profileNames(args[1:])
+		if err != nil {
+			return err
+		}
+		var errs []error
+		for _, fileName := range fileNames {
+			if _, err = LoadProfile(fileName); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			fmt.Printf("%s: ok\n", fileName)
+		}
+		return errors.Join(errs...)
+	case "migrate":
+		flags := flag.NewFlagSet("profiles migrate", flag.ContinueOnError)
+		flags.SetOutput(io.Discard)
+		dryRun := flags.Bool("dry-run", false, "")
+		if err := flags.Parse(args[1:]); err != nil {
+			return usageError("%v", err)
+		}
+		fileNames, err := profileNames(flags.Args())
+		if err != nil {
+			return err
+		}
+		reports, err := Profiles.MigrateProfiles(Settings.GetConfProfileDir(), fileNames, *dryRun)
+		for _, report := range reports {
+			if !report.NeedsMigration() {
+				fmt.Printf("%s: up to date (schema version %d)\n", filepath.Base(report.File), report.FromVersion)
+				continue
+			}
+			fmt.Print(report.String())
+		}
+		return err
+	}
+	return usageError("unknown profiles command %q", args[0])
+}
@@ -4,11 +4,23 @@ import (
 	"fyne.io/fyne/v2/data/binding"
 )
 
+type WhisperResultSegment struct {
+	Start   float64 `json:"start"`
+	End     float64 `json:"end"`
+	Text    string  `json:"text"`
+	Speaker string  `json:"speaker,omitempty"`
+}
+
 type WhisperResult struct {
-	Text                 string `json:"text"`
-	Language             string `json:"language"`
-	TxtTranslation       string `json:"txt_translation,omitempty"`
-	TxtTranslationTarget string `json:"txt_translation_target,omitempty"`
+	Text                 string                 `json:"text"`
+	Language             string                 `json:"language"`
+	TxtTranslation       string                 `json:"txt_translation,omitempty"`
+	TxtTranslationTarget string                 `json:"txt_translation_target,omitempty"`
+	Speaker              string                 `json:"speaker,omitempty"`
+	Start                float64                `json:"start,omitempty"`
+	End                  float64                `json:"end,omitempty"`
+	Segments             []WhisperResultSegment `json:"segments,omitempty"`
+	Words                []WhisperResultWord    `json:"words,omitempty"`
 }
 
 var DataBindings = struct {
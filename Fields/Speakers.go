@@ -0,0 +1,175 @@
+package Fields
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+	"sync"
+	"whispering-tiger-ui/Utilities"
+)
+
+// speaker colors used to color-code speakers in the result list
+var speakerColors = []color.NRGBA{
+	{R: 0x4f, G: 0xa3, B: 0xe0, A: 0xff},
+	{R: 0xe0, G: 0x8a, B: 0x2e, A: 0xff},
+	{R: 0x6a, G: 0xbf, B: 0x4b, A: 0xff},
+	{R: 0xd9, G: 0x5b, B: 0x8c, A: 0xff},
+	{R: 0xa0, G: 0x7c, B: 0xe0, A: 0xff},
+	{R: 0x3c, G: 0xbf, B: 0xae, A: 0xff},
+	{R: 0xd6, G: 0xc1, B: 0x3a, A: 0xff},
+	{R: 0xc2, G: 0x5b, B: 0x4a, A: 0xff},
+}
+
+// SpeakerNames maps speaker ids (like "SPEAKER_01") to user defined names for the current session.
+var SpeakerNames = struct {
+	sync.RWMutex
+	names map[string]string
+	order []string
+}{
+	names: map[string]string{},
+}
+
+// RegisterSpeaker remembers a speaker id so it gets a stable color and shows up in the rename dialog.
+func RegisterSpeaker(speakerId string) {
+	if speakerId == "" {
+		return
+	}
+	SpeakerNames.Lock()
+	defer SpeakerNames.Unlock()
+	for _, id := range SpeakerNames.order {
+		if id == speakerId {
+			return
+		}
+	}
+	SpeakerNames.order = append(SpeakerNames.order, speakerId)
+}
+
+// KnownSpeakers returns all speaker ids seen in this session, in order of appearance.
+func KnownSpeakers() []string {
+	SpeakerNames.RLock()
+	defer SpeakerNames.RUnlock()
+	speakers := make([]string, len(SpeakerNames.order))
+	copy(speakers, SpeakerNames.order)
+	return speakers
+}
+
+func SetSpeakerName(speakerId string, name string) {
+	SpeakerNames.Lock()
+	defer SpeakerNames.Unlock()
+	name = strings.TrimSpace(name)
+	if name == "" || name == speakerId {
+		delete(SpeakerNames.names, speakerId)
+		return
+	}
+	SpeakerNames.names[speakerId] = name
+}
+
+// SpeakerNameMap returns a copy of the user defined speaker names of the current session.
+func SpeakerNameMap() map[string]string {
+	SpeakerNames.RLock()
+	defer SpeakerNames.RUnlock()
+	names := make(map[string]string, len(SpeakerNames.names))
+	for speakerId, name := range SpeakerNames.names {
+		names[speakerId] = name
+	}
+	return names
+}
+
+// GetSpeakerName returns the user defined name of a speaker or the speaker id if it was not renamed.
+func GetSpeakerName(speakerId string) string {
+	SpeakerNames.RLock()
+	defer SpeakerNames.RUnlock()
+	if name, ok := SpeakerNames.names[speakerId]; ok {
+		return name
+	}
+	return speakerId
+}
+
+func GetSpeakerColor(speakerId string) color.Color {
+	SpeakerNames.RLock()
+	defer SpeakerNames.RUnlock()
+	for i, id := range SpeakerNames.order {
+		if id == speakerId {
+			return speakerColors[i%len(speakerColors)]
+		}
+	}
+	return color.NRGBA{R: 0xb2, G: 0xb2, B: 0xb2, A: 0xff}
+}
+
+// ResultSpeakers returns the speaker ids of a result, either from the result itself or from its segments.
+func (res WhisperResult) ResultSpeakers() []string {
+	var speakers []string
+	if res.Speaker != "" {
+		speakers = append(speakers, res.Speaker)
+	}
+	for _, segment := range res.Segments {
+		if segment.Speaker != "" && !Utilities.Contains(speakers, segment.Speaker) {
+			speakers = append(speakers, segment.Speaker)
+		}
+	}
+	return speakers
+}
+
+// FormatTimestamp formats seconds as mm:ss.t (or hh:mm:ss.t for longer sessions)
+func FormatTimestamp(seconds float64) string {
+	if seconds < 0 {
+		seconds = 0
+	}
+	totalTenths := int(seconds*10 + 0.5)
+	hours := totalTenths / 36000
+	minutes := (totalTenths / 600) % 60
+	secs := (totalTenths / 10) % 60
+	tenths := totalTenths % 10
+	if hours > 0 {
+		return fmt.Sprintf("%02d:%02d:%02d.%d", hours, minutes, secs, tenths)
+	}
+	return fmt.Sprintf("%02d:%02d.%d", minutes, secs, tenths)
+}
+
+// TranscriptLines returns the result as text lines using the session speaker names.
+// Results with speaker segments are split into one line per segment.
+func (res WhisperResult) TranscriptLines() []string {
+	var lines []string
+	if len(res.Segments) > 0 {
+		for _, segment := range res.Segments {
+			line := strings.TrimSpace(segment.Text)
+			if segment.Speaker != "" {
+				line = GetSpeakerName(segment.Speaker) + ": " + line
+			}
+			if segment.End > 0 {
+				line = "[" + FormatTimestamp(segment.Start) + " - " + FormatTimestamp(segment.End) + "] " + line
+			}
+			lines = append(lines, line)
+		}
+	} else {
+		line := res.Text
+		if res.Speaker != "" {
+			line = GetSpeakerName(res.Speaker) + ": " + line
+		}
+		if res.End > 0 {
+			line = "[" + FormatTimestamp(res.Start) + " - " + FormatTimestamp(res.End) + "] " + line
+		}
+		lines = append(lines, line)
+	}
+	if res.TxtTranslation != "" {
+		translationLine := res.TxtTranslation
+		if res.Speaker != "" {
+			translationLine = GetSpeakerName(res.Speaker) + ": " + translationLine
+		}
+		lines = append(lines, "  ["+res.TxtTranslationTarget+"] "+translationLine)
+	}
+	return lines
+}
+
+// ExportTranscriptText returns all results in chronological order as plain text.
+func ExportTranscriptText(results []WhisperResult) string {
+	// results are stored newest first
+	var builder strings.Builder
+	for i := len(results) - 1; i >= 0; i-- {
+		for _, line := range results[i].TranscriptLines() {
+			builder.WriteString(line)
+			builder.WriteString("\n")
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,53 @@
+package Fields
+
+import (
+	"fyne.io/fyne/v2"
+	"strings"
+	"unicode"
+)
+
+const DefaultLowConfidenceThreshold = 0.5
+
+type WhisperResultWord struct {
+	Word        string  `json:"word"`
+	Start       float64 `json:"start"`
+	End         float64 `json:"end"`
+	Probability float64 `json:"probability"`
+}
+
+// LowConfidenceThreshold returns the probability below which a word is highlighted as uncertain.
+func LowConfidenceThreshold() float64 {
+	return fyne.CurrentApp().Preferences().FloatWithFallback("LowConfidenceWordThreshold", DefaultLowConfidenceThreshold)
+}
+
+func (word WhisperResultWord) IsLowConfidence() bool {
+	return word.Probability > 0 && word.Probability < LowConfidenceThreshold()
+}
+
+// SetText replaces the word but keeps the surrounding whitespace of the original word,
+// so the full text can still be rebuilt by concatenating all words.
+func (word *WhisperResultWord) SetText(text string) {
+	leading := word.Word[:len(word.Word)-len(strings.TrimLeftFunc(word.Word, unicode.IsSpace))]
+	trailing := word.Word[len(strings.TrimRightFunc(word.Word, unicode.IsSpace)):]
+	word.Word = leading + strings.TrimSpace(text) + trailing
+	// a corrected word is considered to be correct
+	word.Probability = 1
+}
+
+// TextFromWords rebuilds the transcription text from the word list.
+func (res WhisperResult) TextFromWords() string {
+	var builder strings.Builder
+	for _, word := range res.Words {
+		builder.WriteString(word.Word)
+	}
+	return strings.TrimSpace(builder.String())
+}
+
+func (res WhisperResult) HasLowConfidenceWords() bool {
+	for _, word := range res.Words {
+		if word.IsLowConfidence() {
+			return true
+		}
+	}
+	return false
+}
@@ -1,203 +1,163 @@
 package ModelDownloader
 
 import (
+	"errors"
 	"fmt"
-	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
-	"fyne.io/fyne/v2/widget"
-	"github.com/dustin/go-humanize"
-	"math/rand"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
-	"time"
 	"whispering-tiger-ui/Updater"
 	"whispering-tiger-ui/Utilities"
 )
 
 const rootCacheFolder = ".cache"
 
+// DownloadFile downloads a file with the download manager and waits until it is done. Errors are shown in a dialog.
 func DownloadFile(urls []string, targetDir string, checksum string, title string, extractFormat string) error {
-	// find active window
-	window := Utilities.GetCurrentMainWindow("Downloading " + title)
-
-	// select download url
-	randomUrlIndex := rand.Int() % len(urls)
-	downloadUrl := urls[randomUrlIndex]
-
-	// get file name from download url
-	filename := downloadUrl[strings.LastIndex(downloadUrl, "/")+1:]
-
-	// create download dialog
-	statusBar := widget.NewProgressBar()
-	statusBarContainer := container.NewVBox(statusBar)
+	return showDownloadError(Download(urls, targetDir, checksum, title, extractFormat, nil), title)
+}
 
-	dialogTitlePart := filename
-	if title != "" {
-		dialogTitlePart = title + " [" + filename + "]"
+func showDownloadError(err error, title string) error {
+	if err != nil && !errors.Is(err, Updater.ErrDownloadCanceled) && !errors.Is(err, Updater.ErrDownloadPaused) {
+		dialog.ShowError(err, Utilities.GetCurrentMainWindow("Downloading "+title))
 	}
-	downloadDialog := dialog.NewCustom("Downloading "+dialogTitlePart, "Hide (Download will continue)", statusBarContainer, window)
-	downloadDialog.Show()
-	downloadingLabel := widget.NewLabel("Downloading... ")
+	return err
+}
 
-	reOpenAfterHide := false
-	downloadDialog.SetOnClosed(func() {
-		reOpenAfterHide = true
-	})
+// Download downloads (and extracts) a file with the download manager without showing any UI and waits until it is done.
+// onChange is called with the progress of the download (can be nil).
+func Download(urls []string, targetDir string, checksum string, title string, extractFormat string, onChange func(item Updater.DownloadItem)) error {
+	return download(Updater.DownloadRequest{
+		Title:         title,
+		Urls:          urls,
+		Filepath:      targetDir,
+		Checksum:      checksum,
+		ExtractFormat: extractFormat,
+	}, onChange)
+}
 
-	// is filename a zip file?
-	needsExtract := false
-	extractType := ""
-	if strings.HasSuffix(filename, ".zip") {
-		needsExtract = true
-		extractType = "zip"
-	} else if strings.HasSuffix(filename, ".tar.gz") {
-		needsExtract = true
-		extractType = "tar.gz"
+// download adds the request to the download manager with a finished marker and records the download in the registry.
+func download(request Updater.DownloadRequest, onChange func(item Updater.DownloadItem)) error {
+	if len(request.Urls) == 0 {
+		return errors.New("no download url")
 	}
-	if extractFormat != "" {
-		needsExtract = true
-		extractType = extractFormat
-	} else if extractFormat == "none" {
-		needsExtract = false
-		extractType = ""
+	if request.Title == "" {
+		request.Title = request.Urls[0][strings.LastIndex(request.Urls[0], "/")+1:]
 	}
+	request.FinishedMarker = true
+	request.Priority = Updater.PriorityNormal
+	err := Updater.Downloads.Download(request, onChange)
+	if err == nil {
+		recordDownload(request.Urls, request.Filepath, request.Checksum, request.Title, request.ExtractFormat)
+	}
+	return err
+}
 
-	// get subdomain from download url
-	subdomain := downloadUrl[strings.Index(downloadUrl, "://")+3 : strings.Index(downloadUrl, ".")]
-
-	//appExec, _ := os.Executable()
+// ModelReference names a downloadable model by its entry and type in the model list (e.g. "WhisperCT2" "small_float16").
+type ModelReference struct {
+	Name string `json:"name" yaml:"name"`
+	Type string `json:"type" yaml:"type"`
+}
 
-	downloadTargetDir := filepath.Dir(targetDir)
-	os.MkdirAll(downloadTargetDir, 0755)
-	//downloadTargetFile := filepath.Join(downloadTargetDir, filename)
+func (m ModelReference) String() string {
+	return m.Name + " " + m.Type
+}
 
-	// create downloader
-	downloader := Updater.Download{
-		Url:                 downloadUrl,
-		FallbackUrls:        urls,
-		Filepath:            targetDir,
-		ConcurrentDownloads: 4,
-		ChunkSize:           15 * 1024 * 1024, // 15 MB
+func (c modelNameLinksMap) modelLinks(modelName string, modelType string) (*modelNameLinks, *modelLink, error) {
+	modelNameLinks, ok := c[modelName]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown model %s", modelName)
 	}
-	downloader.WriteCounter.OnProgress = func(progress, total uint64, speed float64) {
-		if int64(total) == -1 {
-			statusBarContainer.Remove(statusBar)
-			statusBarContainer.Add(widget.NewProgressBarInfinite())
-			statusBarContainer.Refresh()
-		} else {
-			statusBar.Max = float64(total)
-			statusBar.SetValue(float64(progress))
-
-			resumeStatusText := ""
-			if downloader.IsResuming() {
-				resumeStatusText = " (Resume)"
-			}
-
-			speedStr := ""
-			if speed < 1024 {
-				speedStr = fmt.Sprintf("%.2f B/s", speed)
-			} else if speed < 1024*1024 {
-				speedStr = fmt.Sprintf("%.2f KiB/s", speed/1024)
-			} else {
-				speedStr = fmt.Sprintf("%.2f MiB/s", speed/(1024*1024))
-			}
-
-			downloadingLabel.SetText("Downloading from " + subdomain + "... " + humanize.Bytes(total) + " (" + speedStr + ") " + resumeStatusText)
-		}
+	modelLinks, ok := modelNameLinks.modelLink[modelType]
+	if !ok || len(modelLinks.urls) == 0 {
+		return nil, nil, fmt.Errorf("unknown model type %s of model %s", modelType, modelName)
 	}
+	return modelNameLinks, modelLinks, nil
+}
 
-	statusBarContainer.Add(downloadingLabel)
-	statusBarContainer.Refresh()
-	err := downloader.DownloadFile(3)
+// modelTargetFile returns the file the model is downloaded to.
+func (c modelNameLinksMap) modelTargetFile(modelName string, modelType string) (string, error) {
+	modelNameLinks, modelLinks, err := c.modelLinks(modelName, modelType)
 	if err != nil {
-		dialog.ShowError(err, window)
-		return err
+		return "", err
 	}
+	downloadUrl := modelLinks.urls[0]
+	filename := downloadUrl[strings.LastIndex(downloadUrl, "/")+1:]
+	return filepath.Join(rootCacheFolder, modelNameLinks.cachePath, filename), nil
+}
 
-	// check if the file has the correct hash
-	if checksum != "" {
-		statusBarContainer.Add(widget.NewLabel("Checking checksum..."))
-		if err := Updater.CheckFileHash(targetDir, checksum); err != nil {
-			fmt.Printf("Error: %s\n", err.Error())
-			dialog.ShowError(err, window)
-			statusBarContainer.Add(widget.NewLabel("Checksum check failed. Please delete temporary file and download again. If it still fails, please contact support."))
-			return err
-		}
+// modelRequest returns the download request of a model.
+func (c modelNameLinksMap) modelRequest(modelName string, modelType string) (Updater.DownloadRequest, error) {
+	// get model links from map
+	_, modelLinks, err := c.modelLinks(modelName, modelType)
+	if err != nil {
+		return Updater.DownloadRequest{}, err
 	}
-
-	// wait a bit before trying to extract
-	if needsExtract {
-		time.Sleep(1 * time.Second)
-
-		statusBarContainer.Add(widget.NewLabel("Extracting..."))
-		statusBarContainer.Refresh()
-		if extractType == "zip" {
-			err = Updater.Unzip(targetDir, downloadTargetDir)
-		} else if extractType == "tar.gz" {
-			err = Updater.Untar(targetDir, downloadTargetDir)
-		}
-		if err != nil {
-			dialog.ShowError(err, window)
-			return err
-		}
-		err = downloader.CreateFinishedFile(".finished", 5, 3*time.Second)
-		if err != nil {
-			dialog.ShowError(err, window)
-			return err
-		}
-
-		//err = os.Rename(targetDir, targetDir+".finished")
-		//if err != nil {
-		//	dialog.ShowError(err, window)
-		//	return err
-		//}
-
-		//err = os.Remove(downloadTargetFile)
-		//if err != nil {
-		//	dialog.ShowError(err, window)
-		//	return err
-		//}
-	} else {
-		//err = os.Rename(targetDir, targetDir+".finished")
-		err = downloader.CreateFinishedFile(".finished", 5, 3*time.Second)
-		if err != nil {
-			dialog.ShowError(err, window)
-			return err
-		}
+	targetFile, err := c.modelTargetFile(modelName, modelType)
+	if err != nil {
+		return Updater.DownloadRequest{}, err
 	}
+	return Updater.DownloadRequest{
+		Title:         modelName + " " + modelType,
+		Urls:          modelLinks.urls,
+		Filepath:      targetFile,
+		Checksum:      modelLinks.checksum,
+		ExtractedSize: modelLinks.extractedSize,
+	}, nil
+}
 
-	if err == nil {
-		statusBarContainer.Add(widget.NewLabel("Finished."))
-		downloadDialog.SetDismissText("Close")
-		downloadDialog.Refresh()
-		if reOpenAfterHide {
-			downloadDialog.Show()
-		} else {
-			downloadDialog.Hide()
-		}
+func (c modelNameLinksMap) DownloadModel(modelName string, modelType string) error {
+	request, err := c.modelRequest(modelName, modelType)
+	if err != nil {
+		return err
 	}
-
-	statusBarContainer.Refresh()
-
-	return nil
+	return showDownloadError(download(request, nil), request.Title)
 }
 
-func (c *modelNameLinksMap) DownloadModel(modelName string, modelType string) error {
-	// get model links from map
-	modelLinksMap := (*c)[modelName].modelLink
-	modelCachePath := (*c)[modelName].cachePath
+// ModelExists returns true if the model is part of the model list.
+func ModelExists(model ModelReference) bool {
+	_, _, err := currentModelLinks().modelLinks(model.Name, model.Type)
+	return err == nil
+}
 
-	modelLinks := modelLinksMap[modelType]
-	modelChecksum := modelLinks.checksum
+// IsModelDownloaded returns true if the model was downloaded completely.
+func IsModelDownloaded(model ModelReference) bool {
+	targetFile, err := currentModelLinks().modelTargetFile(model.Name, model.Type)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(targetFile + ".finished")
+	return err == nil
+}
 
-	// find active window
-	window := Utilities.GetCurrentMainWindow("Downloading " + modelName + " " + modelType)
+// DownloadModel downloads a model of the model list.
+func DownloadModel(model ModelReference) error {
+	return currentModelLinks().DownloadModel(model.Name, model.Type)
+}
 
-	err := DownloadFile(modelLinks.urls, modelCachePath, modelChecksum, modelName+" "+modelType, "")
+// DownloadModelWithoutUI downloads a model of the model list and reports the progress to onChange instead of a dialog.
+func DownloadModelWithoutUI(model ModelReference, onChange func(item Updater.DownloadItem)) error {
+	request, err := currentModelLinks().modelRequest(model.Name, model.Type)
 	if err != nil {
-		dialog.ShowError(err, window)
+		return err
 	}
+	return download(request, onChange)
+}
 
-	return err
+// OfflineModels returns the models of the model list which are in the offline source (see Updater.Offline).
+func OfflineModels() []ModelReference {
+	var models []ModelReference
+	for modelName, modelNameLinks := range currentModelLinks() {
+		for modelType, modelLinks := range modelNameLinks.modelLink {
+			if _, ok := Updater.Offline.Find(modelLinks.urls, modelLinks.checksum); ok {
+				models = append(models, ModelReference{Name: modelName, Type: modelType})
+			}
+		}
+	}
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].String() < models[j].String()
+	})
+	return models
 }
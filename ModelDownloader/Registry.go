@@ -0,0 +1,423 @@
+package ModelDownloader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"whispering-tiger-ui/Updater"
+	"whispering-tiger-ui/Utilities"
+)
+
+// The registry remembers what is known about the downloaded models (where they came from, their checksum and when
+// they were used). Models in the cache folder which were not downloaded by the UI are listed without that information.
+
+const registryFileName = "models.json"
+
+type ModelStatus string
+
+const (
+	ModelStatusUnverified ModelStatus = "unverified"
+	ModelStatusOk         ModelStatus = "ok"
+	ModelStatusCorrupt    ModelStatus = "corrupt"
+	ModelStatusIncomplete ModelStatus = "incomplete"
+	// ModelStatusUnknown is used for models without a known checksum
+	ModelStatusUnknown ModelStatus = "unknown"
+)
+
+// modelRecord is the registry entry of a downloaded model.
+type modelRecord struct {
+	Title         string          `json:"title"`
+	File          string          `json:"file"`
+	Checksum      string          `json:"checksum,omitempty"`
+	Urls          []string        `json:"urls,omitempty"`
+	ExtractFormat string          `json:"extract_format,omitempty"`
+	Model         *ModelReference `json:"model,omitempty"`
+	// Extracted are the files and directories which were extracted from the archive (next to File)
+	Extracted   []string  `json:"extracted,omitempty"`
+	Downloaded  time.Time `json:"downloaded,omitempty"`
+	LastUsed    time.Time `json:"last_used,omitempty"`
+	Verified    time.Time `json:"verified,omitempty"`
+	VerifyError string    `json:"verify_error,omitempty"`
+}
+
+// InstalledModel is a model (or any other file or directory) in the cache folder.
+type InstalledModel struct {
+	Title string
+	// Category is the directory in the cache folder (e.g. "whisper")
+	Category string
+	// File is the downloaded file (empty if the model was not downloaded by the UI)
+	File string
+	// Paths are all files and directories which belong to the model
+	Paths         []string
+	Size          int64
+	Checksum      string
+	Urls          []string
+	ExtractFormat string
+	// Model is set if the model is part of the model list
+	Model       *ModelReference
+	Downloaded  time.Time
+	LastUsed    time.Time
+	Verified    time.Time
+	VerifyError string
+	Status      ModelStatus
+}
+
+// CanVerify reports if the downloaded file and its checksum are known.
+func (m InstalledModel) CanVerify() bool {
+	return m.File != "" && m.Checksum != "" && Utilities.FileExists(m.File)
+}
+
+// CanRepair reports if the model can be downloaded again.
+func (m InstalledModel) CanRepair() bool {
+	return m.File != "" && len(m.Urls) > 0
+}
+
+var registryMutex sync.Mutex
+
+func registryKey(file string) string {
+	return filepath.ToSlash(filepath.Clean(file))
+}
+
+func loadRegistryLocked() map[string]*modelRecord {
+	records := map[string]*modelRecord{}
+	data, err := os.ReadFile(filepath.Join(rootCacheFolder, registryFileName))
+	if err != nil {
+		return records
+	}
+	var list []*modelRecord
+	if err = json.Unmarshal(data, &list); err != nil {
+		log.Printf("failed to read model registry: %v", err)
+		return records
+	}
+	for _, record := range list {
+		records[registryKey(record.File)] = record
+	}
+	return records
+}
+
+func saveRegistryLocked(records map[string]*modelRecord) {
+	list := make([]*modelRecord, 0, len(records))
+	for _, record := range records {
+		list = append(list, record)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].File < list[j].File
+	})
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(rootCacheFolder, 0755); err == nil {
+			err = os.WriteFile(filepath.Join(rootCacheFolder, registryFileName), data, 0644)
+		}
+	}
+	if err != nil {
+		log.Printf("failed to save model registry: %v", err)
+	}
+}
+
+// listModelRecord returns a registry entry for a file of the model list (nil if the file is not part of it).
+func listModelRecord(file string) *modelRecord {
+	for modelName, modelNameLinks := range currentModelLinks() {
+		for modelType, modelLinks := range modelNameLinks.modelLink {
+			targetFile, err := currentModelLinks().modelTargetFile(modelName, modelType)
+			if err != nil || registryKey(targetFile) != registryKey(file) {
+				continue
+			}
+			return &modelRecord{
+				Title:    modelName + " " + modelType,
+				File:     targetFile,
+				Checksum: modelLinks.checksum,
+				Urls:     modelLinks.urls,
+				Model:    &ModelReference{Name: modelName, Type: modelType},
+			}
+		}
+	}
+	return nil
+}
+
+// updateRecord changes the registry entry of the file. A missing entry is created (from the model list if possible).
+func updateRecord(file string, update func(record *modelRecord)) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	records := loadRegistryLocked()
+	key := registryKey(file)
+	record, ok := records[key]
+	if !ok {
+		record = listModelRecord(file)
+		if record == nil {
+			record = &modelRecord{Title: filepath.Base(file), File: file}
+		}
+		records[key] = record
+	}
+	update(record)
+	saveRegistryLocked(records)
+}
+
+// recordDownload adds a finished download to the registry.
+func recordDownload(urls []string, file string, checksum string, title string, extractFormat string) {
+	var extracted []string
+	if format := (Updater.DownloadRequest{Urls: urls, Filepath: file, ExtractFormat: extractFormat}).ExtractType(); format != "" {
+		var err error
+		if extracted, err = Updater.ArchiveTopLevelEntries(file, format); err != nil {
+			log.Printf("failed to list extracted files of %s: %v", file, err)
+		}
+	}
+	updateRecord(file, func(record *modelRecord) {
+		record.Title = title
+		record.Checksum = checksum
+		record.Urls = urls
+		record.ExtractFormat = extractFormat
+		record.Extracted = extracted
+		record.Downloaded = time.Now()
+		if checksum != "" {
+			// the download manager checked the hash
+			record.Verified = time.Now()
+			record.VerifyError = ""
+		}
+	})
+}
+
+// MarkModelsUsed sets the last used date of the downloaded models (e.g. when a profile using them is started).
+func MarkModelsUsed(models []ModelReference) {
+	for _, model := range models {
+		if !IsModelDownloaded(model) {
+			continue
+		}
+		targetFile, err := currentModelLinks().modelTargetFile(model.Name, model.Type)
+		if err != nil {
+			continue
+		}
+		updateRecord(targetFile, func(record *modelRecord) {
+			record.LastUsed = time.Now()
+		})
+	}
+}
+
+func pathSize(path string) int64 {
+	var size int64
+	_ = filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil && !entry.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+func lastModified(paths []string) time.Time {
+	var modified time.Time
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+	}
+	return modified
+}
+
+// ScanInstalledModels lists the models in the cache folder.
+func ScanInstalledModels() ([]InstalledModel, error) {
+	registryMutex.Lock()
+	records := loadRegistryLocked()
+	registryMutex.Unlock()
+
+	// all files which are known to belong to a model
+	known := map[string]*modelRecord{}
+	for modelName, modelNameLinks := range currentModelLinks() {
+		for modelType := range modelNameLinks.modelLink {
+			if targetFile, err := currentModelLinks().modelTargetFile(modelName, modelType); err == nil {
+				known[registryKey(targetFile)] = listModelRecord(targetFile)
+			}
+		}
+	}
+	for key, record := range records {
+		known[key] = record
+	}
+
+	categories, err := os.ReadDir(rootCacheFolder)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var models []InstalledModel
+	for _, category := range categories {
+		if !category.IsDir() {
+			continue
+		}
+		categoryDir := filepath.Join(rootCacheFolder, category.Name())
+		claimed := map[string]bool{}
+
+		for key, record := range known {
+			relPath, err := filepath.Rel(categoryDir, filepath.FromSlash(key))
+			if err != nil || strings.HasPrefix(relPath, "..") {
+				continue
+			}
+			fileExists := Utilities.FileExists(record.File)
+			finished := Utilities.FileExists(record.File + ".finished")
+			if !fileExists && !finished {
+				continue
+			}
+			// a model in a sub directory claims the whole directory
+			claimed[strings.Split(filepath.ToSlash(relPath), "/")[0]] = true
+
+			model := InstalledModel{
+				Title:         record.Title,
+				Category:      category.Name(),
+				File:          record.File,
+				Checksum:      record.Checksum,
+				Urls:          record.Urls,
+				ExtractFormat: record.ExtractFormat,
+				Model:         record.Model,
+				Downloaded:    record.Downloaded,
+				LastUsed:      record.LastUsed,
+				Verified:      record.Verified,
+				VerifyError:   record.VerifyError,
+			}
+			for _, path := range []string{record.File, record.File + ".finished"} {
+				if Utilities.FileExists(path) {
+					model.Paths = append(model.Paths, path)
+					if filepath.Dir(path) == categoryDir {
+						claimed[filepath.Base(path)] = true
+					}
+				}
+			}
+
+			extracted := record.Extracted
+			if extracted == nil && fileExists {
+				// models which were downloaded before the registry existed
+				if format := (Updater.DownloadRequest{Urls: record.Urls, Filepath: record.File, ExtractFormat: record.ExtractFormat}).ExtractType(); format != "" {
+					extracted, _ = Updater.ArchiveTopLevelEntries(record.File, format)
+				}
+			}
+			for _, name := range extracted {
+				if !filepath.IsLocal(name) {
+					continue
+				}
+				path := filepath.Join(filepath.Dir(record.File), name)
+				if _, err := os.Stat(path); err == nil {
+					model.Paths = append(model.Paths, path)
+					if filepath.Dir(record.File) == categoryDir {
+						claimed[name] = true
+					}
+				}
+			}
+
+			for _, path := range model.Paths {
+				model.Size += pathSize(path)
+			}
+			if model.Downloaded.IsZero() {
+				model.Downloaded = lastModified(model.Paths)
+			}
+			switch {
+			case !finished:
+				model.Status = ModelStatusIncomplete
+			case model.Checksum == "" || !fileExists:
+				model.Status = ModelStatusUnknown
+			case model.VerifyError != "":
+				model.Status = ModelStatusCorrupt
+			case model.Verified.IsZero() || lastModified([]string{model.File}).After(model.Verified):
+				model.Status = ModelStatusUnverified
+			default:
+				model.Status = ModelStatusOk
+			}
+			models = append(models, model)
+		}
+
+		// everything else in the cache folder (custom models or models downloaded by the backend)
+		entries, err := os.ReadDir(categoryDir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if claimed[entry.Name()] || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			path := filepath.Join(categoryDir, entry.Name())
+			models = append(models, InstalledModel{
+				Title:      entry.Name(),
+				Category:   category.Name(),
+				Paths:      []string{path},
+				Size:       pathSize(path),
+				Downloaded: lastModified([]string{path}),
+				Status:     ModelStatusUnknown,
+			})
+		}
+	}
+
+	sort.Slice(models, func(i, j int) bool {
+		if models[i].Category != models[j].Category {
+			return models[i].Category < models[j].Category
+		}
+		return strings.ToLower(models[i].Title) < strings.ToLower(models[j].Title)
+	})
+	return models, nil
+}
+
+// VerifyModel checks the hash of the downloaded file and remembers the result.
+func VerifyModel(model InstalledModel) error {
+	if !model.CanVerify() {
+		return errors.New("the checksum of the model is unknown")
+	}
+	err := Updater.CheckFileHash(model.File, model.Checksum)
+	updateRecord(model.File, func(record *modelRecord) {
+		record.Verified = time.Now()
+		record.VerifyError = ""
+		if err != nil {
+			record.VerifyError = err.Error()
+		}
+	})
+	return err
+}
+
+// RepairModel downloads the model again.
+func RepairModel(model InstalledModel) error {
+	if !model.CanRepair() {
+		return errors.New("the download url of the model is unknown")
+	}
+	for _, path := range []string{model.File, model.File + ".finished"} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return DownloadFile(model.Urls, model.File, model.Checksum, model.Title, model.ExtractFormat)
+}
+
+// DeleteModel removes all files of the model. Paths outside of the category directory of the model are refused.
+func DeleteModel(model InstalledModel) error {
+	if model.Category == "" || !filepath.IsLocal(model.Category) {
+		return fmt.Errorf("invalid model category %q", model.Category)
+	}
+	categoryDir := filepath.Join(rootCacheFolder, model.Category)
+	for _, path := range model.Paths {
+		relPath, err := filepath.Rel(categoryDir, path)
+		if err != nil || relPath == "." || !filepath.IsLocal(relPath) {
+			return fmt.Errorf("refusing to delete %s outside of %s", path, categoryDir)
+		}
+	}
+	for _, path := range model.Paths {
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	if model.File != "" {
+		registryMutex.Lock()
+		records := loadRegistryLocked()
+		if _, ok := records[registryKey(model.File)]; ok {
+			delete(records, registryKey(model.File))
+			saveRegistryLocked(records)
+		}
+		registryMutex.Unlock()
+	}
+	return nil
+}
@@ -118,6 +118,8 @@ func CreateAdvancedWindow() fyne.CanvasObject {
 		container.NewTabItem(lang.L("About Whispering Tiger"), buildAboutInfo()),
 		container.NewTabItem(lang.L("Advanced Settings"), settingsTabContent),
 		container.NewTabItem(lang.L("Logs"), logTabContent),
+		container.NewTabItem(lang.L("Downloads"), CreateDownloadsPanel("advanced tab")),
+		container.NewTabItem(lang.L("Models"), CreateModelManagerPanel()),
 	)
 	tabs.SetTabLocation(container.TabLocationLeading)
 
@@ -142,6 +142,13 @@ func BuildSinglePluginSettings(pluginClassName string, pluginAccordionItem *widg
 	if err != nil {
 		SettingsFile = Settings.Config
 	}
+	if pluginSettings, ok := appliedPluginSettings[pluginClassName]; ok {
+		if fileSettings, ok := SettingsFile.Plugin_settings.(map[string]interface{}); ok {
+			fileSettings[pluginClassName] = Settings.CopySettingValue(pluginSettings)
+		}
+		delete(appliedPluginSettings, pluginClassName)
+	}
+	lastPluginSettings = Settings.CopySettingValue(SettingsFile.Plugin_settings)
 
 	// plugin to window button
 	pluginToWindowButton := widget.NewButtonWithIcon("", theme.ViewFullScreenIcon(), nil)
@@ -622,10 +629,8 @@ func createSettingsFields(pluginSettings map[string]interface{}, settingName str
 			settingsFields = append(settingsFields, container.NewBorder(nil, nil, widget.NewLabel(settingName), nil, entry))
 		} else if v["type"] == "textfield" {
 			entry := widget.NewEntry()
-			if password, ok := v["password"].(bool); ok {
-				if password {
-					entry = widget.NewPasswordEntry()
-				}
+			if Settings.IsSecretPluginSetting(v) {
+				entry = widget.NewPasswordEntry()
 			}
 			entry.SetText(v["value"].(string))
 			entry.OnChanged = func(text string) {
@@ -925,6 +930,25 @@ func createSettingsFields(pluginSettings map[string]interface{}, settingName str
 	return settingsFields
 }
 
+// lastPluginSettings is a copy of the plugin settings before the last change, since the widgets change the settings map directly
+var lastPluginSettings interface{}
+
+// appliedPluginSettings are the plugin settings of an undo or redo by plugin class. The profile file might not
+// contain them yet, so they are used when the settings of the plugin are built again.
+var appliedPluginSettings = map[string]interface{}{}
+
+func init() {
+	Settings.Journal.OnApply("plugin_settings", func(name string, value interface{}) {
+		if name != "plugin_settings" {
+			return
+		}
+		lastPluginSettings = Settings.CopySettingValue(value)
+		if pluginSettings, ok := value.(map[string]interface{}); ok {
+			appliedPluginSettings = pluginSettings
+		}
+	})
+}
+
 // Helper function to update settings
 func updateSettings(SettingsFile Settings.Conf, pluginClassName string, pluginSettings map[string]interface{}) {
 	SettingsFile.Plugin_settings.(map[string]interface{})[pluginClassName] = pluginSettings
@@ -934,4 +958,7 @@ func updateSettings(SettingsFile Settings.Conf, pluginClassName string, pluginSe
 		Value: SettingsFile.Plugin_settings,
 	}
 	sendMessage.SendMessage()
+
+	Settings.Journal.Record("plugin_settings", lastPluginSettings, SettingsFile.Plugin_settings, pluginClassName)
+	lastPluginSettings = Settings.CopySettingValue(SettingsFile.Plugin_settings)
 }
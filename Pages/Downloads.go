@@ -0,0 +1,241 @@
+package Pages
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/dustin/go-humanize"
+	"strconv"
+	"sync"
+	"whispering-tiger-ui/CustomWidget"
+	"whispering-tiger-ui/Updater"
+	"whispering-tiger-ui/Utilities"
+)
+
+// bandwidth limits which can be selected in KiB/s (0 = unlimited)
+var downloadBandwidthLimits = []int{0, 1024, 2 * 1024, 5 * 1024, 10 * 1024, 25 * 1024, 50 * 1024}
+
+var (
+	downloadsWindow      fyne.Window
+	downloadsWindowMutex sync.Mutex
+)
+
+// ApplyDownloadPreferences applies the saved concurrency and bandwidth limits to the download manager.
+func ApplyDownloadPreferences() {
+	Updater.Downloads.SetMaxConcurrent(fyne.CurrentApp().Preferences().IntWithFallback("DownloadMaxConcurrent", 2))
+	Updater.Downloads.Limiter.SetLimit(int64(fyne.CurrentApp().Preferences().IntWithFallback("DownloadBandwidthLimit", 0)) * 1024)
+}
+
+func downloadStateText(item Updater.DownloadItem) string {
+	switch item.State {
+	case Updater.DownloadRunning:
+		if item.Total < 0 {
+			return humanize.Bytes(item.Progress) + " (" + item.SpeedString() + ")"
+		}
+		text := humanize.Bytes(item.Progress) + " / " + humanize.Bytes(uint64(item.Total)) + " (" + item.SpeedString() + ")"
+		if item.Source != "" {
+			text += " - " + item.Source
+		}
+		if item.Resuming {
+			text += " (" + lang.L("Resuming") + ")"
+		}
+		return text
+	case Updater.DownloadQueued:
+		return lang.L("Queued")
+	case Updater.DownloadVerifying:
+		return lang.L("Checking checksum...")
+	case Updater.DownloadExtracting:
+		if item.Total > 0 {
+			return lang.L("Extracting...") + " " + humanize.Bytes(item.Progress) + " / " + humanize.Bytes(uint64(item.Total))
+		} else if item.Progress > 0 {
+			return lang.L("Extracting...") + " " + humanize.Bytes(item.Progress)
+		}
+		return lang.L("Extracting...")
+	case Updater.DownloadPaused:
+		if item.Total > 0 {
+			return lang.L("Paused") + " - " + humanize.Bytes(item.Progress) + " / " + humanize.Bytes(uint64(item.Total))
+		}
+		return lang.L("Paused")
+	case Updater.DownloadFinished:
+		return lang.L("Finished") + " - " + item.Finished.Format("2006-01-02 15:04")
+	case Updater.DownloadCanceled:
+		return lang.L("Canceled")
+	case Updater.DownloadFailed:
+		return lang.L("Failed") + ": " + item.Error
+	}
+	return string(item.State)
+}
+
+// CreateDownloadsPanel lists all active and finished downloads of the download manager.
+// listenerKey must be unique for every panel which is shown at the same time.
+func CreateDownloadsPanel(listenerKey string) fyne.CanvasObject {
+	defer Utilities.PanicLogger()
+
+	var items []Updater.DownloadItem
+
+	downloadsList := widget.NewList(
+		func() int {
+			return len(items)
+		},
+		func() fyne.CanvasObject {
+			titleLabel := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+			titleLabel.Truncation = fyne.TextTruncateEllipsis
+			stateLabel := widget.NewLabel("")
+			stateLabel.Truncation = fyne.TextTruncateEllipsis
+			progressBar := widget.NewProgressBar()
+			buttons := container.NewHBox(
+				widget.NewButtonWithIcon("", theme.MoveUpIcon(), nil),
+				widget.NewButtonWithIcon("", theme.MediaPauseIcon(), nil),
+				widget.NewButtonWithIcon("", theme.CancelIcon(), nil),
+				widget.NewButtonWithIcon("", theme.DeleteIcon(), nil),
+			)
+			return container.NewBorder(nil, progressBar, nil, buttons, container.NewVBox(titleLabel, stateLabel))
+		},
+		func(id widget.ListItemID, object fyne.CanvasObject) {
+			// newest downloads first
+			item := items[len(items)-1-id]
+			row := object.(*fyne.Container)
+			labels := row.Objects[0].(*fyne.Container)
+			progressBar := row.Objects[1].(*widget.ProgressBar)
+			buttons := row.Objects[2].(*fyne.Container)
+
+			labels.Objects[0].(*widget.Label).SetText(item.Title)
+			labels.Objects[1].(*widget.Label).SetText(downloadStateText(item))
+			if item.Total > 0 {
+				progressBar.Max = float64(item.Total)
+				progressBar.SetValue(float64(item.Progress))
+			} else if item.State == Updater.DownloadFinished {
+				progressBar.Max = 1
+				progressBar.SetValue(1)
+			} else {
+				progressBar.Max = 1
+				progressBar.SetValue(0)
+			}
+
+			moveToFrontButton := buttons.Objects[0].(*widget.Button)
+			moveToFrontButton.OnTapped = func() {
+				Updater.Downloads.MoveToFront(item.ID)
+			}
+			pauseButton := buttons.Objects[1].(*widget.Button)
+			if item.State == Updater.DownloadPaused || item.State == Updater.DownloadFailed {
+				pauseButton.SetIcon(theme.MediaPlayIcon())
+				pauseButton.OnTapped = func() {
+					Updater.Downloads.Resume(item.ID)
+				}
+			} else {
+				pauseButton.SetIcon(theme.MediaPauseIcon())
+				pauseButton.OnTapped = func() {
+					Updater.Downloads.Pause(item.ID)
+				}
+			}
+			cancelButton := buttons.Objects[2].(*widget.Button)
+			cancelButton.OnTapped = func() {
+				Updater.Downloads.Cancel(item.ID)
+			}
+			removeButton := buttons.Objects[3].(*widget.Button)
+			removeButton.OnTapped = func() {
+				Updater.Downloads.Remove(item.ID)
+			}
+
+			if item.IsDone() {
+				moveToFrontButton.Hide()
+				cancelButton.Hide()
+				removeButton.Show()
+				if item.State == Updater.DownloadFailed {
+					pauseButton.Show()
+				} else {
+					pauseButton.Hide()
+				}
+			} else {
+				moveToFrontButton.Show()
+				pauseButton.Show()
+				cancelButton.Show()
+				removeButton.Hide()
+				if item.State != Updater.DownloadQueued {
+					moveToFrontButton.Hide()
+				}
+			}
+		},
+	)
+	downloadsList.OnSelected = func(id widget.ListItemID) {
+		downloadsList.UnselectAll()
+	}
+
+	// limits
+	var concurrentOptions []CustomWidget.TextValueOption
+	for i := 1; i <= 4; i++ {
+		concurrentOptions = append(concurrentOptions, CustomWidget.TextValueOption{Text: strconv.Itoa(i), Value: strconv.Itoa(i)})
+	}
+	concurrentSelect := CustomWidget.NewTextValueSelect("download_max_concurrent", concurrentOptions, func(option CustomWidget.TextValueOption) {
+		maxConcurrent, _ := strconv.Atoi(option.Value)
+		fyne.CurrentApp().Preferences().SetInt("DownloadMaxConcurrent", maxConcurrent)
+		Updater.Downloads.SetMaxConcurrent(maxConcurrent)
+	}, 0)
+	concurrentSelect.SetSelected(strconv.Itoa(Updater.Downloads.MaxConcurrent()))
+
+	var bandwidthOptions []CustomWidget.TextValueOption
+	for _, limit := range downloadBandwidthLimits {
+		text := lang.L("Unlimited")
+		if limit > 0 {
+			text = strconv.Itoa(limit/1024) + " MiB/s"
+		}
+		bandwidthOptions = append(bandwidthOptions, CustomWidget.TextValueOption{Text: text, Value: strconv.Itoa(limit)})
+	}
+	bandwidthSelect := CustomWidget.NewTextValueSelect("download_bandwidth_limit", bandwidthOptions, func(option CustomWidget.TextValueOption) {
+		limit, _ := strconv.Atoi(option.Value)
+		fyne.CurrentApp().Preferences().SetInt("DownloadBandwidthLimit", limit)
+		Updater.Downloads.Limiter.SetLimit(int64(limit) * 1024)
+	}, 0)
+	bandwidthSelect.SetSelected(strconv.FormatInt(Updater.Downloads.Limiter.Limit()/1024, 10))
+
+	clearButton := widget.NewButtonWithIcon(lang.L("Clear finished"), theme.ContentClearIcon(), func() {
+		Updater.Downloads.ClearDone()
+	})
+	emptyLabel := widget.NewLabel(lang.L("No downloads."))
+
+	refreshDownloads := func() {
+		items = Updater.Downloads.Items()
+		if len(items) > 0 {
+			emptyLabel.Hide()
+		} else {
+			emptyLabel.Show()
+		}
+		downloadsList.Refresh()
+	}
+	refreshDownloads()
+	Updater.Downloads.OnChanged(listenerKey, refreshDownloads)
+
+	limitsRow := container.NewHBox(
+		widget.NewLabel(lang.L("Parallel downloads")), concurrentSelect,
+		widget.NewLabel(lang.L("Bandwidth limit")), bandwidthSelect,
+	)
+	return container.NewBorder(
+		container.NewBorder(nil, nil, nil, clearButton, limitsRow),
+		nil, nil, nil,
+		container.NewStack(downloadsList, container.NewCenter(emptyLabel)),
+	)
+}
+
+// ShowDownloadsWindow opens the window with the list of downloads (or focuses it if it is already open).
+func ShowDownloadsWindow() {
+	downloadsWindowMutex.Lock()
+	defer downloadsWindowMutex.Unlock()
+	if downloadsWindow != nil {
+		downloadsWindow.Show()
+		downloadsWindow.RequestFocus()
+		return
+	}
+	downloadsWindow = fyne.CurrentApp().NewWindow(lang.L("Downloads"))
+	downloadsWindow.SetContent(CreateDownloadsPanel("downloads window"))
+	downloadsWindow.Resize(fyne.NewSize(800, 400))
+	downloadsWindow.SetOnClosed(func() {
+		Updater.Downloads.OnChanged("downloads window", nil)
+		downloadsWindowMutex.Lock()
+		downloadsWindow = nil
+		downloadsWindowMutex.Unlock()
+	})
+	downloadsWindow.CenterOnScreen()
+	downloadsWindow.Show()
+}
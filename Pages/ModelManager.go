@@ -0,0 +1,199 @@
+package Pages
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/dustin/go-humanize"
+	"strings"
+	"sync"
+	"time"
+	"whispering-tiger-ui/ModelDownloader"
+	"whispering-tiger-ui/Profiles"
+	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/Utilities"
+)
+
+func modelStatusText(model ModelDownloader.InstalledModel) string {
+	switch model.Status {
+	case ModelDownloader.ModelStatusOk:
+		return lang.L("Checksum OK")
+	case ModelDownloader.ModelStatusCorrupt:
+		return lang.L("Checksum mismatch")
+	case ModelDownloader.ModelStatusIncomplete:
+		return lang.L("Incomplete")
+	case ModelDownloader.ModelStatusUnverified:
+		return lang.L("Not verified")
+	}
+	return lang.L("Unknown checksum")
+}
+
+func formatModelDate(date time.Time) string {
+	if date.IsZero() {
+		return lang.L("unknown")
+	}
+	return date.Format("2006-01-02")
+}
+
+// CreateModelManagerPanel lists the models in the cache folder and allows to verify, repair and delete them.
+func CreateModelManagerPanel() fyne.CanvasObject {
+	defer Utilities.PanicLogger()
+
+	var models []ModelDownloader.InstalledModel
+	var usage map[ModelDownloader.ModelReference][]string
+	var scanMutex sync.Mutex
+
+	modelProfiles := func(model ModelDownloader.InstalledModel) []string {
+		if model.Model == nil {
+			return nil
+		}
+		return usage[*model.Model]
+	}
+
+	window := func() fyne.Window {
+		return fyne.CurrentApp().Driver().AllWindows()[0]
+	}
+
+	summaryLabel := widget.NewLabel("")
+	scanProgress := widget.NewProgressBarInfinite()
+	scanProgress.Hide()
+
+	var refreshModels func()
+
+	modelsList := widget.NewList(
+		func() int {
+			return len(models)
+		},
+		func() fyne.CanvasObject {
+			titleLabel := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+			titleLabel.Truncation = fyne.TextTruncateEllipsis
+			detailsLabel := widget.NewLabel("")
+			detailsLabel.Truncation = fyne.TextTruncateEllipsis
+			buttons := container.NewHBox(
+				widget.NewButtonWithIcon(lang.L("Verify"), theme.ConfirmIcon(), nil),
+				widget.NewButtonWithIcon(lang.L("Repair"), theme.DownloadIcon(), nil),
+				widget.NewButtonWithIcon(lang.L("Delete"), theme.DeleteIcon(), nil),
+			)
+			return container.NewBorder(nil, nil, nil, buttons, container.NewVBox(titleLabel, detailsLabel))
+		},
+		func(id widget.ListItemID, object fyne.CanvasObject) {
+			model := models[id]
+			row := object.(*fyne.Container)
+			labels := row.Objects[0].(*fyne.Container)
+			buttons := row.Objects[1].(*fyne.Container)
+
+			labels.Objects[0].(*widget.Label).SetText(model.Category + " / " + model.Title)
+			details := []string{
+				humanize.Bytes(uint64(model.Size)),
+				modelStatusText(model),
+				lang.L("Downloaded") + ": " + formatModelDate(model.Downloaded),
+				lang.L("Last used") + ": " + formatModelDate(model.LastUsed),
+			}
+			if profiles := modelProfiles(model); len(profiles) > 0 {
+				details = append(details, lang.L("Profiles")+": "+strings.Join(profiles, ", "))
+			}
+			labels.Objects[1].(*widget.Label).SetText(strings.Join(details, "  |  "))
+
+			verifyButton := buttons.Objects[0].(*widget.Button)
+			verifyButton.OnTapped = func() {
+				verifyDialog := dialog.NewCustomWithoutButtons(lang.L("Checking checksum..."), widget.NewProgressBarInfinite(), window())
+				verifyDialog.Show()
+				go func() {
+					err := ModelDownloader.VerifyModel(model)
+					verifyDialog.Hide()
+					if err != nil {
+						dialog.ShowError(err, window())
+					} else {
+						dialog.ShowInformation(lang.L("Verify"), lang.L("Checksum OK"), window())
+					}
+					refreshModels()
+				}()
+			}
+			if model.CanVerify() {
+				verifyButton.Enable()
+			} else {
+				verifyButton.Disable()
+			}
+
+			repairButton := buttons.Objects[1].(*widget.Button)
+			repairButton.OnTapped = func() {
+				dialog.ShowConfirm(lang.L("Repair"), lang.L("Download the model again?", map[string]interface{}{"Model": model.Title}), func(b bool) {
+					if !b {
+						return
+					}
+					go func() {
+						_ = ModelDownloader.RepairModel(model)
+						refreshModels()
+					}()
+				}, window())
+			}
+			if model.CanRepair() {
+				repairButton.Enable()
+			} else {
+				repairButton.Disable()
+			}
+
+			deleteButton := buttons.Objects[2].(*widget.Button)
+			deleteButton.OnTapped = func() {
+				confirmText := lang.L("Delete the model?", map[string]interface{}{"Model": model.Title, "Size": humanize.Bytes(uint64(model.Size))})
+				if profiles := modelProfiles(model); len(profiles) > 0 {
+					confirmText = lang.L("The model is used by profiles. Delete it anyway?", map[string]interface{}{"Model": model.Title, "Profiles": strings.Join(profiles, ", ")})
+				}
+				confirmLabel := widget.NewLabel(confirmText)
+				confirmLabel.Wrapping = fyne.TextWrapWord
+				confirmDialog := dialog.NewCustomConfirm(lang.L("Delete"), lang.L("Delete"), lang.L("Cancel"), confirmLabel, func(b bool) {
+					if !b {
+						return
+					}
+					if err := ModelDownloader.DeleteModel(model); err != nil {
+						dialog.ShowError(err, window())
+					}
+					refreshModels()
+				}, window())
+				confirmDialog.Resize(fyne.NewSize(500, 200))
+				confirmDialog.Show()
+			}
+		},
+	)
+	modelsList.OnSelected = func(id widget.ListItemID) {
+		modelsList.UnselectAll()
+	}
+
+	refreshModels = func() {
+		if !scanMutex.TryLock() {
+			return
+		}
+		scanProgress.Show()
+		go func() {
+			defer Utilities.PanicLogger()
+			defer scanMutex.Unlock()
+			scannedModels, err := ModelDownloader.ScanInstalledModels()
+			if err != nil {
+				dialog.ShowError(err, window())
+			}
+			scannedUsage, _ := Profiles.ModelUsage(Settings.GetConfProfileDir())
+
+			var totalSize int64
+			for _, model := range scannedModels {
+				totalSize += model.Size
+			}
+			models = scannedModels
+			usage = scannedUsage
+			summaryLabel.SetText(lang.L("Installed models summary", map[string]interface{}{"Count": len(models), "Size": humanize.Bytes(uint64(totalSize))}))
+			scanProgress.Hide()
+			modelsList.Refresh()
+		}()
+	}
+	refreshModels()
+
+	refreshButton := widget.NewButtonWithIcon(lang.L("Refresh"), theme.ViewRefreshIcon(), refreshModels)
+
+	return container.NewBorder(
+		container.NewBorder(nil, scanProgress, nil, refreshButton, summaryLabel),
+		nil, nil, nil,
+		modelsList,
+	)
+}
@@ -0,0 +1,309 @@
+package Pages
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"path/filepath"
+	"sort"
+	"strings"
+	"whispering-tiger-ui/CustomWidget"
+	"whispering-tiger-ui/Fields"
+	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/Utilities"
+)
+
+// parseDictionaries parses lines in the format "language: term, term" (or just "term, term" for all languages)
+func parseDictionaries(text string) map[string][]string {
+	dictionaries := map[string][]string{}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		dictionaryLanguage := ""
+		if languagePart, termsPart, found := strings.Cut(line, ":"); found && !strings.Contains(languagePart, ",") && len(strings.TrimSpace(languagePart)) <= 8 {
+			dictionaryLanguage = strings.ToLower(strings.TrimSpace(languagePart))
+			line = termsPart
+		}
+		for _, term := range strings.Split(line, ",") {
+			term = strings.TrimSpace(term)
+			if term != "" {
+				dictionaries[dictionaryLanguage] = append(dictionaries[dictionaryLanguage], term)
+			}
+		}
+	}
+	return dictionaries
+}
+
+func formatDictionaries(dictionaries map[string][]string) string {
+	var lines []string
+	if terms, ok := dictionaries[""]; ok {
+		lines = append(lines, strings.Join(terms, ", "))
+	}
+	var dictionaryLanguages []string
+	for dictionaryLanguage := range dictionaries {
+		if dictionaryLanguage != "" {
+			dictionaryLanguages = append(dictionaryLanguages, dictionaryLanguage)
+		}
+	}
+	sort.Strings(dictionaryLanguages)
+	for _, dictionaryLanguage := range dictionaryLanguages {
+		lines = append(lines, dictionaryLanguage+": "+strings.Join(dictionaries[dictionaryLanguage], ", "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func splitLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// savePostProcessingSettings writes the post-processing settings into the current profile file
+func savePostProcessingSettings(postProcessing Settings.PostProcessingConf) error {
+	profileFile := filepath.Join(Settings.GetConfProfileDir(), Settings.Config.SettingsFilename)
+
+	// the dictionary terms are only added to the initial prompt the backend gets, not to the one of the profile
+	previousInitialPrompt := Settings.Config.BackendInitialPrompt()
+	Settings.Config.Transcript_postprocessing = postProcessing
+	if initialPrompt := Settings.Config.BackendInitialPrompt(); initialPrompt != previousInitialPrompt {
+		sendMessage := Fields.SendMessageStruct{
+			Type:  "setting_change",
+			Name:  "initial_prompt",
+			Value: initialPrompt,
+		}
+		sendMessage.SendMessage()
+	}
+
+	if !Utilities.FileExists(profileFile) {
+		return nil
+	}
+	profileSettings := Settings.Conf{}
+	if err := profileSettings.LoadYamlSettings(profileFile); err != nil {
+		return err
+	}
+	profileSettings.Transcript_postprocessing = Settings.Config.Transcript_postprocessing
+	profileSettings.WriteYamlSettings(profileFile)
+	return nil
+}
+
+func CreatePostProcessingWindow() fyne.CanvasObject {
+	defer Utilities.PanicLogger()
+
+	postProcessing := Settings.Config.Transcript_postprocessing
+	rules := make([]Settings.PostProcessingRule, len(postProcessing.Rules))
+	copy(rules, postProcessing.Rules)
+
+	enabledCheck := widget.NewCheck(lang.L("Enabled"), nil)
+	enabledCheck.Checked = postProcessing.Enabled
+
+	// replacement rules
+	rulesContainer := container.NewVBox()
+	var updatePreview func()
+	var buildRuleRows func()
+	buildRuleRows = func() {
+		rulesContainer.RemoveAll()
+		for i := range rules {
+			ruleIndex := i
+
+			findEntry := widget.NewEntry()
+			findEntry.PlaceHolder = lang.L("Find")
+			findEntry.SetText(rules[ruleIndex].Find)
+			findEntry.OnChanged = func(value string) {
+				rules[ruleIndex].Find = value
+				updatePreview()
+			}
+			replaceEntry := widget.NewEntry()
+			replaceEntry.PlaceHolder = lang.L("Replace")
+			replaceEntry.SetText(rules[ruleIndex].Replace)
+			replaceEntry.OnChanged = func(value string) {
+				rules[ruleIndex].Replace = value
+				updatePreview()
+			}
+			languageEntry := widget.NewEntry()
+			languageEntry.PlaceHolder = lang.L("Language")
+			languageEntry.SetText(rules[ruleIndex].Language)
+			languageEntry.OnChanged = func(value string) {
+				rules[ruleIndex].Language = strings.TrimSpace(value)
+				updatePreview()
+			}
+			regexCheck := widget.NewCheck(lang.L("Regex"), func(value bool) {
+				rules[ruleIndex].Regex = value
+				updatePreview()
+			})
+			regexCheck.Checked = rules[ruleIndex].Regex
+			ignoreCaseCheck := widget.NewCheck(lang.L("Ignore case"), func(value bool) {
+				rules[ruleIndex].Ignore_case = value
+				updatePreview()
+			})
+			ignoreCaseCheck.Checked = rules[ruleIndex].Ignore_case
+			wholeWordCheck := widget.NewCheck(lang.L("Whole word"), func(value bool) {
+				rules[ruleIndex].Whole_word = value
+				updatePreview()
+			})
+			wholeWordCheck.Checked = rules[ruleIndex].Whole_word
+
+			removeButton := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				rules = append(rules[:ruleIndex], rules[ruleIndex+1:]...)
+				buildRuleRows()
+				updatePreview()
+			})
+
+			rulesContainer.Add(container.NewBorder(nil, nil, nil,
+				container.NewHBox(regexCheck, ignoreCaseCheck, wholeWordCheck, removeButton),
+				container.NewGridWithColumns(3, findEntry, replaceEntry, languageEntry),
+			))
+		}
+		rulesContainer.Refresh()
+	}
+
+	addRuleButton := widget.NewButtonWithIcon(lang.L("Add rule"), theme.ContentAddIcon(), func() {
+		rules = append(rules, Settings.PostProcessingRule{})
+		buildRuleRows()
+	})
+
+	// dictionaries
+	dictionariesEntry := widget.NewMultiLineEntry()
+	dictionariesEntry.PlaceHolder = "Whispering Tiger, VRChat\nde: Schnitzel, Lederhose"
+	dictionariesEntry.SetMinRowsVisible(4)
+	dictionariesEntry.SetText(formatDictionaries(postProcessing.Dictionaries))
+
+	termsToPromptCheck := widget.NewCheck(lang.L("Add dictionary terms to the initial prompt"), nil)
+	termsToPromptCheck.Checked = postProcessing.Terms_to_initial_prompt
+
+	// case rules
+	caseRuleSelect := CustomWidget.NewTextValueSelect("case_rule", []CustomWidget.TextValueOption{
+		{Text: lang.L("Keep case"), Value: Settings.CaseRuleNone},
+		{Text: lang.L("Sentence case"), Value: Settings.CaseRuleSentence},
+		{Text: lang.L("lowercase"), Value: Settings.CaseRuleLower},
+		{Text: lang.L("UPPERCASE"), Value: Settings.CaseRuleUpper},
+	}, func(option CustomWidget.TextValueOption) {}, 0)
+	caseRuleSelect.SetSelected(postProcessing.Case_rule)
+
+	// profanity masking
+	profanityCheck := widget.NewCheck(lang.L("Mask profanity"), nil)
+	profanityCheck.Checked = postProcessing.Profanity_masking
+	profanityWordsEntry := widget.NewMultiLineEntry()
+	profanityWordsEntry.PlaceHolder = lang.L("One word per line")
+	profanityWordsEntry.SetMinRowsVisible(3)
+	profanityWordsEntry.SetText(strings.Join(postProcessing.Profanity_words, "\n"))
+	maskCharEntry := widget.NewEntry()
+	maskCharEntry.PlaceHolder = "*"
+	maskCharEntry.SetText(postProcessing.Profanity_mask_char)
+
+	currentPostProcessingSettings := func() Settings.PostProcessingConf {
+		caseRule := ""
+		if caseRuleSelect.GetSelected() != nil {
+			caseRule = caseRuleSelect.GetSelected().Value
+		}
+		currentRules := make([]Settings.PostProcessingRule, 0, len(rules))
+		for _, rule := range rules {
+			if rule.Find != "" {
+				currentRules = append(currentRules, rule)
+			}
+		}
+		return Settings.PostProcessingConf{
+			Enabled:                 enabledCheck.Checked,
+			Rules:                   currentRules,
+			Dictionaries:            parseDictionaries(dictionariesEntry.Text),
+			Case_rule:               caseRule,
+			Profanity_masking:       profanityCheck.Checked,
+			Profanity_words:         splitLines(profanityWordsEntry.Text),
+			Profanity_mask_char:     strings.TrimSpace(maskCharEntry.Text),
+			Terms_to_initial_prompt: termsToPromptCheck.Checked,
+		}
+	}
+
+	// preview tester
+	previewInput := widget.NewMultiLineEntry()
+	previewInput.PlaceHolder = lang.L("Enter a text to test the rules")
+	previewInput.Wrapping = fyne.TextWrapWord
+	previewLanguage := widget.NewEntry()
+	previewLanguage.PlaceHolder = lang.L("Language")
+	previewOutput := widget.NewLabel("")
+	previewOutput.Wrapping = fyne.TextWrapWord
+	previewError := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Italic: true})
+	previewError.Wrapping = fyne.TextWrapWord
+
+	updatePreview = func() {
+		currentSettings := currentPostProcessingSettings()
+		if err := currentSettings.Validate(); err != nil {
+			previewError.SetText(err.Error())
+		} else {
+			previewError.SetText("")
+		}
+		// always preview, even if post-processing is not enabled yet
+		currentSettings.Enabled = true
+		previewOutput.SetText(currentSettings.Apply(previewInput.Text, strings.TrimSpace(previewLanguage.Text)))
+	}
+	previewInput.OnChanged = func(string) { updatePreview() }
+	previewLanguage.OnChanged = func(string) { updatePreview() }
+	dictionariesEntry.OnChanged = func(string) { updatePreview() }
+	profanityWordsEntry.OnChanged = func(string) { updatePreview() }
+	maskCharEntry.OnChanged = func(string) { updatePreview() }
+	profanityCheck.OnChanged = func(bool) { updatePreview() }
+	caseRuleSelect.OnChanged = func(CustomWidget.TextValueOption) { updatePreview() }
+
+	buildRuleRows()
+
+	saveButton := widget.NewButtonWithIcon(lang.L("Save"), theme.DocumentSaveIcon(), func() {
+		currentSettings := currentPostProcessingSettings()
+		if err := currentSettings.Validate(); err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		if err := savePostProcessingSettings(currentSettings); err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		dialog.ShowInformation(lang.L("Settings Saved"), lang.L("Transcript post-processing settings have been saved to the profile."), fyne.CurrentApp().Driver().AllWindows()[0])
+	})
+	saveButton.Importance = widget.HighImportance
+
+	addTermsToPromptButton := widget.NewButton(lang.L("Add terms to initial prompt now"), func() {
+		currentSettings := currentPostProcessingSettings()
+		currentSettings.Enabled = true
+		currentSettings.Terms_to_initial_prompt = true
+		initialPrompt := currentSettings.InitialPromptWithTerms(Settings.Config.Initial_prompt, Settings.Config.Current_language)
+		dialog.ShowConfirm(lang.L("Initial Prompt"), initialPrompt, func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			postProcessingSettings := Settings.Config.Transcript_postprocessing
+			postProcessingSettings.Dictionaries = currentSettings.Dictionaries
+			postProcessingSettings.Terms_to_initial_prompt = true
+			postProcessingSettings.Enabled = true
+			if err := savePostProcessingSettings(postProcessingSettings); err != nil {
+				dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			}
+		}, fyne.CurrentApp().Driver().AllWindows()[0])
+	})
+
+	settingsForm := widget.NewForm(
+		widget.NewFormItem(lang.L("Transcript Post-Processing"), enabledCheck),
+		widget.NewFormItem(lang.L("Replacement rules"), container.NewVBox(rulesContainer, container.NewHBox(addRuleButton))),
+		widget.NewFormItem(lang.L("Dictionaries"), container.NewVBox(dictionariesEntry, container.NewHBox(termsToPromptCheck, addTermsToPromptButton))),
+		widget.NewFormItem(lang.L("Case rule"), caseRuleSelect),
+		widget.NewFormItem(lang.L("Profanity"), container.NewVBox(
+			container.NewBorder(nil, nil, profanityCheck, nil, container.NewBorder(nil, nil, widget.NewLabel(lang.L("Mask character")), nil, maskCharEntry)),
+			profanityWordsEntry,
+		)),
+		widget.NewFormItem("", widget.NewSeparator()),
+		widget.NewFormItem(lang.L("Test"), container.NewVBox(
+			container.NewBorder(nil, nil, nil, previewLanguage, previewInput),
+			previewOutput,
+			previewError,
+		)),
+	)
+
+	return container.NewBorder(nil, container.NewHBox(saveButton), nil, nil, container.NewVScroll(settingsForm))
+}
@@ -0,0 +1,229 @@
+package Pages
+
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"whispering-tiger-ui/ModelDownloader"
+	"whispering-tiger-ui/Profiles"
+)
+
+func setProfileArchiveDialogLocation(fileDialog *dialog.FileDialog) {
+	startingPath := fyne.CurrentApp().Preferences().StringWithFallback("LastProfileArchivePath", "")
+	if startingPath != "" {
+		if _, err := os.Stat(startingPath); !os.IsNotExist(err) {
+			fileLister, _ := storage.ListerForURI(storage.NewFileURI(startingPath))
+			fileDialog.SetLocation(fileLister)
+		}
+	}
+	fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".zip"}))
+	dialogSize := fyne.CurrentApp().Driver().AllWindows()[1].Canvas().Size()
+	fileDialog.Resize(fyne.NewSize(dialogSize.Width-80, dialogSize.Height-80))
+}
+
+// exportProfileArchive saves the profile with its plugins and the list of required models as archive.
+func exportProfileArchive(profilesDir string, profileFileName string) {
+	window := fyne.CurrentApp().Driver().AllWindows()[1]
+
+	fileDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		defer writer.Close()
+		manifest, err := Profiles.ExportProfileArchive(filepath.Join(profilesDir, profileFileName), writer)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		fyne.CurrentApp().Preferences().SetString("LastProfileArchivePath", filepath.Dir(writer.URI().Path()))
+
+		message := lang.L("Exported profile with PluginCount plugins and ModelCount models.", map[string]interface{}{
+			"PluginCount": len(manifest.Plugins) - len(manifest.MissingPluginFiles()),
+			"ModelCount":  len(manifest.Models),
+		})
+		if missingPlugins := manifest.MissingPluginFiles(); len(missingPlugins) > 0 {
+			message += "\n" + lang.L("Plugin files not found:") + " " + strings.Join(missingPlugins, ", ")
+		}
+		dialog.ShowInformation(lang.L("Export profile"), message, window)
+	}, window)
+	setProfileArchiveDialogLocation(fileDialog)
+	fileDialog.SetFileName(strings.TrimSuffix(profileFileName, filepath.Ext(profileFileName)) + ".zip")
+	fileDialog.Show()
+}
+
+func profileArchiveDiffText(diffs []Profiles.ArchiveFileDiff) string {
+	var builder strings.Builder
+	for _, diff := range diffs {
+		builder.WriteString(diff.File + ": " + lang.L(string(diff.Status)) + "\n")
+		for _, setting := range diff.Settings {
+			builder.WriteString("    " + setting.String() + "\n")
+		}
+	}
+	return builder.String()
+}
+
+// importProfileArchive reads a profile archive, shows what would change and imports it if confirmed.
+// onImported is called with the file name of the imported profile.
+func importProfileArchive(profilesDir string, onImported func(profileFileName string)) {
+	window := fyne.CurrentApp().Driver().AllWindows()[1]
+
+	fileDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		fyne.CurrentApp().Preferences().SetString("LastProfileArchivePath", filepath.Dir(reader.URI().Path()))
+
+		archive, err := Profiles.ReadProfileArchive(data)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		showProfileArchiveImportDialog(profilesDir, archive, onImported)
+	}, window)
+	setProfileArchiveDialogLocation(fileDialog)
+	fileDialog.Show()
+}
+
+func showProfileArchiveImportDialog(profilesDir string, archive *Profiles.ProfileArchive, onImported func(profileFileName string)) {
+	window := fyne.CurrentApp().Driver().AllWindows()[1]
+
+	diffLabel := widget.NewLabel("")
+	diffLabel.Wrapping = fyne.TextWrapWord
+	updateDiff := func(profileName string) {
+		diffs, err := archive.Diff(profilesDir, profileName)
+		if err != nil {
+			diffLabel.SetText(err.Error())
+			return
+		}
+		diffLabel.SetText(profileArchiveDiffText(diffs))
+	}
+
+	profileNameEntry := widget.NewEntry()
+	profileNameEntry.SetText(strings.TrimSuffix(archive.Manifest.Profile, filepath.Ext(archive.Manifest.Profile)))
+	profileNameEntry.Validator = func(s string) error {
+		if strings.TrimSpace(s) == "" {
+			return fmt.Errorf(lang.L("please enter a profile name"))
+		}
+		return nil
+	}
+	profileFileName := func() string {
+		return strings.TrimSpace(profileNameEntry.Text) + ".yaml"
+	}
+	profileNameEntry.OnChanged = func(s string) {
+		updateDiff(profileFileName())
+	}
+	updateDiff(profileFileName())
+
+	var modelNames []string
+	for _, model := range archive.Manifest.Models {
+		modelNames = append(modelNames, model.String())
+	}
+	modelsLabel := widget.NewLabel(lang.L("Required models:") + " " + strings.Join(modelNames, ", "))
+	modelsLabel.Wrapping = fyne.TextWrapWord
+
+	infoBox := container.NewVBox(
+		container.NewBorder(nil, nil, widget.NewLabel(lang.L("Profile Name")+":"), nil, profileNameEntry),
+		modelsLabel,
+	)
+	if archive.Migration != nil && archive.Migration.NeedsMigration() {
+		infoBox.Add(widget.NewLabel(lang.L("The profile will be migrated from schema version FromVersion to ToVersion.", map[string]interface{}{
+			"FromVersion": archive.Migration.FromVersion,
+			"ToVersion":   archive.Migration.ToVersion,
+		})))
+	}
+	infoBox.Add(widget.NewSeparator())
+
+	content := container.NewBorder(infoBox, nil, nil, nil, container.NewVScroll(diffLabel))
+
+	importDialog := dialog.NewCustomConfirm(lang.L("Import profile"), lang.L("Import"), lang.L("Cancel"), content, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		if profileNameEntry.Validate() != nil {
+			return
+		}
+		profileName := profileFileName()
+		confirmPluginFiles(archive.PluginFilesToInstall(), func(pluginFiles []string) {
+			if err := archive.Import(profilesDir, profileName, pluginFiles); err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			onImported(profileName)
+			offerMissingModelDownloads(archive.MissingModels())
+		})
+	}, window)
+	windowSize := window.Canvas().Size()
+	importDialog.Resize(fyne.NewSize(windowSize.Width*0.9, windowSize.Height*0.9))
+	importDialog.Show()
+}
+
+// confirmPluginFiles lets the user choose which plugin files of an archive are installed. Plugins are executable code,
+// so none is selected by default. onConfirmed is not called if the import is canceled.
+func confirmPluginFiles(pluginFiles []string, onConfirmed func(pluginFiles []string)) {
+	if len(pluginFiles) == 0 {
+		onConfirmed(nil)
+		return
+	}
+	window := fyne.CurrentApp().Driver().AllWindows()[1]
+
+	warningLabel := widget.NewLabel(lang.L("The archive contains plugins. Plugins are Python code which is executed by the backend with your user rights. Only install plugins from sources you trust."))
+	warningLabel.Wrapping = fyne.TextWrapWord
+	pluginCheckGroup := widget.NewCheckGroup(pluginFiles, nil)
+
+	content := container.NewBorder(warningLabel, nil, nil, nil, container.NewVScroll(pluginCheckGroup))
+	pluginDialog := dialog.NewCustomConfirm(lang.L("Install plugins"), lang.L("Import"), lang.L("Cancel"), content, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		onConfirmed(pluginCheckGroup.Selected)
+	}, window)
+	pluginDialog.Resize(fyne.NewSize(500, 400))
+	pluginDialog.Show()
+}
+
+// offerMissingModelDownloads asks to download the models of an imported profile which are not downloaded yet.
+func offerMissingModelDownloads(missingModels []ModelDownloader.ModelReference) {
+	if len(missingModels) == 0 {
+		return
+	}
+	window := fyne.CurrentApp().Driver().AllWindows()[1]
+	var modelNames []string
+	for _, model := range missingModels {
+		modelNames = append(modelNames, model.String())
+	}
+	dialog.ShowConfirm(lang.L("Download missing models"), lang.L("The imported profile needs models which are not downloaded yet:")+"\n"+strings.Join(modelNames, "\n")+"\n\n"+lang.L("Download them now?"), func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		go func() {
+			for _, model := range missingModels {
+				// errors are shown by the download dialog
+				if err := ModelDownloader.DownloadModel(model); err != nil {
+					return
+				}
+			}
+		}()
+	}, window)
+}
@@ -0,0 +1,216 @@
+package Pages
+
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"path/filepath"
+	"strings"
+	"whispering-tiger-ui/CustomWidget"
+	"whispering-tiger-ui/Pages/ProfileSettings"
+	"whispering-tiger-ui/Settings"
+)
+
+const (
+	compareSourceProfilePrefix = "profile:"
+	compareSourcePresetPrefix  = "preset:"
+	compareSourceDefault       = "default"
+)
+
+// compareRow is either a category header or a differing setting in the comparison list
+type compareRow struct {
+	Category string
+	Diff     *Settings.SettingDiff
+}
+
+func formatCompareValue(key string, value interface{}) string {
+	if value == nil {
+		return "-"
+	}
+	return formatInheritanceValue(Settings.MaskSecretValue(key, value))
+}
+
+// loadCompareSource loads the settings of a comparison source. Profiles are loaded with their inherited settings.
+func loadCompareSource(profilesDir string, source string) (*Settings.Conf, error) {
+	switch {
+	case strings.HasPrefix(source, compareSourceProfilePrefix):
+		var conf Settings.Conf
+		if err := conf.LoadYamlSettings(filepath.Join(profilesDir, strings.TrimPrefix(source, compareSourceProfilePrefix))); err != nil {
+			return nil, err
+		}
+		return &conf, nil
+	case strings.HasPrefix(source, compareSourcePresetPrefix):
+		preset, ok := ProfileSettings.Presets[strings.TrimPrefix(source, compareSourcePresetPrefix)]
+		if !ok {
+			return nil, fmt.Errorf("unknown preset %s", strings.TrimPrefix(source, compareSourcePresetPrefix))
+		}
+		return &preset, nil
+	case source == compareSourceDefault:
+		defaultSettings := ProfileSettings.DefaultProfileSetting
+		return &defaultSettings, nil
+	}
+	return nil, fmt.Errorf("nothing selected")
+}
+
+// showProfileCompareDialog compares two profiles (or a profile with the default settings or a preset) field by field.
+// Selected settings can be copied between the two sides, if the target is a profile.
+func showProfileCompareDialog(profilesDir string, profileFileName string, profileFileNames []string, presetOptions []CustomWidget.TextValueOption, onChanged func(profileFileName string)) {
+	window := fyne.CurrentApp().Driver().AllWindows()[1]
+
+	var sourceOptions []CustomWidget.TextValueOption
+	for _, fileName := range profileFileNames {
+		sourceOptions = append(sourceOptions, CustomWidget.TextValueOption{Text: fileName, Value: compareSourceProfilePrefix + fileName})
+	}
+	sourceOptions = append(sourceOptions, CustomWidget.TextValueOption{Text: lang.L("Default settings"), Value: compareSourceDefault})
+	for _, presetOption := range presetOptions {
+		if presetOption.Value != "" {
+			sourceOptions = append(sourceOptions, CustomWidget.TextValueOption{Text: lang.L("Preset") + ": " + presetOption.Text, Value: compareSourcePresetPrefix + presetOption.Value})
+		}
+	}
+
+	var leftConf, rightConf *Settings.Conf
+	var rows []compareRow
+	selectedKeys := map[string]bool{}
+
+	statusLabel := widget.NewLabel("")
+	diffList := widget.NewList(
+		func() int {
+			return len(rows)
+		},
+		func() fyne.CanvasObject {
+			check := widget.NewCheck("", nil)
+			keyLabel := widget.NewLabel("")
+			leftLabel := widget.NewLabel("")
+			leftLabel.Truncation = fyne.TextTruncateEllipsis
+			rightLabel := widget.NewLabel("")
+			rightLabel.Truncation = fyne.TextTruncateEllipsis
+			return container.NewBorder(nil, nil, check, nil, container.NewGridWithColumns(3, keyLabel, leftLabel, rightLabel))
+		},
+		func(id widget.ListItemID, object fyne.CanvasObject) {
+			row := object.(*fyne.Container)
+			columns := row.Objects[0].(*fyne.Container)
+			check := row.Objects[1].(*widget.Check)
+			keyLabel := columns.Objects[0].(*widget.Label)
+			leftLabel := columns.Objects[1].(*widget.Label)
+			rightLabel := columns.Objects[2].(*widget.Label)
+
+			if rows[id].Diff == nil {
+				check.Hide()
+				keyLabel.TextStyle = fyne.TextStyle{Bold: true}
+				keyLabel.SetText(lang.L(rows[id].Category))
+				leftLabel.SetText("")
+				rightLabel.SetText("")
+				return
+			}
+			diff := rows[id].Diff
+			check.Show()
+			check.OnChanged = nil
+			check.SetChecked(selectedKeys[diff.Key])
+			check.OnChanged = func(checked bool) {
+				selectedKeys[diff.Key] = checked
+			}
+			keyLabel.TextStyle = fyne.TextStyle{}
+			keyLabel.SetText(diff.Key)
+			leftLabel.SetText(formatCompareValue(diff.Key, diff.Left))
+			rightLabel.SetText(formatCompareValue(diff.Key, diff.Right))
+		},
+	)
+
+	leftSelect := CustomWidget.NewTextValueSelect("left", sourceOptions, nil, -1)
+	rightSelect := CustomWidget.NewTextValueSelect("right", sourceOptions, nil, -1)
+
+	updateComparison := func() {
+		rows = nil
+		selectedKeys = map[string]bool{}
+		leftConf, rightConf = nil, nil
+		if leftSelect.GetSelected() == nil || rightSelect.GetSelected() == nil {
+			diffList.Refresh()
+			return
+		}
+		var err error
+		if leftConf, err = loadCompareSource(profilesDir, leftSelect.GetSelected().Value); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if rightConf, err = loadCompareSource(profilesDir, rightSelect.GetSelected().Value); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		diffs, err := Settings.CompareConfs(leftConf, rightConf)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		for i := range diffs {
+			if len(rows) == 0 || rows[len(rows)-1].Category != diffs[i].Category {
+				rows = append(rows, compareRow{Category: diffs[i].Category})
+			}
+			rows = append(rows, compareRow{Category: diffs[i].Category, Diff: &diffs[i]})
+		}
+		statusLabel.SetText(lang.L("DiffCount differing settings", map[string]interface{}{"DiffCount": len(diffs)}))
+		diffList.Refresh()
+	}
+	leftSelect.OnChanged = func(option CustomWidget.TextValueOption) { updateComparison() }
+	rightSelect.OnChanged = func(option CustomWidget.TextValueOption) { updateComparison() }
+
+	copySelected := func(from *Settings.Conf, to *Settings.Conf, target *CustomWidget.TextValueOption) {
+		if from == nil || to == nil || target == nil {
+			return
+		}
+		if !strings.HasPrefix(target.Value, compareSourceProfilePrefix) {
+			dialog.ShowInformation(lang.L("Compare Profiles"), lang.L("Settings can only be copied into profiles."), window)
+			return
+		}
+		var keys []string
+		for key, selected := range selectedKeys {
+			if selected {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			return
+		}
+		if err := Settings.CopySettings(from, to, keys); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if validationErrors := to.ValidateFields(keys...); len(validationErrors) > 0 {
+			dialog.ShowError(validationErrors, window)
+			return
+		}
+		targetFileName := strings.TrimPrefix(target.Value, compareSourceProfilePrefix)
+		to.WriteYamlSettings(filepath.Join(profilesDir, targetFileName))
+		updateComparison()
+		onChanged(targetFileName)
+	}
+
+	copyToRightButton := widget.NewButtonWithIcon(lang.L("Copy selected"), theme.NavigateNextIcon(), func() {
+		copySelected(leftConf, rightConf, rightSelect.GetSelected())
+	})
+	copyToRightButton.IconPlacement = widget.ButtonIconTrailingText
+	copyToLeftButton := widget.NewButtonWithIcon(lang.L("Copy selected"), theme.NavigateBackIcon(), func() {
+		copySelected(rightConf, leftConf, leftSelect.GetSelected())
+	})
+
+	leftSelect.SetSelected(compareSourceProfilePrefix + profileFileName)
+	rightSelect.SetSelected(compareSourceDefault)
+
+	content := container.NewBorder(
+		container.NewVBox(
+			container.NewGridWithColumns(3, widget.NewLabel(""), leftSelect, rightSelect),
+			container.NewGridWithColumns(3, statusLabel, copyToLeftButton, copyToRightButton),
+			widget.NewSeparator(),
+		),
+		nil, nil, nil,
+		diffList,
+	)
+
+	compareDialog := dialog.NewCustom(lang.L("Compare Profiles"), lang.L("Close"), content, window)
+	windowSize := window.Canvas().Size()
+	compareDialog.Resize(fyne.NewSize(windowSize.Width*0.9, windowSize.Height*0.9))
+	compareDialog.Show()
+}
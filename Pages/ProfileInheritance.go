@@ -0,0 +1,167 @@
+package Pages
+
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"path/filepath"
+	"whispering-tiger-ui/Settings"
+)
+
+func formatInheritanceValue(value interface{}) string {
+	text := fmt.Sprintf("%v", value)
+	if len(text) > 60 {
+		text = text[:57] + "..."
+	}
+	return text
+}
+
+// showProfileInheritanceDialog shows which settings of a profile are inherited from the profile it extends
+// and which are overridden, with an action to reset overridden settings to the inherited value.
+func showProfileInheritanceDialog(profilesDir string, profileFileName string, profileFileNames []string, onChanged func()) {
+	window := fyne.CurrentApp().Driver().AllWindows()[1]
+	profileFile := filepath.Join(profilesDir, profileFileName)
+
+	var settings []Settings.SettingInheritance
+	var loadError error
+	showOnlyOverridden := false
+	var visibleSettings []Settings.SettingInheritance
+
+	settingsList := widget.NewList(
+		func() int {
+			return len(visibleSettings)
+		},
+		func() fyne.CanvasObject {
+			keyLabel := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+			valueLabel := widget.NewLabel("")
+			valueLabel.Truncation = fyne.TextTruncateEllipsis
+			sourceLabel := widget.NewLabelWithStyle("", fyne.TextAlignTrailing, fyne.TextStyle{Italic: true})
+			resetButton := widget.NewButtonWithIcon(lang.L("Reset to inherited"), theme.ContentUndoIcon(), nil)
+			return container.NewBorder(nil, nil, keyLabel, container.NewHBox(sourceLabel, resetButton), valueLabel)
+		},
+		nil,
+	)
+
+	var reload func()
+	settingsList.UpdateItem = func(id widget.ListItemID, object fyne.CanvasObject) {
+		setting := visibleSettings[id]
+		row := object.(*fyne.Container)
+		valueLabel := row.Objects[0].(*widget.Label)
+		keyLabel := row.Objects[1].(*widget.Label)
+		rightSide := row.Objects[2].(*fyne.Container)
+		sourceLabel := rightSide.Objects[0].(*widget.Label)
+		resetButton := rightSide.Objects[1].(*widget.Button)
+
+		keyLabel.SetText(setting.Key)
+		valueLabel.SetText(formatInheritanceValue(setting.Value))
+		switch {
+		case setting.Overridden:
+			sourceLabel.SetText(lang.L("overridden (inherited: Value)", map[string]interface{}{"Value": formatInheritanceValue(setting.InheritedValue)}))
+			resetButton.Show()
+		case setting.Inherited:
+			sourceLabel.SetText(lang.L("inherited from Profile", map[string]interface{}{"Profile": filepath.Base(setting.Source)}))
+			resetButton.Hide()
+		default:
+			sourceLabel.SetText(lang.L("own value"))
+			resetButton.Hide()
+		}
+		resetButton.OnTapped = func() {
+			if err := Settings.ResetToInherited(profileFile, setting.Key); err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			reload()
+			onChanged()
+		}
+	}
+
+	reload = func() {
+		settings, loadError = Settings.ProfileInheritance(profileFile)
+		if loadError != nil {
+			dialog.ShowError(loadError, window)
+		}
+		visibleSettings = nil
+		for _, setting := range settings {
+			if !showOnlyOverridden || setting.Overridden {
+				visibleSettings = append(visibleSettings, setting)
+			}
+		}
+		settingsList.Refresh()
+	}
+
+	// profiles that can be extended
+	extendsOptions := []string{lang.L("None")}
+	for _, fileName := range profileFileNames {
+		if fileName != profileFileName {
+			extendsOptions = append(extendsOptions, fileName)
+		}
+	}
+	extendsSelect := widget.NewSelect(extendsOptions, nil)
+	var currentConf Settings.Conf
+	if err := currentConf.LoadYamlSettings(profileFile); err == nil && currentConf.Extends != "" {
+		extendsSelect.SetSelected(currentConf.Extends)
+	} else {
+		extendsSelect.SetSelected(lang.L("None"))
+	}
+	extendsSelect.OnChanged = func(selected string) {
+		extends := selected
+		if selected == lang.L("None") {
+			extends = ""
+		}
+		if err := Settings.SetProfileExtends(profileFile, extends); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		reload()
+		onChanged()
+	}
+
+	onlyOverriddenCheck := widget.NewCheck(lang.L("Only show overridden settings"), func(checked bool) {
+		showOnlyOverridden = checked
+		reload()
+	})
+
+	resetAllButton := widget.NewButtonWithIcon(lang.L("Reset all to inherited"), theme.ContentUndoIcon(), func() {
+		var overriddenKeys []string
+		for _, setting := range settings {
+			if setting.Overridden {
+				overriddenKeys = append(overriddenKeys, setting.Key)
+			}
+		}
+		if len(overriddenKeys) == 0 {
+			return
+		}
+		dialog.ShowConfirm(lang.L("Reset all to inherited"), lang.L("Remove all overridden settings from this profile?"), func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			if err := Settings.ResetToInherited(profileFile, overriddenKeys...); err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			reload()
+			onChanged()
+		}, window)
+	})
+
+	reload()
+
+	content := container.NewBorder(
+		container.NewVBox(
+			container.NewBorder(nil, nil, widget.NewLabel(lang.L("Extends")+":"), nil, extendsSelect),
+			container.NewHBox(onlyOverriddenCheck, resetAllButton),
+			widget.NewSeparator(),
+		),
+		nil, nil, nil,
+		settingsList,
+	)
+
+	inheritanceDialog := dialog.NewCustom(lang.L("Profile Inheritance")+" - "+profileFileName, lang.L("Close"), content, window)
+	windowSize := window.Canvas().Size()
+	inheritanceDialog.Resize(fyne.NewSize(windowSize.Width*0.9, windowSize.Height*0.9))
+	inheritanceDialog.Show()
+}
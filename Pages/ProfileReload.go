@@ -0,0 +1,91 @@
+package Pages
+
+import (
+	"fmt"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/widget"
+	"log"
+	"strings"
+	"time"
+	"whispering-tiger-ui/RuntimeBackend"
+	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/Utilities"
+	"whispering-tiger-ui/Websocket"
+)
+
+var activeProfileWatcher *Settings.ProfileWatcher
+
+// WatchActiveProfile applies changes of the active profile file made outside the application (e.g. in a text editor).
+func WatchActiveProfile(profileFile string) {
+	if activeProfileWatcher != nil {
+		activeProfileWatcher.Close()
+		activeProfileWatcher = nil
+	}
+
+	var lastConf Settings.Conf
+	if err := lastConf.LoadYamlSettings(profileFile); err != nil {
+		log.Printf("profile hot-reload disabled: %v", err)
+		return
+	}
+	lastConf.SettingsFilename = Settings.Config.SettingsFilename
+
+	watcher, err := Settings.WatchProfile(profileFile, func(ownWrite bool) {
+		reload := Settings.ReloadProfile(&lastConf, profileFile)
+		if ownWrite {
+			// the application saved the profile, so its settings are applied already
+			if reload.Err == nil {
+				lastConf = *reload.Conf
+			}
+			return
+		}
+		if reload.Err != nil {
+			dialog.ShowError(fmt.Errorf("%s\n\n%w", lang.L("The changed profile file was not applied:"), reload.Err), Utilities.GetCurrentMainWindow(""))
+			return
+		}
+		if !reload.HasChanges() {
+			return
+		}
+		lastConf = *reload.Conf
+		log.Printf("profile reloaded. changed: %v, restart required: %v", append(reload.ChangedKeys(), reload.UiChanged...), reload.RestartRequired)
+
+		Websocket.ApplyProfileReload(reload)
+		if len(reload.RestartRequired) > 0 {
+			showRestartRequiredDialog(profileFile, reload)
+		}
+	})
+	if err != nil {
+		log.Printf("profile hot-reload disabled: %v", err)
+		return
+	}
+	activeProfileWatcher = watcher
+}
+
+func showRestartRequiredDialog(profileFile string, reload *Settings.ProfileReload) {
+	window := Utilities.GetCurrentMainWindow("")
+	message := lang.L("The following changed settings are only applied after a restart of the backend:") + "\n" +
+		strings.Join(reload.RestartRequired, "\n") + "\n\n" + lang.L("Restart the backend now?")
+
+	dialog.ShowConfirm(lang.L("Profile changed"), message, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		if err := Settings.CopySettings(reload.Conf, &Settings.Config, reload.RestartRequired); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if len(RuntimeBackend.BackendsList) == 0 || !RuntimeBackend.BackendsList[0].IsRunning() {
+			return
+		}
+		infinityProcessDialog := dialog.NewCustom(lang.L("Restarting Backend"), lang.L("OK"), container.NewVBox(widget.NewLabel(lang.L("Restarting Backend")+"..."), widget.NewProgressBarInfinite()), window)
+		infinityProcessDialog.Show()
+		RuntimeBackend.BackendsList[0].Stop()
+		time.Sleep(2 * time.Second)
+		RuntimeBackend.BackendsList[0].DeviceIndex = fmt.Sprint(Settings.Config.Device_index)
+		RuntimeBackend.BackendsList[0].DeviceOutIndex = fmt.Sprint(Settings.Config.Device_out_index)
+		RuntimeBackend.BackendsList[0].SettingsFile = Settings.PrepareBackendSettingsFile(profileFile)
+		RuntimeBackend.BackendsList[0].Start()
+		infinityProcessDialog.Hide()
+	}, window)
+}
@@ -0,0 +1,262 @@
+package Pages
+
+import (
+	"context"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/widget"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+	"whispering-tiger-ui/Pages/ProfileSettings"
+	"whispering-tiger-ui/Profiles"
+	"whispering-tiger-ui/RuntimeBackend"
+	"whispering-tiger-ui/Utilities"
+	"whispering-tiger-ui/Utilities/Hardwareinfo"
+)
+
+// maximum number of candidates which are benchmarked, since every benchmark starts the backend
+const wizardBenchmarkCount = 4
+
+var wizardTargetLatencies = []string{"0.5s", "1s", "2s", "3s", "5s"}
+
+// text which is translated by the benchmark
+const wizardBenchmarkText = "Hello, this is a test of the translation speed. How long does it take until this sentence is translated?"
+
+// showProfileWizard detects the hardware, benchmarks fitting model combinations which are already downloaded
+// and creates a profile with the recommended combination.
+// The benchmark runs in the background, so its results are shown through data bindings.
+func showProfileWizard(profilesDir string, onCreated func(profileFileName string)) {
+	defer Utilities.PanicLogger()
+
+	window := fyne.CurrentApp().Driver().AllWindows()[1]
+
+	hardwareText := binding.NewString()
+	_ = hardwareText.Set(lang.L("Detecting hardware..."))
+	hardwareLabel := widget.NewLabelWithData(hardwareText)
+	hardwareLabel.Wrapping = fyne.TextWrapWord
+
+	targetLatencySelect := widget.NewSelect(wizardTargetLatencies, nil)
+	targetLatencySelect.SetSelected("1s")
+
+	benchmarkCheck := widget.NewCheck(lang.L("Benchmark the translation speed of the candidates with downloaded models (starts the backend)"), nil)
+	benchmarkCheck.SetChecked(RuntimeBackend.BackendAvailable())
+	if !RuntimeBackend.BackendAvailable() {
+		benchmarkCheck.Disable()
+	}
+
+	profileNameEntry := widget.NewEntry()
+	profileNameEntry.SetText("recommended")
+	profileNameEntry.Validator = func(s string) error {
+		s = strings.TrimSpace(s)
+		if len(s) == 0 {
+			return fmt.Errorf(lang.L("please enter a profile name"))
+		}
+		if strings.HasSuffix(s, ".yaml") || strings.HasSuffix(s, ".yml") {
+			return fmt.Errorf(lang.L("please do not include file extension"))
+		}
+		if Utilities.FileExists(filepath.Join(profilesDir, s+".yaml")) {
+			return fmt.Errorf(lang.L("a profile with this name already exists"))
+		}
+		return nil
+	}
+
+	progress := binding.NewFloat()
+	progressBar := widget.NewProgressBarWithData(progress)
+	progressBar.Hide()
+	benchmarkLog := binding.NewString()
+	resultText := widget.NewEntryWithData(benchmarkLog)
+	resultText.MultiLine = true
+	resultText.Wrapping = fyne.TextWrapWord
+	resultText.Disable()
+	recommendationText := binding.NewString()
+	recommendationLabel := widget.NewLabelWithData(recommendationText)
+	recommendationLabel.TextStyle = fyne.TextStyle{Bold: true}
+	recommendationLabel.Wrapping = fyne.TextWrapWord
+
+	var hardware Hardwareinfo.HardwareProbe
+	var candidates []Profiles.WizardCandidate
+	var recommended *Profiles.WizardCandidate
+	var cancelBenchmark context.CancelFunc
+
+	startButton := widget.NewButton(lang.L("Find recommended settings"), nil)
+	startButton.Importance = widget.HighImportance
+	startButton.Disable()
+	createButton := widget.NewButton(lang.L("Create Profile"), nil)
+	createButton.Disable()
+
+	// the buttons are enabled by the state bindings, which are set by the background tasks
+	canStart := binding.NewBool()
+	canStart.AddListener(binding.NewDataListener(func() {
+		if enabled, _ := canStart.Get(); enabled {
+			startButton.Enable()
+		} else {
+			startButton.Disable()
+		}
+	}))
+	benchmarkRunning := binding.NewBool()
+	benchmarkRunning.AddListener(binding.NewDataListener(func() {
+		if running, _ := benchmarkRunning.Get(); running {
+			progressBar.Show()
+		} else {
+			progressBar.Hide()
+		}
+	}))
+	canCreate := binding.NewBool()
+	canCreate.AddListener(binding.NewDataListener(func() {
+		if enabled, _ := canCreate.Get(); enabled {
+			createButton.Enable()
+		} else {
+			createButton.Disable()
+		}
+	}))
+
+	go func() {
+		hardware = Hardwareinfo.ProbeHardware()
+		candidates = Profiles.WizardCandidates(hardware)
+
+		text := lang.L("CPU RAM:") + " " + fmt.Sprintf("%d MiB", hardware.CPUMemoryMB) + "\n"
+		if hardware.GPUVendor != "" {
+			text += lang.L("GPU:") + " " + strings.TrimSpace(strings.ToUpper(hardware.GPUVendor)+" "+hardware.GPUName) + "\n"
+			text += lang.L("Video-RAM:") + " " + fmt.Sprintf("%d MiB", hardware.GPUMemoryMB)
+			if hardware.ComputeCapability > 0 {
+				text += ", Compute Capability: " + fmt.Sprintf("%.1f", hardware.ComputeCapability)
+			}
+		} else {
+			text += lang.L("No dedicated GPU found.")
+		}
+		text += "\n" + lang.L("Combinations fitting into memory:") + " " + fmt.Sprint(len(candidates))
+		_ = hardwareText.Set(text)
+		_ = canStart.Set(len(candidates) > 0)
+	}()
+
+	setRecommendation := func(candidate Profiles.WizardCandidate, note string) {
+		recommended = &candidate
+		_ = recommendationText.Set(lang.L("Recommended:") + " " + candidate.String() + "\n" + note)
+		_ = canCreate.Set(true)
+	}
+
+	startButton.OnTapped = func() {
+		recommended = nil
+		_ = canCreate.Set(false)
+		_ = benchmarkLog.Set("")
+		_ = recommendationText.Set("")
+
+		if !benchmarkCheck.Checked {
+			if candidate, ok := Profiles.RecommendWithoutBenchmark(candidates); ok {
+				setRecommendation(candidate, lang.L("Chosen by estimated memory usage without benchmark."))
+			}
+			return
+		}
+
+		targetLatency, _ := time.ParseDuration(targetLatencySelect.Selected)
+		shortlist := Profiles.BenchmarkShortlist(candidates, wizardBenchmarkCount)
+		if len(shortlist) == 0 {
+			if candidate, ok := Profiles.RecommendWithoutBenchmark(candidates); ok {
+				setRecommendation(candidate, lang.L("The models of the candidates are not downloaded yet. Chosen by estimated memory usage."))
+			}
+			return
+		}
+
+		var ctx context.Context
+		ctx, cancelBenchmark = context.WithCancel(context.Background())
+		_ = canStart.Set(false)
+		progressBar.Max = float64(len(shortlist))
+		_ = progress.Set(0)
+		_ = benchmarkRunning.Set(true)
+
+		go func() {
+			defer Utilities.PanicLogger()
+
+			var results []Profiles.BenchmarkResult
+			logText := ""
+			for index, candidate := range shortlist {
+				logText += candidate.String() + ": " + lang.L("running...")
+				_ = benchmarkLog.Set(logText)
+
+				result := Profiles.BenchmarkCandidate(ctx, ProfileSettings.DefaultProfileSetting, candidate, wizardBenchmarkText)
+				results = append(results, result)
+
+				logText = strings.TrimSuffix(logText, lang.L("running..."))
+				if result.Err != nil {
+					logText += lang.L("failed") + " (" + result.Err.Error() + ")\n"
+				} else {
+					logText += lang.L("Text-Translate") + ": " + result.TranslationTime.Round(time.Millisecond).String() + "\n"
+				}
+				_ = benchmarkLog.Set(logText)
+				_ = progress.Set(float64(index + 1))
+
+				// candidates are sorted best first, so the first one meeting the target is the recommendation
+				if (result.Err == nil && result.Latency() <= targetLatency) || ctx.Err() != nil {
+					break
+				}
+			}
+			_ = benchmarkRunning.Set(false)
+			_ = canStart.Set(true)
+
+			if ctx.Err() != nil {
+				return
+			}
+			if result, ok := Profiles.RecommendCandidate(results, targetLatency); ok {
+				note := lang.L("Meets the target latency.")
+				if result.Latency() > targetLatency {
+					note = lang.L("No combination met the target latency. This is the fastest one.")
+				}
+				setRecommendation(result.Candidate, note)
+			} else if candidate, ok := Profiles.RecommendWithoutBenchmark(candidates); ok {
+				setRecommendation(candidate, lang.L("The benchmark failed. Chosen by estimated memory usage."))
+			}
+		}()
+	}
+
+	var wizardDialog dialog.Dialog
+	createButton.OnTapped = func() {
+		if recommended == nil || profileNameEntry.Validate() != nil {
+			return
+		}
+		profileFileName := strings.TrimSpace(profileNameEntry.Text) + ".yaml"
+		profile := ProfileSettings.DefaultProfileSetting
+		recommended.Apply(&profile)
+		profile.SettingsFilename = profileFileName
+		profile.Schema_version = Profiles.CurrentSchemaVersion
+		profile.WriteYamlSettings(filepath.Join(profilesDir, profileFileName))
+		log.Printf("created profile %s with %s", profileFileName, recommended.String())
+
+		wizardDialog.Hide()
+		onCreated(profileFileName)
+	}
+
+	content := container.NewBorder(
+		container.NewVBox(
+			hardwareLabel,
+			widget.NewForm(
+				widget.NewFormItem(lang.L("Target latency"), targetLatencySelect),
+				widget.NewFormItem("", benchmarkCheck),
+			),
+			startButton,
+			progressBar,
+		),
+		container.NewVBox(
+			recommendationLabel,
+			widget.NewForm(widget.NewFormItem(lang.L("Profile Name"), profileNameEntry)),
+			createButton,
+		),
+		nil, nil,
+		resultText,
+	)
+
+	wizardDialog = dialog.NewCustom(lang.L("Profile Wizard"), lang.L("Close"), content, window)
+	wizardDialog.SetOnClosed(func() {
+		if cancelBenchmark != nil {
+			cancelBenchmark()
+		}
+	})
+	windowSize := window.Canvas().Size()
+	wizardDialog.Resize(fyne.NewSize(windowSize.Width*0.8, windowSize.Height*0.8))
+	wizardDialog.Show()
+}
@@ -16,10 +16,10 @@ import (
 	"github.com/youpy/go-wav"
 	"image/color"
 	"io"
+	"log"
 	"math"
 	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strconv"
@@ -29,6 +29,7 @@ import (
 	"whispering-tiger-ui/Pages/ProfileSettings"
 	"whispering-tiger-ui/Profiles"
 	"whispering-tiger-ui/Resources"
+	"whispering-tiger-ui/RuntimeBackend"
 	"whispering-tiger-ui/Settings"
 	"whispering-tiger-ui/Utilities"
 	"whispering-tiger-ui/Utilities/AudioAPI"
@@ -459,7 +460,7 @@ func (p ProfileAIModelOption) CalculateMemoryConsumption(CPUbar *widget.Progress
 	}
 	if lastIndex > -1 && len(AllProfileAIModelOptions) >= lastIndex+1 {
 		// iterate through all Hardwareinfo.Models structs and find the one that matches the current Name
-		for _, model := range Hardwareinfo.Models {
+		for _, model := range Hardwareinfo.Models() {
 			fullModelName := AllProfileAIModelOptions[lastIndex].AIModel + AllProfileAIModelOptions[lastIndex].AIModelType + "_" + AllProfileAIModelOptions[lastIndex].AIModelSize
 			if model.Name == fullModelName {
 				finalMemoryUsage := Hardwareinfo.EstimateMemoryUsage(model.Float32PrecisionMemoryUsage, AllProfileAIModelOptions[lastIndex].Precision)
@@ -621,25 +622,10 @@ func CreateProfileWindow(onClose func()) fyne.CanvasObject {
 	totalGPUMemory := int64(0)
 	var ComputeCapability float32 = 0.0
 	go func() {
-		if Hardwareinfo.HasNVIDIACard() {
-			_, totalGPUMemory = Hardwareinfo.GetGPUMemory()
-			if totalGPUMemory <= 0 {
-				// fall back to registry reading of Video Memory
-				foundGPU, _ := Hardwareinfo.FindDedicatedGPUByVendor([]string{"nvidia"})
-				if foundGPU != nil && len(foundGPU) > 0 {
-					totalGPUMemory = foundGPU[0].MemoryMB
-				}
-			}
-			GPUMemoryBar.Max = float64(totalGPUMemory)
-
-			ComputeCapability = Hardwareinfo.GetGPUComputeCapability()
-		} else {
-			foundGPU, _ := Hardwareinfo.FindDedicatedGPUByVendor([]string{"nvidia", "amd", "intel"})
-			if foundGPU != nil && len(foundGPU) > 0 {
-				totalGPUMemory = foundGPU[0].MemoryMB
-			}
-			GPUMemoryBar.Max = float64(totalGPUMemory)
-		}
+		hardware := Hardwareinfo.ProbeHardware()
+		totalGPUMemory = hardware.GPUMemoryMB
+		GPUMemoryBar.Max = float64(totalGPUMemory)
+		ComputeCapability = hardware.ComputeCapability
 	}()
 
 	GPUMemoryBar.TextFormatter = func() string {
@@ -736,19 +722,12 @@ func CreateProfileWindow(onClose func()) fyne.CanvasObject {
 					detectDialog := dialog.NewCustom(lang.L("Detecting..."), lang.L("Hide"), statusBarContainer, fyne.CurrentApp().Driver().AllWindows()[1])
 					detectDialog.Show()
 
-					cmd := exec.Command("---")
 					// start application that detects the energy level and returns the value before exiting.
-					cmdArguments := []string{"--audio_api", audioApiSelect.GetSelected().Value, "--device_index", audioInputSelect.GetSelected().Value, "--audio_input_device", audioInputSelect.GetSelected().Text, "--detect_energy", "--detect_energy_time", strconv.Itoa(energyDetectionTime)}
-					if Utilities.FileExists("audioWhisper.py") {
-						cmdArguments = append([]string{"-u", "audioWhisper.py"}, cmdArguments...)
-						cmd = exec.Command("python", cmdArguments...)
-					} else if Utilities.FileExists("audioWhisper/audioWhisper.exe") {
-						cmd = exec.Command("audioWhisper/audioWhisper.exe", cmdArguments...)
-					} else {
+					cmd, err := RuntimeBackend.NewBackendToolCommand("--audio_api", audioApiSelect.GetSelected().Value, "--device_index", audioInputSelect.GetSelected().Value, "--audio_input_device", audioInputSelect.GetSelected().Text, "--detect_energy", "--detect_energy_time", strconv.Itoa(energyDetectionTime))
+					if err != nil {
 						dialog.ShowInformation(lang.L("Error"), lang.L("Could not find audioWhisper.py or audioWhisper.exe"), fyne.CurrentApp().Driver().AllWindows()[1])
 						return
 					}
-					Utilities.ProcessHideWindowAttr(cmd)
 					out, err := cmd.Output()
 					if err != nil {
 						dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[1])
@@ -921,29 +900,7 @@ func CreateProfileWindow(onClose func()) fyne.CanvasObject {
 		}
 
 		sttPrecisionSelect.OnChanged = func(s CustomWidget.TextValueOption) {
-			precisionType := Hardwareinfo.Float32
-			switch s.Value {
-			case "float32":
-				precisionType = Hardwareinfo.Float32
-			case "float16":
-				precisionType = Hardwareinfo.Float16
-			case "int32":
-				precisionType = Hardwareinfo.Int32
-			case "int16":
-				precisionType = Hardwareinfo.Int16
-			case "int8_float16":
-				precisionType = Hardwareinfo.Int8
-			case "int8":
-				precisionType = Hardwareinfo.Int8
-			case "bfloat16":
-				precisionType = Hardwareinfo.Float16
-			case "int8_bfloat16":
-				precisionType = Hardwareinfo.Int8
-			case "8bit":
-				precisionType = Hardwareinfo.Bit8
-			case "4bit":
-				precisionType = Hardwareinfo.Bit4
-			}
+			precisionType := Hardwareinfo.PrecisionFactor(s.Value)
 			if sttAiDeviceSelect.GetSelected().Value == "cpu" && (s.Value == "float16" || s.Value == "int8_float16") {
 				dialog.ShowInformation(lang.L("Information"), lang.L("Most Devices of this type do not support this precision computation. Please consider switching to some other precision.", map[string]interface{}{"Device": "CPU's", "Precision": "float16"}), fyne.CurrentApp().Driver().AllWindows()[1])
 			}
@@ -1336,29 +1293,7 @@ func CreateProfileWindow(onClose func()) fyne.CanvasObject {
 		}
 
 		txtTranslatorPrecisionSelect.OnChanged = func(s CustomWidget.TextValueOption) {
-			precisionType := Hardwareinfo.Float32
-			switch s.Value {
-			case "float32":
-				precisionType = Hardwareinfo.Float32
-			case "float16":
-				precisionType = Hardwareinfo.Float16
-			case "int32":
-				precisionType = Hardwareinfo.Int32
-			case "int16":
-				precisionType = Hardwareinfo.Int16
-			case "int8_float16":
-				precisionType = Hardwareinfo.Int8
-			case "int8":
-				precisionType = Hardwareinfo.Int8
-			case "bfloat16":
-				precisionType = Hardwareinfo.Float16
-			case "int8_bfloat16":
-				precisionType = Hardwareinfo.Int8
-			case "8bit":
-				precisionType = Hardwareinfo.Bit8
-			case "4bit":
-				precisionType = Hardwareinfo.Bit4
-			}
+			precisionType := Hardwareinfo.PrecisionFactor(s.Value)
 			if txtTranslatorDeviceSelect.GetSelected() != nil && txtTranslatorDeviceSelect.GetSelected().Value == "cpu" && (s.Value == "float16" || s.Value == "int8_float16") {
 				dialog.ShowInformation(lang.L("Information"), lang.L("Most Devices of this type do not support this precision computation. Please consider switching to some other precision.", map[string]interface{}{"Device": "CPU's", "Precision": "float16"}), fyne.CurrentApp().Driver().AllWindows()[1])
 			}
@@ -1612,16 +1547,17 @@ func CreateProfileWindow(onClose func()) fyne.CanvasObject {
 
 	// build profile list
 	profilesDir := Settings.GetConfProfileDir()
-	var settingsFiles []string
-	files, err := os.ReadDir(profilesDir)
+	settingsFiles, err := Profiles.ListProfiles(profilesDir)
 	if err != nil {
 		println(err)
 	}
-	for _, file := range files {
-		if !file.IsDir() && !strings.HasPrefix(file.Name(), ".") && (strings.HasSuffix(file.Name(), ".yaml") || strings.HasSuffix(file.Name(), ".yml")) {
-			settingsFiles = append(settingsFiles, file.Name())
-		}
+
+	// check for profiles with an older schema version and show what a migration would change
+	migrationReports, err := Profiles.MigrateProfiles(profilesDir, settingsFiles, true)
+	if err != nil {
+		log.Printf("failed to check profile migrations: %v", err)
 	}
+	showProfileMigrationDialog(profilesDir, migrationReports)
 
 	profileList := widget.NewList(
 		func() int {
@@ -1635,7 +1571,9 @@ func CreateProfileWindow(onClose func()) fyne.CanvasObject {
 		},
 	)
 
+	selectedProfileId := -1
 	profileList.OnSelected = func(id widget.ListItemID) {
+		selectedProfileId = id
 		isLoadingSettingsFile = true
 		profileHelpTextContent.Hide()
 		profileListContent.Show()
@@ -1647,6 +1585,8 @@ func CreateProfileWindow(onClose func()) fyne.CanvasObject {
 			err = profileSettings.LoadYamlSettings(filepath.Join(profilesDir, settingsFiles[id]))
 			if err != nil {
 				dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[1])
+			} else if validationErrors := profileSettings.Validate(); validationErrors != nil {
+				dialog.ShowError(validationErrors, fyne.CurrentApp().Driver().AllWindows()[1])
 			}
 		}
 		profileSettings.SettingsFilename = settingsFiles[id]
@@ -1774,8 +1714,14 @@ func CreateProfileWindow(onClose func()) fyne.CanvasObject {
 		profileForm.Items[22].Widget.(*CustomWidget.TextValueSelect).SetSelected(profileSettings.Tts_ai_device)
 
 		profileForm.OnSubmit = func() {
+			previousSettings := profileSettings
 			profileSettings.Websocket_ip = profileForm.Items[0].Widget.(*fyne.Container).Objects[0].(*widget.Entry).Text
-			profileSettings.Websocket_port, _ = strconv.Atoi(profileForm.Items[0].Widget.(*fyne.Container).Objects[1].(*widget.Entry).Text)
+			websocketPort, err := Settings.ConvertOption("websocket_port", profileForm.Items[0].Widget.(*fyne.Container).Objects[1].(*widget.Entry).Text)
+			if err != nil {
+				dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[1])
+				return
+			}
+			profileSettings.Websocket_port = websocketPort.(int)
 			profileSettings.Run_backend = profileForm.Items[0].Widget.(*fyne.Container).Objects[2].(*widget.Check).Checked
 
 			profileSettings.Audio_api = profileForm.Items[2].Widget.(*CustomWidget.TextValueSelect).GetSelected().Value
@@ -1810,12 +1756,28 @@ func CreateProfileWindow(onClose func()) fyne.CanvasObject {
 			profileSettings.Tts_type = profileForm.Items[21].Widget.(*fyne.Container).Objects[0].(*CustomWidget.TextValueSelect).GetSelected().Value
 			profileSettings.Tts_ai_device = profileForm.Items[22].Widget.(*CustomWidget.TextValueSelect).GetSelected().Value
 
+			// only the changed fields are validated, so an unchanged value does not block saving the profile
+			settingDiffs, err := Settings.CompareConfs(&previousSettings, &profileSettings)
+			if err != nil {
+				dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[1])
+				return
+			}
+			var changedFields []string
+			for _, settingDiff := range settingDiffs {
+				changedFields = append(changedFields, settingDiff.Key)
+			}
+			if validationErrors := profileSettings.ValidateFields(changedFields...); validationErrors != nil {
+				dialog.ShowError(validationErrors, fyne.CurrentApp().Driver().AllWindows()[1])
+				return
+			}
+
 			// update existing settings or create new one if it does not exist yet
 			if Utilities.FileExists(filepath.Join(profilesDir, settingsFiles[id])) {
 				profileSettings.WriteYamlSettings(filepath.Join(profilesDir, settingsFiles[id]))
 			} else {
 				newProfileEntry := Profiles.Profile{
 					SettingsFilename: settingsFiles[id],
+					Schema_version:   Profiles.CurrentSchemaVersion,
 					Websocket_ip:     profileSettings.Websocket_ip,
 					Websocket_port:   profileSettings.Websocket_port,
 					Run_Backend:      profileSettings.Run_backend,
@@ -1937,7 +1899,89 @@ func CreateProfileWindow(onClose func()) fyne.CanvasObject {
 		return nil
 	}
 
-	newProfileRow := container.NewBorder(nil, nil, nil, widget.NewButtonWithIcon(lang.L("New"), theme.DocumentCreateIcon(), func() {
+	inheritanceButton := widget.NewButtonWithIcon(lang.L("Inheritance"), theme.ListIcon(), func() {
+		if selectedProfileId < 0 || selectedProfileId >= len(settingsFiles) {
+			return
+		}
+		profileId := selectedProfileId
+		if !Utilities.FileExists(filepath.Join(profilesDir, settingsFiles[profileId])) {
+			dialog.ShowInformation(lang.L("Profile Inheritance"), lang.L("Please save the profile first."), fyne.CurrentApp().Driver().AllWindows()[1])
+			return
+		}
+		showProfileInheritanceDialog(profilesDir, settingsFiles[profileId], settingsFiles, func() {
+			// reload the profile to show the new values
+			profileList.Unselect(profileId)
+			profileList.Select(profileId)
+		})
+	})
+
+	exportButton := widget.NewButtonWithIcon(lang.L("Export"), theme.UploadIcon(), func() {
+		if selectedProfileId < 0 || selectedProfileId >= len(settingsFiles) {
+			return
+		}
+		if !Utilities.FileExists(filepath.Join(profilesDir, settingsFiles[selectedProfileId])) {
+			dialog.ShowInformation(lang.L("Export profile"), lang.L("Please save the profile first."), fyne.CurrentApp().Driver().AllWindows()[1])
+			return
+		}
+		exportProfileArchive(profilesDir, settingsFiles[selectedProfileId])
+	})
+	importButton := widget.NewButtonWithIcon(lang.L("Import"), theme.DownloadIcon(), func() {
+		importProfileArchive(profilesDir, func(profileFileName string) {
+			profileId := -1
+			for i, fileName := range settingsFiles {
+				if fileName == profileFileName {
+					profileId = i
+				}
+			}
+			if profileId < 0 {
+				settingsFiles = append(settingsFiles, profileFileName)
+				profileId = len(settingsFiles) - 1
+				profileList.Refresh()
+			}
+			profileList.Unselect(profileId)
+			profileList.Select(profileId)
+		})
+	})
+
+	selectCreatedProfile := func(profileFileName string) {
+		settingsFiles = append(settingsFiles, profileFileName)
+		profileList.Refresh()
+		profileList.Select(len(settingsFiles) - 1)
+	}
+	wizardButton := widget.NewButtonWithIcon(lang.L("Wizard"), theme.ComputerIcon(), func() {
+		showProfileWizard(profilesDir, selectCreatedProfile)
+	})
+	// guide the user through the first profile
+	if len(settingsFiles) == 0 {
+		dialog.ShowConfirm(lang.L("Profile Wizard"), lang.L("No profile found. Detect your hardware and create a profile with recommended settings?"), func(confirmed bool) {
+			if confirmed {
+				showProfileWizard(profilesDir, selectCreatedProfile)
+			}
+		}, fyne.CurrentApp().Driver().AllWindows()[1])
+	}
+
+	compareButton := widget.NewButtonWithIcon(lang.L("Compare"), theme.ViewRestoreIcon(), func() {
+		var savedProfiles []string
+		for _, fileName := range settingsFiles {
+			if Utilities.FileExists(filepath.Join(profilesDir, fileName)) {
+				savedProfiles = append(savedProfiles, fileName)
+			}
+		}
+		selectedFileName := ""
+		if selectedProfileId >= 0 && selectedProfileId < len(settingsFiles) {
+			selectedFileName = settingsFiles[selectedProfileId]
+		}
+		showProfileCompareDialog(profilesDir, selectedFileName, savedProfiles, createProfilePresetSelect.Options, func(changedFileName string) {
+			// reload the profile if it was changed
+			if selectedProfileId >= 0 && selectedProfileId < len(settingsFiles) && settingsFiles[selectedProfileId] == changedFileName {
+				profileId := selectedProfileId
+				profileList.Unselect(profileId)
+				profileList.Select(profileId)
+			}
+		})
+	})
+
+	newProfileRow := container.NewBorder(nil, nil, nil, container.NewHBox(wizardButton, importButton, exportButton, compareButton, inheritanceButton, widget.NewButtonWithIcon(lang.L("New"), theme.DocumentCreateIcon(), func() {
 		validationError := newProfileEntry.Validate()
 		if validationError != nil {
 			return
@@ -1948,7 +1992,7 @@ func CreateProfileWindow(onClose func()) fyne.CanvasObject {
 		settingsFiles = append(settingsFiles, newEntryName)
 		profileList.Select(len(settingsFiles) - 1)
 		profileList.Refresh()
-	}), container.NewAdaptiveGrid(2, createProfilePresetSelect, newProfileEntry))
+	})), container.NewAdaptiveGrid(2, createProfilePresetSelect, newProfileEntry))
 
 	memoryArea := container.NewVBox(
 		CPUMemoryBar,
@@ -1964,3 +2008,45 @@ func CreateProfileWindow(onClose func()) fyne.CanvasObject {
 
 	return mainContent
 }
+
+// showProfileMigrationDialog shows the dry-run report of pending profile migrations
+// and migrates the profiles (with backup) if confirmed.
+func showProfileMigrationDialog(profilesDir string, dryRunReports []*Profiles.MigrationReport) {
+	var pendingFiles []string
+	var reportText strings.Builder
+	for _, report := range dryRunReports {
+		if report.NeedsMigration() {
+			pendingFiles = append(pendingFiles, filepath.Base(report.File))
+			reportText.WriteString(report.String() + "\n")
+		}
+	}
+	if len(pendingFiles) == 0 {
+		return
+	}
+
+	reportEntry := widget.NewMultiLineEntry()
+	reportEntry.SetText(reportText.String())
+	reportEntry.Wrapping = fyne.TextWrapWord
+	content := container.NewBorder(
+		widget.NewLabel(lang.L("Some profiles were created with an older version and need to be migrated. A backup is created before a profile is changed.")),
+		nil, nil, nil,
+		reportEntry,
+	)
+
+	window := fyne.CurrentApp().Driver().AllWindows()[1]
+	migrationDialog := dialog.NewCustomConfirm(lang.L("Profile Migration"), lang.L("Migrate"), lang.L("Later"), content, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		reports, err := Profiles.MigrateProfiles(profilesDir, pendingFiles, false)
+		for _, report := range reports {
+			log.Print(report.String())
+		}
+		if err != nil {
+			dialog.ShowError(err, window)
+		}
+	}, window)
+	windowSize := window.Canvas().Size()
+	migrationDialog.Resize(fyne.NewSize(windowSize.Width*0.8, windowSize.Height*0.8))
+	migrationDialog.Show()
+}
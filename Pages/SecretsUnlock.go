@@ -0,0 +1,66 @@
+package Pages
+
+import (
+	"errors"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/widget"
+	"sync"
+	"whispering-tiger-ui/Secrets"
+)
+
+var secretsDialogMutex sync.Mutex
+var secretsDialogOpen = false
+
+// ShowSecretsUnlockDialog asks for the passphrase of the encrypted secrets file.
+// If the file does not exist yet, the passphrase is used to create it.
+func ShowSecretsUnlockDialog(window fyne.Window, onUnlocked func()) {
+	secretsDialogMutex.Lock()
+	if secretsDialogOpen {
+		secretsDialogMutex.Unlock()
+		return
+	}
+	secretsDialogOpen = true
+	secretsDialogMutex.Unlock()
+
+	creating := !Secrets.FileStore.Exists()
+
+	passphraseEntry := widget.NewPasswordEntry()
+	repeatEntry := widget.NewPasswordEntry()
+	formItems := []*widget.FormItem{
+		widget.NewFormItem(lang.L("Passphrase"), passphraseEntry),
+	}
+	title := lang.L("Unlock Secrets")
+	message := lang.L("Enter the passphrase of the encrypted secrets file to use the stored API keys and passwords.")
+	if creating {
+		formItems = append(formItems, widget.NewFormItem(lang.L("Repeat Passphrase"), repeatEntry))
+		title = lang.L("Protect Secrets")
+		message = lang.L("No system keyring is available. Choose a passphrase to encrypt API keys and passwords, which are not saved in the profiles.")
+	}
+	messageLabel := widget.NewLabel(message)
+	messageLabel.Wrapping = fyne.TextWrapWord
+	formItems = append([]*widget.FormItem{widget.NewFormItem("", messageLabel)}, formItems...)
+
+	unlockDialog := dialog.NewForm(title, lang.L("Unlock"), lang.L("Cancel"), formItems, func(confirmed bool) {
+		secretsDialogMutex.Lock()
+		secretsDialogOpen = false
+		secretsDialogMutex.Unlock()
+		if !confirmed {
+			return
+		}
+		if creating && passphraseEntry.Text != repeatEntry.Text {
+			dialog.ShowError(errors.New(lang.L("The passphrases do not match.")), window)
+			return
+		}
+		if err := Secrets.FileStore.Unlock(passphraseEntry.Text); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if onUnlocked != nil {
+			onUnlocked()
+		}
+	}, window)
+	unlockDialog.Resize(fyne.NewSize(450, 0))
+	unlockDialog.Show()
+}
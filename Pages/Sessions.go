@@ -0,0 +1,393 @@
+package Pages
+
+import (
+	"errors"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"whispering-tiger-ui/Fields"
+	"whispering-tiger-ui/Sessions"
+	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/Utilities"
+	"whispering-tiger-ui/Utilities/AudioAPI"
+)
+
+// sessionAudioPlayer makes sure only one clip is played at a time
+type sessionAudioPlayer struct {
+	mutex sync.Mutex
+	stop  chan struct{}
+}
+
+func (p *sessionAudioPlayer) Play(fileName string, outputDeviceName string) {
+	p.Stop()
+
+	p.mutex.Lock()
+	stop := make(chan struct{})
+	p.stop = stop
+	p.mutex.Unlock()
+
+	go func() {
+		audioBackend := AudioAPI.GetAudioBackendByID(Settings.Config.Audio_api)
+		if err := Utilities.PlayWavFile(fileName, audioBackend.Backend, outputDeviceName, stop); err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+		}
+		p.mutex.Lock()
+		if p.stop == stop {
+			p.stop = nil
+		}
+		p.mutex.Unlock()
+	}()
+}
+
+func (p *sessionAudioPlayer) Stop() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.stop != nil {
+		close(p.stop)
+		p.stop = nil
+	}
+}
+
+var sessionPlayer = &sessionAudioPlayer{}
+
+func sessionOutputDeviceOptions() ([]string, string) {
+	var options []string
+	selected := ""
+	outputDevices := Utilities.AudioOutputDeviceList[strings.ToLower(Settings.Config.Audio_api)]
+	for _, device := range outputDevices.Devices {
+		options = append(options, device.Name)
+		if outputIndex, ok := Settings.Config.Device_out_index.(int); ok && device.Index == outputIndex {
+			selected = device.Name
+		}
+	}
+	return options, selected
+}
+
+// sendRetranslateRequest translates the text of a session entry again. The result is stored in the entry and
+// onUpdated is called after that.
+func sendRetranslateRequest(session *Sessions.Session, index int, result Fields.WhisperResult, onUpdated func(err error)) {
+	toLang := result.TxtTranslationTarget
+	if toLang == "" {
+		toLang = Settings.Config.Trg_lang
+	}
+	session.AddRetranslation(index, result.Text, toLang, onUpdated)
+	fromLang := result.Language
+	if fromLang == "" {
+		fromLang = "auto"
+	}
+	sendMessage := Fields.SendMessageStruct{
+		Type: "translate_req",
+		Value: struct {
+			Text                string `json:"text"`
+			From_lang           string `json:"from_lang"`
+			To_lang             string `json:"to_lang"`
+			To_romaji           bool   `json:"to_romaji"`
+			Ignore_send_options bool   `json:"ignore_send_options"`
+		}{
+			Text:                result.Text,
+			From_lang:           fromLang,
+			To_lang:             toLang,
+			To_romaji:           Settings.Config.Txt_romaji,
+			Ignore_send_options: true,
+		},
+	}
+	sendMessage.SendMessage()
+}
+
+func CreateSessionsWindow() fyne.CanvasObject {
+	defer Utilities.PanicLogger()
+
+	var sessionList []*Sessions.Session
+	var selectedSession *Sessions.Session
+	var entries []Sessions.SessionEntry
+	selectedEntry := -1
+
+	outputDeviceOptions, selectedOutputDevice := sessionOutputDeviceOptions()
+	outputDeviceSelect := widget.NewSelect(outputDeviceOptions, nil)
+	if selectedOutputDevice != "" {
+		outputDeviceSelect.SetSelected(selectedOutputDevice)
+	}
+
+	entryList := widget.NewList(
+		func() int {
+			return len(entries)
+		},
+		func() fyne.CanvasObject {
+			timeLabel := widget.NewLabelWithStyle("00:00:00", fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+			speakerLabel := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+			audioLabel := widget.NewLabelWithStyle("", fyne.TextAlignTrailing, fyne.TextStyle{Italic: true})
+			text := widget.NewLabel("")
+			text.Wrapping = fyne.TextWrapWord
+			return container.NewBorder(nil, nil, container.NewHBox(timeLabel, speakerLabel), audioLabel, text)
+		},
+		func(id widget.ListItemID, object fyne.CanvasObject) {
+			entry := entries[id]
+			row := object.(*fyne.Container)
+			text := row.Objects[0].(*widget.Label)
+			left := row.Objects[1].(*fyne.Container)
+			audioLabel := row.Objects[2].(*widget.Label)
+
+			left.Objects[0].(*widget.Label).SetText(entry.Received.Format("15:04:05"))
+			speaker := ""
+			if entry.Result.Speaker != "" && selectedSession != nil {
+				speaker = selectedSession.SpeakerName(entry.Result.Speaker)
+			}
+			left.Objects[1].(*widget.Label).SetText(speaker)
+
+			entryText := entry.Result.Text
+			if entry.Result.TxtTranslation != "" && entry.Result.TxtTranslation != entry.Result.Text {
+				entryText += "\n" + entry.Result.TxtTranslation
+			}
+			text.SetText(entryText)
+
+			if entry.Audio_file != "" {
+				audioLabel.SetText(filepath.Base(entry.Audio_file))
+			} else {
+				audioLabel.SetText(lang.L("no audio"))
+			}
+		},
+	)
+
+	var playButton, retranslateButton *widget.Button
+	updateActionButtons := func() {
+		if selectedEntry < 0 || selectedEntry >= len(entries) {
+			playButton.Disable()
+			retranslateButton.Disable()
+			return
+		}
+		if Sessions.IsPlayableAudioFile(entries[selectedEntry].Audio_file) {
+			playButton.Enable()
+		} else {
+			playButton.Disable()
+		}
+		retranslateButton.Enable()
+	}
+
+	playButton = widget.NewButtonWithIcon(lang.L("Play"), theme.MediaPlayIcon(), func() {
+		if selectedEntry < 0 || selectedEntry >= len(entries) {
+			return
+		}
+		sessionPlayer.Play(entries[selectedEntry].Audio_file, outputDeviceSelect.Selected)
+	})
+	stopButton := widget.NewButtonWithIcon(lang.L("Stop"), theme.MediaStopIcon(), func() {
+		sessionPlayer.Stop()
+	})
+	retranslateButton = widget.NewButtonWithIcon(lang.L("Re-translate"), theme.ViewRefreshIcon(), func() {
+		if selectedSession == nil || selectedEntry < 0 || selectedEntry >= len(entries) {
+			return
+		}
+		session := selectedSession
+		sendRetranslateRequest(session, selectedEntry, entries[selectedEntry].Result, func(err error) {
+			if err != nil {
+				dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			}
+			if selectedSession == session {
+				entries = session.GetEntries()
+				entryList.Refresh()
+			}
+		})
+	})
+	updateActionButtons()
+
+	entryList.OnSelected = func(id widget.ListItemID) {
+		selectedEntry = id
+		updateActionButtons()
+	}
+	entryList.OnUnselected = func(id widget.ListItemID) {
+		selectedEntry = -1
+		updateActionButtons()
+	}
+
+	summaryText := widget.NewRichText()
+	summaryText.Wrapping = fyne.TextWrapWord
+	summaryStatus := widget.NewLabel("")
+	showSummary := func(session *Sessions.Session) {
+		if session == nil || session.GetSummary() == nil {
+			summaryText.ParseMarkdown(lang.L("No summary yet. Use 'Summarize session' to let the LLM plugin write one."))
+			return
+		}
+		summaryText.ParseMarkdown(session.GetSummary().Markdown())
+	}
+
+	showSession := func(session *Sessions.Session) {
+		selectedSession = session
+		showSummary(session)
+		selectedEntry = -1
+		entries = nil
+		if session != nil {
+			session.MatchAudioFiles()
+			entries = session.GetEntries()
+		}
+		entryList.UnselectAll()
+		entryList.Refresh()
+		updateActionButtons()
+	}
+
+	sessionListWidget := widget.NewList(
+		func() int {
+			return len(sessionList)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, object fyne.CanvasObject) {
+			session := sessionList[id]
+			label := session.Started.Format("2006-01-02 15:04:05")
+			if session.Profile != "" {
+				label += " (" + session.Profile + ")"
+			}
+			if session == Sessions.Current {
+				label += " - " + lang.L("current")
+			}
+			object.(*widget.Label).SetText(label)
+		},
+	)
+	sessionListWidget.OnSelected = func(id widget.ListItemID) {
+		showSession(sessionList[id])
+	}
+
+	refreshSessions := func() {
+		sessionList = Sessions.ListSessions()
+		sessionListWidget.UnselectAll()
+		sessionListWidget.Refresh()
+		showSession(nil)
+		if len(sessionList) > 0 {
+			sessionListWidget.Select(0)
+		}
+	}
+
+	refreshButton := widget.NewButtonWithIcon(lang.L("Refresh"), theme.ViewRefreshIcon(), func() {
+		outputDeviceSelect.Options, selectedOutputDevice = sessionOutputDeviceOptions()
+		if outputDeviceSelect.Selected == "" && selectedOutputDevice != "" {
+			outputDeviceSelect.SetSelected(selectedOutputDevice)
+		}
+		outputDeviceSelect.Refresh()
+		refreshSessions()
+	})
+	deleteButton := widget.NewButtonWithIcon(lang.L("Delete Session"), theme.DeleteIcon(), func() {
+		if selectedSession == nil {
+			return
+		}
+		session := selectedSession
+		dialog.ShowConfirm(lang.L("Delete Session"), lang.L("Are you sure you want to delete this session? Saved audio files are not deleted."), func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			if err := Sessions.DeleteSession(session.Id); err != nil {
+				dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+				return
+			}
+			refreshSessions()
+		}, fyne.CurrentApp().Driver().AllWindows()[0])
+	})
+
+	summarizeButton := widget.NewButtonWithIcon(lang.L("Summarize session"), theme.DocumentCreateIcon(), nil)
+	cancelSummaryButton := widget.NewButtonWithIcon(lang.L("Cancel"), theme.CancelIcon(), func() {
+		Sessions.CancelSummary()
+	})
+	cancelSummaryButton.Hide()
+	summarizeButton.OnTapped = func() {
+		if selectedSession == nil {
+			return
+		}
+		session := selectedSession
+		chunkSize := fyne.CurrentApp().Preferences().IntWithFallback("LlmSummaryChunkSize", Sessions.DefaultSummaryChunkSize)
+		err := session.Summarize(chunkSize, func(summary *Sessions.SessionSummary, err error) {
+			summarizeButton.Enable()
+			cancelSummaryButton.Hide()
+			summaryStatus.SetText("")
+			if err != nil && !errors.Is(err, Sessions.ErrSummaryCanceled) {
+				dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			}
+			if selectedSession == session {
+				showSummary(session)
+			}
+		})
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		summarizeButton.Disable()
+		cancelSummaryButton.Show()
+		summaryStatus.SetText(lang.L("Waiting for the LLM plugin..."))
+	}
+	exportMarkdownButton := widget.NewButtonWithIcon(lang.L("Export Markdown"), theme.DocumentSaveIcon(), func() {
+		if selectedSession == nil {
+			return
+		}
+		session := selectedSession
+		fileDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+				return
+			}
+			if writer == nil {
+				return
+			}
+			defer writer.Close()
+			_, err = writer.Write([]byte(session.ExportMarkdown()))
+			if err != nil {
+				dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+				return
+			}
+			fyne.CurrentApp().Preferences().SetString("LastCSVTranscriptionSavePath", filepath.Dir(writer.URI().Path()))
+		}, fyne.CurrentApp().Driver().AllWindows()[0])
+
+		fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".md"}))
+		dialogSize := fyne.CurrentApp().Driver().AllWindows()[0].Canvas().Size()
+		fileDialog.Resize(fyne.NewSize(dialogSize.Width-80, dialogSize.Height-80))
+
+		saveStartingPath := fyne.CurrentApp().Preferences().StringWithFallback("LastCSVTranscriptionSavePath", "")
+		if saveStartingPath != "" {
+			if _, err := os.Stat(saveStartingPath); !os.IsNotExist(err) {
+				fileLister, _ := storage.ListerForURI(storage.NewFileURI(saveStartingPath))
+				fileDialog.SetLocation(fileLister)
+			}
+		}
+		fileDialog.SetFileName("session_" + session.Id + ".md")
+		fileDialog.Show()
+	})
+
+	refreshSessions()
+
+	sessionsPanel := container.NewBorder(
+		widget.NewLabelWithStyle(lang.L("Sessions"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		container.NewHBox(refreshButton, deleteButton),
+		nil, nil,
+		sessionListWidget,
+	)
+
+	// There is no re-transcribe action: the backend has no request to transcribe a saved audio file (it only
+	// transcribes its audio input), so a re-transcription could never be answered.
+	entryActions := container.NewHBox(playButton, stopButton, retranslateButton, layout.NewSpacer())
+	entriesPanel := container.NewBorder(
+		container.NewBorder(nil, nil, widget.NewLabel(lang.L("Playback device")+":"), nil, outputDeviceSelect),
+		entryActions,
+		nil, nil,
+		entryList,
+	)
+
+	summaryPanel := container.NewBorder(
+		nil,
+		container.NewHBox(summarizeButton, cancelSummaryButton, exportMarkdownButton, summaryStatus),
+		nil, nil,
+		container.NewVScroll(summaryText),
+	)
+
+	split := container.NewHSplit(sessionsPanel, container.NewAppTabs(
+		container.NewTabItem(lang.L("Transcript"), entriesPanel),
+		container.NewTabItem(lang.L("Summary"), summaryPanel),
+	))
+	split.SetOffset(0.3)
+
+	return split
+}
@@ -11,14 +11,32 @@ import (
 func CreateSettingsWindow() fyne.CanvasObject {
 	defer Utilities.PanicLogger()
 
-	settingsFormTabs := container.NewAppTabs(
-		container.NewTabItem(lang.L("Application Options"), SettingsMappings.CreateSettingsFormByMapping(SettingsMappings.ApplicationSettingsMapping)),
-		container.NewTabItem(lang.L("Speech-to-Text Options"), SettingsMappings.CreateSettingsFormByMapping(SettingsMappings.SpeechToTextSettingsMapping)),
-		container.NewTabItem(lang.L("Text-Translate Options"), SettingsMappings.CreateSettingsFormByMapping(SettingsMappings.TextTranslateSettingsMapping)),
-		container.NewTabItem(lang.L("Text-to-Speech Options"), SettingsMappings.CreateSettingsFormByMapping(SettingsMappings.TextToSpeechSettingsMapping)),
-		container.NewTabItem(lang.L("OSC (VRChat) Options"), SettingsMappings.CreateSettingsFormByMapping(SettingsMappings.OSCSettingsMapping)),
-		container.NewTabItem(lang.L("Experimental Options"), SettingsMappings.CreateSettingsFormByMapping(SettingsMappings.ExperimentalSettingsMapping)),
-	)
+	settingsMappings := []struct {
+		name    string
+		mapping SettingsMappings.SettingsMapping
+	}{
+		{lang.L("Application Options"), SettingsMappings.ApplicationSettingsMapping},
+		{lang.L("Speech-to-Text Options"), SettingsMappings.SpeechToTextSettingsMapping},
+		{lang.L("Text-Translate Options"), SettingsMappings.TextTranslateSettingsMapping},
+		{lang.L("Text-to-Speech Options"), SettingsMappings.TextToSpeechSettingsMapping},
+		{lang.L("OSC (VRChat) Options"), SettingsMappings.OSCSettingsMapping},
+		{lang.L("Experimental Options"), SettingsMappings.ExperimentalSettingsMapping},
+	}
+
+	settingsFormTabs := container.NewAppTabs()
+	for _, settingsMapping := range settingsMappings {
+		settingsFormTabs.Append(container.NewTabItem(settingsMapping.name, SettingsMappings.CreateSettingsFormByMapping(settingsMapping.mapping)))
+	}
+	settingsFormTabs.Append(container.NewTabItem(lang.L("Transcript Post-Processing"), CreatePostProcessingWindow()))
+
+	// recreate the forms after changes were undone or redone, so they show the current values
+	rebuildSettingsForms := func() {
+		for index, settingsMapping := range settingsMappings {
+			settingsFormTabs.Items[index].Content = SettingsMappings.CreateSettingsFormByMapping(settingsMapping.mapping)
+		}
+		settingsFormTabs.Refresh()
+	}
+	settingsFormTabs.Append(container.NewTabItem(lang.L("History"), CreateSettingsHistoryWindow(rebuildSettingsForms)))
 	settingsFormTabs.SetTabLocation(container.TabLocationLeading)
 
 	return settingsFormTabs
@@ -0,0 +1,129 @@
+package Pages
+
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"strings"
+	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/Utilities"
+)
+
+// formatHistoryValue shortens a setting value for the history list
+func formatHistoryValue(name string, value interface{}) string {
+	text := strings.ReplaceAll(fmt.Sprint(Settings.MaskSecretValue(name, value)), "\n", " ")
+	if value == nil {
+		text = "-"
+	}
+	if len([]rune(text)) > 60 {
+		text = string([]rune(text)[:60]) + "..."
+	}
+	return text
+}
+
+// CreateSettingsHistoryWindow lists the setting changes of the current session, which can be undone and redone.
+func CreateSettingsHistoryWindow(onApplied func()) fyne.CanvasObject {
+	defer Utilities.PanicLogger()
+
+	entries, position := Settings.Journal.Entries()
+
+	historyList := widget.NewList(
+		func() int {
+			return len(entries)
+		},
+		func() fyne.CanvasObject {
+			timeLabel := widget.NewLabelWithStyle("00:00:00", fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+			nameLabel := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+			sourceLabel := widget.NewLabelWithStyle("", fyne.TextAlignTrailing, fyne.TextStyle{Italic: true})
+			valueLabel := widget.NewLabel("")
+			valueLabel.Truncation = fyne.TextTruncateEllipsis
+			return container.NewBorder(nil, nil, container.NewHBox(timeLabel, nameLabel), sourceLabel, valueLabel)
+		},
+		func(id widget.ListItemID, object fyne.CanvasObject) {
+			// newest entries first
+			index := len(entries) - 1 - id
+			entry := entries[index]
+			row := object.(*fyne.Container)
+			valueLabel := row.Objects[0].(*widget.Label)
+			left := row.Objects[1].(*fyne.Container)
+			sourceLabel := row.Objects[2].(*widget.Label)
+
+			undone := index >= position
+			left.Objects[0].(*widget.Label).SetText(entry.Time.Format("15:04:05"))
+			left.Objects[1].(*widget.Label).SetText(entry.Name)
+			valueLabel.SetText(formatHistoryValue(entry.Name, entry.OldValue) + " → " + formatHistoryValue(entry.Name, entry.NewValue))
+			valueLabel.TextStyle = fyne.TextStyle{Italic: undone}
+			valueLabel.Importance = widget.MediumImportance
+			if undone {
+				valueLabel.Importance = widget.LowImportance
+			}
+			valueLabel.Refresh()
+			source := entry.Source
+			if undone {
+				source = lang.L("undone") + " - " + source
+			}
+			sourceLabel.SetText(source)
+		},
+	)
+	historyList.OnSelected = func(id widget.ListItemID) {
+		historyList.UnselectAll()
+	}
+
+	undoButton := widget.NewButtonWithIcon(lang.L("Undo"), theme.ContentUndoIcon(), func() {
+		Settings.Journal.Undo()
+	})
+	redoButton := widget.NewButtonWithIcon(lang.L("Redo"), theme.ContentRedoIcon(), func() {
+		Settings.Journal.Redo()
+	})
+	revertAllButton := widget.NewButtonWithIcon(lang.L("Revert all"), theme.DeleteIcon(), func() {
+		dialog.ShowConfirm(lang.L("Revert all"), lang.L("Revert all setting changes made in this session?"), func(confirmed bool) {
+			if confirmed {
+				Settings.Journal.RevertAll()
+			}
+		}, fyne.CurrentApp().Driver().AllWindows()[0])
+	})
+	revertAllButton.Importance = widget.DangerImportance
+	emptyLabel := widget.NewLabel(lang.L("No settings were changed in this session."))
+
+	refreshHistory := func() {
+		entries, position = Settings.Journal.Entries()
+		if position > 0 {
+			undoButton.Enable()
+			revertAllButton.Enable()
+		} else {
+			undoButton.Disable()
+			revertAllButton.Disable()
+		}
+		if position < len(entries) {
+			redoButton.Enable()
+		} else {
+			redoButton.Disable()
+		}
+		if len(entries) > 0 {
+			emptyLabel.Hide()
+		} else {
+			emptyLabel.Show()
+		}
+		historyList.Refresh()
+	}
+	refreshHistory()
+
+	Settings.Journal.OnChanged("settings history", func(applied bool) {
+		refreshHistory()
+		if applied && onApplied != nil {
+			onApplied()
+		}
+	})
+
+	shortcutLabel := widget.NewLabelWithStyle(lang.L("Shortcuts: Ctrl+Z to undo, Ctrl+Y or Ctrl+Shift+Z to redo."), fyne.TextAlignLeading, fyne.TextStyle{Italic: true})
+
+	return container.NewBorder(
+		container.NewVBox(container.NewHBox(undoButton, redoButton, revertAllButton), shortcutLabel, emptyLabel),
+		nil, nil, nil,
+		historyList,
+	)
+}
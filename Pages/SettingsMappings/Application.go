@@ -5,9 +5,16 @@ import (
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/widget"
+	"strconv"
+	"strings"
+	"time"
+	"whispering-tiger-ui/CustomWidget"
 	"whispering-tiger-ui/Pages/Advanced"
+	"whispering-tiger-ui/Secrets"
 	"whispering-tiger-ui/UpdateUtility"
+	"whispering-tiger-ui/Updater"
 )
 
 var ApplicationSettingsMapping = SettingsMapping{
@@ -86,6 +93,198 @@ var ApplicationSettingsMapping = SettingsMapping{
 				return container.NewHBox(widgetCheckbox, checkForUpdatesButton)
 			},
 		},
+		{
+			SettingsName:         "Platform version",
+			SettingsInternalName: "",
+			SettingsDescription:  "",
+			DoNotSendToBackend:   true,
+			_widget: func() fyne.CanvasObject {
+				versionLabel := widget.NewLabel("")
+				rollbackButton := widget.NewButton(lang.L("Rollback"), nil)
+				updatePlatformInfo := func() {
+					info, _ := Updater.LoadPlatformInfo()
+					versionLabel.SetText(info.Version)
+					if info.CanRollback() {
+						rollbackButton.SetText(lang.L("Rollback to version", map[string]interface{}{"Version": info.PreviousVersion}))
+						rollbackButton.Enable()
+					} else {
+						rollbackButton.SetText(lang.L("Rollback"))
+						rollbackButton.Disable()
+					}
+				}
+				updatePlatformInfo()
+				rollbackButton.OnTapped = func() {
+					UpdateUtility.PlatformRollback(fyne.CurrentApp().Driver().AllWindows()[0], updatePlatformInfo)
+				}
+				historyButton := widget.NewButton(lang.L("History"), func() {
+					UpdateUtility.ShowPlatformHistory(fyne.CurrentApp().Driver().AllWindows()[0])
+				})
+
+				return container.NewHBox(versionLabel, rollbackButton, historyButton)
+			},
+		},
+		{
+			SettingsName:         "Offline source",
+			SettingsInternalName: "",
+			SettingsDescription:  "Directory or zip archive with the platform and models for installations without internet.",
+			DoNotSendToBackend:   true,
+			_widget: func() fyne.CanvasObject {
+				window := func() fyne.Window {
+					return fyne.CurrentApp().Driver().AllWindows()[0]
+				}
+				sourceLabel := widget.NewLabel("")
+				sourceLabel.Truncation = fyne.TextTruncateEllipsis
+				installButton := widget.NewButton(lang.L("Install"), func() {
+					UpdateUtility.InstallFromOfflineSource(window())
+				})
+				updateSourceLabel := func() {
+					if source := Updater.Offline.Source(); source != "" {
+						sourceLabel.SetText(source)
+						installButton.Enable()
+					} else {
+						sourceLabel.SetText(lang.L("None"))
+						installButton.Disable()
+					}
+				}
+				updateSourceLabel()
+				setSource := func(source string) {
+					progressDialog := dialog.NewCustomWithoutButtons(lang.L("Checking offline source..."), widget.NewProgressBarInfinite(), window())
+					progressDialog.Show()
+					go func() {
+						err := Updater.Offline.SetSource(source)
+						progressDialog.Hide()
+						if err != nil {
+							dialog.ShowError(err, window())
+						} else {
+							fyne.CurrentApp().Preferences().SetString("OfflineSource", source)
+						}
+						updateSourceLabel()
+					}()
+				}
+				folderButton := widget.NewButton(lang.L("Select Folder"), func() {
+					folderDialog := dialog.NewFolderOpen(func(uri fyne.ListableURI, err error) {
+						if err == nil && uri != nil {
+							setSource(uri.Path())
+						}
+					}, window())
+					folderDialog.Resize(window().Canvas().Size().SubtractWidthHeight(50, 50))
+					folderDialog.Show()
+				})
+				archiveButton := widget.NewButton(lang.L("Select Archive"), func() {
+					archiveDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+						if err == nil && reader != nil {
+							_ = reader.Close()
+							setSource(reader.URI().Path())
+						}
+					}, window())
+					archiveDialog.SetFilter(storage.NewExtensionFileFilter([]string{".zip", ".7z", ".zst", ".gz"}))
+					archiveDialog.Resize(window().Canvas().Size().SubtractWidthHeight(50, 50))
+					archiveDialog.Show()
+				})
+				clearButton := widget.NewButton(lang.L("Clear"), func() {
+					setSource("")
+				})
+
+				return container.NewBorder(nil, nil, nil, container.NewHBox(folderButton, archiveButton, clearButton, installButton), sourceLabel)
+			},
+		},
+		{
+			SettingsName:         "Network",
+			SettingsInternalName: "",
+			SettingsDescription:  "Proxy (http://, https:// or socks5://host:port), additional certificate authorities (PEM file), timeout and user agent of all downloads and update checks.",
+			DoNotSendToBackend:   true,
+			_widget: func() fyne.CanvasObject {
+				window := func() fyne.Window {
+					return fyne.CurrentApp().Driver().AllWindows()[0]
+				}
+				preferences := fyne.CurrentApp().Preferences()
+
+				proxyEntry := widget.NewEntry()
+				proxyEntry.SetPlaceHolder(lang.L("System proxy"))
+				proxyEntry.SetText(preferences.String("NetworkProxy"))
+				usernameEntry := widget.NewEntry()
+				usernameEntry.SetText(preferences.String("NetworkProxyUsername"))
+				passwordEntry := widget.NewPasswordEntry()
+				if usernameEntry.Text != "" {
+					passwordEntry.SetText(Secrets.MaskedValue)
+				}
+				caBundleEntry := widget.NewEntry()
+				caBundleEntry.SetText(preferences.String("NetworkCABundle"))
+				caBundleButton := widget.NewButton(lang.L("Select File"), func() {
+					fileDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+						if err == nil && reader != nil {
+							_ = reader.Close()
+							caBundleEntry.SetText(reader.URI().Path())
+						}
+					}, window())
+					fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".pem", ".crt", ".cer"}))
+					fileDialog.Resize(window().Canvas().Size().SubtractWidthHeight(50, 50))
+					fileDialog.Show()
+				})
+				var timeoutOptions []CustomWidget.TextValueOption
+				for _, timeout := range UpdateUtility.NetworkTimeouts {
+					timeoutOptions = append(timeoutOptions, CustomWidget.TextValueOption{Text: strconv.Itoa(timeout) + " s", Value: strconv.Itoa(timeout)})
+				}
+				timeoutSelect := CustomWidget.NewTextValueSelect("network_timeout", timeoutOptions, func(option CustomWidget.TextValueOption) {}, 0)
+				timeoutSelect.SetSelected(strconv.Itoa(preferences.IntWithFallback("NetworkTimeout", int(Updater.DefaultNetworkTimeout.Seconds()))))
+				userAgentEntry := widget.NewEntry()
+				userAgentEntry.SetPlaceHolder(Updater.DefaultUserAgent())
+				userAgentEntry.SetText(preferences.String("NetworkUserAgent"))
+
+				applyButton := widget.NewButton(lang.L("Apply"), func() {
+					settings := Updater.NetworkSettings{
+						ProxyUrl:      strings.TrimSpace(proxyEntry.Text),
+						ProxyUsername: strings.TrimSpace(usernameEntry.Text),
+						ProxyPassword: passwordEntry.Text,
+						CABundle:      strings.TrimSpace(caBundleEntry.Text),
+						UserAgent:     strings.TrimSpace(userAgentEntry.Text),
+					}
+					timeout := int(Updater.DefaultNetworkTimeout.Seconds())
+					if selected := timeoutSelect.GetSelected(); selected != nil {
+						timeout, _ = strconv.Atoi(selected.Value)
+					}
+					settings.Timeout = time.Duration(timeout) * time.Second
+					if settings.ProxyPassword == Secrets.MaskedValue {
+						// unchanged password
+						storedSettings, err := UpdateUtility.NetworkPreferences()
+						if err != nil {
+							dialog.ShowError(err, window())
+							return
+						}
+						settings.ProxyPassword = storedSettings.ProxyPassword
+					}
+					if err := Updater.ConfigureNetwork(settings); err != nil {
+						dialog.ShowError(err, window())
+						return
+					}
+					if settings.ProxyUsername != "" && settings.ProxyPassword != "" {
+						if _, err := Secrets.Save(UpdateUtility.ProxyPasswordSecret, settings.ProxyPassword); err != nil {
+							dialog.ShowError(err, window())
+						}
+					} else {
+						_ = Secrets.CurrentStore().Delete(UpdateUtility.ProxyPasswordSecret)
+					}
+					preferences.SetString("NetworkProxy", settings.ProxyUrl)
+					preferences.SetString("NetworkProxyUsername", settings.ProxyUsername)
+					preferences.SetString("NetworkCABundle", settings.CABundle)
+					preferences.SetInt("NetworkTimeout", timeout)
+					preferences.SetString("NetworkUserAgent", settings.UserAgent)
+					dialog.ShowInformation(lang.L("Network"), lang.L("Network settings applied."), window())
+				})
+
+				return container.NewVBox(
+					widget.NewForm(
+						widget.NewFormItem(lang.L("Proxy"), proxyEntry),
+						widget.NewFormItem(lang.L("Proxy username"), usernameEntry),
+						widget.NewFormItem(lang.L("Proxy password"), passwordEntry),
+						widget.NewFormItem(lang.L("CA bundle"), container.NewBorder(nil, nil, nil, caBundleButton, caBundleEntry)),
+						widget.NewFormItem(lang.L("Timeout"), timeoutSelect),
+						widget.NewFormItem(lang.L("User agent"), userAgentEntry),
+					),
+					container.NewHBox(applyButton),
+				)
+			},
+		},
 		{
 			SettingsName:         "Check for Plugin updates at startup",
 			SettingsInternalName: "",
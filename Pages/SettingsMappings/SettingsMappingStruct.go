@@ -11,6 +11,7 @@ import (
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"image/color"
+	"log"
 	"reflect"
 	"strconv"
 	"strings"
@@ -51,19 +52,42 @@ func (s *SettingsMapping) FindSettingByInternalName(internalName string) (*Setti
 	return nil, fmt.Errorf("could not find setting with internal name %s", internalName)
 }
 
+// validateValue shows an error if the value does not match the settings schema
+func (s *SettingMapping) validateValue(value interface{}) bool {
+	if _, err := Settings.Config.ValidateOption(s.SettingsInternalName, value); err != nil {
+		dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+		return false
+	}
+	return true
+}
+
+// setOption sets the value in the current settings and records the change in the settings journal
+func (s *SettingMapping) setOption(value interface{}) {
+	oldValue, _ := Settings.Config.GetOption(s.SettingsInternalName)
+	if err := Settings.Config.SetOption(s.SettingsInternalName, value); err != nil {
+		log.Printf("failed to set option: %v", err)
+		return
+	}
+	newValue, _ := Settings.Config.GetOption(s.SettingsInternalName)
+	Settings.Journal.Record(s.SettingsInternalName, oldValue, newValue, "settings")
+}
+
 func (s *SettingMapping) SendUpdatedValue(value interface{}) {
 	timerLock.Lock()
 	defer timerLock.Unlock()
 
 	// If the value is a boolean, send the update immediately
 	if v, ok := value.(bool); ok {
+		if !s.validateValue(v) {
+			return
+		}
 		sendMessage := Fields.SendMessageStruct{
 			Type:  "setting_change",
 			Name:  s.SettingsInternalName,
 			Value: v,
 		}
 		sendMessage.SendMessage()
-		Settings.Config.SetOption(s.SettingsInternalName, v)
+		s.setOption(v)
 		fmt.Println("sent message with value" + fmt.Sprintf("%v", v))
 		return
 	}
@@ -75,13 +99,16 @@ func (s *SettingMapping) SendUpdatedValue(value interface{}) {
 
 	// Set up a new timer that calls the actual message sending after debounceDuration
 	debounceTimers[s.SettingsInternalName] = time.AfterFunc(debounceDuration, func() {
+		if !s.validateValue(value) {
+			return
+		}
 		sendMessage := Fields.SendMessageStruct{
 			Type:  "setting_change",
 			Name:  s.SettingsInternalName,
 			Value: value,
 		}
 		sendMessage.SendMessage()
-		Settings.Config.SetOption(s.SettingsInternalName, value)
+		s.setOption(value)
 		fmt.Println("sent message with value" + fmt.Sprintf("%v", value))
 	})
 }
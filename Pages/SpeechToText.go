@@ -15,6 +15,7 @@ import (
 	"strings"
 	"time"
 	"whispering-tiger-ui/Fields"
+	"whispering-tiger-ui/Sessions"
 	"whispering-tiger-ui/Settings"
 	"whispering-tiger-ui/Utilities"
 )
@@ -97,20 +98,22 @@ func CreateSpeechToTextWindow() fyne.CanvasObject {
 			return len(Fields.DataBindings.WhisperResultsData)
 		},
 		func() fyne.CanvasObject {
+			speakerText := canvas.NewText("", color.NRGBA{R: 0xb2, G: 0xb2, B: 0xb2, A: 0xff})
+			speakerText.TextStyle = fyne.TextStyle{Bold: true}
 			return container.New(layout.NewGridLayout(1),
 				container.NewBorder(
 					nil,
 					nil,
-					nil,
+					container.NewPadded(speakerText),
 					widget.NewLabelWithStyle("[ResultLang]", fyne.TextAlignLeading, fyne.TextStyle{Italic: true}),
-					widget.NewLabelWithStyle("TranslateResult", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+					widget.NewRichTextWithText("TranslateResult"),
 				),
 				container.NewBorder(
 					nil,
 					nil,
 					nil,
 					widget.NewLabelWithStyle("[ResultLang]", fyne.TextAlignLeading, fyne.TextStyle{Italic: true}),
-					widget.NewLabel("Transcription"),
+					widget.NewRichTextWithText("Transcription"),
 				),
 			)
 		},
@@ -122,26 +125,53 @@ func CreateSpeechToTextWindow() fyne.CanvasObject {
 			finalTranslationContainer := mainContainer.Objects[0].(*fyne.Container)
 			originalTranscriptionContainer := mainContainer.Objects[1].(*fyne.Container)
 
-			translateResultLabel := finalTranslationContainer.Objects[0].(*widget.Label)
+			translateResultLabel := finalTranslationContainer.Objects[0].(*widget.RichText)
 			translateResultLabel.Wrapping = fyne.TextWrapWord
-			translateResultLanguageLabel := finalTranslationContainer.Objects[1].(*widget.Label)
+			speakerText := finalTranslationContainer.Objects[1].(*fyne.Container).Objects[0].(*canvas.Text)
+			translateResultLanguageLabel := finalTranslationContainer.Objects[2].(*widget.Label)
 
-			originalTranscriptionLabel := originalTranscriptionContainer.Objects[0].(*widget.Label)
+			originalTranscriptionLabel := originalTranscriptionContainer.Objects[0].(*widget.RichText)
 			originalTranscriptionLabel.Wrapping = fyne.TextWrapWord
 			originalTranscriptionLanguageLabel := originalTranscriptionContainer.Objects[1].(*widget.Label)
 
+			// color-coded speaker label (only set if speaker diarization is enabled)
+			resultSpeakers := whisperMessage.ResultSpeakers()
+			if len(resultSpeakers) > 0 {
+				speakerText.Text = Fields.GetSpeakerName(resultSpeakers[0]) + ":"
+				speakerText.Color = Fields.GetSpeakerColor(resultSpeakers[0])
+			} else {
+				speakerText.Text = ""
+			}
+			speakerText.Refresh()
+
+			transcriptionText := whisperMessage.Text
+			// show one line per speaker turn if the result contains multiple speakers
+			if len(resultSpeakers) > 1 {
+				var speakerLines []string
+				for _, segment := range whisperMessage.Segments {
+					speakerLines = append(speakerLines, Fields.GetSpeakerName(segment.Speaker)+": "+strings.TrimSpace(segment.Text))
+				}
+				transcriptionText = strings.Join(speakerLines, "\n")
+			}
+
+			// highlight low-confidence words (only available if word timestamps are enabled)
+			var resultWords []Fields.WhisperResultWord
+			if len(resultSpeakers) <= 1 {
+				resultWords = whisperMessage.Words
+			}
+
 			// bind data to elements if no translation is generated (sets transcription to top label)
 			if whisperMessage.TxtTranslation == "" {
-				translateResultLabel.SetText(whisperMessage.Text)
+				setResultRichText(translateResultLabel, transcriptionText, resultWords, true)
 				translateResultLanguageLabel.SetText("[" + whisperMessage.Language + "]")
 
-				originalTranscriptionLabel.SetText("")
+				setResultRichText(originalTranscriptionLabel, "", nil, false)
 				originalTranscriptionLanguageLabel.SetText("")
 			} else { // bind data to elements if translation was generated
-				translateResultLabel.SetText(whisperMessage.TxtTranslation)
+				setResultRichText(translateResultLabel, whisperMessage.TxtTranslation, nil, true)
 				translateResultLanguageLabel.SetText("[" + whisperMessage.TxtTranslationTarget + "]")
 
-				originalTranscriptionLabel.SetText(whisperMessage.Text)
+				setResultRichText(originalTranscriptionLabel, transcriptionText, resultWords, false)
 				originalTranscriptionLanguageLabel.SetText("[" + whisperMessage.Language + "]")
 			}
 
@@ -161,6 +191,11 @@ func CreateSpeechToTextWindow() fyne.CanvasObject {
 			Fields.Field.TranscriptionTranslationInput.SetText(whisperMessage.Text)
 		}
 
+		// open word correction editor if word timestamps are available
+		if len(whisperMessage.Words) > 0 {
+			showWordCorrectionDialog(id)
+		}
+
 		go func() {
 			time.Sleep(200 * time.Millisecond)
 			Fields.Field.WhisperResultList.Unselect(id)
@@ -215,7 +250,44 @@ func CreateSpeechToTextWindow() fyne.CanvasObject {
 
 		fileDialog.Show()
 	})
-	lastResultLine := container.NewBorder(nil, nil, saveCsvButton, nil, Fields.Field.ProcessingStatus)
+	saveTextButton := widget.NewButton(lang.L("Save Text"), func() {
+		fileDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+				return
+			}
+			if writer == nil {
+				return
+			}
+			defer writer.Close()
+			_, err = writer.Write([]byte(Fields.ExportTranscriptText(Fields.DataBindings.WhisperResultsData)))
+			if err != nil {
+				dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+				return
+			}
+			fyne.CurrentApp().Preferences().SetString("LastCSVTranscriptionSavePath", filepath.Dir(writer.URI().Path()))
+		}, fyne.CurrentApp().Driver().AllWindows()[0])
+
+		fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".txt"}))
+		dialogSize := fyne.CurrentApp().Driver().AllWindows()[0].Canvas().Size()
+		fileDialog.Resize(fyne.NewSize(dialogSize.Width-80, dialogSize.Height-80))
+
+		saveStartingPath := fyne.CurrentApp().Preferences().StringWithFallback("LastCSVTranscriptionSavePath", "")
+		if saveStartingPath != "" {
+			if _, err := os.Stat(saveStartingPath); !os.IsNotExist(err) {
+				fileLister, _ := storage.ListerForURI(storage.NewFileURI(saveStartingPath))
+				fileDialog.SetLocation(fileLister)
+			}
+		}
+		fileDialog.SetFileName("transcription_" + time.Now().Format("2006-01-02_15-04-05") + ".txt")
+		fileDialog.Show()
+	})
+
+	speakerNamesButton := widget.NewButton(lang.L("Speakers"), func() {
+		showSpeakerNamesDialog()
+	})
+
+	lastResultLine := container.NewBorder(nil, nil, container.NewHBox(saveCsvButton, saveTextButton, speakerNamesButton), nil, Fields.Field.ProcessingStatus)
 
 	whisperResultContainer := container.NewStack(
 		container.NewBorder(
@@ -237,3 +309,45 @@ func CreateSpeechToTextWindow() fyne.CanvasObject {
 
 	return mainContent
 }
+
+// showSpeakerNamesDialog lets the user rename the detected speakers of the current session
+func showSpeakerNamesDialog() {
+	parentWindow := fyne.CurrentApp().Driver().AllWindows()[0]
+
+	speakers := Fields.KnownSpeakers()
+	if len(speakers) == 0 {
+		dialog.ShowInformation(lang.L("Speakers"), lang.L("No speakers detected yet. Enable speaker diarization in the profile settings."), parentWindow)
+		return
+	}
+
+	var formItems []*widget.FormItem
+	speakerEntries := map[string]*widget.Entry{}
+	for _, speaker := range speakers {
+		speakerEntry := widget.NewEntry()
+		speakerEntry.PlaceHolder = speaker
+		if name := Fields.GetSpeakerName(speaker); name != speaker {
+			speakerEntry.SetText(name)
+		}
+		speakerEntries[speaker] = speakerEntry
+
+		speakerColor := canvas.NewRectangle(Fields.GetSpeakerColor(speaker))
+		speakerColor.SetMinSize(fyne.NewSize(12, 12))
+		formItems = append(formItems, widget.NewFormItem(speaker, container.NewBorder(nil, nil, container.NewCenter(speakerColor), nil, speakerEntry)))
+	}
+
+	speakerDialog := dialog.NewForm(lang.L("Rename Speakers"), lang.L("Save"), lang.L("Cancel"), formItems, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		for speaker, speakerEntry := range speakerEntries {
+			Fields.SetSpeakerName(speaker, speakerEntry.Text)
+		}
+		// the names are stored in the session, so it is shown with them later
+		if err := Sessions.Current.SetSpeakerNames(Fields.SpeakerNameMap()); err != nil {
+			dialog.ShowError(err, parentWindow)
+		}
+		Fields.Field.WhisperResultList.Refresh()
+	}, parentWindow)
+	speakerDialog.Resize(fyne.NewSize(400, 0))
+	speakerDialog.Show()
+}
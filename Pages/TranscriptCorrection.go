@@ -0,0 +1,164 @@
+package Pages
+
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"strings"
+	"whispering-tiger-ui/Fields"
+)
+
+// setResultRichText sets the text of a result list entry.
+// If words are given and match the text, low-confidence words are highlighted.
+func setResultRichText(richText *widget.RichText, text string, words []Fields.WhisperResultWord, bold bool) {
+	textStyle := widget.RichTextStyleInline
+	textStyle.TextStyle = fyne.TextStyle{Bold: bold}
+
+	if len(words) == 0 || (Fields.WhisperResult{Words: words}).TextFromWords() != text {
+		richText.Segments = []widget.RichTextSegment{&widget.TextSegment{Text: text, Style: textStyle}}
+		richText.Refresh()
+		return
+	}
+
+	lowConfidenceStyle := textStyle
+	lowConfidenceStyle.ColorName = theme.ColorNameWarning
+	lowConfidenceStyle.TextStyle.Italic = true
+
+	var segments []widget.RichTextSegment
+	for i, word := range words {
+		wordText := word.Word
+		if i == 0 {
+			wordText = strings.TrimLeft(wordText, " ")
+		}
+		if word.IsLowConfidence() {
+			segments = append(segments, &widget.TextSegment{Text: wordText, Style: lowConfidenceStyle})
+		} else {
+			segments = append(segments, &widget.TextSegment{Text: wordText, Style: textStyle})
+		}
+	}
+	richText.Segments = segments
+	richText.Refresh()
+}
+
+func sendTextToOsc(text string) {
+	sendMessage := Fields.SendMessageStruct{
+		Type: "send_osc",
+		Value: struct {
+			Text *string `json:"text"`
+		}{
+			Text: &text,
+		},
+	}
+	sendMessage.SendMessage()
+}
+
+func sendTextToTts(text string) {
+	sendMessage := Fields.SendMessageStruct{
+		Type: "tts_req",
+		Value: struct {
+			Text     string `json:"text"`
+			ToDevice bool   `json:"to_device"`
+			Download bool   `json:"download"`
+		}{
+			Text:     text,
+			ToDevice: true,
+			Download: false,
+		},
+	}
+	sendMessage.SendMessage()
+}
+
+// showWordCorrectionDialog shows an editor to fix misheard words of a result before sending it to OSC or TTS.
+func showWordCorrectionDialog(resultIndex int) {
+	parentWindow := fyne.CurrentApp().Driver().AllWindows()[0]
+
+	// results are only ever prepended, so the current index can be calculated from the list length later
+	resultCountOnOpen := len(Fields.DataBindings.WhisperResultsData)
+	whisperResult := Fields.DataBindings.WhisperResultsData[resultIndex]
+
+	words := make([]Fields.WhisperResultWord, len(whisperResult.Words))
+	copy(words, whisperResult.Words)
+
+	correctedText := func() string {
+		return Fields.WhisperResult{Words: words}.TextFromWords()
+	}
+
+	previewText := widget.NewRichText()
+	previewText.Wrapping = fyne.TextWrapWord
+	setResultRichText(previewText, correctedText(), words, false)
+
+	wordRows := container.NewVBox()
+	for i := range words {
+		wordIndex := i
+
+		timeLabel := widget.NewLabelWithStyle(Fields.FormatTimestamp(words[wordIndex].Start)+" - "+Fields.FormatTimestamp(words[wordIndex].End), fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+
+		probabilityText := canvas.NewText(fmt.Sprintf("%3.0f%%", words[wordIndex].Probability*100), theme.Color(theme.ColorNameForeground))
+		if words[wordIndex].IsLowConfidence() {
+			probabilityText.Color = theme.Color(theme.ColorNameWarning)
+			probabilityText.TextStyle = fyne.TextStyle{Bold: true}
+		}
+
+		wordEntry := widget.NewEntry()
+		wordEntry.SetText(strings.TrimSpace(words[wordIndex].Word))
+		wordEntry.OnChanged = func(value string) {
+			words[wordIndex].SetText(value)
+			probabilityText.Text = lang.L("corrected")
+			probabilityText.Color = theme.Color(theme.ColorNameSuccess)
+			probabilityText.Refresh()
+			setResultRichText(previewText, correctedText(), words, false)
+		}
+
+		wordRows.Add(container.NewBorder(nil, nil, timeLabel, container.NewPadded(probabilityText), wordEntry))
+	}
+
+	applyCorrection := func() {
+		currentIndex := resultIndex + len(Fields.DataBindings.WhisperResultsData) - resultCountOnOpen
+		if currentIndex < 0 || currentIndex >= len(Fields.DataBindings.WhisperResultsData) {
+			return
+		}
+		Fields.DataBindings.WhisperResultsData[currentIndex].Words = words
+		Fields.DataBindings.WhisperResultsData[currentIndex].Text = correctedText()
+		Fields.Field.WhisperResultList.RefreshItem(currentIndex)
+
+		Fields.Field.TranscriptionInput.SetText(correctedText())
+		if Fields.DataBindings.WhisperResultsData[currentIndex].TxtTranslation == "" {
+			Fields.Field.TranscriptionTranslationInput.SetText(correctedText())
+		}
+	}
+
+	buttonRow := container.NewHBox(
+		layout.NewSpacer(),
+		widget.NewButton(lang.L("Send to OSC (VRChat)"), func() {
+			applyCorrection()
+			sendTextToOsc(correctedText())
+		}),
+		widget.NewButton(lang.L("Send to Text-to-Speech"), func() {
+			applyCorrection()
+			sendTextToTts(correctedText())
+		}),
+	)
+
+	content := container.NewBorder(
+		container.NewVBox(previewText, widget.NewSeparator()),
+		container.NewVBox(widget.NewSeparator(), buttonRow),
+		nil, nil,
+		container.NewVScroll(wordRows),
+	)
+
+	correctionDialog := dialog.NewCustomConfirm(lang.L("Correct Transcription"), lang.L("Apply"), lang.L("Cancel"), content, func(confirmed bool) {
+		if confirmed {
+			applyCorrection()
+		}
+	}, parentWindow)
+
+	dialogSize := parentWindow.Canvas().Size()
+	correctionDialog.Resize(fyne.NewSize(dialogSize.Width*0.6, dialogSize.Height-80))
+	correctionDialog.Show()
+}
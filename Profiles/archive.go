@@ -0,0 +1,493 @@
+package Profiles
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"whispering-tiger-ui/ModelDownloader"
+	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/UpdateUtility"
+	"whispering-tiger-ui/Utilities"
+)
+
+// A profile archive is a zip file containing the resolved profile, the files of the enabled plugins
+// and a manifest listing the plugins with their settings and the models the profile needs.
+
+const ArchiveFormatVersion = 1
+
+const (
+	archiveManifestFile = "manifest.json"
+	archiveProfileFile  = "profile.yaml"
+	archivePluginDir    = "plugins/"
+)
+
+//goland:noinspection GoSnakeCaseUsage
+type ArchivePlugin struct {
+	Class   string `json:"class"`
+	File    string `json:"file,omitempty"`
+	Version string `json:"version,omitempty"`
+	Sha256  string `json:"sha256,omitempty"`
+	Enabled bool   `json:"enabled"`
+	// Settings are the plugin_settings of the plugin class
+	Settings interface{} `json:"settings,omitempty"`
+}
+
+//goland:noinspection GoSnakeCaseUsage
+type ArchiveManifest struct {
+	Format_version int                              `json:"format_version"`
+	Profile        string                           `json:"profile"`
+	Schema_version int                              `json:"schema_version"`
+	Created        time.Time                        `json:"created"`
+	Plugins        []ArchivePlugin                  `json:"plugins,omitempty"`
+	Models         []ModelDownloader.ModelReference `json:"models,omitempty"`
+}
+
+// MissingPluginFiles returns the classes of enabled plugins whose file was not found while exporting.
+func (m *ArchiveManifest) MissingPluginFiles() []string {
+	var classes []string
+	for _, plugin := range m.Plugins {
+		if plugin.File == "" {
+			classes = append(classes, plugin.Class)
+		}
+	}
+	return classes
+}
+
+func whisperCT2ModelType(model string, precision string) string {
+	// CT2 models are only available as float16 and float32 and are converted to the other precisions when loaded
+	if strings.Contains(precision, "float32") {
+		return model + "_float32"
+	}
+	return model + "_float16"
+}
+
+func whisperModels(sttType string, model string, precision string) []ModelDownloader.ModelReference {
+	switch sttType {
+	case "original_whisper":
+		return []ModelDownloader.ModelReference{{Name: "Whisper", Type: model}}
+	case "faster_whisper":
+		tokenizer := "normal"
+		if strings.HasSuffix(model, ".en") {
+			tokenizer = "en"
+		}
+		return []ModelDownloader.ModelReference{
+			{Name: "WhisperCT2", Type: whisperCT2ModelType(model, precision)},
+			{Name: "WhisperCT2_Tokenizer", Type: tokenizer},
+		}
+	}
+	return nil
+}
+
+// RequiredModels returns the models of the model list which are used by the settings.
+// Models which are not part of the list (like custom models) are not returned.
+func RequiredModels(conf *Settings.Conf) []ModelDownloader.ModelReference {
+	candidates := whisperModels(conf.Stt_type, conf.Model, conf.Whisper_precision)
+	if conf.Realtime && conf.Realtime_whisper_model != "" {
+		candidates = append(candidates, whisperModels(conf.Stt_type, conf.Realtime_whisper_model, conf.Whisper_precision)...)
+	}
+	if conf.Txt_translator == "NLLB200_CT2" {
+		candidates = append(candidates,
+			ModelDownloader.ModelReference{Name: "NLLB200CT2", Type: conf.Txt_translator_size},
+			ModelDownloader.ModelReference{Name: "sentencepiece", Type: "default"},
+		)
+	}
+
+	var models []ModelDownloader.ModelReference
+	for _, model := range candidates {
+		if ModelDownloader.ModelExists(model) && !containsModel(models, model) {
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
+func containsModel(models []ModelDownloader.ModelReference, model ModelDownloader.ModelReference) bool {
+	for _, m := range models {
+		if m == model {
+			return true
+		}
+	}
+	return false
+}
+
+func pluginSettingsOf(pluginSettings interface{}, class string) interface{} {
+	if settingsMap, ok := pluginSettings.(map[string]interface{}); ok {
+		return settingsMap[class]
+	}
+	return nil
+}
+
+// ExportProfileArchive writes the profile with its enabled plugins and the manifest as zip archive.
+// Profiles which extend other profiles are exported fully resolved.
+func ExportProfileArchive(profileFile string, writer io.Writer) (*ArchiveManifest, error) {
+	var conf Settings.Conf
+	if err := conf.LoadYamlSettings(profileFile); err != nil {
+		return nil, err
+	}
+	conf.Extends = ""
+	// secrets are not exported, they have to be entered again after the import
+	conf.RemoveSecrets()
+	profileYaml, err := yaml.Marshal(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &ArchiveManifest{
+		Format_version: ArchiveFormatVersion,
+		Profile:        filepath.Base(profileFile),
+		Schema_version: conf.Schema_version,
+		Created:        time.Now(),
+		Models:         RequiredModels(&conf),
+	}
+
+	var pluginClasses []string
+	for class, enabled := range conf.Plugins {
+		if enabled {
+			pluginClasses = append(pluginClasses, class)
+		}
+	}
+	sort.Strings(pluginClasses)
+
+	zipWriter := zip.NewWriter(writer)
+	localPluginFiles := UpdateUtility.ParseLocalPluginFiles()
+	for _, class := range pluginClasses {
+		plugin := ArchivePlugin{
+			Class:    class,
+			Enabled:  true,
+			Settings: pluginSettingsOf(conf.Plugin_settings, class),
+		}
+		localPlugin := UpdateUtility.FindLocalPluginFileByClass(localPluginFiles, class)
+		if localPlugin.FilePath != "" {
+			pluginCode, err := os.ReadFile(localPlugin.FilePath)
+			if err != nil {
+				return nil, err
+			}
+			plugin.File = filepath.Base(localPlugin.FilePath)
+			plugin.Version = localPlugin.LocalVersion
+			plugin.Sha256 = localPlugin.SHA256
+			if err = writeZipFile(zipWriter, archivePluginDir+plugin.File, pluginCode); err != nil {
+				return nil, err
+			}
+		}
+		manifest.Plugins = append(manifest.Plugins, plugin)
+	}
+
+	if err = writeZipFile(zipWriter, archiveProfileFile, profileYaml); err != nil {
+		return nil, err
+	}
+	manifestJson, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err = writeZipFile(zipWriter, archiveManifestFile, manifestJson); err != nil {
+		return nil, err
+	}
+	return manifest, zipWriter.Close()
+}
+
+func writeZipFile(zipWriter *zip.Writer, name string, content []byte) error {
+	fileWriter, err := zipWriter.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = fileWriter.Write(content)
+	return err
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// isPlainFileName returns true if the name does not contain a path, so it can not be written outside the target directory.
+func isPlainFileName(name string) bool {
+	return name != "" && name != "." && name != ".." && filepath.Base(name) == name && !strings.ContainsAny(name, `/\:`)
+}
+
+type ProfileArchive struct {
+	Manifest ArchiveManifest
+	// Profile is the profile yaml, migrated to the current schema version
+	Profile   []byte
+	Conf      Settings.Conf
+	Migration *MigrationReport
+	// Plugins maps the plugin file names to their content
+	Plugins map[string][]byte
+}
+
+// ReadProfileArchive reads and validates a profile archive.
+func ReadProfileArchive(data []byte) (*ProfileArchive, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("not a profile archive: %w", err)
+	}
+	files := map[string]*zip.File{}
+	for _, file := range zipReader.File {
+		files[file.Name] = file
+	}
+
+	archive := &ProfileArchive{Plugins: map[string][]byte{}}
+
+	manifestFile, ok := files[archiveManifestFile]
+	if !ok {
+		return nil, fmt.Errorf("not a profile archive: %s is missing", archiveManifestFile)
+	}
+	manifestJson, err := readZipFile(manifestFile)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(manifestJson, &archive.Manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", archiveManifestFile, err)
+	}
+	if archive.Manifest.Format_version > ArchiveFormatVersion {
+		return nil, fmt.Errorf("the profile archive was created by a newer version (format %d)", archive.Manifest.Format_version)
+	}
+	if !isPlainFileName(archive.Manifest.Profile) || (!strings.HasSuffix(archive.Manifest.Profile, ".yaml") && !strings.HasSuffix(archive.Manifest.Profile, ".yml")) {
+		return nil, fmt.Errorf("invalid profile name %q", archive.Manifest.Profile)
+	}
+
+	// profile
+	profileFile, ok := files[archiveProfileFile]
+	if !ok {
+		return nil, fmt.Errorf("not a profile archive: %s is missing", archiveProfileFile)
+	}
+	profileYaml, err := readZipFile(profileFile)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	if err = yaml.Unmarshal(profileYaml, &values); err != nil {
+		return nil, &Settings.LoadError{File: archiveProfileFile, Err: err}
+	}
+	// the base profile of the exporting system is not part of the archive
+	delete(values, "extends")
+	archive.Migration, err = MigrateSettings(values)
+	if err != nil {
+		return nil, err
+	}
+	if archive.Profile, err = yaml.Marshal(values); err != nil {
+		return nil, err
+	}
+	if err = yaml.Unmarshal(archive.Profile, &archive.Conf); err != nil {
+		return nil, &Settings.LoadError{File: archiveProfileFile, Err: err}
+	}
+	// only the settings of the archive are validated, the other settings keep the defaults
+	var archiveFields []string
+	for key := range values {
+		archiveFields = append(archiveFields, key)
+	}
+	sort.Strings(archiveFields)
+	if validationErrors := archive.Conf.ValidateFields(archiveFields...); len(validationErrors) > 0 {
+		return nil, validationErrors
+	}
+
+	// plugins
+	for _, plugin := range archive.Manifest.Plugins {
+		if plugin.File == "" {
+			continue
+		}
+		if !isPlainFileName(plugin.File) || !strings.HasSuffix(plugin.File, ".py") {
+			return nil, fmt.Errorf("invalid plugin file name %q", plugin.File)
+		}
+		pluginFile, ok := files[archivePluginDir+plugin.File]
+		if !ok {
+			return nil, fmt.Errorf("plugin file %s is missing in the archive", plugin.File)
+		}
+		pluginCode, err := readZipFile(pluginFile)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := Utilities.FileHash(bytes.NewReader(pluginCode))
+		if err != nil {
+			return nil, err
+		}
+		if plugin.Sha256 != "" && !strings.EqualFold(hash, plugin.Sha256) {
+			return nil, fmt.Errorf("checksum of plugin file %s does not match", plugin.File)
+		}
+		archive.Plugins[plugin.File] = pluginCode
+	}
+
+	for _, model := range archive.Manifest.Models {
+		if !ModelDownloader.ModelExists(model) {
+			return nil, fmt.Errorf("unknown model %s", model)
+		}
+	}
+
+	return archive, nil
+}
+
+type ArchiveFileStatus string
+
+const (
+	ArchiveFileNew       ArchiveFileStatus = "new"
+	ArchiveFileChanged   ArchiveFileStatus = "changed"
+	ArchiveFileUnchanged ArchiveFileStatus = "unchanged"
+)
+
+type SettingDifference struct {
+	Key      string
+	OldValue interface{}
+	NewValue interface{}
+}
+
+func (d SettingDifference) String() string {
+	d.OldValue = Settings.MaskSecretValue(d.Key, d.OldValue)
+	d.NewValue = Settings.MaskSecretValue(d.Key, d.NewValue)
+	switch {
+	case d.OldValue == nil:
+		return fmt.Sprintf("%s: + %s", d.Key, formatChangeValue(d.NewValue))
+	case d.NewValue == nil:
+		return fmt.Sprintf("%s: - %s", d.Key, formatChangeValue(d.OldValue))
+	}
+	return fmt.Sprintf("%s: %s -> %s", d.Key, formatChangeValue(d.OldValue), formatChangeValue(d.NewValue))
+}
+
+type ArchiveFileDiff struct {
+	File   string
+	Status ArchiveFileStatus
+	// Settings lists the changed settings if the file is a profile
+	Settings []SettingDifference
+}
+
+func diffSettings(oldYaml []byte, newYaml []byte) ([]SettingDifference, error) {
+	oldValues := map[string]interface{}{}
+	newValues := map[string]interface{}{}
+	if err := yaml.Unmarshal(oldYaml, &oldValues); err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(newYaml, &newValues); err != nil {
+		return nil, err
+	}
+	var keys []string
+	for key := range oldValues {
+		keys = append(keys, key)
+	}
+	for key := range newValues {
+		if _, ok := oldValues[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var differences []SettingDifference
+	for _, key := range keys {
+		oldValue, newValue := oldValues[key], newValues[key]
+		if fmt.Sprint(oldValue) != fmt.Sprint(newValue) {
+			differences = append(differences, SettingDifference{Key: key, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+	return differences, nil
+}
+
+// Diff compares the files of the archive with the existing files they would replace.
+func (a *ProfileArchive) Diff(profilesDir string, profileName string) ([]ArchiveFileDiff, error) {
+	var diffs []ArchiveFileDiff
+
+	profileFile := filepath.Join(profilesDir, profileName)
+	profileDiff := ArchiveFileDiff{File: profileFile, Status: ArchiveFileNew}
+	if Utilities.FileExists(profileFile) {
+		// compare with the resolved profile, since the archive contains all values
+		var existingConf Settings.Conf
+		if err := existingConf.LoadYamlSettings(profileFile); err != nil {
+			return nil, err
+		}
+		existingConf.Extends = ""
+		existingYaml, err := yaml.Marshal(existingConf)
+		if err != nil {
+			return nil, err
+		}
+		var importedConf Settings.Conf
+		if err = yaml.Unmarshal(a.Profile, &importedConf); err != nil {
+			return nil, err
+		}
+		importedYaml, err := yaml.Marshal(importedConf)
+		if err != nil {
+			return nil, err
+		}
+		if profileDiff.Settings, err = diffSettings(existingYaml, importedYaml); err != nil {
+			return nil, err
+		}
+		profileDiff.Status = ArchiveFileUnchanged
+		if len(profileDiff.Settings) > 0 {
+			profileDiff.Status = ArchiveFileChanged
+		}
+	}
+	diffs = append(diffs, profileDiff)
+
+	var pluginFiles []string
+	for pluginFile := range a.Plugins {
+		pluginFiles = append(pluginFiles, pluginFile)
+	}
+	sort.Strings(pluginFiles)
+	for _, pluginFile := range pluginFiles {
+		pluginPath := filepath.Join(UpdateUtility.PluginDir, pluginFile)
+		pluginDiff := ArchiveFileDiff{File: pluginPath, Status: ArchiveFileNew}
+		if existingCode, err := os.ReadFile(pluginPath); err == nil {
+			pluginDiff.Status = ArchiveFileUnchanged
+			if !bytes.Equal(existingCode, a.Plugins[pluginFile]) {
+				pluginDiff.Status = ArchiveFileChanged
+			}
+		}
+		diffs = append(diffs, pluginDiff)
+	}
+	return diffs, nil
+}
+
+// PluginFilesToInstall returns the plugin files of the archive which are not installed or differ from the installed file.
+// Plugins are executable code, so they are only written after the user confirmed them.
+func (a *ProfileArchive) PluginFilesToInstall() []string {
+	var pluginFiles []string
+	for pluginFile, pluginCode := range a.Plugins {
+		existingCode, err := os.ReadFile(filepath.Join(UpdateUtility.PluginDir, pluginFile))
+		if err != nil || !bytes.Equal(existingCode, pluginCode) {
+			pluginFiles = append(pluginFiles, pluginFile)
+		}
+	}
+	sort.Strings(pluginFiles)
+	return pluginFiles
+}
+
+// Import writes the profile and the confirmed plugin files of the archive. The profile is saved as profileName in the
+// profiles directory. Plugin files which are not listed in pluginFiles are skipped.
+func (a *ProfileArchive) Import(profilesDir string, profileName string, pluginFiles []string) error {
+	if !isPlainFileName(profileName) {
+		return fmt.Errorf("invalid profile name %q", profileName)
+	}
+	if len(pluginFiles) > 0 {
+		if err := os.MkdirAll(UpdateUtility.PluginDir, 0755); err != nil {
+			return err
+		}
+	}
+	for _, pluginFile := range pluginFiles {
+		pluginCode, ok := a.Plugins[pluginFile]
+		if !ok {
+			return fmt.Errorf("plugin file %s is not part of the archive", pluginFile)
+		}
+		if err := os.WriteFile(filepath.Join(UpdateUtility.PluginDir, pluginFile), pluginCode, 0644); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(filepath.Join(profilesDir, profileName), a.Profile, 0644)
+}
+
+// MissingModels returns the required models of the archive which are not downloaded yet.
+func (a *ProfileArchive) MissingModels() []ModelDownloader.ModelReference {
+	var models []ModelDownloader.ModelReference
+	for _, model := range a.Manifest.Models {
+		if !ModelDownloader.IsModelDownloaded(model) {
+			models = append(models, model)
+		}
+	}
+	return models
+}
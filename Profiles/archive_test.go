@@ -0,0 +1,208 @@
+package Profiles
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"whispering-tiger-ui/UpdateUtility"
+)
+
+const testPluginCode = "class TestPlugin:\n    pass\n"
+
+var testPluginSha256 = fmt.Sprintf("%x", sha256.Sum256([]byte(testPluginCode)))
+
+// writeTestArchive creates a profile archive with the manifest and the files by their name.
+func writeTestArchive(t *testing.T, manifest ArchiveManifest, files map[string]string) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	zipWriter := zip.NewWriter(&buffer)
+	manifestJson, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = writeZipFile(zipWriter, archiveManifestFile, manifestJson); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err = writeZipFile(zipWriter, name, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = zipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+// chdirTemp changes into a temporary directory for the duration of the test, since the plugin directory is relative.
+func chdirTemp(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(previous) })
+	return dir
+}
+
+func TestIsPlainFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "profile.yaml", want: true},
+		{name: "", want: false},
+		{name: ".", want: false},
+		{name: "..", want: false},
+		{name: "../profile.yaml", want: false},
+		{name: "dir/profile.yaml", want: false},
+		{name: `dir\profile.yaml`, want: false},
+		{name: "C:profile.yaml", want: false},
+		{name: "/etc/profile.yaml", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPlainFileName(tt.name); got != tt.want {
+				t.Errorf("isPlainFileName(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadProfileArchive(t *testing.T) {
+	validManifest := ArchiveManifest{
+		Format_version: ArchiveFormatVersion,
+		Profile:        "profile.yaml",
+		Plugins:        []ArchivePlugin{{Class: "TestPlugin", File: "test_plugin.py", Sha256: testPluginSha256, Enabled: true}},
+	}
+	validFiles := map[string]string{
+		archiveProfileFile:                  "energy: 200\nextends: base.yaml\n",
+		archivePluginDir + "test_plugin.py": testPluginCode,
+	}
+	withManifest := func(change func(manifest *ArchiveManifest)) ArchiveManifest {
+		manifest := validManifest
+		manifest.Plugins = append([]ArchivePlugin{}, validManifest.Plugins...)
+		change(&manifest)
+		return manifest
+	}
+	withFiles := func(name, content string) map[string]string {
+		files := map[string]string{}
+		for fileName, fileContent := range validFiles {
+			files[fileName] = fileContent
+		}
+		if content == "" {
+			delete(files, name)
+		} else {
+			files[name] = content
+		}
+		return files
+	}
+
+	tests := []struct {
+		name     string
+		manifest ArchiveManifest
+		files    map[string]string
+		wantErr  string
+	}{
+		{name: "valid", manifest: validManifest, files: validFiles},
+		{name: "newer format", manifest: withManifest(func(m *ArchiveManifest) { m.Format_version = ArchiveFormatVersion + 1 }), files: validFiles, wantErr: "newer version"},
+		{name: "profile path", manifest: withManifest(func(m *ArchiveManifest) { m.Profile = "../profile.yaml" }), files: validFiles, wantErr: "invalid profile name"},
+		{name: "profile extension", manifest: withManifest(func(m *ArchiveManifest) { m.Profile = "profile.txt" }), files: validFiles, wantErr: "invalid profile name"},
+		{name: "missing profile", manifest: validManifest, files: withFiles(archiveProfileFile, ""), wantErr: "profile.yaml is missing"},
+		{name: "invalid setting", manifest: validManifest, files: withFiles(archiveProfileFile, "websocket_port: 70000\n"), wantErr: "websocket_port"},
+		{name: "plugin path", manifest: withManifest(func(m *ArchiveManifest) { m.Plugins[0].File = "../test_plugin.py" }), files: validFiles, wantErr: "invalid plugin file name"},
+		{name: "plugin extension", manifest: withManifest(func(m *ArchiveManifest) { m.Plugins[0].File = "test_plugin.exe" }), files: validFiles, wantErr: "invalid plugin file name"},
+		{name: "missing plugin", manifest: validManifest, files: withFiles(archivePluginDir+"test_plugin.py", ""), wantErr: "is missing in the archive"},
+		{name: "plugin checksum", manifest: validManifest, files: withFiles(archivePluginDir+"test_plugin.py", "import os\n"), wantErr: "checksum"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, err := ReadProfileArchive(writeTestArchive(t, tt.manifest, tt.files))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ReadProfileArchive() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadProfileArchive() error = %v", err)
+			}
+			if archive.Conf.Energy != 200 || archive.Conf.Extends != "" {
+				t.Errorf("Conf = energy %d, extends %q, want 200 without extends", archive.Conf.Energy, archive.Conf.Extends)
+			}
+			if archive.Conf.Schema_version != CurrentSchemaVersion {
+				t.Errorf("Schema_version = %d, want the migrated version %d", archive.Conf.Schema_version, CurrentSchemaVersion)
+			}
+			if string(archive.Plugins["test_plugin.py"]) != testPluginCode {
+				t.Errorf("Plugins = %v, want test_plugin.py", archive.Plugins)
+			}
+		})
+	}
+
+	if _, err := ReadProfileArchive([]byte("no zip")); err == nil {
+		t.Error("ReadProfileArchive() of no zip file succeeded")
+	}
+}
+
+func TestProfileArchiveImport(t *testing.T) {
+	dir := chdirTemp(t)
+	if err := os.MkdirAll(UpdateUtility.PluginDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{"installed.py": "same", "changed.py": "old"}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(UpdateUtility.PluginDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	archive := &ProfileArchive{
+		Profile: []byte("energy: 200\n"),
+		Plugins: map[string][]byte{"installed.py": []byte("same"), "changed.py": []byte("new"), "new.py": []byte("new")},
+	}
+
+	wantInstall := []string{"changed.py", "new.py"}
+	if got := archive.PluginFilesToInstall(); !reflect.DeepEqual(got, wantInstall) {
+		t.Errorf("PluginFilesToInstall() = %v, want %v", got, wantInstall)
+	}
+
+	tests := []struct {
+		name        string
+		profileName string
+		pluginFiles []string
+		wantErr     bool
+	}{
+		{name: "profile path", profileName: "../profile.yaml", wantErr: true},
+		{name: "unknown plugin", profileName: "profile.yaml", pluginFiles: []string{"other.py"}, wantErr: true},
+		{name: "confirmed plugins", profileName: "profile.yaml", pluginFiles: []string{"new.py"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := archive.Import(dir, tt.profileName, tt.pluginFiles)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Import() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if profile, err := os.ReadFile(filepath.Join(dir, "profile.yaml")); err != nil || string(profile) != "energy: 200\n" {
+		t.Errorf("imported profile = %q, %v", profile, err)
+	}
+	// plugins which were not confirmed are not written
+	if changed, _ := os.ReadFile(filepath.Join(UpdateUtility.PluginDir, "changed.py")); string(changed) != "old" {
+		t.Errorf("changed.py = %q, want it unchanged", changed)
+	}
+	if got := archive.PluginFilesToInstall(); !reflect.DeepEqual(got, []string{"changed.py"}) {
+		t.Errorf("PluginFilesToInstall() after the import = %v, want [changed.py]", got)
+	}
+}
@@ -0,0 +1,294 @@
+package Profiles
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+	"whispering-tiger-ui/Fields"
+	"whispering-tiger-ui/ModelDownloader"
+	"whispering-tiger-ui/RuntimeBackend"
+	"whispering-tiger-ui/Settings"
+)
+
+// only candidates with downloaded models are benchmarked, so the backend only needs to load them
+const benchmarkStartTimeout = 2 * time.Minute
+const benchmarkRequestTimeout = 30 * time.Second
+
+// BenchmarkResult is the measured latency of a candidate.
+type BenchmarkResult struct {
+	Candidate       WizardCandidate
+	TranslationTime time.Duration
+	Err             error
+}
+
+// Latency is the time from sending a transcript until the translated text is available.
+// The backend can not transcribe a file on request, so the speech-to-text time is not measured.
+func (r BenchmarkResult) Latency() time.Duration {
+	return r.TranslationTime
+}
+
+// candidateModels returns the models of the model list which the candidate needs.
+// ok is false if a model can not be found in the model list.
+func candidateModels(candidate WizardCandidate) (models []ModelDownloader.ModelReference, ok bool) {
+	models = whisperModels(candidate.Stt_type, candidate.Model, candidate.Whisper_precision)
+	if len(models) == 0 {
+		return nil, false
+	}
+	models = append(models,
+		ModelDownloader.ModelReference{Name: "NLLB200CT2", Type: candidate.Txt_translator_size},
+		ModelDownloader.ModelReference{Name: "sentencepiece", Type: "default"},
+	)
+	for _, model := range models {
+		if !ModelDownloader.ModelExists(model) {
+			return nil, false
+		}
+	}
+	return models, true
+}
+
+// IsDownloaded returns true if all models of the candidate are downloaded, so a benchmark does not download them.
+func (c WizardCandidate) IsDownloaded() bool {
+	models, ok := candidateModels(c)
+	if !ok {
+		return false
+	}
+	for _, model := range models {
+		if !ModelDownloader.IsModelDownloaded(model) {
+			return false
+		}
+	}
+	return true
+}
+
+// BenchmarkShortlist returns the best downloaded candidate of every whisper model and device, at most maxCount
+// candidates. Whisper models which are too large for the CPU are left out, since their speed is not measured.
+func BenchmarkShortlist(candidates []WizardCandidate, maxCount int) []WizardCandidate {
+	var shortlist []WizardCandidate
+	added := map[string]bool{}
+	for _, candidate := range candidates {
+		if !candidate.withinCPUModelLimit() || !candidate.IsDownloaded() {
+			continue
+		}
+		key := candidate.Ai_device + "/" + candidate.Model
+		if added[key] {
+			continue
+		}
+		added[key] = true
+		shortlist = append(shortlist, candidate)
+		if len(shortlist) >= maxCount {
+			break
+		}
+	}
+	return shortlist
+}
+
+// RecommendCandidate returns the best benchmarked candidate which meets the target latency.
+// If no candidate meets it, the fastest one is returned. ok is false if no benchmark succeeded.
+func RecommendCandidate(results []BenchmarkResult, targetLatency time.Duration) (recommended BenchmarkResult, ok bool) {
+	for _, result := range results {
+		if result.Err != nil {
+			continue
+		}
+		if result.Latency() <= targetLatency && (!ok || result.Candidate.quality > recommended.Candidate.quality) {
+			recommended = result
+			ok = true
+		}
+	}
+	if ok {
+		return recommended, true
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			continue
+		}
+		if !ok || result.Latency() < recommended.Latency() {
+			recommended = result
+			ok = true
+		}
+	}
+	return recommended, ok
+}
+
+// BenchmarkCandidate starts a separate backend with the candidate's settings and measures
+// how long the translation of the text takes.
+func BenchmarkCandidate(ctx context.Context, base Settings.Conf, candidate WizardCandidate, text string) BenchmarkResult {
+	result := BenchmarkResult{Candidate: candidate}
+
+	port, err := freePort()
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	conf := base
+	candidate.Apply(&conf)
+	conf.Extends = ""
+	conf.Websocket_ip = "127.0.0.1"
+	conf.Websocket_port = port
+	conf.Stt_enabled = false
+	conf.Realtime = false
+	conf.Tts_type = ""
+	conf.Txt_translate = false
+	conf.Osc_auto_processing_enabled = false
+
+	tempDir, err := os.MkdirTemp("", "whispering-tiger-benchmark")
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer os.RemoveAll(tempDir)
+	settingsFile := filepath.Join(tempDir, "benchmark.yaml")
+	conf.WriteYamlSettings(settingsFile)
+
+	backend, err := RuntimeBackend.NewBackendCommand(settingsFile, "-1", "-1")
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if err = backend.Start(); err != nil {
+		result.Err = err
+		return result
+	}
+	backendExited := make(chan error, 1)
+	go func() {
+		backendExited <- backend.Wait()
+	}()
+	defer func() {
+		select {
+		case <-backendExited:
+		case <-time.After(5 * time.Second):
+			_ = backend.Process.Kill()
+			<-backendExited
+		}
+	}()
+
+	conn, err := connectBenchmarkBackend(ctx, port, backendExited)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer func() {
+		_ = conn.WriteJSON(Fields.SendMessageStruct{Type: "quit", Name: "quit", Value: ""})
+		_ = conn.Close()
+	}()
+
+	messages := make(chan benchmarkMessage)
+	stopReading := make(chan struct{})
+	defer close(stopReading)
+	go readBenchmarkMessages(conn, messages, stopReading)
+
+	// the first translation also warms up the model, so only the second one is measured
+	for run := 0; run < 2; run++ {
+		start := time.Now()
+		err = conn.WriteJSON(Fields.SendMessageStruct{
+			Type: "translate_req",
+			Value: struct {
+				Text                string `json:"text"`
+				From_lang           string `json:"from_lang"`
+				To_lang             string `json:"to_lang"`
+				To_romaji           bool   `json:"to_romaji"`
+				Ignore_send_options bool   `json:"ignore_send_options"`
+			}{
+				Text:                text,
+				From_lang:           "auto",
+				To_lang:             "deu_Latn",
+				Ignore_send_options: true,
+			},
+		})
+		if err == nil {
+			timeout := benchmarkRequestTimeout
+			if run == 0 {
+				timeout = benchmarkStartTimeout
+			}
+			_, err = waitForBenchmarkMessage(ctx, messages, "translate_result", timeout)
+		}
+		if err != nil {
+			result.Err = fmt.Errorf("translation failed: %w", err)
+			return result
+		}
+		result.TranslationTime = time.Since(start)
+	}
+	return result
+}
+
+type benchmarkMessage struct {
+	Type string `json:"type"`
+	err  error
+}
+
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// connectBenchmarkBackend connects to the websocket server of the backend as soon as it is started.
+func connectBenchmarkBackend(ctx context.Context, port int, backendExited chan error) (*websocket.Conn, error) {
+	address := "ws://127.0.0.1:" + strconv.Itoa(port) + "/"
+	deadline := time.Now().Add(benchmarkStartTimeout)
+	for time.Now().Before(deadline) {
+		conn, _, err := websocket.DefaultDialer.DialContext(ctx, address, nil)
+		if err == nil {
+			return conn, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case err = <-backendExited:
+			backendExited <- err
+			return nil, fmt.Errorf("backend stopped: %v", err)
+		case <-time.After(time.Second):
+		}
+	}
+	return nil, errors.New("backend did not start in time")
+}
+
+func readBenchmarkMessages(conn *websocket.Conn, messages chan benchmarkMessage, stopReading chan struct{}) {
+	for {
+		var message benchmarkMessage
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			message.err = err
+		} else if json.Unmarshal(data, &message) != nil {
+			continue
+		}
+		select {
+		case messages <- message:
+		case <-stopReading:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func waitForBenchmarkMessage(ctx context.Context, messages chan benchmarkMessage, messageType string, timeout time.Duration) (benchmarkMessage, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return benchmarkMessage{}, ctx.Err()
+		case <-timer.C:
+			return benchmarkMessage{}, errors.New("no answer from backend")
+		case message, ok := <-messages:
+			if !ok || message.err != nil {
+				return benchmarkMessage{}, errors.New("connection to the backend closed")
+			}
+			if message.Type == messageType {
+				return message, nil
+			}
+		}
+	}
+}
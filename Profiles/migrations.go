@@ -0,0 +1,314 @@
+package Profiles
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Profile migrations work on the raw YAML of a profile, so keys which are no longer part of Settings.Conf can still be read.
+// Every Migration raises the schema_version of a profile by one. Add new migrations to the end of the Migrations list.
+
+const SchemaVersionKey = "schema_version"
+
+// Change is a single modification done by a migration step.
+type Change struct {
+	Version     int
+	Key         string
+	Description string
+	OldValue    interface{}
+	NewValue    interface{}
+}
+
+func formatChangeValue(value interface{}) string {
+	if stringValue, ok := value.(string); ok {
+		return strconv.Quote(stringValue)
+	}
+	return fmt.Sprint(value)
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("v%d %s: %s (%s -> %s)", c.Version, c.Key, c.Description, formatChangeValue(c.OldValue), formatChangeValue(c.NewValue))
+}
+
+type MigrationStep interface {
+	Apply(settings map[string]interface{}) ([]Change, error)
+}
+
+// RenameKey moves the value of a key to a new key. Existing values of the new key are kept.
+type RenameKey struct {
+	From string
+	To   string
+}
+
+func (s RenameKey) Apply(settings map[string]interface{}) ([]Change, error) {
+	value, ok := settings[s.From]
+	if !ok {
+		return nil, nil
+	}
+	delete(settings, s.From)
+	if _, exists := settings[s.To]; exists {
+		return []Change{{Key: s.From, Description: "removed, " + s.To + " already set", OldValue: value}}, nil
+	}
+	settings[s.To] = value
+	return []Change{{Key: s.From, Description: "renamed to " + s.To, OldValue: value, NewValue: value}}, nil
+}
+
+// ConvertType converts the value of a key. Convert returns false if the value does not need to be converted.
+type ConvertType struct {
+	Key     string
+	Convert func(value interface{}) (interface{}, bool, error)
+}
+
+func (s ConvertType) Apply(settings map[string]interface{}) ([]Change, error) {
+	value, ok := settings[s.Key]
+	if !ok {
+		return nil, nil
+	}
+	newValue, converted, err := s.Convert(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.Key, err)
+	}
+	if !converted {
+		return nil, nil
+	}
+	settings[s.Key] = newValue
+	return []Change{{Key: s.Key, Description: "converted type", OldValue: value, NewValue: newValue}}, nil
+}
+
+// SplitField replaces a key with multiple keys. Split returns the new keys with their values.
+type SplitField struct {
+	Key          string
+	Split        func(value interface{}) (map[string]interface{}, error)
+	KeepOriginal bool
+}
+
+func (s SplitField) Apply(settings map[string]interface{}) ([]Change, error) {
+	value, ok := settings[s.Key]
+	if !ok {
+		return nil, nil
+	}
+	newValues, err := s.Split(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.Key, err)
+	}
+	var changes []Change
+	// sort keys so the report is stable
+	var newKeys []string
+	for newKey := range newValues {
+		newKeys = append(newKeys, newKey)
+	}
+	sort.Strings(newKeys)
+	for _, newKey := range newKeys {
+		changes = append(changes, Change{Key: newKey, Description: "split from " + s.Key, OldValue: settings[newKey], NewValue: newValues[newKey]})
+		settings[newKey] = newValues[newKey]
+	}
+	if !s.KeepOriginal {
+		delete(settings, s.Key)
+		changes = append(changes, Change{Key: s.Key, Description: "removed after split", OldValue: value})
+	}
+	return changes, nil
+}
+
+// ChangeDefault replaces the old default value with the new one. Values which were changed by the user are kept.
+type ChangeDefault struct {
+	Key        string
+	OldDefault interface{}
+	NewDefault interface{}
+	// SetIfMissing adds the key with the new default if it is not part of the profile
+	SetIfMissing bool
+}
+
+func (s ChangeDefault) Apply(settings map[string]interface{}) ([]Change, error) {
+	value, ok := settings[s.Key]
+	if (!ok && s.SetIfMissing) || (ok && fmt.Sprint(value) == fmt.Sprint(s.OldDefault)) {
+		settings[s.Key] = s.NewDefault
+		return []Change{{Key: s.Key, Description: "changed default", OldValue: value, NewValue: s.NewDefault}}, nil
+	}
+	return nil, nil
+}
+
+type Migration struct {
+	Version     int
+	Description string
+	Steps       []MigrationStep
+}
+
+func stringToSlice(value interface{}) (interface{}, bool, error) {
+	if stringValue, ok := value.(string); ok {
+		if stringValue == "" {
+			return []interface{}{}, true, nil
+		}
+		return []interface{}{stringValue}, true, nil
+	}
+	return value, false, nil
+}
+
+func numberToString(value interface{}) (interface{}, bool, error) {
+	switch v := value.(type) {
+	case int:
+		return strconv.Itoa(v), true, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true, nil
+	}
+	return value, false, nil
+}
+
+func numericStringToInt(value interface{}) (interface{}, bool, error) {
+	if stringValue, ok := value.(string); ok {
+		if intValue, err := strconv.Atoi(strings.TrimSpace(stringValue)); err == nil {
+			return intValue, true, nil
+		}
+	}
+	return value, false, nil
+}
+
+// Migrations is the ordered registry of all profile migrations.
+var Migrations = []Migration{
+	{
+		Version:     1,
+		Description: "fix value types of old profiles",
+		Steps: []MigrationStep{
+			ConvertType{Key: "tts_model", Convert: stringToSlice},
+			ConvertType{Key: "logprob_threshold", Convert: numberToString},
+			ConvertType{Key: "no_speech_threshold", Convert: numberToString},
+			ConvertType{Key: "device_index", Convert: numericStringToInt},
+			ConvertType{Key: "device_out_index", Convert: numericStringToInt},
+		},
+	},
+}
+
+// CurrentSchemaVersion is the schema_version of profiles after all migrations ran.
+var CurrentSchemaVersion = Migrations[len(Migrations)-1].Version
+
+type MigrationReport struct {
+	File        string
+	FromVersion int
+	ToVersion   int
+	Changes     []Change
+	BackupFile  string
+}
+
+// NeedsMigration returns true if the profile is older than the current schema version.
+func (r *MigrationReport) NeedsMigration() bool {
+	return r.FromVersion < r.ToVersion
+}
+
+func (r *MigrationReport) String() string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("%s: schema version %d -> %d\n", filepath.Base(r.File), r.FromVersion, r.ToVersion))
+	for _, change := range r.Changes {
+		builder.WriteString("  " + change.String() + "\n")
+	}
+	if r.BackupFile != "" {
+		builder.WriteString("  backup: " + r.BackupFile + "\n")
+	}
+	return builder.String()
+}
+
+func schemaVersionOf(settings map[string]interface{}) int {
+	switch v := settings[SchemaVersionKey].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	case string:
+		version, _ := strconv.Atoi(v)
+		return version
+	}
+	return 0
+}
+
+// MigrateSettings runs all migrations newer than the schema_version of the settings on them.
+func MigrateSettings(settings map[string]interface{}) (*MigrationReport, error) {
+	report := &MigrationReport{
+		FromVersion: schemaVersionOf(settings),
+		ToVersion:   CurrentSchemaVersion,
+	}
+	if report.FromVersion >= CurrentSchemaVersion {
+		report.ToVersion = report.FromVersion
+		return report, nil
+	}
+	for _, migration := range Migrations {
+		if migration.Version <= report.FromVersion {
+			continue
+		}
+		for _, step := range migration.Steps {
+			changes, err := step.Apply(settings)
+			if err != nil {
+				return report, fmt.Errorf("migration %d (%s): %w", migration.Version, migration.Description, err)
+			}
+			for i := range changes {
+				changes[i].Version = migration.Version
+			}
+			report.Changes = append(report.Changes, changes...)
+		}
+		settings[SchemaVersionKey] = migration.Version
+	}
+	return report, nil
+}
+
+func backupProfile(fileName string, yamlFile []byte, version int) (string, error) {
+	backupDir := filepath.Join(filepath.Dir(fileName), "backups")
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return "", err
+	}
+	backupFile := filepath.Join(backupDir, fmt.Sprintf("%s.v%d.%s.bak", filepath.Base(fileName), version, time.Now().Format("20060102-150405")))
+	return backupFile, os.WriteFile(backupFile, yamlFile, 0644)
+}
+
+// MigrateProfileFile migrates a profile file to the current schema version.
+// With dryRun set, only the report of what would change is returned.
+// Otherwise, a backup of the original file is written to the backups directory before the profile is saved.
+func MigrateProfileFile(fileName string, dryRun bool) (*MigrationReport, error) {
+	yamlFile, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	settings := map[string]interface{}{}
+	if err = yaml.Unmarshal(yamlFile, &settings); err != nil {
+		return nil, err
+	}
+
+	report, err := MigrateSettings(settings)
+	if err != nil {
+		return report, err
+	}
+	report.File = fileName
+	if dryRun || !report.NeedsMigration() {
+		return report, nil
+	}
+
+	report.BackupFile, err = backupProfile(fileName, yamlFile, report.FromVersion)
+	if err != nil {
+		return report, fmt.Errorf("failed to backup profile: %w", err)
+	}
+	migratedYaml, err := yaml.Marshal(settings)
+	if err != nil {
+		return report, err
+	}
+	return report, os.WriteFile(fileName, migratedYaml, 0644)
+}
+
+// MigrateProfiles migrates all given profile files of a directory. Errors of single profiles do not stop the migration of others.
+func MigrateProfiles(profilesDir string, fileNames []string, dryRun bool) ([]*MigrationReport, error) {
+	var reports []*MigrationReport
+	var errorMessages []string
+	for _, fileName := range fileNames {
+		report, err := MigrateProfileFile(filepath.Join(profilesDir, fileName), dryRun)
+		if err != nil {
+			errorMessages = append(errorMessages, fmt.Sprintf("%s: %v", fileName, err))
+			continue
+		}
+		reports = append(reports, report)
+	}
+	if len(errorMessages) > 0 {
+		return reports, fmt.Errorf("%s", strings.Join(errorMessages, "\n"))
+	}
+	return reports, nil
+}
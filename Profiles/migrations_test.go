@@ -0,0 +1,133 @@
+package Profiles
+
+import (
+	"errors"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMigrationSteps(t *testing.T) {
+	tests := []struct {
+		name        string
+		step        MigrationStep
+		settings    map[string]interface{}
+		want        map[string]interface{}
+		wantChanges int
+		wantErr     bool
+	}{
+		{name: "rename", step: RenameKey{From: "old", To: "new"}, settings: map[string]interface{}{"old": 1}, want: map[string]interface{}{"new": 1}, wantChanges: 1},
+		{name: "rename to existing key", step: RenameKey{From: "old", To: "new"}, settings: map[string]interface{}{"old": 1, "new": 2}, want: map[string]interface{}{"new": 2}, wantChanges: 1},
+		{name: "rename missing key", step: RenameKey{From: "old", To: "new"}, settings: map[string]interface{}{"other": 1}, want: map[string]interface{}{"other": 1}},
+		{name: "convert string to slice", step: ConvertType{Key: "tts_model", Convert: stringToSlice}, settings: map[string]interface{}{"tts_model": "voice"}, want: map[string]interface{}{"tts_model": []interface{}{"voice"}}, wantChanges: 1},
+		{name: "convert empty string to slice", step: ConvertType{Key: "tts_model", Convert: stringToSlice}, settings: map[string]interface{}{"tts_model": ""}, want: map[string]interface{}{"tts_model": []interface{}{}}, wantChanges: 1},
+		{name: "convert slice unchanged", step: ConvertType{Key: "tts_model", Convert: stringToSlice}, settings: map[string]interface{}{"tts_model": []interface{}{"a"}}, want: map[string]interface{}{"tts_model": []interface{}{"a"}}},
+		{name: "convert number to string", step: ConvertType{Key: "logprob_threshold", Convert: numberToString}, settings: map[string]interface{}{"logprob_threshold": -1.5}, want: map[string]interface{}{"logprob_threshold": "-1.5"}, wantChanges: 1},
+		{name: "convert numeric string to int", step: ConvertType{Key: "device_index", Convert: numericStringToInt}, settings: map[string]interface{}{"device_index": " 3 "}, want: map[string]interface{}{"device_index": 3}, wantChanges: 1},
+		{name: "convert text unchanged", step: ConvertType{Key: "device_index", Convert: numericStringToInt}, settings: map[string]interface{}{"device_index": "None"}, want: map[string]interface{}{"device_index": "None"}},
+		{
+			name: "split",
+			step: SplitField{Key: "size", Split: func(value interface{}) (map[string]interface{}, error) {
+				return map[string]interface{}{"width": value, "height": value}, nil
+			}},
+			settings:    map[string]interface{}{"size": 10},
+			want:        map[string]interface{}{"width": 10, "height": 10},
+			wantChanges: 3,
+		},
+		{
+			name: "split error",
+			step: SplitField{Key: "size", Split: func(value interface{}) (map[string]interface{}, error) {
+				return nil, errors.New("invalid size")
+			}},
+			settings: map[string]interface{}{"size": "large"},
+			want:     map[string]interface{}{"size": "large"},
+			wantErr:  true,
+		},
+		{name: "change default", step: ChangeDefault{Key: "energy", OldDefault: 300, NewDefault: 200}, settings: map[string]interface{}{"energy": 300.0}, want: map[string]interface{}{"energy": 200}, wantChanges: 1},
+		{name: "keep user value", step: ChangeDefault{Key: "energy", OldDefault: 300, NewDefault: 200}, settings: map[string]interface{}{"energy": 250}, want: map[string]interface{}{"energy": 250}},
+		{name: "set missing default", step: ChangeDefault{Key: "energy", NewDefault: 200, SetIfMissing: true}, settings: map[string]interface{}{}, want: map[string]interface{}{"energy": 200}, wantChanges: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := tt.step.Apply(tt.settings)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(changes) != tt.wantChanges {
+				t.Errorf("Apply() returned %d changes, want %d", len(changes), tt.wantChanges)
+			}
+			if !reflect.DeepEqual(tt.settings, tt.want) {
+				t.Errorf("settings = %v, want %v", tt.settings, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrateSettings(t *testing.T) {
+	tests := []struct {
+		name        string
+		settings    map[string]interface{}
+		wantFrom    int
+		wantChanges int
+	}{
+		{name: "unversioned", settings: map[string]interface{}{"tts_model": "voice", "device_index": "2"}, wantChanges: 2},
+		{name: "current version", settings: map[string]interface{}{SchemaVersionKey: CurrentSchemaVersion, "tts_model": "voice"}, wantFrom: CurrentSchemaVersion},
+		{name: "version as string", settings: map[string]interface{}{SchemaVersionKey: "1", "tts_model": "voice"}, wantFrom: 1},
+		{name: "newer version", settings: map[string]interface{}{SchemaVersionKey: CurrentSchemaVersion + 1}, wantFrom: CurrentSchemaVersion + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := MigrateSettings(tt.settings)
+			if err != nil {
+				t.Fatalf("MigrateSettings() error = %v", err)
+			}
+			if report.FromVersion != tt.wantFrom || len(report.Changes) != tt.wantChanges {
+				t.Errorf("MigrateSettings() = from %d with %d changes, want from %d with %d changes", report.FromVersion, len(report.Changes), tt.wantFrom, tt.wantChanges)
+			}
+			if report.ToVersion < CurrentSchemaVersion || report.ToVersion < tt.wantFrom {
+				t.Errorf("ToVersion = %d, want at least %d", report.ToVersion, max(CurrentSchemaVersion, tt.wantFrom))
+			}
+			if tt.wantChanges > 0 && tt.settings[SchemaVersionKey] != CurrentSchemaVersion {
+				t.Errorf("%s = %v, want %d", SchemaVersionKey, tt.settings[SchemaVersionKey], CurrentSchemaVersion)
+			}
+		})
+	}
+}
+
+func TestMigrateProfileFile(t *testing.T) {
+	dir := t.TempDir()
+	profileFile := filepath.Join(dir, "profile.yaml")
+	original := []byte("tts_model: voice\nenergy: 300\n")
+	if err := os.WriteFile(profileFile, original, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := MigrateProfileFile(profileFile, true)
+	if err != nil {
+		t.Fatalf("MigrateProfileFile() dry run error = %v", err)
+	}
+	if data, _ := os.ReadFile(profileFile); !report.NeedsMigration() || string(data) != string(original) {
+		t.Fatalf("dry run changed the profile or found nothing to migrate: %s", data)
+	}
+
+	if report, err = MigrateProfileFile(profileFile, false); err != nil {
+		t.Fatalf("MigrateProfileFile() error = %v", err)
+	}
+	if backup, err := os.ReadFile(report.BackupFile); err != nil || string(backup) != string(original) {
+		t.Errorf("backup = %q, %v, want the original profile", backup, err)
+	}
+	data, err := os.ReadFile(profileFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]interface{}{}
+	if err = yaml.Unmarshal(data, &values); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"tts_model": []interface{}{"voice"}, "energy": 300, SchemaVersionKey: CurrentSchemaVersion}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("migrated profile = %v, want %v", values, want)
+	}
+}
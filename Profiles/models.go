@@ -0,0 +1,26 @@
+package Profiles
+
+import (
+	"path/filepath"
+	"whispering-tiger-ui/ModelDownloader"
+	"whispering-tiger-ui/Pages/ProfileSettings"
+)
+
+// ModelUsage returns the profiles which use each model of the model list.
+func ModelUsage(profilesDir string) (map[ModelDownloader.ModelReference][]string, error) {
+	profileFiles, err := ListProfiles(profilesDir)
+	if err != nil {
+		return nil, err
+	}
+	usage := map[ModelDownloader.ModelReference][]string{}
+	for _, profileFile := range profileFiles {
+		conf := ProfileSettings.DefaultProfileSetting
+		if err := conf.LoadYamlSettings(filepath.Join(profilesDir, profileFile)); err != nil {
+			continue
+		}
+		for _, model := range RequiredModels(&conf) {
+			usage[model] = append(usage[model], profileFile)
+		}
+	}
+	return usage, nil
+}
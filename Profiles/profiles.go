@@ -4,11 +4,13 @@ import (
 	"gopkg.in/yaml.v3"
 	"log"
 	"os"
+	"strings"
 )
 
 //goland:noinspection GoSnakeCaseUsage
 type Profile struct {
 	SettingsFilename string
+	Schema_version   int         `yaml:"schema_version"`
 	Device_index     interface{} `yaml:"device_index"`
 	Device_out_index interface{} `yaml:"device_out_index"`
 
@@ -69,3 +71,18 @@ func (p *Profile) Save(fileName string) {
 		log.Printf("error: %v", err)
 	}
 }
+
+// ListProfiles returns the file names of all profiles in the profiles directory.
+func ListProfiles(profilesDir string) ([]string, error) {
+	files, err := os.ReadDir(profilesDir)
+	if err != nil {
+		return nil, err
+	}
+	var profileFiles []string
+	for _, file := range files {
+		if !file.IsDir() && !strings.HasPrefix(file.Name(), ".") && (strings.HasSuffix(file.Name(), ".yaml") || strings.HasSuffix(file.Name(), ".yml")) {
+			profileFiles = append(profileFiles, file.Name())
+		}
+	}
+	return profileFiles, nil
+}
@@ -0,0 +1,196 @@
+package Profiles
+
+import (
+	"fmt"
+	"sort"
+	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/Utilities/Hardwareinfo"
+)
+
+// share of the memory which the models may use, since the system and other applications need memory as well
+const (
+	wizardGPUMemoryShare = 0.9
+	wizardCPUMemoryShare = 0.6
+)
+
+// whisper models which are tried by the wizard, with their size name in Hardwareinfo.Models (best first)
+var wizardWhisperModels = []struct {
+	Model string
+	Size  string
+}{
+	{"large-v3", "large"},
+	{"medium", "medium"},
+	{"small", "small"},
+	{"base", "base"},
+	{"tiny", "tiny"},
+}
+
+var wizardTranslatorSizes = []string{"medium", "small"}
+
+// WizardCandidate is a model / precision / translator combination the profile wizard can recommend.
+//
+//goland:noinspection GoSnakeCaseUsage
+type WizardCandidate struct {
+	Stt_type          string
+	Ai_device         string
+	Model             string
+	Whisper_precision string
+
+	Txt_translator           string
+	Txt_translator_size      string
+	Txt_translator_device    string
+	Txt_translator_precision string
+
+	// estimated memory usage in MB
+	CPUMemoryMB float64
+	GPUMemoryMB float64
+
+	quality int
+}
+
+// Apply sets the candidate's settings in a profile.
+func (c WizardCandidate) Apply(conf *Settings.Conf) {
+	conf.Stt_type = c.Stt_type
+	conf.Ai_device = c.Ai_device
+	conf.Model = c.Model
+	conf.Whisper_precision = c.Whisper_precision
+	conf.Txt_translator = c.Txt_translator
+	conf.Txt_translator_size = c.Txt_translator_size
+	conf.Txt_translator_device = c.Txt_translator_device
+	conf.Txt_translator_precision = c.Txt_translator_precision
+}
+
+func (c WizardCandidate) String() string {
+	return fmt.Sprintf("Whisper %s (%s, %s) + NLLB200 %s (%s, %s)", c.Model, c.Ai_device, c.Whisper_precision, c.Txt_translator_size, c.Txt_translator_device, c.Txt_translator_precision)
+}
+
+// WizardCandidates returns the combinations which fit into the memory of the hardware, best first.
+func WizardCandidates(hardware Hardwareinfo.HardwareProbe) []WizardCandidate {
+	type deviceOption struct {
+		device     string
+		sttType    string
+		modelName  string // prefix of the model name in Hardwareinfo.Models
+		precisions []string
+		// translator settings for this device
+		translatorDevice     string
+		translatorPrecisions []string
+	}
+	var deviceOptions []deviceOption
+	switch {
+	case hardware.GPUVendor == "nvidia":
+		precisions := cudaPrecisions(hardware.ComputeCapability)
+		deviceOptions = append(deviceOptions, deviceOption{
+			device: "cuda", sttType: "faster_whisper", modelName: "WhisperCT2_", precisions: precisions,
+			translatorDevice: "cuda", translatorPrecisions: precisions,
+		})
+	case hardware.GPUVendor == "amd" || hardware.GPUVendor == "intel":
+		deviceOptions = append(deviceOptions, deviceOption{
+			device: "direct-ml:0", sttType: "transformer_whisper", modelName: "WhisperO_", precisions: []string{"float16"},
+			translatorDevice: "cpu", translatorPrecisions: []string{"int8"},
+		})
+	}
+	// the CPU is always a fallback
+	deviceOptions = append(deviceOptions, deviceOption{
+		device: "cpu", sttType: "faster_whisper", modelName: "WhisperCT2_", precisions: []string{"float32", "int8"},
+		translatorDevice: "cpu", translatorPrecisions: []string{"int8"},
+	})
+
+	gpuMemory := float64(hardware.GPUMemoryMB) * wizardGPUMemoryShare
+	cpuMemory := float64(hardware.CPUMemoryMB) * wizardCPUMemoryShare
+
+	var candidates []WizardCandidate
+	for deviceIndex, option := range deviceOptions {
+		for modelIndex, model := range wizardWhisperModels {
+			for precisionIndex, precision := range option.precisions {
+				sttMemory, ok := Hardwareinfo.ModelMemoryUsage(option.modelName+model.Size, precision)
+				if !ok {
+					continue
+				}
+				for translatorIndex, translatorSize := range wizardTranslatorSizes {
+					for translatorPrecisionIndex, translatorPrecision := range option.translatorPrecisions {
+						translatorMemory, ok := Hardwareinfo.ModelMemoryUsage("TxtTranslatorNLLB200_CT2_"+translatorSize, translatorPrecision)
+						if !ok {
+							continue
+						}
+						candidate := WizardCandidate{
+							Stt_type:                 option.sttType,
+							Ai_device:                option.device,
+							Model:                    model.Model,
+							Whisper_precision:        precision,
+							Txt_translator:           "NLLB200_CT2",
+							Txt_translator_size:      translatorSize,
+							Txt_translator_device:    option.translatorDevice,
+							Txt_translator_precision: translatorPrecision,
+							// the GPU is preferred, then the model size, the translator size and the precision matter
+							quality: (len(deviceOptions)-deviceIndex)*10000 + (len(wizardWhisperModels)-modelIndex)*1000 + (len(wizardTranslatorSizes)-translatorIndex)*100 - precisionIndex*10 - translatorPrecisionIndex,
+						}
+						if option.device == "cpu" {
+							candidate.CPUMemoryMB = sttMemory
+						} else {
+							candidate.GPUMemoryMB = sttMemory
+						}
+						if option.translatorDevice == "cpu" {
+							candidate.CPUMemoryMB += translatorMemory
+						} else {
+							candidate.GPUMemoryMB += translatorMemory
+						}
+						if candidate.GPUMemoryMB > 0 && (hardware.GPUMemoryMB <= 0 || candidate.GPUMemoryMB > gpuMemory) {
+							continue
+						}
+						if hardware.CPUMemoryMB > 0 && candidate.CPUMemoryMB > cpuMemory {
+							continue
+						}
+						candidates = append(candidates, candidate)
+					}
+				}
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+	return candidates
+}
+
+// cudaPrecisions returns the precisions which CTranslate2 supports on an NVIDIA GPU with the compute capability.
+// float16 needs a compute capability of 7.0 and int8 of 6.1. An unknown compute capability (0) gets float32 and int8.
+func cudaPrecisions(computeCapability float32) []string {
+	switch {
+	case computeCapability >= 7.0:
+		return []string{"float16", "int8_float16"}
+	case computeCapability >= 6.1 || computeCapability == 0:
+		return []string{"float32", "int8"}
+	}
+	return []string{"float32"}
+}
+
+// wizardCPUModelLimit is the largest whisper model which is recommended on the CPU, since its speed is not measured
+const wizardCPUModelLimit = "small"
+
+// withinCPUModelLimit returns false for candidates with a whisper model that is too large for the CPU.
+func (c WizardCandidate) withinCPUModelLimit() bool {
+	if c.Ai_device != "cpu" {
+		return true
+	}
+	limitReached := false
+	for _, model := range wizardWhisperModels {
+		if model.Model == wizardCPUModelLimit {
+			limitReached = true
+		}
+		if model.Model == c.Model {
+			return limitReached
+		}
+	}
+	return false
+}
+
+// RecommendWithoutBenchmark returns a candidate if the candidates can not be benchmarked.
+// Without measurements, large models are only recommended on a GPU.
+func RecommendWithoutBenchmark(candidates []WizardCandidate) (WizardCandidate, bool) {
+	for _, candidate := range candidates {
+		if candidate.withinCPUModelLimit() {
+			return candidate, true
+		}
+	}
+	return WizardCandidate{}, false
+}
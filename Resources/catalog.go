@@ -0,0 +1,8 @@
+package Resources
+
+import _ "embed"
+
+// ModelCatalog is the bundled default model catalog (see ModelDownloader).
+//
+//go:embed model_catalog.yaml
+var ModelCatalog []byte
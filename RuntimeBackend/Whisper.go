@@ -14,6 +14,7 @@ import (
 	"syscall"
 	"time"
 	"whispering-tiger-ui/Fields"
+	"whispering-tiger-ui/Updater"
 	"whispering-tiger-ui/Utilities"
 )
 
@@ -313,7 +314,6 @@ func (c *WhisperProcessConfig) Start() {
 		defer Utilities.PanicLogger()
 
 		var tmpReader io.Reader
-		var err error
 
 		cmdArguments := []string{
 			"--device_index", c.DeviceIndex,
@@ -325,23 +325,12 @@ func (c *WhisperProcessConfig) Start() {
 			cmdArguments = append(cmdArguments, "--ui_download")
 		}
 
-		if Utilities.FileExists("audioWhisper.py") {
-			cmdArguments = append([]string{"-u", "audioWhisper.py"}, cmdArguments...)
-			err = c.RunWithStreams("python", cmdArguments, tmpReader, c.WriterBackend, c.WriterBackend)
-		} else if Utilities.FileExists("audioWhisper/audioWhisper.exe") {
-			err = c.RunWithStreams("audioWhisper/audioWhisper.exe", cmdArguments, tmpReader, c.WriterBackend, c.WriterBackend)
-		} else if Utilities.FileExists("audioWhisper/audioWhisper") { // Linux variant without file extension
-			err = c.RunWithStreams("audioWhisper/audioWhisper", cmdArguments, tmpReader, c.WriterBackend, c.WriterBackend)
-		} else if Utilities.FileExists("audioWhisper/audioWhisper.py") && Utilities.FileExists("audioWhisper/venv/Scripts/python.exe") {
-			c.AttachEnvironment("VIRTUAL_ENV", "audioWhisper/venv/")
-			cmdArguments = append([]string{"-u", "audioWhisper.py"}, cmdArguments...)
-			err = c.RunWithStreams("audioWhisper/venv/Scripts/python.exe", cmdArguments, tmpReader, c.WriterBackend, c.WriterBackend)
-		} else if Utilities.FileExists("audioWhisper/audioWhisper.py") && Utilities.FileExists("audioWhisper/venv/Scripts/python") { // Linux variant without file extension
-			c.AttachEnvironment("VIRTUAL_ENV", "audioWhisper/venv/")
-			cmdArguments = append([]string{"-u", "audioWhisper.py"}, cmdArguments...)
-			err = c.RunWithStreams("audioWhisper/venv/Scripts/python", cmdArguments, tmpReader, c.WriterBackend, c.WriterBackend)
-		} else {
-			err = errors.New("could not start audioWhisper")
+		name, arguments, virtualEnv, err := backendCommand(cmdArguments)
+		if err == nil {
+			if virtualEnv != "" {
+				c.AttachEnvironment("VIRTUAL_ENV", virtualEnv)
+			}
+			err = c.RunWithStreams(name, arguments, tmpReader, c.WriterBackend, c.WriterBackend)
 		}
 
 		if err != nil {
@@ -350,3 +339,50 @@ func (c *WhisperProcessConfig) Start() {
 		}
 	}(stdoutTee)
 }
+
+// backendCommand returns the executable and arguments to run the backend and the virtual environment it needs (if any).
+func backendCommand(cmdArguments []string) (name string, arguments []string, virtualEnv string, err error) {
+	if Utilities.FileExists("audioWhisper.py") {
+		return "python", append([]string{"-u", "audioWhisper.py"}, cmdArguments...), "", nil
+	}
+	name, arguments, virtualEnv, err = Updater.PlatformCommand(Updater.ActivePlatformDir(), cmdArguments)
+	if err != nil {
+		// fall back to the platform in the application directory
+		name, arguments, virtualEnv, err = Updater.PlatformCommand(".", cmdArguments)
+	}
+	if err != nil {
+		return "", nil, "", errors.New("could not start audioWhisper")
+	}
+	return name, arguments, virtualEnv, nil
+}
+
+// BackendAvailable returns true if a backend installation was found.
+func BackendAvailable() bool {
+	_, _, _, err := backendCommand(nil)
+	return err == nil
+}
+
+// NewBackendToolCommand creates a backend process with the given arguments (e.g. to run a detection and read its output).
+func NewBackendToolCommand(cmdArguments ...string) (*exec.Cmd, error) {
+	name, arguments, virtualEnv, err := backendCommand(cmdArguments)
+	if err != nil {
+		return nil, err
+	}
+	proc := exec.Command(name, arguments...)
+	Utilities.ProcessHideWindowAttr(proc)
+	proc.Env = append(os.Environ(), "PYTHONIOENCODING=UTF-8", "PYTHONUTF8=1")
+	if virtualEnv != "" {
+		proc.Env = append(proc.Env, "VIRTUAL_ENV="+virtualEnv)
+	}
+	return proc, nil
+}
+
+// NewBackendCommand creates a backend process which is not connected to the UI (e.g. to benchmark settings or to run headless).
+// Use "-1" as device index to run without audio devices.
+func NewBackendCommand(settingsFile string, deviceIndex string, deviceOutIndex string) (*exec.Cmd, error) {
+	return NewBackendToolCommand(
+		"--device_index", deviceIndex,
+		"--device_out_index", deviceOutIndex,
+		"--config", settingsFile,
+	)
+}
@@ -0,0 +1,241 @@
+package Secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	fileStoreVersion    = 1
+	fileStoreIterations = 310000
+	fileStoreKeyLength  = 32
+)
+
+// encryptedFile is the content of the secrets file. Data is the AES-GCM encrypted json of all secrets.
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Data       []byte `json:"data"`
+}
+
+// EncryptedFileStore keeps the secrets in a file encrypted with a key derived from a passphrase.
+// Secrets can only be set while the store is unlocked.
+type EncryptedFileStore struct {
+	FileName string
+
+	mutex   sync.Mutex
+	key     []byte
+	salt    []byte
+	secrets map[string]string
+	// OnPassphraseRequired is called when a secret is set while the store is locked
+	OnPassphraseRequired func()
+}
+
+var FileStore = &EncryptedFileStore{
+	FileName: filepath.Join(".", "Profiles", ".secrets"),
+}
+
+func (s *EncryptedFileStore) Name() string {
+	return "encrypted file"
+}
+
+// Exists returns true if the secrets file was created.
+func (s *EncryptedFileStore) Exists() bool {
+	_, err := os.Stat(s.FileName)
+	return err == nil
+}
+
+func (s *EncryptedFileStore) IsLocked() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.key == nil
+}
+
+// Unlock decrypts the secrets file with the passphrase, or creates it if it does not exist yet.
+func (s *EncryptedFileStore) Unlock(passphrase string) error {
+	if passphrase == "" {
+		return errors.New("the passphrase must not be empty")
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	secrets := map[string]string{}
+	var key, salt []byte
+	data, err := os.ReadFile(s.FileName)
+	if err == nil {
+		var file encryptedFile
+		if err = json.Unmarshal(data, &file); err != nil {
+			return err
+		}
+		key = pbkdf2SHA256([]byte(passphrase), file.Salt, file.Iterations, fileStoreKeyLength)
+		plaintext, err := decrypt(key, file.Nonce, file.Data)
+		if err != nil {
+			return errors.New("wrong passphrase")
+		}
+		if err = json.Unmarshal(plaintext, &secrets); err != nil {
+			return err
+		}
+		salt = file.Salt
+		if file.Iterations != fileStoreIterations {
+			key = nil
+		}
+	} else if os.IsNotExist(err) {
+		salt = make([]byte, 16)
+		if _, err = rand.Read(salt); err != nil {
+			return err
+		}
+	} else {
+		return err
+	}
+
+	if key == nil {
+		key = pbkdf2SHA256([]byte(passphrase), salt, fileStoreIterations, fileStoreKeyLength)
+	}
+	s.key = key
+	s.salt = salt
+	s.secrets = secrets
+	return s.save()
+}
+
+// Lock forgets the key and the decrypted secrets.
+func (s *EncryptedFileStore) Lock() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.key = nil
+	s.secrets = nil
+}
+
+func (s *EncryptedFileStore) Get(id string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.key == nil {
+		return "", ErrLocked
+	}
+	value, ok := s.secrets[id]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// Set stores the secret. While the store is locked, ErrLocked is returned (and the passphrase is asked for), so no
+// reference to a secret which was not saved is written.
+func (s *EncryptedFileStore) Set(id string, value string) error {
+	s.mutex.Lock()
+	if s.key == nil {
+		s.mutex.Unlock()
+		if s.OnPassphraseRequired != nil {
+			s.OnPassphraseRequired()
+		}
+		return ErrLocked
+	}
+	defer s.mutex.Unlock()
+	if current, ok := s.secrets[id]; ok && current == value {
+		return nil
+	}
+	s.secrets[id] = value
+	return s.save()
+}
+
+func (s *EncryptedFileStore) Delete(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.key == nil {
+		return ErrLocked
+	}
+	delete(s.secrets, id)
+	return s.save()
+}
+
+func (s *EncryptedFileStore) save() error {
+	plaintext, err := json.Marshal(s.secrets)
+	if err != nil {
+		return err
+	}
+	nonce, data, err := encrypt(s.key, plaintext)
+	if err != nil {
+		return err
+	}
+	file, err := json.Marshal(encryptedFile{
+		Version:    fileStoreVersion,
+		Iterations: fileStoreIterations,
+		Salt:       s.salt,
+		Nonce:      nonce,
+		Data:       data,
+	})
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(s.FileName), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.FileName, file, 0600)
+}
+
+func encrypt(key []byte, plaintext []byte) (nonce []byte, data []byte, err error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce = make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	return nonce, gcm.Seal(nil, nonce, plaintext, nil), nil
+}
+
+func decrypt(key []byte, nonce []byte, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Open(nil, nonce, data, nil)
+}
+
+// pbkdf2SHA256 derives a key from the passphrase (RFC 8018)
+func pbkdf2SHA256(password []byte, salt []byte, iterations int, keyLength int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLength := prf.Size()
+	blockCount := (keyLength + hashLength - 1) / hashLength
+
+	var key []byte
+	blockIndex := make([]byte, 4)
+	for block := 1; block <= blockCount; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(blockIndex, uint32(block))
+		prf.Write(blockIndex)
+		u := prf.Sum(nil)
+		t := make([]byte, len(u))
+		copy(t, u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLength]
+}
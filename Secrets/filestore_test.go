@@ -0,0 +1,94 @@
+package Secrets
+
+import (
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPbkdf2SHA256(t *testing.T) {
+	// test vectors of PBKDF2-HMAC-SHA256 for the password "password" and the salt "salt"
+	tests := []struct {
+		iterations int
+		want       string
+	}{
+		{iterations: 1, want: "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{iterations: 2, want: "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+		{iterations: 4096, want: "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+	}
+	for _, tt := range tests {
+		got := hex.EncodeToString(pbkdf2SHA256([]byte("password"), []byte("salt"), tt.iterations, 32))
+		if got != tt.want {
+			t.Errorf("pbkdf2SHA256() with %d iterations = %s, want %s", tt.iterations, got, tt.want)
+		}
+	}
+}
+
+func TestEncryptedFileStore(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "Profiles", ".secrets")
+	store := &EncryptedFileStore{FileName: fileName}
+	passphraseRequired := 0
+	store.OnPassphraseRequired = func() { passphraseRequired++ }
+
+	if store.Exists() || !store.IsLocked() {
+		t.Fatal("new store exists or is unlocked")
+	}
+	if err := store.Set("api_key", "secret"); !errors.Is(err, ErrLocked) || passphraseRequired != 1 {
+		t.Errorf("Set() while locked = %v, passphrase asked %d times, want %v, 1", err, passphraseRequired, ErrLocked)
+	}
+	if err := store.Unlock(""); err == nil {
+		t.Error("Unlock() with an empty passphrase succeeded")
+	}
+	if err := store.Unlock("passphrase"); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	if !store.Exists() {
+		t.Fatal("Unlock() did not create the secrets file")
+	}
+	if err := store.Set("api_key", "secret"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := store.Set("other_key", "other"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := store.Delete("other_key"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if data, _ := os.ReadFile(fileName); strings.Contains(string(data), "secret") {
+		t.Error("the secrets file contains the plain secret")
+	}
+
+	store.Lock()
+	if _, err := store.Get("api_key"); !errors.Is(err, ErrLocked) {
+		t.Errorf("Get() while locked error = %v, want %v", err, ErrLocked)
+	}
+
+	reopened := &EncryptedFileStore{FileName: fileName}
+	if err := reopened.Unlock("wrong"); err == nil || !reopened.IsLocked() {
+		t.Errorf("Unlock() with a wrong passphrase = %v, locked %v, want an error while locked", err, reopened.IsLocked())
+	}
+	if err := reopened.Unlock("passphrase"); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+
+	tests := []struct {
+		id      string
+		want    string
+		wantErr error
+	}{
+		{id: "api_key", want: "secret"},
+		{id: "other_key", wantErr: ErrNotFound},
+		{id: "unknown", wantErr: ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			got, err := reopened.Get(tt.id)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("Get() = %q, %v, want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
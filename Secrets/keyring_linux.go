@@ -0,0 +1,50 @@
+//go:build linux
+
+package Secrets
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// keyringStore uses the Secret Service (GNOME Keyring, KWallet) with the secret-tool command of libsecret.
+type keyringStore struct{}
+
+func keyringAvailable() bool {
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+		return false
+	}
+	_, err := exec.LookPath("secret-tool")
+	return err == nil
+}
+
+func (keyringStore) Name() string {
+	return "Secret Service"
+}
+
+func (keyringStore) Get(id string) (string, error) {
+	output, err := exec.Command("secret-tool", "lookup", "service", serviceName, "id", id).Output()
+	if err != nil {
+		var exitError *exec.ExitError
+		if errors.As(err, &exitError) && len(exitError.Stderr) == 0 {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return string(output), nil
+}
+
+func (keyringStore) Set(id string, value string) error {
+	cmd := exec.Command("secret-tool", "store", "--label", serviceName+" "+id, "service", serviceName, "id", id)
+	cmd.Stdin = strings.NewReader(value)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return errors.New(strings.TrimSpace(string(output)) + " " + err.Error())
+	}
+	return nil
+}
+
+func (keyringStore) Delete(id string) error {
+	return exec.Command("secret-tool", "clear", "service", serviceName, "id", id).Run()
+}
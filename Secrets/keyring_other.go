@@ -0,0 +1,32 @@
+//go:build !linux && !windows
+
+package Secrets
+
+import (
+	"errors"
+)
+
+// keyringStore is not supported on this platform, so the secrets are kept in the passphrase protected FileStore.
+type keyringStore struct{}
+
+var errKeyringNotSupported = errors.New("the OS keyring is not supported on this platform")
+
+func keyringAvailable() bool {
+	return false
+}
+
+func (keyringStore) Name() string {
+	return "OS keyring"
+}
+
+func (keyringStore) Get(id string) (string, error) {
+	return "", errKeyringNotSupported
+}
+
+func (keyringStore) Set(id string, value string) error {
+	return errKeyringNotSupported
+}
+
+func (keyringStore) Delete(id string) error {
+	return errKeyringNotSupported
+}
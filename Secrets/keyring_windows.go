@@ -0,0 +1,109 @@
+//go:build windows
+
+package Secrets
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// keyringStore uses the Windows Credential Manager.
+type keyringStore struct{}
+
+var (
+	advapi32        = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW   = advapi32.NewProc("CredReadW")
+	procCredWriteW  = advapi32.NewProc("CredWriteW")
+	procCredDeleteW = advapi32.NewProc("CredDeleteW")
+	procCredFree    = advapi32.NewProc("CredFree")
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	errorNotFound           = syscall.Errno(1168)
+)
+
+// credential is the CREDENTIALW structure
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+func keyringAvailable() bool {
+	return advapi32.Load() == nil
+}
+
+func credentialTarget(id string) (*uint16, error) {
+	return syscall.UTF16PtrFromString(serviceName + ":" + id)
+}
+
+func (keyringStore) Name() string {
+	return "Windows Credential Manager"
+}
+
+func (keyringStore) Get(id string) (string, error) {
+	target, err := credentialTarget(id)
+	if err != nil {
+		return "", err
+	}
+	var cred *credential
+	result, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if result == 0 {
+		if err == errorNotFound {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+	if cred.CredentialBlobSize == 0 {
+		return "", nil
+	}
+	return string(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
+
+func (keyringStore) Set(id string, value string) error {
+	target, err := credentialTarget(id)
+	if err != nil {
+		return err
+	}
+	userName, _ := syscall.UTF16PtrFromString(serviceName)
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(value)),
+		Persist:            credPersistLocalMachine,
+		UserName:           userName,
+	}
+	if len(value) > 0 {
+		blob := []byte(value)
+		cred.CredentialBlob = &blob[0]
+	}
+	result, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if result == 0 {
+		return err
+	}
+	return nil
+}
+
+func (keyringStore) Delete(id string) error {
+	target, err := credentialTarget(id)
+	if err != nil {
+		return err
+	}
+	result, _, err := procCredDeleteW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0)
+	if result == 0 && err != errorNotFound {
+		return err
+	}
+	return nil
+}
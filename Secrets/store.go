@@ -0,0 +1,81 @@
+package Secrets
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// Secrets (like API keys in plugin settings) are not written to profiles.
+// The profile contains a reference instead and the value is kept in the OS keyring
+// or, where no keyring is available, in a passphrase encrypted file.
+
+// ReferencePrefix marks a value as reference to a stored secret.
+const ReferencePrefix = "secret://"
+
+// MaskedValue is shown instead of a secret.
+const MaskedValue = "********"
+
+const serviceName = "whispering-tiger"
+
+var ErrNotFound = errors.New("secret not found")
+
+// ErrLocked is returned while the encrypted secrets file is not unlocked with its passphrase.
+var ErrLocked = errors.New("secrets are locked")
+
+type Store interface {
+	Get(id string) (string, error)
+	Set(id string, value string) error
+	Delete(id string) error
+	// Name describes where the secrets are stored
+	Name() string
+}
+
+var (
+	storeOnce    sync.Once
+	currentStore Store
+)
+
+// CurrentStore returns the OS keyring if it is available, otherwise the encrypted secrets file.
+func CurrentStore() Store {
+	storeOnce.Do(func() {
+		if keyringAvailable() {
+			currentStore = keyringStore{}
+		} else {
+			currentStore = FileStore
+		}
+	})
+	return currentStore
+}
+
+// Reference returns the value which is saved in place of a secret.
+func Reference(id string) string {
+	return ReferencePrefix + id
+}
+
+// IsReference returns true if the value is a reference to a stored secret.
+func IsReference(value interface{}) bool {
+	text, ok := value.(string)
+	return ok && strings.HasPrefix(text, ReferencePrefix)
+}
+
+// Resolve returns the secret a reference points to.
+func Resolve(reference string) (string, error) {
+	return CurrentStore().Get(strings.TrimPrefix(reference, ReferencePrefix))
+}
+
+// Save stores the secret and returns the reference to it.
+func Save(id string, value string) (string, error) {
+	if err := CurrentStore().Set(id, value); err != nil {
+		return "", err
+	}
+	return Reference(id), nil
+}
+
+// Mask returns MaskedValue for set secrets.
+func Mask(value interface{}) interface{} {
+	if value == nil || value == "" {
+		return value
+	}
+	return MaskedValue
+}
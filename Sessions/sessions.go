@@ -0,0 +1,389 @@
+package Sessions
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"whispering-tiger-ui/Fields"
+)
+
+// audio files saved by the backend are matched to a transcript if they were written in this time window
+// before (or shortly after) the transcript was received.
+const (
+	audioMatchWindowBefore = 60 * time.Second
+	audioMatchWindowAfter  = 5 * time.Second
+)
+
+var audioFileExtensions = []string{".wav", ".mp3", ".flac", ".ogg"}
+
+// sessionSaveDelay collects the transcripts received in this time into one write of the session file
+const sessionSaveDelay = 5 * time.Second
+
+// retranslationTimeout is the time after which a re-translation without answer is dropped
+const retranslationTimeout = 2 * time.Minute
+
+func GetSessionsDir() string {
+	return filepath.Join(".", "Sessions")
+}
+
+//goland:noinspection GoSnakeCaseUsage
+type SessionEntry struct {
+	Received   time.Time            `yaml:"received"`
+	Result     Fields.WhisperResult `yaml:"result"`
+	Audio_file string               `yaml:"audio_file,omitempty"`
+}
+
+//goland:noinspection GoSnakeCaseUsage
+type Session struct {
+	Id        string         `yaml:"id"`
+	Started   time.Time      `yaml:"started"`
+	Profile   string         `yaml:"profile"`
+	Audio_dir string         `yaml:"audio_dir,omitempty"`
+	Entries   []SessionEntry `yaml:"entries"`
+
+	Summary *SessionSummary `yaml:"summary,omitempty"`
+
+	// Speaker_names are the user defined names of the speaker ids (like "SPEAKER_01") of this session
+	Speaker_names map[string]string `yaml:"speaker_names,omitempty"`
+
+	mutex     sync.Mutex
+	saveTimer *time.Timer
+}
+
+// Current is the session recorded while the application is running
+var Current *Session
+
+// StartSession starts recording a new session for the loaded profile.
+func StartSession(profileName string, audioDir string) *Session {
+	started := time.Now()
+	Current = &Session{
+		Id:        started.Format("2006-01-02_15-04-05"),
+		Started:   started,
+		Profile:   profileName,
+		Audio_dir: audioDir,
+	}
+	return Current
+}
+
+func (s *Session) FileName() string {
+	return filepath.Join(GetSessionsDir(), s.Id+".yaml")
+}
+
+// AddResult adds a transcript to the session. The session is saved (and the saved audio clips are matched) after
+// sessionSaveDelay, so a long session is not written completely for every transcript.
+func (s *Session) AddResult(result Fields.WhisperResult) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry := SessionEntry{
+		Received: time.Now(),
+		Result:   result,
+	}
+	s.Entries = append(s.Entries, entry)
+	if s.saveTimer == nil {
+		s.saveTimer = time.AfterFunc(sessionSaveDelay, s.saveDelayed)
+	}
+}
+
+func (s *Session) saveDelayed() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.saveTimer = nil
+	s.matchAudioFiles()
+	if err := s.save(); err != nil {
+		log.Printf("failed to save session: %v", err)
+	}
+}
+
+// MatchAudioFiles assigns saved audio clips to entries which have none yet.
+// The backend might write the audio file after the transcript was sent, so this is also called when a session is shown.
+func (s *Session) MatchAudioFiles() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.matchAudioFiles() {
+		if err := s.save(); err != nil {
+			log.Printf("failed to save session: %v", err)
+		}
+	}
+}
+
+type audioFile struct {
+	path    string
+	modTime time.Time
+}
+
+func (s *Session) listAudioFiles() []audioFile {
+	var files []audioFile
+	if s.Audio_dir == "" {
+		return files
+	}
+	_ = filepath.WalkDir(s.Audio_dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		extension := strings.ToLower(filepath.Ext(path))
+		for _, audioExtension := range audioFileExtensions {
+			if extension == audioExtension {
+				info, err := d.Info()
+				if err == nil && !info.ModTime().Before(s.Started.Add(-audioMatchWindowBefore)) {
+					files = append(files, audioFile{path: path, modTime: info.ModTime()})
+				}
+				break
+			}
+		}
+		return nil
+	})
+	return files
+}
+
+// matchAudioFiles returns true if any entry has been changed
+func (s *Session) matchAudioFiles() bool {
+	var unmatched []int
+	usedFiles := map[string]bool{}
+	for i, entry := range s.Entries {
+		if entry.Audio_file == "" {
+			unmatched = append(unmatched, i)
+		} else {
+			usedFiles[entry.Audio_file] = true
+		}
+	}
+	if len(unmatched) == 0 {
+		return false
+	}
+
+	files := s.listAudioFiles()
+	changed := false
+	for _, entryIndex := range unmatched {
+		received := s.Entries[entryIndex].Received
+		bestMatch := ""
+		bestDistance := math.MaxFloat64
+		for _, file := range files {
+			if usedFiles[file.path] {
+				continue
+			}
+			if file.modTime.Before(received.Add(-audioMatchWindowBefore)) || file.modTime.After(received.Add(audioMatchWindowAfter)) {
+				continue
+			}
+			distance := math.Abs(received.Sub(file.modTime).Seconds())
+			if distance < bestDistance {
+				bestDistance = distance
+				bestMatch = file.path
+			}
+		}
+		if bestMatch != "" {
+			s.Entries[entryIndex].Audio_file = bestMatch
+			usedFiles[bestMatch] = true
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (s *Session) save() error {
+	if err := os.MkdirAll(GetSessionsDir(), 0755); err != nil {
+		return err
+	}
+	yamlFile, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.FileName(), yamlFile, 0644)
+}
+
+// Save matches the saved audio clips and writes the session to the sessions directory (e.g. before the application
+// exits).
+func (s *Session) Save() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.saveTimer != nil {
+		s.saveTimer.Stop()
+		s.saveTimer = nil
+	}
+	s.matchAudioFiles()
+	return s.save()
+}
+
+// SetSpeakerNames stores the user defined speaker names in the session and saves it.
+func (s *Session) SetSpeakerNames(names map[string]string) error {
+	if s == nil {
+		return nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Speaker_names = map[string]string{}
+	for speakerId, name := range names {
+		s.Speaker_names[speakerId] = name
+	}
+	return s.save()
+}
+
+// SpeakerName returns the name of a speaker in this session or the speaker id if it was not renamed.
+func (s *Session) SpeakerName(speakerId string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if name, ok := s.Speaker_names[speakerId]; ok {
+		return name
+	}
+	return speakerId
+}
+
+// GetEntries returns a copy of all entries of the session
+func (s *Session) GetEntries() []SessionEntry {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	entries := make([]SessionEntry, len(s.Entries))
+	copy(entries, s.Entries)
+	return entries
+}
+
+// UpdateEntryResult replaces the result of an entry (i.e. after re-transcribing or re-translating it)
+func (s *Session) UpdateEntryResult(index int, result Fields.WhisperResult) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if index < 0 || index >= len(s.Entries) {
+		return fmt.Errorf("session entry %d not found", index)
+	}
+	s.Entries[index].Result = result
+	return s.save()
+}
+
+func LoadSession(id string) (*Session, error) {
+	// the current session is still recorded, so return it directly
+	if Current != nil && Current.Id == id {
+		return Current, nil
+	}
+	yamlFile, err := os.ReadFile(filepath.Join(GetSessionsDir(), id+".yaml"))
+	if err != nil {
+		return nil, err
+	}
+	session := &Session{}
+	if err = yaml.Unmarshal(yamlFile, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// ListSessions returns all recorded sessions, newest first.
+func ListSessions() []*Session {
+	var sessions []*Session
+	files, err := os.ReadDir(GetSessionsDir())
+	if err != nil {
+		if Current != nil {
+			sessions = append(sessions, Current)
+		}
+		return sessions
+	}
+	currentListed := false
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".yaml") {
+			continue
+		}
+		session, err := LoadSession(strings.TrimSuffix(file.Name(), ".yaml"))
+		if err != nil {
+			log.Printf("failed to load session %s: %v", file.Name(), err)
+			continue
+		}
+		if session == Current {
+			currentListed = true
+		}
+		sessions = append(sessions, session)
+	}
+	// the current session is only saved some time after its first transcript
+	if Current != nil && !currentListed {
+		sessions = append(sessions, Current)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Started.After(sessions[j].Started)
+	})
+	return sessions
+}
+
+func DeleteSession(id string) error {
+	if Current != nil && Current.Id == id {
+		return fmt.Errorf("the current session can not be deleted")
+	}
+	return os.Remove(filepath.Join(GetSessionsDir(), id+".yaml"))
+}
+
+// IsPlayableAudioFile returns true if the audio clip can be played (only wav files are supported).
+func IsPlayableAudioFile(fileName string) bool {
+	return strings.EqualFold(filepath.Ext(fileName), ".wav")
+}
+
+// retranslation is a translation requested for a session entry, which is matched to the answer by its text.
+type retranslation struct {
+	session        *Session
+	index          int
+	text           string
+	targetLanguage string
+	requested      time.Time
+	onUpdated      func(err error)
+}
+
+var (
+	pendingRetranslations      []*retranslation
+	pendingRetranslationsMutex sync.Mutex
+)
+
+// AddRetranslation remembers that the text of a session entry is translated again, so the translation result is
+// stored in the entry instead of being shown as a regular translation. onUpdated is called when the entry was updated.
+func (s *Session) AddRetranslation(index int, text string, targetLanguage string, onUpdated func(err error)) {
+	pendingRetranslationsMutex.Lock()
+	defer pendingRetranslationsMutex.Unlock()
+	pendingRetranslations = append(pendingRetranslations, &retranslation{
+		session:        s,
+		index:          index,
+		text:           strings.TrimSpace(text),
+		targetLanguage: targetLanguage,
+		requested:      time.Now(),
+		onUpdated:      onUpdated,
+	})
+}
+
+// HandleTranslateResult stores a translation result in the session entry it was requested for.
+// Returns false if the result does not belong to a re-translation.
+func HandleTranslateResult(originalText string, translation string) bool {
+	pendingRetranslationsMutex.Lock()
+	var request *retranslation
+	var pending []*retranslation
+	for _, entry := range pendingRetranslations {
+		if time.Since(entry.requested) > retranslationTimeout {
+			continue
+		}
+		if request == nil && entry.text == strings.TrimSpace(originalText) {
+			request = entry
+			continue
+		}
+		pending = append(pending, entry)
+	}
+	pendingRetranslations = pending
+	pendingRetranslationsMutex.Unlock()
+	if request == nil {
+		return false
+	}
+
+	request.session.mutex.Lock()
+	if request.index >= len(request.session.Entries) {
+		request.session.mutex.Unlock()
+		return true
+	}
+	result := request.session.Entries[request.index].Result
+	request.session.mutex.Unlock()
+	result.TxtTranslation = strings.TrimSpace(translation)
+	result.TxtTranslationTarget = request.targetLanguage
+	err := request.session.UpdateEntryResult(request.index, result)
+	if request.onUpdated != nil {
+		request.onUpdated(err)
+	}
+	return true
+}
@@ -0,0 +1,122 @@
+package Sessions
+
+import (
+	"os"
+	"testing"
+	"time"
+	"whispering-tiger-ui/Fields"
+)
+
+// useTempSessionsDir changes into a temporary directory for the duration of the test, since the sessions directory
+// is relative to the working directory.
+func useTempSessionsDir(t *testing.T) {
+	t.Helper()
+	previousDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	previousCurrent := Current
+	t.Cleanup(func() {
+		Current = previousCurrent
+		_ = os.Chdir(previousDir)
+	})
+}
+
+func TestIsPlayableAudioFile(t *testing.T) {
+	tests := []struct {
+		fileName string
+		want     bool
+	}{
+		{fileName: "audio/clip.wav", want: true},
+		{fileName: "clip.WAV", want: true},
+		{fileName: "clip.mp3", want: false},
+		{fileName: "clip", want: false},
+		{fileName: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := IsPlayableAudioFile(tt.fileName); got != tt.want {
+			t.Errorf("IsPlayableAudioFile(%q) = %v, want %v", tt.fileName, got, tt.want)
+		}
+	}
+}
+
+func TestHandleTranslateResult(t *testing.T) {
+	useTempSessionsDir(t)
+	session := &Session{Id: "test", Entries: []SessionEntry{
+		{Received: time.Now(), Result: Fields.WhisperResult{Text: "Guten Morgen"}},
+		{Received: time.Now(), Result: Fields.WhisperResult{Text: "Hallo Welt"}},
+	}}
+	updated := 0
+	session.AddRetranslation(1, " Hallo Welt", "English", func(err error) {
+		if err != nil {
+			t.Errorf("update error = %v", err)
+		}
+		updated++
+	})
+
+	tests := []struct {
+		name         string
+		originalText string
+		want         bool
+	}{
+		{name: "other translation", originalText: "Guten Morgen", want: false},
+		{name: "re-translation", originalText: "Hallo Welt ", want: true},
+		{name: "answered re-translation", originalText: "Hallo Welt", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HandleTranslateResult(tt.originalText, " Hello world "); got != tt.want {
+				t.Errorf("HandleTranslateResult() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	entries := session.GetEntries()
+	if updated != 1 || entries[1].Result.TxtTranslation != "Hello world" || entries[1].Result.TxtTranslationTarget != "English" {
+		t.Errorf("entry = %+v, updated %d times, want the translation stored once", entries[1].Result, updated)
+	}
+	if entries[0].Result.TxtTranslation != "" {
+		t.Errorf("other entry was changed: %+v", entries[0].Result)
+	}
+	if saved, err := LoadSession("test"); err != nil || saved.Entries[1].Result.TxtTranslation != "Hello world" {
+		t.Errorf("saved session = %v, %v, want the translation saved", saved, err)
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	useTempSessionsDir(t)
+	saved := &Session{Id: "saved", Started: time.Now().Add(-time.Hour)}
+	if err := saved.Save(); err != nil {
+		t.Fatal(err)
+	}
+	Current = &Session{Id: "current", Started: time.Now()}
+
+	tests := []struct {
+		name      string
+		saveFirst bool
+	}{
+		// the current session is listed before it was saved for the first time
+		{name: "current not saved"},
+		{name: "current saved", saveFirst: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.saveFirst {
+				if err := Current.Save(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			sessions := ListSessions()
+			if len(sessions) != 2 || sessions[0] != Current || sessions[1].Id != "saved" {
+				var ids []string
+				for _, session := range sessions {
+					ids = append(ids, session.Id)
+				}
+				t.Errorf("ListSessions() = %v, want [current saved]", ids)
+			}
+		})
+	}
+}
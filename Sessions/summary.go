@@ -0,0 +1,335 @@
+package Sessions
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+	"whispering-tiger-ui/Fields"
+)
+
+// DefaultSummaryChunkSize is the maximal number of bytes of the transcript sent in one LLM request.
+const DefaultSummaryChunkSize = 6000
+
+// summaryAnswerTimeout is the time to wait for the answer to one LLM request. The LLM plugin might not be
+// enabled, so a summarization never waits forever.
+const summaryAnswerTimeout = 3 * time.Minute
+
+var (
+	ErrSummaryTimeout  = errors.New("the LLM plugin did not answer the summary request. Make sure the LLM plugin is enabled")
+	ErrSummaryCanceled = errors.New("summary canceled")
+)
+
+const summaryPrompt = `You are writing meeting minutes from a transcript.
+Answer in the language of the transcript using exactly these Markdown sections:
+## Summary
+A short summary of the conversation.
+## Action Items
+- one action item per line (who does what)
+## Decisions
+- one key decision per line
+Leave a section empty if there is nothing to list.`
+
+const summaryChunkPrompt = `You are summarizing part %d of %d of a long transcript.
+Write concise notes of the discussed topics, action items and decisions of this part only.`
+
+const summaryCombinePrompt = `The following are notes of consecutive parts of one transcript.
+Combine them into one result.
+` + summaryPrompt
+
+//goland:noinspection GoSnakeCaseUsage
+type SessionSummary struct {
+	Created      time.Time `yaml:"created"`
+	Summary      string    `yaml:"summary"`
+	Action_items []string  `yaml:"action_items,omitempty"`
+	Decisions    []string  `yaml:"decisions,omitempty"`
+}
+
+// TranscriptText returns the transcript of the session with one line per entry.
+func (s *Session) TranscriptText() string {
+	var builder strings.Builder
+	for _, entry := range s.GetEntries() {
+		builder.WriteString("[" + entry.Received.Format("15:04:05") + "] ")
+		if entry.Result.Speaker != "" {
+			builder.WriteString(s.SpeakerName(entry.Result.Speaker) + ": ")
+		}
+		builder.WriteString(strings.TrimSpace(entry.Result.Text))
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// SetSummary stores the summary in the session and saves it.
+func (s *Session) SetSummary(summary *SessionSummary) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Summary = summary
+	return s.save()
+}
+
+func (s *Session) GetSummary() *SessionSummary {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.Summary
+}
+
+// ChunkText splits a text at line breaks into chunks of at most maxChars bytes.
+// Single lines which are longer are split at spaces or else between characters (a single character is never split).
+func ChunkText(text string, maxChars int) []string {
+	if maxChars <= 0 {
+		maxChars = DefaultSummaryChunkSize
+	}
+	var chunks []string
+	var current strings.Builder
+	flush := func() {
+		if strings.TrimSpace(current.String()) != "" {
+			chunks = append(chunks, current.String())
+		}
+		current.Reset()
+	}
+	for _, line := range strings.SplitAfter(text, "\n") {
+		for len(line) > maxChars {
+			// cut at a rune boundary, so multi-byte characters are not split
+			cutIndex := maxChars
+			for cutIndex > 0 && !utf8.RuneStart(line[cutIndex]) {
+				cutIndex--
+			}
+			if cutIndex == 0 {
+				_, cutIndex = utf8.DecodeRuneInString(line)
+			}
+			splitIndex := strings.LastIndex(line[:cutIndex], " ")
+			if splitIndex <= 0 {
+				splitIndex = cutIndex
+			}
+			flush()
+			chunks = append(chunks, line[:splitIndex])
+			line = line[splitIndex:]
+		}
+		if current.Len()+len(line) > maxChars {
+			flush()
+		}
+		current.WriteString(line)
+	}
+	flush()
+	return chunks
+}
+
+func parseListItem(line string) string {
+	line = strings.TrimSpace(line)
+	for _, prefix := range []string{"- [ ]", "- [x]", "-", "*", "•"} {
+		if strings.HasPrefix(line, prefix) {
+			return strings.TrimSpace(strings.TrimPrefix(line, prefix))
+		}
+	}
+	// numbered lists
+	if dotIndex := strings.Index(line, ". "); dotIndex > 0 && dotIndex <= 3 && strings.Trim(line[:dotIndex], "0123456789") == "" {
+		return strings.TrimSpace(line[dotIndex+2:])
+	}
+	return line
+}
+
+// ParseSummaryAnswer reads the sections of an LLM answer that was requested with the summary prompt.
+// If the answer does not contain the expected sections, it is used as summary text.
+func ParseSummaryAnswer(answer string) *SessionSummary {
+	summary := &SessionSummary{Created: time.Now()}
+	var summaryLines []string
+	section := "summary"
+	for _, line := range strings.Split(answer, "\n") {
+		heading := strings.ToLower(strings.Trim(strings.TrimSpace(line), "#*: "))
+		if strings.HasPrefix(strings.TrimSpace(line), "#") || strings.HasSuffix(strings.TrimSpace(line), ":") || strings.HasPrefix(strings.TrimSpace(line), "**") {
+			switch {
+			case strings.HasPrefix(heading, "summary"):
+				section = "summary"
+				continue
+			case strings.HasPrefix(heading, "action item"):
+				section = "action_items"
+				continue
+			case strings.HasPrefix(heading, "decision") || strings.HasPrefix(heading, "key decision"):
+				section = "decisions"
+				continue
+			}
+		}
+		switch section {
+		case "summary":
+			summaryLines = append(summaryLines, line)
+		case "action_items":
+			if item := parseListItem(line); item != "" {
+				summary.Action_items = append(summary.Action_items, item)
+			}
+		case "decisions":
+			if item := parseListItem(line); item != "" {
+				summary.Decisions = append(summary.Decisions, item)
+			}
+		}
+	}
+	summary.Summary = strings.TrimSpace(strings.Join(summaryLines, "\n"))
+	return summary
+}
+
+// Markdown returns the summary as Markdown document.
+func (summary *SessionSummary) Markdown() string {
+	var builder strings.Builder
+	builder.WriteString("## Summary\n\n" + summary.Summary + "\n\n")
+	builder.WriteString("## Action Items\n\n")
+	for _, item := range summary.Action_items {
+		builder.WriteString("- [ ] " + item + "\n")
+	}
+	builder.WriteString("\n## Decisions\n\n")
+	for _, decision := range summary.Decisions {
+		builder.WriteString("- " + decision + "\n")
+	}
+	return builder.String()
+}
+
+// ExportMarkdown returns the session summary (if any) together with the transcript as Markdown document.
+func (s *Session) ExportMarkdown() string {
+	var builder strings.Builder
+	builder.WriteString("# Session " + s.Started.Format("2006-01-02 15:04:05") + "\n\n")
+	if summary := s.GetSummary(); summary != nil {
+		builder.WriteString(summary.Markdown() + "\n")
+	}
+	builder.WriteString("## Transcript\n\n")
+	for _, line := range strings.Split(strings.TrimSpace(s.TranscriptText()), "\n") {
+		builder.WriteString(line + "  \n")
+	}
+	return builder.String()
+}
+
+// summaryRequest tracks a running summarization. Long transcripts are summarized chunk by chunk
+// and the notes of all chunks are combined in a final request.
+type summaryRequest struct {
+	session *Session
+	// sentText is the text of the last request. The LLM plugin answers with it in the text field.
+	sentText   string
+	chunks     []string
+	notes      []string
+	timeout    *time.Timer
+	onFinished func(summary *SessionSummary, err error)
+}
+
+var (
+	pendingSummary      *summaryRequest
+	pendingSummaryMutex sync.Mutex
+)
+
+func sendLlmRequest(prompt string, text string) {
+	// request to the LLM plugin. It answers with an "llm_answer" message containing the text of the request.
+	sendMessage := Fields.SendMessageStruct{
+		Type: "llm_req",
+		Value: struct {
+			Text   string `json:"text"`
+			Prompt string `json:"prompt"`
+		}{
+			Text:   text,
+			Prompt: prompt,
+		},
+	}
+	sendMessage.SendMessage()
+}
+
+// sendNext sends the next request of the summarization and restarts the answer timeout.
+func (r *summaryRequest) sendNext() {
+	pendingSummaryMutex.Lock()
+	if r.timeout != nil {
+		r.timeout.Stop()
+	}
+	r.timeout = time.AfterFunc(summaryAnswerTimeout, func() {
+		finishSummary(r, nil, ErrSummaryTimeout)
+	})
+	chunkIndex := len(r.notes)
+	prompt, text := summaryCombinePrompt, strings.Join(r.notes, "\n\n")
+	if len(r.chunks) == 1 {
+		prompt, text = summaryPrompt, r.chunks[0]
+	} else if chunkIndex < len(r.chunks) {
+		prompt, text = fmt.Sprintf(summaryChunkPrompt, chunkIndex+1, len(r.chunks)), r.chunks[chunkIndex]
+	}
+	r.sentText = text
+	pendingSummaryMutex.Unlock()
+
+	sendLlmRequest(prompt, text)
+}
+
+// finishSummary ends the summarization if it is still running and calls its onFinished function.
+func finishSummary(request *summaryRequest, summary *SessionSummary, err error) {
+	pendingSummaryMutex.Lock()
+	if pendingSummary != request {
+		pendingSummaryMutex.Unlock()
+		return
+	}
+	pendingSummary = nil
+	if request.timeout != nil {
+		request.timeout.Stop()
+	}
+	pendingSummaryMutex.Unlock()
+
+	if request.onFinished != nil {
+		request.onFinished(summary, err)
+	}
+}
+
+// Summarize sends the transcript of the session to the LLM plugin. onFinished is called once the summary is received,
+// the summarization was canceled or the LLM plugin did not answer in time.
+// A running summarization is canceled.
+func (s *Session) Summarize(chunkSize int, onFinished func(summary *SessionSummary, err error)) error {
+	chunks := ChunkText(s.TranscriptText(), chunkSize)
+	if len(chunks) == 0 {
+		return fmt.Errorf("the session has no transcript to summarize")
+	}
+
+	request := &summaryRequest{
+		session:    s,
+		chunks:     chunks,
+		onFinished: onFinished,
+	}
+	CancelSummary()
+
+	pendingSummaryMutex.Lock()
+	pendingSummary = request
+	pendingSummaryMutex.Unlock()
+
+	request.sendNext()
+	return nil
+}
+
+// CancelSummary stops a running summarization. Answers which are received later are ignored.
+func CancelSummary() {
+	pendingSummaryMutex.Lock()
+	request := pendingSummary
+	pendingSummaryMutex.Unlock()
+	if request != nil {
+		finishSummary(request, nil, ErrSummaryCanceled)
+	}
+}
+
+// HandleLlmAnswer processes answers of the LLM plugin that belong to a summarization. text is the text the answer
+// was requested for. Returns false if it is not the text of the last request of the running summarization.
+func HandleLlmAnswer(text string, answer string) bool {
+	pendingSummaryMutex.Lock()
+	request := pendingSummary
+	if request == nil || strings.TrimSpace(text) == "" || strings.TrimSpace(text) != strings.TrimSpace(request.sentText) {
+		pendingSummaryMutex.Unlock()
+		return false
+	}
+
+	answer = strings.TrimSpace(answer)
+	if len(request.chunks) > 1 && len(request.notes) < len(request.chunks) {
+		request.notes = append(request.notes, answer)
+		pendingSummaryMutex.Unlock()
+		request.sendNext()
+		return true
+	}
+	pendingSummary = nil
+	if request.timeout != nil {
+		request.timeout.Stop()
+	}
+	pendingSummaryMutex.Unlock()
+
+	summary := ParseSummaryAnswer(answer)
+	err := request.session.SetSummary(summary)
+	if request.onFinished != nil {
+		request.onFinished(summary, err)
+	}
+	return true
+}
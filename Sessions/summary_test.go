@@ -0,0 +1,149 @@
+package Sessions
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+	"whispering-tiger-ui/Fields"
+)
+
+func TestChunkText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxChars int
+		want     []string
+	}{
+		{name: "empty", text: "", maxChars: 10},
+		{name: "fits", text: "a\nb\n", maxChars: 10, want: []string{"a\nb\n"}},
+		{name: "split at lines", text: "aaaa\nbbbb\ncccc\n", maxChars: 10, want: []string{"aaaa\nbbbb\n", "cccc\n"}},
+		{name: "long line split at spaces", text: "aaaa bbbb cccc", maxChars: 10, want: []string{"aaaa bbbb", " cccc"}},
+		{name: "multi-byte characters", text: "会議は金曜日に終わります", maxChars: 10, want: []string{"会議は", "金曜日", "に終わ", "ります"}},
+		{name: "multi-byte characters with spaces", text: "Grüße aus Köln", maxChars: 8, want: []string{"Grüße", " aus", " Köln"}},
+		{name: "blank lines dropped", text: "\n\n\n", maxChars: 1},
+		{name: "default size", text: "a", maxChars: 0, want: []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ChunkText(tt.text, tt.maxChars)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ChunkText() = %q, want %q", got, tt.want)
+			}
+			maxChars := tt.maxChars
+			if maxChars <= 0 {
+				maxChars = DefaultSummaryChunkSize
+			}
+			for _, chunk := range got {
+				if !utf8.ValidString(chunk) {
+					t.Errorf("chunk %q is not valid UTF-8", chunk)
+				}
+				if len(chunk) > maxChars {
+					t.Errorf("chunk %q is longer than %d", chunk, maxChars)
+				}
+			}
+			if strings.Join(got, "") != tt.text && strings.TrimSpace(tt.text) != "" {
+				t.Errorf("chunks do not contain the whole text")
+			}
+		})
+	}
+}
+
+func TestParseSummaryAnswer(t *testing.T) {
+	tests := []struct {
+		name          string
+		answer        string
+		wantSummary   string
+		wantActions   []string
+		wantDecisions []string
+	}{
+		{name: "plain text", answer: "We talked about the release.\n", wantSummary: "We talked about the release."},
+		{
+			name:          "markdown headings",
+			answer:        "## Summary\nThe release was planned.\n\n## Action Items\n- [ ] Write notes\n- Test build\n\n## Decisions\n1. Ship on Friday\n",
+			wantSummary:   "The release was planned.",
+			wantActions:   []string{"Write notes", "Test build"},
+			wantDecisions: []string{"Ship on Friday"},
+		},
+		{
+			name:          "bold and colon headings",
+			answer:        "**Summary**\nShort meeting.\nAction items:\n* Fix bug\nKey decisions:\n• Keep the design\n",
+			wantSummary:   "Short meeting.",
+			wantActions:   []string{"Fix bug"},
+			wantDecisions: []string{"Keep the design"},
+		},
+		{name: "other headings are text", answer: "# Notes\nText\n", wantSummary: "# Notes\nText"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseSummaryAnswer(tt.answer)
+			if got.Summary != tt.wantSummary {
+				t.Errorf("Summary = %q, want %q", got.Summary, tt.wantSummary)
+			}
+			if !reflect.DeepEqual(got.Action_items, tt.wantActions) {
+				t.Errorf("Action_items = %q, want %q", got.Action_items, tt.wantActions)
+			}
+			if !reflect.DeepEqual(got.Decisions, tt.wantDecisions) {
+				t.Errorf("Decisions = %q, want %q", got.Decisions, tt.wantDecisions)
+			}
+		})
+	}
+}
+
+func TestHandleLlmAnswer(t *testing.T) {
+	useTempSessionsDir(t)
+
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	go func() {
+		for {
+			select {
+			case <-Fields.SendMessageChannel:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	session := &Session{Id: "test", Entries: []SessionEntry{
+		{Received: time.Now(), Result: Fields.WhisperResult{Text: "We ship on Friday."}},
+	}}
+	finished := make(chan *SessionSummary, 1)
+	if err := session.Summarize(0, func(summary *SessionSummary, err error) {
+		if err != nil {
+			t.Errorf("summary error = %v", err)
+		}
+		finished <- summary
+	}); err != nil {
+		t.Fatalf("Summarize() error = %v", err)
+	}
+	t.Cleanup(CancelSummary)
+
+	tests := []struct {
+		name string
+		text string
+		want bool
+	}{
+		{name: "ordinary answer", text: "What is the weather?", want: false},
+		{name: "answer without text", text: "", want: false},
+		{name: "summary answer", text: "\n" + session.TranscriptText() + " ", want: true},
+		{name: "answer after the summary finished", text: session.TranscriptText(), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HandleLlmAnswer(tt.text, "## Summary\nRelease planning.\n## Decisions\n- Ship on Friday\n"); got != tt.want {
+				t.Errorf("HandleLlmAnswer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	select {
+	case summary := <-finished:
+		if summary.Summary != "Release planning." || session.GetSummary() != summary {
+			t.Errorf("summary = %+v, want it stored in the session", summary)
+		}
+	default:
+		t.Error("the summarization did not finish")
+	}
+}
@@ -0,0 +1,196 @@
+package Settings
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"sort"
+	"strings"
+	"whispering-tiger-ui/Utilities"
+)
+
+// keys which are not part of a profile comparison
+var notComparedKeys = []string{"settingsfilename", "process_id", "schema_version", "extends", "run_backend_reconnect", "last_auto_txt_translate_lang"}
+
+// keys whose map values are compared per entry (like the settings of every plugin)
+var expandedCompareKeys = []string{"plugins", "plugin_settings", "plugin_timer", "plugin_timer_timeout"}
+
+// SettingCategories is the order in which the categories of SettingCategory are displayed.
+var SettingCategories = []string{"Audio", "VAD", "Speaker Diarization", "Whisper", "Text Translation", "OSC", "TTS", "Plugins", "Other"}
+
+var settingCategoryPrefixes = []struct {
+	Prefix   string
+	Category string
+}{
+	{"vad_", "VAD"},
+	{"osc_", "OSC"},
+	{"tts_", "TTS"},
+	{"plugin", "Plugins"},
+	{"txt_", "Text Translation"},
+	{"src_lang", "Text Translation"},
+	{"trg_lang", "Text Translation"},
+	{"speaker_", "Speaker Diarization"},
+	{"min_speaker", "Speaker Diarization"},
+	{"max_speakers", "Speaker Diarization"},
+	{"audio_", "Audio"},
+	{"device_", "Audio"},
+	{"phrase_time_limit", "Audio"},
+	{"pause", "Audio"},
+	{"energy", "Audio"},
+	{"denoise_", "Audio"},
+	{"silence_", "Audio"},
+	{"max_silence_length", "Audio"},
+	{"keep_silence_length", "Audio"},
+	{"normalize_", "Audio"},
+	{"websocket_", "Other"},
+	{"run_backend", "Other"},
+	{"ocr_", "Other"},
+	{"transcription_", "Other"},
+	{"transcript_", "Other"},
+}
+
+// SettingCategory returns the category of a setting key. Settings without a known prefix belong to Whisper.
+func SettingCategory(key string) string {
+	for _, categoryPrefix := range settingCategoryPrefixes {
+		if strings.HasPrefix(key, categoryPrefix.Prefix) {
+			return categoryPrefix.Category
+		}
+	}
+	return "Whisper"
+}
+
+func categoryIndex(category string) int {
+	for i, c := range SettingCategories {
+		if c == category {
+			return i
+		}
+	}
+	return len(SettingCategories)
+}
+
+type SettingDiff struct {
+	// Key of the setting. Entries of expanded maps use "key.entry" (like "plugin_settings.MyPlugin")
+	Key      string
+	Category string
+	Left     interface{}
+	Right    interface{}
+}
+
+func (c *Conf) comparableValues() (map[string]interface{}, error) {
+	confYaml, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	if err = yaml.Unmarshal(confYaml, &values); err != nil {
+		return nil, err
+	}
+	for _, key := range notComparedKeys {
+		delete(values, key)
+	}
+	for _, key := range expandedCompareKeys {
+		if entries, ok := values[key].(map[string]interface{}); ok {
+			delete(values, key)
+			for entryKey, entryValue := range entries {
+				values[key+"."+entryKey] = entryValue
+			}
+		}
+	}
+	return values, nil
+}
+
+// CompareConfs returns the settings which differ between the two configurations, sorted by category and key.
+func CompareConfs(left *Conf, right *Conf) ([]SettingDiff, error) {
+	leftValues, err := left.comparableValues()
+	if err != nil {
+		return nil, err
+	}
+	rightValues, err := right.comparableValues()
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []SettingDiff
+	for key := range mergedKeys(leftValues, rightValues) {
+		leftValue, rightValue := leftValues[key], rightValues[key]
+		// compare the printed values, since yaml might read the same number as int or float
+		if fmt.Sprint(leftValue) != fmt.Sprint(rightValue) {
+			diffs = append(diffs, SettingDiff{Key: key, Category: SettingCategory(key), Left: leftValue, Right: rightValue})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].Category != diffs[j].Category {
+			return categoryIndex(diffs[i].Category) < categoryIndex(diffs[j].Category)
+		}
+		return diffs[i].Key < diffs[j].Key
+	})
+	return diffs, nil
+}
+
+func mergedKeys(first map[string]interface{}, second map[string]interface{}) map[string]bool {
+	keys := map[string]bool{}
+	for key := range first {
+		keys[key] = true
+	}
+	for key := range second {
+		keys[key] = true
+	}
+	return keys
+}
+
+// CopySettings copies the values of the given keys (as returned by CompareConfs) from one configuration to the other.
+func CopySettings(from *Conf, to *Conf, keys []string) error {
+	fromYaml, err := yaml.Marshal(from)
+	if err != nil {
+		return err
+	}
+	toYaml, err := yaml.Marshal(to)
+	if err != nil {
+		return err
+	}
+	fromValues := map[string]interface{}{}
+	toValues := map[string]interface{}{}
+	if err = yaml.Unmarshal(fromYaml, &fromValues); err != nil {
+		return err
+	}
+	if err = yaml.Unmarshal(toYaml, &toValues); err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		mapKey, entryKey, isEntry := strings.Cut(key, ".")
+		if !isEntry || !Utilities.Contains(expandedCompareKeys, mapKey) {
+			if value, ok := fromValues[key]; ok {
+				toValues[key] = value
+			} else {
+				delete(toValues, key)
+			}
+			continue
+		}
+		toEntries, _ := toValues[mapKey].(map[string]interface{})
+		if toEntries == nil {
+			toEntries = map[string]interface{}{}
+		}
+		fromEntries, _ := fromValues[mapKey].(map[string]interface{})
+		if value, ok := fromEntries[entryKey]; ok {
+			toEntries[entryKey] = value
+		} else {
+			delete(toEntries, entryKey)
+		}
+		toValues[mapKey] = toEntries
+	}
+
+	mergedYaml, err := yaml.Marshal(toValues)
+	if err != nil {
+		return err
+	}
+	// unmarshal into a new Conf, so removed map entries are not kept
+	var result Conf
+	if err = yaml.Unmarshal(mergedYaml, &result); err != nil {
+		return err
+	}
+	result.SettingsFilename = to.SettingsFilename
+	result.Extends = to.Extends
+	result.Schema_version = to.Schema_version
+	*to = result
+	return nil
+}
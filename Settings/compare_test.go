@@ -0,0 +1,77 @@
+package Settings
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareConfs(t *testing.T) {
+	tests := []struct {
+		name     string
+		left     Conf
+		right    Conf
+		wantKeys []string
+	}{
+		{name: "equal", left: Conf{Energy: 300}, right: Conf{Energy: 300}},
+		{name: "ignored keys", left: Conf{SettingsFilename: "a.yaml", Extends: "base.yaml"}, right: Conf{SettingsFilename: "b.yaml"}},
+		{
+			name:     "sorted by category and key",
+			left:     Conf{Energy: 300, Beam_size: 5, Osc_ip: "127.0.0.1", Pause: 1},
+			right:    Conf{Energy: 200, Beam_size: 3, Osc_ip: "0.0.0.0", Pause: 1},
+			wantKeys: []string{"energy", "beam_size", "osc_ip"},
+		},
+		{
+			name:     "plugin entries",
+			left:     Conf{Plugins: map[string]bool{"A": true, "B": true}},
+			right:    Conf{Plugins: map[string]bool{"A": true, "B": false, "C": true}},
+			wantKeys: []string{"plugins.B", "plugins.C"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs, err := CompareConfs(&tt.left, &tt.right)
+			if err != nil {
+				t.Fatalf("CompareConfs() error = %v", err)
+			}
+			var gotKeys []string
+			for _, diff := range diffs {
+				gotKeys = append(gotKeys, diff.Key)
+			}
+			if !reflect.DeepEqual(gotKeys, tt.wantKeys) {
+				t.Errorf("CompareConfs() keys = %v, want %v", gotKeys, tt.wantKeys)
+			}
+		})
+	}
+}
+
+func TestCopySettings(t *testing.T) {
+	from := Conf{Energy: 200, Beam_size: 3, Plugins: map[string]bool{"A": false, "B": true}}
+	tests := []struct {
+		name        string
+		to          Conf
+		keys        []string
+		wantEnergy  int
+		wantBeam    int
+		wantPlugins map[string]bool
+	}{
+		{name: "single key", to: Conf{Energy: 300, Beam_size: 5}, keys: []string{"energy"}, wantEnergy: 200, wantBeam: 5},
+		{name: "plugin entry", to: Conf{Plugins: map[string]bool{"A": true}}, keys: []string{"plugins.B"}, wantPlugins: map[string]bool{"A": true, "B": true}},
+		{name: "removed plugin entry", to: Conf{Plugins: map[string]bool{"A": true, "C": true}}, keys: []string{"plugins.C"}, wantPlugins: map[string]bool{"A": true}},
+		{name: "whole map", to: Conf{Plugins: map[string]bool{"C": true}}, keys: []string{"plugins"}, wantPlugins: map[string]bool{"A": false, "B": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			to := tt.to
+			to.SettingsFilename = "profile.yaml"
+			if err := CopySettings(&from, &to, tt.keys); err != nil {
+				t.Fatalf("CopySettings() error = %v", err)
+			}
+			if to.Energy != tt.wantEnergy || to.Beam_size != tt.wantBeam || !reflect.DeepEqual(to.Plugins, tt.wantPlugins) {
+				t.Errorf("CopySettings() = energy %d, beam_size %d, plugins %v, want %d, %d, %v", to.Energy, to.Beam_size, to.Plugins, tt.wantEnergy, tt.wantBeam, tt.wantPlugins)
+			}
+			if to.SettingsFilename != "profile.yaml" {
+				t.Errorf("SettingsFilename = %q, want it unchanged", to.SettingsFilename)
+			}
+		})
+	}
+}
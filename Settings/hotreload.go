@@ -0,0 +1,273 @@
+package Settings
+
+import (
+	"bytes"
+	"github.com/fsnotify/fsnotify"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"whispering-tiger-ui/Utilities"
+)
+
+// RestartRequiredSettings are only read by the backend when it starts (they are selected in the profile window).
+// Changing them in the profile file while the backend runs needs a restart of the backend.
+var RestartRequiredSettings = []string{
+	"device_index",
+	"device_out_index",
+	"audio_api",
+	"audio_input_device",
+	"audio_output_device",
+	"ai_device",
+	"model",
+	"stt_type",
+	"whisper_precision",
+	"txt_translator",
+	"txt_translator_size",
+	"txt_translator_device",
+	"txt_translator_precision",
+	"tts_type",
+	"tts_ai_device",
+	"websocket_ip",
+	"websocket_port",
+	"run_backend",
+}
+
+// settings which are only used by the UI and are not sent to the backend
+var uiOnlySettings = []string{"transcript_postprocessing"}
+
+const profileReloadDelay = 500 * time.Millisecond
+
+// ProfileReload is the result of reloading a profile file that was changed outside the application.
+type ProfileReload struct {
+	File string
+	// Conf is the newly loaded profile
+	Conf *Conf
+	// Changed holds the changed settings which can be applied while the backend runs, with their new values
+	Changed map[string]interface{}
+	// UiChanged lists the changed settings which are only used by the UI
+	UiChanged []string
+	// RestartRequired lists the changed settings which need a restart of the backend
+	RestartRequired []string
+	// Err is set if the profile could not be loaded or is invalid. Nothing is applied in that case.
+	Err error
+}
+
+// HasChanges returns true if the reload found changed settings.
+func (r *ProfileReload) HasChanges() bool {
+	return len(r.Changed) > 0 || len(r.UiChanged) > 0 || len(r.RestartRequired) > 0
+}
+
+// ChangedKeys returns the sorted keys of the settings which can be applied.
+func (r *ProfileReload) ChangedKeys() []string {
+	var keys []string
+	for key := range r.Changed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ReloadProfile loads a profile file and compares it with the previously loaded state of the file.
+func ReloadProfile(previous *Conf, fileName string) *ProfileReload {
+	reload := &ProfileReload{File: fileName, Changed: map[string]interface{}{}}
+
+	var conf Conf
+	if err := conf.LoadYamlSettings(fileName); err != nil {
+		reload.Err = err
+		return reload
+	}
+	conf.SettingsFilename = previous.SettingsFilename
+	reload.Conf = &conf
+
+	diffs, err := CompareConfs(previous, &conf)
+	if err != nil {
+		reload.Err = err
+		return reload
+	}
+	var changedKeys []string
+	for _, diff := range diffs {
+		// entries of maps (like "plugins.MyPlugin") are sent as the complete map
+		key, _, _ := strings.Cut(diff.Key, ".")
+		if Utilities.Contains(changedKeys, key) {
+			continue
+		}
+		changedKeys = append(changedKeys, key)
+		switch {
+		case Utilities.Contains(RestartRequiredSettings, key):
+			reload.RestartRequired = append(reload.RestartRequired, key)
+		case Utilities.Contains(uiOnlySettings, key):
+			reload.UiChanged = append(reload.UiChanged, key)
+		default:
+			reload.Changed[key], _ = conf.GetOption(key)
+		}
+	}
+
+	if validationErrors := conf.ValidateFields(changedKeys...); len(validationErrors) > 0 {
+		reload.Err = validationErrors
+	}
+	return reload
+}
+
+// ownProfileWrites are the hashes of the profile files as they were last written by the application
+var ownProfileWrites = struct {
+	sync.Mutex
+	hashes map[string]string
+}{hashes: map[string]string{}}
+
+// writeProfileFile writes a profile file and remembers its content, so the profile watcher can tell the writes of the
+// application from changes made outside of it.
+func writeProfileFile(fileName string, data []byte, perm os.FileMode) error {
+	absoluteFile, _ := filepath.Abs(fileName)
+	hash, _ := Utilities.FileHash(bytes.NewReader(data))
+	ownProfileWrites.Lock()
+	ownProfileWrites.hashes[absoluteFile] = hash
+	ownProfileWrites.Unlock()
+	return os.WriteFile(fileName, data, perm)
+}
+
+// isOwnProfileWrite returns true if the file has the content the application last wrote into it.
+func isOwnProfileWrite(absoluteFile string) bool {
+	ownProfileWrites.Lock()
+	writtenHash, ok := ownProfileWrites.hashes[absoluteFile]
+	ownProfileWrites.Unlock()
+	if !ok {
+		return false
+	}
+	file, err := os.Open(absoluteFile)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	hash, err := Utilities.FileHash(file)
+	return err == nil && hash == writtenHash
+}
+
+// ProfileWatcher watches a profile file (and the profiles it extends) for changes.
+type ProfileWatcher struct {
+	watcher  *fsnotify.Watcher
+	fileName string
+	onChange func(ownWrite bool)
+
+	mutex        sync.Mutex
+	watchedFiles map[string]bool
+	changedFiles map[string]bool
+	timer        *time.Timer
+}
+
+// WatchProfile calls onChange after the profile file or one of the profiles it extends was changed.
+// Multiple changes in a short time (like editors saving with temporary files) are reported once.
+// ownWrite is true if all changed files were written by the application itself (like saving the settings), so the
+// changes are already applied.
+func WatchProfile(fileName string, onChange func(ownWrite bool)) (*ProfileWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	profileWatcher := &ProfileWatcher{
+		watcher:  watcher,
+		fileName: fileName,
+		onChange: onChange,
+	}
+	if err = profileWatcher.updateWatchedFiles(); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	go profileWatcher.run()
+	return profileWatcher, nil
+}
+
+// updateWatchedFiles watches the directories of all profile layers.
+// Directories are watched instead of files, since many editors replace the file when saving.
+func (w *ProfileWatcher) updateWatchedFiles() error {
+	files := []string{w.fileName}
+	if layers, err := ProfileLayers(w.fileName); err == nil {
+		files = nil
+		for _, layer := range layers {
+			files = append(files, layer.File)
+		}
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.watchedFiles = map[string]bool{}
+	watchedDirs := map[string]bool{}
+	for _, watchList := range w.watcher.WatchList() {
+		watchedDirs[watchList] = true
+	}
+	for _, file := range files {
+		absoluteFile, _ := filepath.Abs(file)
+		w.watchedFiles[absoluteFile] = true
+		dir := filepath.Dir(absoluteFile)
+		if !watchedDirs[dir] {
+			if err := w.watcher.Add(dir); err != nil {
+				return err
+			}
+			watchedDirs[dir] = true
+		}
+	}
+	return nil
+}
+
+func (w *ProfileWatcher) run() {
+	defer Utilities.PanicLogger()
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) {
+				continue
+			}
+			absoluteFile, _ := filepath.Abs(event.Name)
+			w.mutex.Lock()
+			if w.watchedFiles[absoluteFile] {
+				if w.changedFiles == nil {
+					w.changedFiles = map[string]bool{}
+				}
+				w.changedFiles[absoluteFile] = true
+				if w.timer != nil {
+					w.timer.Stop()
+				}
+				w.timer = time.AfterFunc(profileReloadDelay, w.changed)
+			}
+			w.mutex.Unlock()
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("profile watcher error: %v", err)
+		}
+	}
+}
+
+func (w *ProfileWatcher) changed() {
+	defer Utilities.PanicLogger()
+	w.mutex.Lock()
+	changedFiles := w.changedFiles
+	w.changedFiles = nil
+	w.mutex.Unlock()
+	ownWrite := true
+	for changedFile := range changedFiles {
+		ownWrite = ownWrite && isOwnProfileWrite(changedFile)
+	}
+
+	// the profile might extend another profile now
+	if err := w.updateWatchedFiles(); err != nil {
+		log.Printf("profile watcher error: %v", err)
+	}
+	w.onChange(ownWrite)
+}
+
+func (w *ProfileWatcher) Close() error {
+	w.mutex.Lock()
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.mutex.Unlock()
+	return w.watcher.Close()
+}
@@ -0,0 +1,391 @@
+package Settings
+
+import (
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"whispering-tiger-ui/Secrets"
+	"whispering-tiger-ui/Utilities"
+)
+
+// Profiles can extend other profiles with `extends: base.yaml`.
+// Settings are resolved by layering base -> parent -> profile, so a profile only needs to contain the values it overrides.
+
+const maxProfileInheritanceDepth = 10
+
+// keys which are never inherited
+var notInheritedKeys = []string{"extends", "schema_version"}
+
+type ProfileLayer struct {
+	File   string
+	Raw    []byte
+	Values map[string]interface{}
+}
+
+func resolveExtendsPath(fileName string, extends string) string {
+	if filepath.IsAbs(extends) {
+		return extends
+	}
+	return filepath.Join(filepath.Dir(fileName), extends)
+}
+
+// ProfileLayers returns the layers of a profile, starting with the base profile and ending with the profile itself.
+func ProfileLayers(fileName string) ([]ProfileLayer, error) {
+	var layers []ProfileLayer
+	visited := map[string]bool{}
+	currentFile := fileName
+	for currentFile != "" {
+		absolutePath, _ := filepath.Abs(currentFile)
+		if visited[absolutePath] {
+			return nil, &LoadError{File: fileName, Err: fmt.Errorf("profile inheritance loop at %s", filepath.Base(currentFile))}
+		}
+		visited[absolutePath] = true
+		if len(layers) >= maxProfileInheritanceDepth {
+			return nil, &LoadError{File: fileName, Err: fmt.Errorf("profile inheritance is deeper than %d levels", maxProfileInheritanceDepth)}
+		}
+
+		yamlFile, err := os.ReadFile(currentFile)
+		if err != nil {
+			return nil, &LoadError{File: currentFile, Err: err}
+		}
+		values := map[string]interface{}{}
+		if err = yaml.Unmarshal(yamlFile, &values); err != nil {
+			return nil, &LoadError{File: currentFile, Err: err}
+		}
+		// prepend, so the base profile is the first layer
+		layers = append([]ProfileLayer{{File: currentFile, Raw: yamlFile, Values: values}}, layers...)
+
+		extends, _ := values["extends"].(string)
+		if extends == "" {
+			break
+		}
+		currentFile = resolveExtendsPath(currentFile, extends)
+	}
+	return layers, nil
+}
+
+// inheritedValues merges the raw values of all parent profiles of a profile that extends the given file.
+func inheritedValues(fileName string, extends string) (map[string]interface{}, error) {
+	inherited := map[string]interface{}{}
+	if extends == "" {
+		return inherited, nil
+	}
+	layers, err := ProfileLayers(resolveExtendsPath(fileName, extends))
+	if err != nil {
+		return nil, err
+	}
+	for _, layer := range layers {
+		for key, value := range layer.Values {
+			if !Utilities.Contains(notInheritedKeys, key) {
+				inherited[key] = value
+			}
+		}
+	}
+	return inherited, nil
+}
+
+// marshalOverrides returns the yaml of the Conf with only the values that differ from the inherited ones.
+func (c *Conf) marshalOverrides(fileName string) ([]byte, error) {
+	inherited, err := inheritedValues(fileName, c.Extends)
+	if err != nil {
+		return nil, err
+	}
+	fullYaml, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	if err = yaml.Unmarshal(fullYaml, &values); err != nil {
+		return nil, err
+	}
+	for key, value := range values {
+		inheritedValue, ok := inherited[key]
+		// compare the printed values, since yaml might read the same number as int or float
+		if ok && fmt.Sprint(inheritedValue) == fmt.Sprint(value) {
+			delete(values, key)
+		}
+	}
+	return yaml.Marshal(values)
+}
+
+//goland:noinspection GoSnakeCaseUsage
+type SettingInheritance struct {
+	Key   string
+	Value interface{}
+	// Source is the file the effective value is read from
+	Source         string
+	Inherited      bool
+	Overridden     bool
+	InheritedValue interface{}
+}
+
+// ProfileInheritance returns for every setting of a profile where its value comes from.
+func ProfileInheritance(fileName string) ([]SettingInheritance, error) {
+	layers, err := ProfileLayers(fileName)
+	if err != nil {
+		return nil, err
+	}
+	profileLayer := layers[len(layers)-1]
+	parentLayers := layers[:len(layers)-1]
+
+	var keys []string
+	seenKeys := map[string]bool{}
+	for _, layer := range layers {
+		for key := range layer.Values {
+			if !seenKeys[key] && !Utilities.Contains(notInheritedKeys, key) {
+				seenKeys[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	var settings []SettingInheritance
+	for _, key := range keys {
+		setting := SettingInheritance{Key: key}
+		for _, layer := range parentLayers {
+			if value, ok := layer.Values[key]; ok {
+				setting.InheritedValue = value
+				setting.Value = value
+				setting.Source = layer.File
+				setting.Inherited = true
+			}
+		}
+		if value, ok := profileLayer.Values[key]; ok {
+			setting.Overridden = setting.Inherited
+			setting.Inherited = false
+			setting.Value = value
+			setting.Source = profileLayer.File
+		}
+		settings = append(settings, setting)
+	}
+	return settings, nil
+}
+
+func updateProfileValues(fileName string, update func(values map[string]interface{})) error {
+	yamlFile, err := os.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	values := map[string]interface{}{}
+	if err = yaml.Unmarshal(yamlFile, &values); err != nil {
+		return &LoadError{File: fileName, Err: err}
+	}
+	update(values)
+	yamlFile, err = yaml.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return writeProfileFile(fileName, yamlFile, 0644)
+}
+
+// ResetToInherited removes the given settings from the profile, so the values of the parent profiles are used again.
+func ResetToInherited(fileName string, keys ...string) error {
+	return updateProfileValues(fileName, func(values map[string]interface{}) {
+		for _, key := range keys {
+			delete(values, key)
+		}
+	})
+}
+
+// SetProfileExtends sets the profile a profile extends (or removes it if extends is empty).
+// When the parent is removed, the inherited values are copied into the profile, so the effective settings do not change.
+func SetProfileExtends(fileName string, extends string) error {
+	if extends != "" {
+		parentFile := resolveExtendsPath(fileName, extends)
+		absoluteParent, _ := filepath.Abs(parentFile)
+		absoluteFile, _ := filepath.Abs(fileName)
+		if absoluteParent == absoluteFile {
+			return fmt.Errorf("a profile can not extend itself")
+		}
+		// check for loops
+		layers, err := ProfileLayers(parentFile)
+		if err != nil {
+			return err
+		}
+		for _, layer := range layers {
+			absoluteLayer, _ := filepath.Abs(layer.File)
+			if absoluteLayer == absoluteFile {
+				return fmt.Errorf("profile inheritance loop at %s", filepath.Base(layer.File))
+			}
+		}
+	}
+
+	var resolvedConf Conf
+	if err := resolvedConf.LoadYamlSettings(fileName); err != nil {
+		return err
+	}
+	resolvedConf.Extends = extends
+	resolvedConf.WriteYamlSettings(fileName)
+	return nil
+}
+
+// backendSettingsFile is the last settings file written by PrepareBackendSettingsFile
+var backendSettingsFile struct {
+	sync.Mutex
+	profile  string
+	resolved string
+}
+
+// PrepareBackendSettingsFile returns the settings file to pass to the backend.
+// The backend does not know about profile inheritance and the secrets store, so the profile is always written fully
+// resolved into a temporary file, which is only readable by the user. It should be removed with
+// RemoveBackendSettingsFile after the backend started. Changes the backend saved into an earlier resolved file are
+// copied back into the profile first.
+func PrepareBackendSettingsFile(profileFile string) string {
+	resolvedDir := filepath.Join(filepath.Dir(profileFile), ".resolved")
+	resolvedFile := filepath.Join(resolvedDir, filepath.Base(profileFile))
+	copyBackendChanges(profileFile, resolvedFile)
+
+	var resolvedConf Conf
+	if err := resolvedConf.LoadYamlSettings(profileFile); err != nil {
+		return profileFile
+	}
+	resolvedConf.Extends = ""
+	resolvedConf.Initial_prompt = resolvedConf.BackendInitialPrompt()
+	// references which can not be resolved while the secrets are locked are not sent to the backend
+	resolvedConf.removeSecretReferences()
+	yamlFile, err := yaml.Marshal(resolvedConf)
+	if err != nil {
+		return profileFile
+	}
+	if err = os.MkdirAll(resolvedDir, 0700); err != nil {
+		return profileFile
+	}
+	if err = os.WriteFile(resolvedFile, yamlFile, 0600); err != nil {
+		log.Printf("error: %v", err)
+		return profileFile
+	}
+	// the hash shows if the backend saved changes into the file
+	hash, _ := Utilities.FileHash(bytes.NewReader(yamlFile))
+	if err = os.WriteFile(resolvedFile+".sha256", []byte(hash), 0600); err != nil {
+		log.Printf("error: %v", err)
+	}
+
+	backendSettingsFile.Lock()
+	backendSettingsFile.profile, backendSettingsFile.resolved = profileFile, resolvedFile
+	backendSettingsFile.Unlock()
+	return resolvedFile
+}
+
+// RemoveBackendSettingsFile copies the changes the backend saved into the last prepared settings file back into the
+// profile and removes the file, so the secrets it contains are not kept on the disk. It is called after the backend
+// loaded its settings (when it accepts connections) and when it stopped.
+func RemoveBackendSettingsFile() {
+	backendSettingsFile.Lock()
+	defer backendSettingsFile.Unlock()
+	if backendSettingsFile.resolved == "" {
+		return
+	}
+	copyBackendChanges(backendSettingsFile.profile, backendSettingsFile.resolved)
+	if err := os.Remove(backendSettingsFile.resolved); err != nil && !os.IsNotExist(err) {
+		log.Printf("failed to remove the backend settings file: %v", err)
+	}
+}
+
+// copyBackendChanges copies the settings the backend saved into the resolved file back into the profile.
+// Only the keys which differ from the resolved profile layers are copied, so the profile does not get the values
+// of its parents and a resolved file the backend did not change is ignored. Changed secrets are saved to the secrets
+// store, the profile only gets the references.
+func copyBackendChanges(profileFile, resolvedFile string) {
+	yamlFile, err := os.ReadFile(resolvedFile)
+	if err != nil {
+		return
+	}
+	writtenHash, _ := os.ReadFile(resolvedFile + ".sha256")
+	hash, _ := Utilities.FileHash(bytes.NewReader(yamlFile))
+	if strings.EqualFold(hash, strings.TrimSpace(string(writtenHash))) {
+		return
+	}
+	var backendConf Conf
+	if err = yaml.Unmarshal(yamlFile, &backendConf); err != nil {
+		log.Printf("backend settings file %s not read: %v", resolvedFile, err)
+		return
+	}
+
+	// the profile values with the references to the secrets
+	var profileConf Conf
+	if err = profileConf.loadProfileLayers(profileFile); err != nil {
+		return
+	}
+	_ = backendConf.forEachSecret(func(path []string, value string) (string, error) {
+		profileValue := profileConf.secretAt(path)
+		if value == profileValue {
+			return value, nil
+		}
+		if Secrets.IsReference(profileValue) {
+			if secret, err := Secrets.Resolve(profileValue); err == nil && secret == value {
+				return profileValue, nil
+			}
+		}
+		reference, err := Secrets.Save(secretID(profileFile, path...), value)
+		if err != nil {
+			// never write the secret into the profile
+			log.Printf("secret %s changed by the backend not saved: %v", strings.Join(path, "."), err)
+			return profileValue, nil
+		}
+		return reference, nil
+	})
+	// the backend got empty values for the secrets which could not be resolved
+	_ = profileConf.forEachSecret(func(path []string, value string) (string, error) {
+		if backendConf.secretAt(path) == "" {
+			backendConf.setSecretAt(path, value)
+		}
+		return value, nil
+	})
+
+	// the backend got the initial prompt with the dictionary terms
+	profileConf.Initial_prompt = profileConf.BackendInitialPrompt()
+
+	backendValues, err := confValues(&backendConf)
+	if err != nil {
+		return
+	}
+	profileValues, err := confValues(&profileConf)
+	if err != nil {
+		return
+	}
+	changedValues := map[string]interface{}{}
+	var changedKeys []string
+	for key, value := range backendValues {
+		if Utilities.Contains(notInheritedKeys, key) {
+			continue
+		}
+		// compare the printed values, since yaml might read the same number as int or float
+		if profileValue, ok := profileValues[key]; ok && fmt.Sprint(profileValue) == fmt.Sprint(value) {
+			continue
+		}
+		changedValues[key] = value
+		changedKeys = append(changedKeys, key)
+	}
+	if len(changedKeys) == 0 {
+		return
+	}
+	sort.Strings(changedKeys)
+	log.Printf("copying the settings changed by the backend into %s: %s", profileFile, strings.Join(changedKeys, ", "))
+	err = updateProfileValues(profileFile, func(values map[string]interface{}) {
+		for key, value := range changedValues {
+			values[key] = value
+		}
+	})
+	if err != nil {
+		log.Printf("backend settings not copied into %s: %v", profileFile, err)
+	}
+}
+
+// confValues returns the settings as yaml values.
+func confValues(c *Conf) (map[string]interface{}, error) {
+	yamlFile, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	err = yaml.Unmarshal(yamlFile, &values)
+	return values, err
+}
@@ -0,0 +1,143 @@
+package Settings
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// writeTestProfiles writes the profile files by their name into a temporary directory.
+func writeTestProfiles(t *testing.T, profiles map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range profiles {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestProfileLayers(t *testing.T) {
+	deepProfiles := map[string]string{}
+	for i := 0; i <= maxProfileInheritanceDepth; i++ {
+		deepProfiles[strconv.Itoa(i)+".yaml"] = "extends: " + strconv.Itoa(i+1) + ".yaml\n"
+	}
+	deepProfiles[strconv.Itoa(maxProfileInheritanceDepth+1)+".yaml"] = "energy: 1\n"
+
+	tests := []struct {
+		name       string
+		profiles   map[string]string
+		wantLayers []string
+		wantErr    bool
+	}{
+		{name: "single", profiles: map[string]string{"0.yaml": "energy: 1\n"}, wantLayers: []string{"0.yaml"}},
+		{name: "chain", profiles: map[string]string{"0.yaml": "extends: 1.yaml\n", "1.yaml": "extends: 2.yaml\n", "2.yaml": "energy: 1\n"}, wantLayers: []string{"2.yaml", "1.yaml", "0.yaml"}},
+		{name: "loop", profiles: map[string]string{"0.yaml": "extends: 1.yaml\n", "1.yaml": "extends: 0.yaml\n"}, wantErr: true},
+		{name: "self", profiles: map[string]string{"0.yaml": "extends: 0.yaml\n"}, wantErr: true},
+		{name: "missing parent", profiles: map[string]string{"0.yaml": "extends: missing.yaml\n"}, wantErr: true},
+		{name: "invalid yaml", profiles: map[string]string{"0.yaml": "energy: [\n"}, wantErr: true},
+		{name: "too deep", profiles: deepProfiles, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeTestProfiles(t, tt.profiles)
+			layers, err := ProfileLayers(filepath.Join(dir, "0.yaml"))
+			if tt.wantErr {
+				if _, ok := err.(*LoadError); !ok {
+					t.Fatalf("ProfileLayers() error = %v, want a LoadError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ProfileLayers() error = %v", err)
+			}
+			var gotLayers []string
+			for _, layer := range layers {
+				gotLayers = append(gotLayers, filepath.Base(layer.File))
+			}
+			if !reflect.DeepEqual(gotLayers, tt.wantLayers) {
+				t.Errorf("ProfileLayers() = %v, want %v", gotLayers, tt.wantLayers)
+			}
+		})
+	}
+}
+
+func TestProfileInheritance(t *testing.T) {
+	dir := writeTestProfiles(t, map[string]string{
+		"base.yaml":    "energy: 300\npause: 1.0\nbeam_size: 5\n",
+		"parent.yaml":  "extends: base.yaml\npause: 2.0\n",
+		"profile.yaml": "extends: parent.yaml\nbeam_size: 3\nosc_ip: 127.0.0.1\n",
+	})
+	settings, err := ProfileInheritance(filepath.Join(dir, "profile.yaml"))
+	if err != nil {
+		t.Fatalf("ProfileInheritance() error = %v", err)
+	}
+
+	tests := []struct {
+		key            string
+		wantSource     string
+		wantInherited  bool
+		wantOverridden bool
+	}{
+		{key: "beam_size", wantSource: "profile.yaml", wantOverridden: true},
+		{key: "energy", wantSource: "base.yaml", wantInherited: true},
+		{key: "osc_ip", wantSource: "profile.yaml"},
+		{key: "pause", wantSource: "parent.yaml", wantInherited: true},
+	}
+	if len(settings) != len(tests) {
+		t.Fatalf("ProfileInheritance() returned %d settings, want %d", len(settings), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			setting := settings[i]
+			if setting.Key != tt.key || filepath.Base(setting.Source) != tt.wantSource || setting.Inherited != tt.wantInherited || setting.Overridden != tt.wantOverridden {
+				t.Errorf("setting = %s from %s, inherited %v, overridden %v, want %s from %s, %v, %v", setting.Key, filepath.Base(setting.Source), setting.Inherited, setting.Overridden, tt.key, tt.wantSource, tt.wantInherited, tt.wantOverridden)
+			}
+		})
+	}
+}
+
+func TestMarshalOverrides(t *testing.T) {
+	dir := writeTestProfiles(t, map[string]string{"base.yaml": "energy: 300\npause: 1.0\n"})
+	profileFile := filepath.Join(dir, "profile.yaml")
+	conf := Conf{Extends: "base.yaml", Energy: 300, Pause: 2}
+
+	data, err := conf.marshalOverrides(profileFile)
+	if err != nil {
+		t.Fatalf("marshalOverrides() error = %v", err)
+	}
+	values := map[string]interface{}{}
+	if err = yaml.Unmarshal(data, &values); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := values["energy"]; ok {
+		t.Error("marshalOverrides() kept the inherited energy")
+	}
+	// compare the printed values, since yaml reads the float 2 as int
+	if fmt.Sprint(values["pause"]) != "2" || values["extends"] != "base.yaml" {
+		t.Errorf("marshalOverrides() = pause %v, extends %v, want 2, base.yaml", values["pause"], values["extends"])
+	}
+}
+
+func TestResetToInherited(t *testing.T) {
+	dir := writeTestProfiles(t, map[string]string{
+		"base.yaml":    "energy: 300\n",
+		"profile.yaml": "extends: base.yaml\nenergy: 200\nbeam_size: 3\n",
+	})
+	profileFile := filepath.Join(dir, "profile.yaml")
+	if err := ResetToInherited(profileFile, "energy"); err != nil {
+		t.Fatalf("ResetToInherited() error = %v", err)
+	}
+	var conf Conf
+	if err := conf.loadProfileLayers(profileFile); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Energy != 300 || conf.Beam_size != 3 {
+		t.Errorf("after ResetToInherited() energy = %d, beam_size = %d, want 300, 3", conf.Energy, conf.Beam_size)
+	}
+}
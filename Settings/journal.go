@@ -0,0 +1,209 @@
+package Settings
+
+import (
+	"gopkg.in/yaml.v3"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+	"whispering-tiger-ui/Fields"
+)
+
+// The settings journal records every setting change of the current session, so changes can be undone and redone.
+
+// changes of the same setting within this time are merged into one journal entry (e.g. while typing into an entry)
+const journalMergeTime = 2 * time.Second
+
+type JournalEntry struct {
+	Time     time.Time
+	Name     string
+	OldValue interface{}
+	NewValue interface{}
+	// Source describes where the change was made (like "settings form" or "plugin")
+	Source string
+}
+
+type SettingsJournal struct {
+	mutex   sync.Mutex
+	entries []JournalEntry
+	// position is the number of applied entries. Entries after it can be redone.
+	position       int
+	listeners      map[string]func(applied bool)
+	applyListeners map[string]func(name string, value interface{})
+	// SendValue sends an undone or redone value to the backend. Values are sent as setting_change message if not set.
+	SendValue func(name string, value interface{})
+}
+
+var Journal = &SettingsJournal{}
+
+// CopySettingValue returns a deep copy of a setting value, so later changes of maps (like plugin_settings) do not change the copy.
+func CopySettingValue(value interface{}) interface{} {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		valueYaml, err := yaml.Marshal(value)
+		if err != nil {
+			return value
+		}
+		var copied interface{}
+		if err = yaml.Unmarshal(valueYaml, &copied); err != nil {
+			return value
+		}
+		return copied
+	}
+	return value
+}
+
+// Record adds a setting change to the journal. Changes which can be redone are discarded.
+func (j *SettingsJournal) Record(name string, oldValue interface{}, newValue interface{}, source string) {
+	oldValue = CopySettingValue(oldValue)
+	newValue = CopySettingValue(newValue)
+	if reflect.DeepEqual(oldValue, newValue) {
+		return
+	}
+
+	j.mutex.Lock()
+	j.entries = j.entries[:j.position]
+	if j.position > 0 {
+		lastEntry := &j.entries[j.position-1]
+		if lastEntry.Name == name && lastEntry.Source == source && time.Since(lastEntry.Time) < journalMergeTime {
+			lastEntry.NewValue = newValue
+			lastEntry.Time = time.Now()
+			if reflect.DeepEqual(lastEntry.OldValue, lastEntry.NewValue) {
+				j.entries = j.entries[:j.position-1]
+				j.position--
+			}
+			j.mutex.Unlock()
+			j.notify(false)
+			return
+		}
+	}
+	j.entries = append(j.entries, JournalEntry{
+		Time:     time.Now(),
+		Name:     name,
+		OldValue: oldValue,
+		NewValue: newValue,
+		Source:   source,
+	})
+	j.position = len(j.entries)
+	j.mutex.Unlock()
+	j.notify(false)
+}
+
+// Entries returns a copy of all journal entries and the number of applied entries.
+func (j *SettingsJournal) Entries() ([]JournalEntry, int) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	entries := make([]JournalEntry, len(j.entries))
+	copy(entries, j.entries)
+	return entries, j.position
+}
+
+func (j *SettingsJournal) CanUndo() bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.position > 0
+}
+
+func (j *SettingsJournal) CanRedo() bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.position < len(j.entries)
+}
+
+// Undo reverts the last applied change.
+func (j *SettingsJournal) Undo() bool {
+	j.mutex.Lock()
+	if j.position == 0 {
+		j.mutex.Unlock()
+		return false
+	}
+	j.position--
+	entry := j.entries[j.position]
+	j.mutex.Unlock()
+
+	j.apply(entry.Name, entry.OldValue)
+	j.notify(true)
+	return true
+}
+
+// Redo applies the last undone change again.
+func (j *SettingsJournal) Redo() bool {
+	j.mutex.Lock()
+	if j.position >= len(j.entries) {
+		j.mutex.Unlock()
+		return false
+	}
+	entry := j.entries[j.position]
+	j.position++
+	j.mutex.Unlock()
+
+	j.apply(entry.Name, entry.NewValue)
+	j.notify(true)
+	return true
+}
+
+// RevertAll undoes all changes of the current session.
+func (j *SettingsJournal) RevertAll() {
+	for j.Undo() {
+	}
+}
+
+// OnChanged sets a function which is called after the journal changed. applied is true if a change was undone or redone.
+// Setting a listener with the same key again replaces it (e.g. when a page is recreated).
+func (j *SettingsJournal) OnChanged(key string, listener func(applied bool)) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if j.listeners == nil {
+		j.listeners = map[string]func(applied bool){}
+	}
+	j.listeners[key] = listener
+}
+
+// OnApply sets a function which is called when a value is applied by an undo or redo, before the OnChanged listeners
+// are called. Setting a listener with the same key again replaces it.
+func (j *SettingsJournal) OnApply(key string, listener func(name string, value interface{})) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if j.applyListeners == nil {
+		j.applyListeners = map[string]func(name string, value interface{}){}
+	}
+	j.applyListeners[key] = listener
+}
+
+func (j *SettingsJournal) notify(applied bool) {
+	j.mutex.Lock()
+	var listeners []func(applied bool)
+	for _, listener := range j.listeners {
+		listeners = append(listeners, listener)
+	}
+	j.mutex.Unlock()
+	for _, listener := range listeners {
+		listener(applied)
+	}
+}
+
+func (j *SettingsJournal) apply(name string, value interface{}) {
+	if j.SendValue != nil {
+		j.SendValue(name, value)
+	} else {
+		sendMessage := Fields.SendMessageStruct{
+			Type:  "setting_change",
+			Name:  name,
+			Value: value,
+		}
+		sendMessage.SendMessage()
+	}
+	if err := Config.SetOption(name, value); err != nil {
+		log.Printf("failed to set option: %v", err)
+	}
+
+	j.mutex.Lock()
+	var listeners []func(name string, value interface{})
+	for _, listener := range j.applyListeners {
+		listeners = append(listeners, listener)
+	}
+	j.mutex.Unlock()
+	for _, listener := range listeners {
+		listener(name, CopySettingValue(value))
+	}
+}
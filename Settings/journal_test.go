@@ -0,0 +1,111 @@
+package Settings
+
+import (
+	"reflect"
+	"testing"
+)
+
+// newTestJournal returns an empty journal which applies the values to a test Config instead of sending them.
+func newTestJournal(t *testing.T) (*SettingsJournal, *[]interface{}) {
+	t.Helper()
+	previous := Config
+	Config = Conf{}
+	t.Cleanup(func() { Config = previous })
+	var sent []interface{}
+	journal := &SettingsJournal{SendValue: func(name string, value interface{}) {
+		sent = append(sent, value)
+	}}
+	return journal, &sent
+}
+
+func TestSettingsJournalRecord(t *testing.T) {
+	type change struct {
+		name     string
+		old, new interface{}
+		source   string
+	}
+	tests := []struct {
+		name       string
+		changes    []change
+		wantValues []interface{}
+	}{
+		{name: "unchanged value", changes: []change{{"energy", 300, 300, "form"}}},
+		{name: "different settings", changes: []change{{"energy", 300, 200, "form"}, {"pause", 1.0, 2.0, "form"}}, wantValues: []interface{}{200, 2.0}},
+		{name: "merged changes", changes: []change{{"energy", 300, 250, "form"}, {"energy", 250, 200, "form"}}, wantValues: []interface{}{200}},
+		{name: "different sources", changes: []change{{"energy", 300, 250, "form"}, {"energy", 250, 200, "plugin"}}, wantValues: []interface{}{250, 200}},
+		{name: "merged back to old value", changes: []change{{"energy", 300, 250, "form"}, {"energy", 250, 300, "form"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			journal, _ := newTestJournal(t)
+			for _, c := range tt.changes {
+				journal.Record(c.name, c.old, c.new, c.source)
+			}
+			entries, position := journal.Entries()
+			var gotValues []interface{}
+			for _, entry := range entries {
+				gotValues = append(gotValues, entry.NewValue)
+			}
+			if !reflect.DeepEqual(gotValues, tt.wantValues) || position != len(tt.wantValues) {
+				t.Errorf("Entries() = %v, %d, want %v, %d", gotValues, position, tt.wantValues, len(tt.wantValues))
+			}
+		})
+	}
+}
+
+func TestSettingsJournalUndoRedo(t *testing.T) {
+	journal, sent := newTestJournal(t)
+	var applied []string
+	journal.OnApply("test", func(name string, value interface{}) {
+		applied = append(applied, name)
+	})
+	journal.Record("energy", 300, 200, "form")
+	journal.Record("beam_size", 5, 3, "form")
+
+	if journal.Redo() {
+		t.Error("Redo() without undone changes succeeded")
+	}
+	if !journal.Undo() || !journal.Undo() {
+		t.Fatal("Undo() failed")
+	}
+	if journal.Undo() {
+		t.Error("Undo() without changes succeeded")
+	}
+	if Config.Energy != 300 || Config.Beam_size != 5 {
+		t.Errorf("after Undo() energy = %d, beam_size = %d, want 300, 5", Config.Energy, Config.Beam_size)
+	}
+	if !journal.Redo() {
+		t.Fatal("Redo() failed")
+	}
+	if Config.Energy != 200 || !journal.CanRedo() {
+		t.Errorf("after Redo() energy = %d, CanRedo() = %v, want 200, true", Config.Energy, journal.CanRedo())
+	}
+
+	// a new change discards the changes which can be redone
+	journal.Record("pause", 1.0, 2.0, "form")
+	if entries, _ := journal.Entries(); len(entries) != 2 || journal.CanRedo() {
+		t.Errorf("Record() kept %d entries, CanRedo() = %v, want 2, false", len(entries), journal.CanRedo())
+	}
+
+	wantSent := []interface{}{5, 300, 200}
+	if !reflect.DeepEqual(*sent, wantSent) {
+		t.Errorf("sent values = %v, want %v", *sent, wantSent)
+	}
+	wantApplied := []string{"beam_size", "energy", "energy"}
+	if !reflect.DeepEqual(applied, wantApplied) {
+		t.Errorf("applied settings = %v, want %v", applied, wantApplied)
+	}
+}
+
+func TestSettingsJournalCopiesValues(t *testing.T) {
+	journal, _ := newTestJournal(t)
+	pluginSettings := map[string]interface{}{"Plugin": map[string]interface{}{"volume": 1}}
+	journal.Record("plugin_settings", map[string]interface{}{}, pluginSettings, "plugin")
+	pluginSettings["Plugin"].(map[string]interface{})["volume"] = 2
+
+	entries, _ := journal.Entries()
+	want := map[string]interface{}{"Plugin": map[string]interface{}{"volume": 1}}
+	if !reflect.DeepEqual(entries[0].NewValue, want) {
+		t.Errorf("recorded value = %v, want %v", entries[0].NewValue, want)
+	}
+}
@@ -0,0 +1,297 @@
+package Settings
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Transcript post-processing which is applied on the UI side to every received transcription,
+// before it is added to the result list.
+
+const (
+	CaseRuleNone     = ""
+	CaseRuleLower    = "lower"
+	CaseRuleUpper    = "upper"
+	CaseRuleSentence = "sentence"
+)
+
+//goland:noinspection GoSnakeCaseUsage
+type PostProcessingRule struct {
+	Find        string `yaml:"find" json:"find"`
+	Replace     string `yaml:"replace" json:"replace"`
+	Regex       bool   `yaml:"regex,omitempty" json:"regex,omitempty"`
+	Ignore_case bool   `yaml:"ignore_case,omitempty" json:"ignore_case,omitempty"`
+	Whole_word  bool   `yaml:"whole_word,omitempty" json:"whole_word,omitempty"`
+	Language    string `yaml:"language,omitempty" json:"language,omitempty"` // only applied if the language matches (empty for all languages)
+}
+
+//goland:noinspection GoSnakeCaseUsage
+type PostProcessingConf struct {
+	Enabled bool                 `yaml:"enabled" json:"enabled"`
+	Rules   []PostProcessingRule `yaml:"rules,omitempty" json:"rules,omitempty"`
+
+	// Dictionaries contains terms per language code (use "" for all languages).
+	// Terms are matched case-insensitive as whole words and replaced with the spelling from the dictionary.
+	Dictionaries map[string][]string `yaml:"dictionaries,omitempty" json:"dictionaries,omitempty"`
+
+	Case_rule string `yaml:"case_rule,omitempty" json:"case_rule,omitempty"`
+
+	Profanity_masking   bool     `yaml:"profanity_masking,omitempty" json:"profanity_masking,omitempty"`
+	Profanity_words     []string `yaml:"profanity_words,omitempty" json:"profanity_words,omitempty"`
+	Profanity_mask_char string   `yaml:"profanity_mask_char,omitempty" json:"profanity_mask_char,omitempty"`
+
+	Terms_to_initial_prompt bool `yaml:"terms_to_initial_prompt,omitempty" json:"terms_to_initial_prompt,omitempty"`
+}
+
+func languageMatches(ruleLanguage string, language string) bool {
+	return ruleLanguage == "" || strings.EqualFold(ruleLanguage, language)
+}
+
+// Pattern returns the compiled regular expression of a rule.
+func (r PostProcessingRule) Pattern() (*regexp.Regexp, error) {
+	pattern := r.Find
+	if !r.Regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if r.Whole_word {
+		pattern = `\b` + pattern + `\b`
+	}
+	if r.Ignore_case {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+func (r PostProcessingRule) Apply(text string) (string, error) {
+	if r.Find == "" {
+		return text, nil
+	}
+	// fast path for simple literal replacements
+	if !r.Regex && !r.Ignore_case && !r.Whole_word {
+		return strings.ReplaceAll(text, r.Find, r.Replace), nil
+	}
+	pattern, err := r.Pattern()
+	if err != nil {
+		return text, err
+	}
+	if r.Regex {
+		return pattern.ReplaceAllString(text, r.Replace), nil
+	}
+	return pattern.ReplaceAllLiteralString(text, r.Replace), nil
+}
+
+// Validate checks all regex rules for errors.
+func (c PostProcessingConf) Validate() error {
+	for i, rule := range c.Rules {
+		if _, err := rule.Pattern(); err != nil {
+			return fmt.Errorf("rule %d (%s): %w", i+1, rule.Find, err)
+		}
+	}
+	return nil
+}
+
+// DictionaryTerms returns all dictionary terms that apply to the language.
+func (c PostProcessingConf) DictionaryTerms(language string) []string {
+	return c.dictionaryTerms(func(dictionaryLanguage string) bool {
+		return languageMatches(dictionaryLanguage, language)
+	})
+}
+
+func (c PostProcessingConf) dictionaryTerms(includeDictionary func(dictionaryLanguage string) bool) []string {
+	// sort languages so the order of terms is stable
+	var dictionaryLanguages []string
+	for dictionaryLanguage := range c.Dictionaries {
+		dictionaryLanguages = append(dictionaryLanguages, dictionaryLanguage)
+	}
+	sort.Strings(dictionaryLanguages)
+
+	var terms []string
+	for _, dictionaryLanguage := range dictionaryLanguages {
+		if includeDictionary(dictionaryLanguage) {
+			for _, term := range c.Dictionaries[dictionaryLanguage] {
+				term = strings.TrimSpace(term)
+				if term != "" {
+					terms = append(terms, term)
+				}
+			}
+		}
+	}
+	return terms
+}
+
+func maskWord(word string, maskChar string) string {
+	if maskChar == "" {
+		maskChar = "*"
+	}
+	runeCount := utf8.RuneCountInString(word)
+	if runeCount <= 1 {
+		return strings.Repeat(maskChar, runeCount)
+	}
+	firstRune, _ := utf8.DecodeRuneInString(word)
+	return string(firstRune) + strings.Repeat(maskChar, runeCount-1)
+}
+
+func applyCaseRule(text string, caseRule string) string {
+	switch caseRule {
+	case CaseRuleLower:
+		return strings.ToLower(text)
+	case CaseRuleUpper:
+		return strings.ToUpper(text)
+	case CaseRuleSentence:
+		// uppercase the first letter of the text and of every sentence
+		var builder strings.Builder
+		capitalizeNext := true
+		for _, r := range text {
+			if capitalizeNext && unicode.IsLetter(r) {
+				r = unicode.ToUpper(r)
+				capitalizeNext = false
+			}
+			if r == '.' || r == '!' || r == '?' {
+				capitalizeNext = true
+			}
+			builder.WriteRune(r)
+		}
+		return builder.String()
+	}
+	return text
+}
+
+// Apply runs all post-processing steps on a text of the given language.
+// Rules that fail to compile are skipped.
+func (c PostProcessingConf) Apply(text string, language string) string {
+	if !c.Enabled || text == "" {
+		return text
+	}
+	text = c.applyReplacements(text, language)
+	text = applyCaseRule(text, c.Case_rule)
+	return c.maskProfanity(text)
+}
+
+// ApplyWords runs the post-processing on the words of a transcription (with their surrounding whitespace), so they
+// still match the processed text. Dictionaries, rules and profanity masking are applied to every single word, the case
+// rule to the whole text. Rules which match across words can not be applied to the words.
+func (c PostProcessingConf) ApplyWords(words []string, language string) []string {
+	processedWords := make([]string, len(words))
+	copy(processedWords, words)
+	if !c.Enabled {
+		return processedWords
+	}
+	applyToWords := func(apply func(text string) string) {
+		for i, word := range processedWords {
+			leading := word[:len(word)-len(strings.TrimLeftFunc(word, unicode.IsSpace))]
+			trailing := word[len(strings.TrimRightFunc(word, unicode.IsSpace)):]
+			if text := strings.TrimSpace(word); text != "" {
+				processedWords[i] = leading + apply(text) + trailing
+			}
+		}
+	}
+	applyToWords(func(text string) string {
+		return c.applyReplacements(text, language)
+	})
+
+	// the case rule changes single letters, so the text is split at the same rune counts again
+	processedText := []rune(applyCaseRule(strings.Join(processedWords, ""), c.Case_rule))
+	offset := 0
+	for i, word := range processedWords {
+		runeCount := utf8.RuneCountInString(word)
+		if offset+runeCount > len(processedText) {
+			break
+		}
+		processedWords[i] = string(processedText[offset : offset+runeCount])
+		offset += runeCount
+	}
+
+	applyToWords(c.maskProfanity)
+	return processedWords
+}
+
+// applyReplacements fixes the spelling of the dictionary terms and applies the replacement rules.
+func (c PostProcessingConf) applyReplacements(text string, language string) string {
+	// dictionary terms fix the spelling of known words
+	for _, term := range c.DictionaryTerms(language) {
+		pattern, err := regexp.Compile(`(?i)\b` + regexp.QuoteMeta(term) + `\b`)
+		if err != nil {
+			continue
+		}
+		text = pattern.ReplaceAllLiteralString(text, term)
+	}
+
+	for _, rule := range c.Rules {
+		if !languageMatches(rule.Language, language) {
+			continue
+		}
+		processedText, err := rule.Apply(text)
+		if err != nil {
+			log.Printf("post-processing rule '%s' skipped: %v", rule.Find, err)
+			continue
+		}
+		text = processedText
+	}
+	return text
+}
+
+func (c PostProcessingConf) maskProfanity(text string) string {
+	if !c.Profanity_masking {
+		return text
+	}
+	for _, profanity := range c.Profanity_words {
+		profanity = strings.TrimSpace(profanity)
+		if profanity == "" {
+			continue
+		}
+		pattern, err := regexp.Compile(`(?i)\b` + regexp.QuoteMeta(profanity) + `\b`)
+		if err != nil {
+			continue
+		}
+		text = pattern.ReplaceAllStringFunc(text, func(match string) string {
+			return maskWord(match, c.Profanity_mask_char)
+		})
+	}
+	return text
+}
+
+// InitialPromptWithTerms returns the initial prompt with all dictionary terms of the language added
+// that are not yet part of it. If no language is set (auto-detection), the terms of all dictionaries are used.
+func (c PostProcessingConf) InitialPromptWithTerms(initialPrompt string, language string) string {
+	terms := c.DictionaryTerms(language)
+	if language == "" || strings.EqualFold(language, "auto") {
+		terms = c.dictionaryTerms(func(string) bool { return true })
+	}
+	var missingTerms []string
+	for _, term := range terms {
+		if !strings.Contains(strings.ToLower(initialPrompt), strings.ToLower(term)) && !containsFold(missingTerms, term) {
+			missingTerms = append(missingTerms, term)
+		}
+	}
+	if len(missingTerms) == 0 {
+		return initialPrompt
+	}
+	if strings.TrimSpace(initialPrompt) == "" {
+		return strings.Join(missingTerms, ", ")
+	}
+	return strings.TrimSpace(initialPrompt) + " " + strings.Join(missingTerms, ", ")
+}
+
+// BackendInitialPrompt returns the initial prompt which is sent to the backend. If enabled, the dictionary terms are
+// added to it, without changing the initial prompt of the settings.
+func (c *Conf) BackendInitialPrompt() string {
+	postProcessing := c.Transcript_postprocessing
+	if !postProcessing.Enabled || !postProcessing.Terms_to_initial_prompt {
+		return c.Initial_prompt
+	}
+	return postProcessing.InitialPromptWithTerms(c.Initial_prompt, c.Current_language)
+}
+
+func containsFold(s []string, str string) bool {
+	for _, v := range s {
+		if strings.EqualFold(v, str) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package Settings
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPostProcessingRuleApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    PostProcessingRule
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{name: "literal", rule: PostProcessingRule{Find: "colour", Replace: "color"}, text: "colour and colours", want: "color and colors"},
+		{name: "empty find", rule: PostProcessingRule{Replace: "x"}, text: "text", want: "text"},
+		{name: "whole word", rule: PostProcessingRule{Find: "cat", Replace: "dog", Whole_word: true}, text: "cat concat cat.", want: "dog concat dog."},
+		{name: "ignore case", rule: PostProcessingRule{Find: "hello", Replace: "hi", Ignore_case: true}, text: "Hello HELLO", want: "hi hi"},
+		{name: "literal with regex characters", rule: PostProcessingRule{Find: "a.b", Replace: "$1", Whole_word: true}, text: "a.b axb", want: "$1 axb"},
+		{name: "regex with groups", rule: PostProcessingRule{Find: `(\d+) percent`, Replace: "$1%", Regex: true}, text: "50 percent", want: "50%"},
+		{name: "invalid regex", rule: PostProcessingRule{Find: "(", Regex: true}, text: "text", want: "text", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rule.Apply(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Apply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostProcessingConfApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		conf     PostProcessingConf
+		text     string
+		language string
+		want     string
+	}{
+		{name: "disabled", conf: PostProcessingConf{Case_rule: CaseRuleUpper}, text: "text", want: "text"},
+		{name: "dictionary", conf: PostProcessingConf{Enabled: true, Dictionaries: map[string][]string{"": {"OpenAI"}}}, text: "openai and openaix", want: "OpenAI and openaix"},
+		{name: "dictionary of other language", conf: PostProcessingConf{Enabled: true, Dictionaries: map[string][]string{"de": {"OpenAI"}}}, text: "openai", language: "en", want: "openai"},
+		{name: "rule of other language", conf: PostProcessingConf{Enabled: true, Rules: []PostProcessingRule{{Find: "a", Replace: "b", Language: "de"}}}, text: "a", language: "en", want: "a"},
+		{name: "rule of same language", conf: PostProcessingConf{Enabled: true, Rules: []PostProcessingRule{{Find: "a", Replace: "b", Language: "DE"}}}, text: "a", language: "de", want: "b"},
+		{name: "invalid rule skipped", conf: PostProcessingConf{Enabled: true, Rules: []PostProcessingRule{{Find: "(", Regex: true}, {Find: "a", Replace: "b"}}}, text: "a", want: "b"},
+		{name: "lower case", conf: PostProcessingConf{Enabled: true, Case_rule: CaseRuleLower}, text: "Hello World", want: "hello world"},
+		{name: "sentence case", conf: PostProcessingConf{Enabled: true, Case_rule: CaseRuleSentence}, text: "hello. is it me? yes", want: "Hello. Is it me? Yes"},
+		{name: "profanity", conf: PostProcessingConf{Enabled: true, Profanity_masking: true, Profanity_words: []string{"darn"}}, text: "Darn, darning", want: "D***, darning"},
+		{name: "profanity mask char", conf: PostProcessingConf{Enabled: true, Profanity_masking: true, Profanity_words: []string{"darn"}, Profanity_mask_char: "#"}, text: "darn", want: "d###"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.conf.Apply(tt.text, tt.language); got != tt.want {
+				t.Errorf("Apply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostProcessingConfApplyWords(t *testing.T) {
+	tests := []struct {
+		name  string
+		conf  PostProcessingConf
+		words []string
+		want  []string
+	}{
+		{name: "disabled", conf: PostProcessingConf{Case_rule: CaseRuleUpper}, words: []string{" a", " b"}, want: []string{" a", " b"}},
+		{name: "keeps whitespace", conf: PostProcessingConf{Enabled: true, Rules: []PostProcessingRule{{Find: "colour", Replace: "color"}}}, words: []string{" colour", " red "}, want: []string{" color", " red "}},
+		{name: "sentence case over words", conf: PostProcessingConf{Enabled: true, Case_rule: CaseRuleSentence}, words: []string{" hello.", " again"}, want: []string{" Hello.", " Again"}},
+		{name: "changed word length", conf: PostProcessingConf{Enabled: true, Rules: []PostProcessingRule{{Find: "one", Replace: "1"}}, Case_rule: CaseRuleUpper}, words: []string{" one", " two"}, want: []string{" 1", " TWO"}},
+		{name: "profanity", conf: PostProcessingConf{Enabled: true, Profanity_masking: true, Profanity_words: []string{"darn"}}, words: []string{" darn", " it"}, want: []string{" d***", " it"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.conf.ApplyWords(tt.words, "en")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ApplyWords() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostProcessingConfInitialPromptWithTerms(t *testing.T) {
+	conf := PostProcessingConf{Dictionaries: map[string][]string{"": {"Fyne"}, "de": {"Grüße"}, "en": {"Whisper", "fyne"}}}
+	tests := []struct {
+		name          string
+		initialPrompt string
+		language      string
+		want          string
+	}{
+		{name: "empty prompt", language: "en", want: "Fyne, Whisper"},
+		{name: "term already in prompt", initialPrompt: "Uses whisper.", language: "en", want: "Uses whisper. Fyne"},
+		{name: "auto language uses all dictionaries", language: "auto", want: "Fyne, Grüße, Whisper"},
+		{name: "all terms in prompt", initialPrompt: "fyne whisper", language: "en", want: "fyne whisper"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := conf.InitialPromptWithTerms(tt.initialPrompt, tt.language); got != tt.want {
+				t.Errorf("InitialPromptWithTerms() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,442 @@
+package Settings
+
+import (
+	"errors"
+	"fmt"
+	"fyne.io/fyne/v2/lang"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"whispering-tiger-ui/Utilities"
+)
+
+// Settings schema, used to validate settings when profiles are loaded, forms are submitted
+// and before a setting_change is sent to the backend.
+
+var (
+	ErrInvalidType       = errors.New("invalid type")
+	ErrOutOfRange        = errors.New("out of range")
+	ErrNotAllowed        = errors.New("value not allowed")
+	ErrMissingDependency = errors.New("missing dependency")
+)
+
+// ValidationError describes why the value of a single setting is invalid.
+// Use errors.Is with the Err* variables to check the kind of error.
+type ValidationError struct {
+	Field   string
+	Value   interface{}
+	Message string
+	Kind    error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Kind
+}
+
+// ValidationErrors collects the errors of multiple settings.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	var messages []string
+	for _, validationError := range e {
+		messages = append(messages, validationError.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// ByField returns the first error of the given setting or nil.
+func (e ValidationErrors) ByField(field string) *ValidationError {
+	for _, validationError := range e {
+		if validationError.Field == field {
+			return validationError
+		}
+	}
+	return nil
+}
+
+// LoadError is returned if a settings file could not be read or parsed.
+type LoadError struct {
+	File string
+	Err  error
+}
+
+func (e *LoadError) Error() string {
+	return fmt.Sprintf("failed to load settings file %s: %v", e.File, e.Err)
+}
+
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+type SettingSchema struct {
+	Name string
+	Kind reflect.Kind
+
+	Min *float64
+	Max *float64
+
+	// Enum lists the allowed values. If empty, the values received with settings_values are used.
+	Enum []string
+	// NoEnum disables the check against settings_values (i.e. for language names which are converted later)
+	NoEnum bool
+	// FloatString is set for string settings which contain a float or "none" / ""
+	FloatString bool
+	// Requires is the name of a bool setting which has to be enabled to change this setting
+	Requires string
+}
+
+func floatPointer(value float64) *float64 {
+	return &value
+}
+
+// schemaOverrides adds ranges, enums and dependencies to the types read from the Conf struct.
+var schemaOverrides = map[string]SettingSchema{
+	"phrase_time_limit": {Min: floatPointer(0)},
+	"pause":             {Min: floatPointer(0)},
+	"energy":            {Min: floatPointer(0)},
+
+	"vad_confidence_threshold": {Min: floatPointer(0), Max: floatPointer(1)},
+	"vad_frames_per_buffer":    {Min: floatPointer(0)},
+	"vad_thread_num":           {Min: floatPointer(0)},
+
+	"speaker_change_split": {Requires: "speaker_diarization"},
+	"min_speaker_length":   {Min: floatPointer(0)},
+	"min_speakers":         {Min: floatPointer(0)},
+	"max_speakers":         {Min: floatPointer(0)},
+
+	"current_language":            {NoEnum: true},
+	"target_language":             {NoEnum: true},
+	"prompt_reset_on_temperature": {Min: floatPointer(0), Max: floatPointer(1)},
+	"logprob_threshold":           {FloatString: true},
+	"no_speech_threshold":         {FloatString: true},
+	"beam_size":                   {Min: floatPointer(1)},
+	"length_penalty":              {Min: floatPointer(0)},
+	"beam_search_patience":        {Min: floatPointer(0)},
+	"repetition_penalty":          {Min: floatPointer(0)},
+	"no_repeat_ngram_size":        {Min: floatPointer(0)},
+	"whisper_cpu_threads":         {Min: floatPointer(0)},
+	"whisper_num_workers":         {Min: floatPointer(0)},
+	"max_sentence_repetition":     {Min: floatPointer(-1)},
+
+	"realtime_frame_multiply":    {Min: floatPointer(0)},
+	"realtime_frequency_time":    {Min: floatPointer(0)},
+	"realtime_whisper_model":     {Requires: "realtime"},
+	"realtime_whisper_beam_size": {Min: floatPointer(0)},
+	"txt_translate_realtime":     {Requires: "realtime"},
+
+	"max_silence_length":        {Min: floatPointer(0)},
+	"keep_silence_length":       {Min: floatPointer(0)},
+	"normalize_lower_threshold": {Min: floatPointer(-100), Max: floatPointer(0)},
+	"normalize_upper_threshold": {Min: floatPointer(-100), Max: floatPointer(0)},
+	"normalize_gain_factor":     {Min: floatPointer(0)},
+
+	"src_lang":                         {NoEnum: true},
+	"trg_lang":                         {NoEnum: true},
+	"txt_second_translation_languages": {NoEnum: true},
+
+	"websocket_port":                {Min: floatPointer(0), Max: floatPointer(65535)},
+	"osc_port":                      {Min: floatPointer(0), Max: floatPointer(65535)},
+	"osc_server_port":               {Min: floatPointer(0), Max: floatPointer(65535)},
+	"osc_min_time_between_messages": {Min: floatPointer(0)},
+	"osc_chat_limit":                {Min: floatPointer(0)},
+	"osc_time_limit":                {Min: floatPointer(0)},
+	"osc_scroll_time_limit":         {Min: floatPointer(0)},
+	"osc_initial_time_limit":        {Min: floatPointer(0)},
+	"osc_scroll_size":               {Min: floatPointer(0)},
+	"osc_max_scroll_size":           {Min: floatPointer(0)},
+	"osc_delay_timeout":             {Min: floatPointer(0)},
+
+	"ocr_txt_src_lang": {NoEnum: true},
+	"ocr_txt_trg_lang": {NoEnum: true},
+	"ocr_lang":         {NoEnum: true},
+
+	"tts_model":                     {NoEnum: true},
+	"tts_voice":                     {NoEnum: true},
+	"tts_secondary_playback_device": {Min: floatPointer(-1)},
+}
+
+// Schema contains the schema of every Conf field, by lowercase field name.
+var Schema = buildSchema()
+
+func buildSchema() map[string]SettingSchema {
+	schema := map[string]SettingSchema{}
+	confType := reflect.TypeOf(Conf{})
+	for i := 0; i < confType.NumField(); i++ {
+		name := strings.ToLower(confType.Field(i).Name)
+		settingSchema := schemaOverrides[name]
+		settingSchema.Name = name
+		settingSchema.Kind = confType.Field(i).Type.Kind()
+		schema[name] = settingSchema
+	}
+	return schema
+}
+
+func newValidationError(field string, value interface{}, kind error, message string) *ValidationError {
+	return &ValidationError{Field: field, Value: value, Message: message, Kind: kind}
+}
+
+// ConvertOption converts a value (i.e. the text of an entry) to the type of the setting.
+// Unknown settings are returned unchanged.
+func ConvertOption(name string, value interface{}) (interface{}, error) {
+	name = strings.ToLower(name)
+	settingSchema, ok := Schema[name]
+	if !ok || value == nil {
+		return value, nil
+	}
+
+	switch settingSchema.Kind {
+	case reflect.Int:
+		switch v := value.(type) {
+		case int:
+			return v, nil
+		case float64:
+			// YAML and JSON numbers might be floats, but fractions are not silently cut off
+			if v != math.Trunc(v) || math.IsInf(v, 0) {
+				return value, newValidationError(name, value, ErrInvalidType, lang.L("must be a whole number"))
+			}
+			return int(v), nil
+		case string:
+			intValue, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return value, newValidationError(name, value, ErrInvalidType, lang.L("must be a whole number"))
+			}
+			return intValue, nil
+		}
+		return value, newValidationError(name, value, ErrInvalidType, lang.L("must be a whole number"))
+	case reflect.Float64:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		case string:
+			floatValue, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return value, newValidationError(name, value, ErrInvalidType, lang.L("must be a number"))
+			}
+			return floatValue, nil
+		}
+		return value, newValidationError(name, value, ErrInvalidType, lang.L("must be a number"))
+	case reflect.String:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case int:
+			return strconv.Itoa(v), nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		}
+		return value, newValidationError(name, value, ErrInvalidType, lang.L("must be a text"))
+	case reflect.Bool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			boolValue, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return value, newValidationError(name, value, ErrInvalidType, lang.L("must be true or false"))
+			}
+			return boolValue, nil
+		}
+		return value, newValidationError(name, value, ErrInvalidType, lang.L("must be true or false"))
+	case reflect.Slice:
+		switch v := value.(type) {
+		case string:
+			return []string{v}, nil
+		}
+	}
+	return value, nil
+}
+
+// allowedValues returns the values a setting is checked against. It is empty if the setting has no Enum and its
+// settings_values are missing, so the value is not restricted.
+func allowedValues(settingSchema SettingSchema) []string {
+	if len(settingSchema.Enum) > 0 {
+		return settingSchema.Enum
+	}
+	if settingSchema.NoEnum || ConfigValues == nil {
+		return nil
+	}
+	values, ok := ConfigValues[settingSchema.Name].([]interface{})
+	if !ok {
+		return nil
+	}
+	var allowed []string
+	for _, value := range values {
+		allowed = append(allowed, fmt.Sprintf("%v", value))
+	}
+	return allowed
+}
+
+func validateValue(settingSchema SettingSchema, value interface{}) *ValidationError {
+	name := settingSchema.Name
+
+	var number *float64
+	switch v := value.(type) {
+	case int:
+		number = floatPointer(float64(v))
+	case float64:
+		number = floatPointer(v)
+	}
+	if number != nil {
+		if settingSchema.Min != nil && *number < *settingSchema.Min {
+			return newValidationError(name, value, ErrOutOfRange, lang.L("must be at least Min", map[string]interface{}{"Min": strconv.FormatFloat(*settingSchema.Min, 'f', -1, 64)}))
+		}
+		if settingSchema.Max != nil && *number > *settingSchema.Max {
+			return newValidationError(name, value, ErrOutOfRange, lang.L("must be at most Max", map[string]interface{}{"Max": strconv.FormatFloat(*settingSchema.Max, 'f', -1, 64)}))
+		}
+	}
+
+	stringValue, isString := value.(string)
+	if !isString {
+		return nil
+	}
+	if settingSchema.FloatString {
+		if stringValue != "" && !strings.EqualFold(stringValue, "none") {
+			if _, err := strconv.ParseFloat(stringValue, 64); err != nil {
+				return newValidationError(name, value, ErrInvalidType, lang.L("must be a number or none"))
+			}
+		}
+		return nil
+	}
+	if stringValue == "" || stringValue == "None" {
+		return nil
+	}
+	if allowed := allowedValues(settingSchema); len(allowed) > 0 {
+		for _, allowedValue := range allowed {
+			if allowedValue == stringValue {
+				return nil
+			}
+		}
+		return newValidationError(name, value, ErrNotAllowed, lang.L("is not one of the available values"))
+	}
+	return nil
+}
+
+func isZeroValue(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	if stringValue, ok := value.(string); ok && stringValue == "None" {
+		return true
+	}
+	return reflect.ValueOf(value).IsZero()
+}
+
+func (c *Conf) validateDependency(settingSchema SettingSchema, value interface{}) *ValidationError {
+	if settingSchema.Requires == "" || isZeroValue(value) {
+		return nil
+	}
+	requiredValue, err := c.GetOption(settingSchema.Requires)
+	if err != nil {
+		return nil
+	}
+	if enabled, ok := requiredValue.(bool); ok && !enabled {
+		return newValidationError(settingSchema.Name, value, ErrMissingDependency, lang.L("requires Setting to be enabled", map[string]interface{}{"Setting": settingSchema.Requires}))
+	}
+	return nil
+}
+
+// ValidateOption converts the value to the type of the setting and validates it against the schema
+// and the dependencies on the other settings of the Conf. Returns the converted value.
+func (c *Conf) ValidateOption(name string, value interface{}) (interface{}, error) {
+	name = strings.ToLower(name)
+	convertedValue, err := ConvertOption(name, value)
+	if err != nil {
+		return value, err
+	}
+	settingSchema, ok := Schema[name]
+	if !ok {
+		return convertedValue, nil
+	}
+	if validationError := validateValue(settingSchema, convertedValue); validationError != nil {
+		return convertedValue, validationError
+	}
+	if validationError := c.validateDependency(settingSchema, convertedValue); validationError != nil {
+		return convertedValue, validationError
+	}
+	return convertedValue, nil
+}
+
+// Validate checks all settings against the schema.
+// Dependencies are only checked for the given changedFields, so unused settings of disabled features do not fail a profile.
+// Values are checked against the settings_values of the backend (ErrNotAllowed) only if the list of the setting was
+// received and is not empty.
+func (c *Conf) Validate(changedFields ...string) ValidationErrors {
+	var names []string
+	for name := range Schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	names = append(names, "transcript_postprocessing")
+	return c.validate(names, changedFields)
+}
+
+// ValidateFields checks only the given settings (i.e. the changed fields of a form) against the schema and their
+// dependencies, so an unchanged invalid value does not block saving other settings.
+// Like Validate, values are only checked against settings_values which were received and are not empty.
+func (c *Conf) ValidateFields(fields ...string) ValidationErrors {
+	var names []string
+	for _, name := range fields {
+		name = strings.ToLower(name)
+		if !Utilities.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return c.validate(names, names)
+}
+
+func (c *Conf) validate(names []string, dependencyFields []string) ValidationErrors {
+	var validationErrors ValidationErrors
+	add := func(validationError *ValidationError) {
+		if validationError != nil && validationErrors.ByField(validationError.Field) == nil {
+			validationErrors = append(validationErrors, validationError)
+		}
+	}
+
+	for _, name := range names {
+		settingSchema, ok := Schema[name]
+		if !ok {
+			continue
+		}
+		value, err := c.GetOption(name)
+		if err != nil {
+			continue
+		}
+		add(validateValue(settingSchema, value))
+	}
+
+	for _, name := range dependencyFields {
+		name = strings.ToLower(name)
+		settingSchema, ok := Schema[name]
+		if !ok {
+			continue
+		}
+		value, err := c.GetOption(name)
+		if err != nil {
+			continue
+		}
+		add(c.validateDependency(settingSchema, value))
+	}
+
+	if (Utilities.Contains(names, "min_speakers") || Utilities.Contains(names, "max_speakers")) && c.Min_speakers > 0 && c.Max_speakers > 0 && c.Min_speakers > c.Max_speakers {
+		add(newValidationError("max_speakers", c.Max_speakers, ErrOutOfRange, lang.L("must be at least Min", map[string]interface{}{"Min": strconv.Itoa(c.Min_speakers)})))
+	}
+
+	if Utilities.Contains(names, "transcript_postprocessing") {
+		if err := c.Transcript_postprocessing.Validate(); err != nil {
+			add(newValidationError("transcript_postprocessing", nil, ErrInvalidType, err.Error()))
+		}
+	}
+
+	if len(validationErrors) == 0 {
+		return nil
+	}
+	return validationErrors
+}
@@ -0,0 +1,153 @@
+package Settings
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestConvertOption(t *testing.T) {
+	tests := []struct {
+		name     string
+		setting  string
+		value    interface{}
+		want     interface{}
+		wantKind error
+	}{
+		{name: "int from string", setting: "websocket_port", value: " 5000 ", want: 5000},
+		{name: "int from whole float", setting: "websocket_port", value: 5000.0, want: 5000},
+		{name: "int from fraction", setting: "websocket_port", value: 5000.5, wantKind: ErrInvalidType},
+		{name: "int from text", setting: "websocket_port", value: "port", wantKind: ErrInvalidType},
+		{name: "int from bool", setting: "websocket_port", value: true, wantKind: ErrInvalidType},
+		{name: "float from int", setting: "pause", value: 2, want: 2.0},
+		{name: "float from string", setting: "pause", value: "0.5", want: 0.5},
+		{name: "float from text", setting: "pause", value: "long", wantKind: ErrInvalidType},
+		{name: "string from int", setting: "osc_ip", value: 127, want: "127"},
+		{name: "string from float", setting: "osc_ip", value: 1.5, want: "1.5"},
+		{name: "bool from string", setting: "txt_translate", value: "true", want: true},
+		{name: "bool from text", setting: "txt_translate", value: "yes please", wantKind: ErrInvalidType},
+		{name: "upper case name", setting: "Websocket_Port", value: "80", want: 80},
+		{name: "unknown setting", setting: "unknown_setting", value: "value", want: "value"},
+		{name: "nil value", setting: "websocket_port", value: nil, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertOption(tt.setting, tt.value)
+			if tt.wantKind != nil {
+				if !errors.Is(err, tt.wantKind) {
+					t.Fatalf("ConvertOption() error = %v, want %v", err, tt.wantKind)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ConvertOption() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ConvertOption() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+// useConfigValues sets the settings_values received from the backend for the test
+func useConfigValues(t *testing.T, values map[string]interface{}) {
+	t.Helper()
+	previous := ConfigValues
+	ConfigValues = values
+	t.Cleanup(func() {
+		ConfigValues = previous
+	})
+}
+
+func TestValidateOption(t *testing.T) {
+	tests := []struct {
+		name     string
+		conf     Conf
+		setting  string
+		value    interface{}
+		wantKind error
+	}{
+		{name: "in range", setting: "websocket_port", value: "65535"},
+		{name: "above max", setting: "websocket_port", value: 65536, wantKind: ErrOutOfRange},
+		{name: "below min", setting: "beam_size", value: 0, wantKind: ErrOutOfRange},
+		{name: "float string", setting: "logprob_threshold", value: "-1.0"},
+		{name: "float string none", setting: "logprob_threshold", value: "none"},
+		{name: "float string text", setting: "logprob_threshold", value: "low", wantKind: ErrInvalidType},
+		{name: "dependency disabled", setting: "speaker_change_split", value: true, wantKind: ErrMissingDependency},
+		{name: "dependency enabled", conf: Conf{Speaker_diarization: true}, setting: "speaker_change_split", value: true},
+		{name: "dependency disabled with zero value", setting: "speaker_change_split", value: false},
+		{name: "allowed value", setting: "whisper_precision", value: "float16"},
+		{name: "value not allowed", setting: "whisper_precision", value: "float64", wantKind: ErrNotAllowed},
+		{name: "no settings_values", setting: "model", value: "any-model"},
+		{name: "empty settings_values", setting: "stt_type", value: "any-type"},
+		{name: "settings_values ignored", setting: "src_lang", value: "any-language"},
+	}
+	useConfigValues(t, map[string]interface{}{
+		"whisper_precision": []interface{}{"float16", "float32"},
+		"stt_type":          []interface{}{},
+		"src_lang":          []interface{}{"English"},
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.conf.ValidateOption(tt.setting, tt.value)
+			if tt.wantKind == nil {
+				if err != nil {
+					t.Errorf("ValidateOption() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantKind) {
+				t.Errorf("ValidateOption() error = %v, want %v", err, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestValidateFields(t *testing.T) {
+	// an invalid unchanged value only fails the settings which are validated
+	conf := Conf{
+		Min_speakers:   3,
+		Max_speakers:   2,
+		Websocket_port: 70000,
+		Beam_size:      5,
+		Model:          "unknown",
+		Transcript_postprocessing: PostProcessingConf{
+			Rules: []PostProcessingRule{{Find: "(", Regex: true}},
+		},
+	}
+	tests := []struct {
+		name       string
+		fields     []string
+		wantFields []string
+	}{
+		{name: "valid field", fields: []string{"energy"}},
+		{name: "unknown field", fields: []string{"unknown_setting"}},
+		{name: "out of range", fields: []string{"Websocket_port", "beam_size"}, wantFields: []string{"websocket_port"}},
+		{name: "speakers", fields: []string{"min_speakers"}, wantFields: []string{"max_speakers"}},
+		{name: "postprocessing", fields: []string{"transcript_postprocessing"}, wantFields: []string{"transcript_postprocessing"}},
+		{name: "value not allowed", fields: []string{"model"}, wantFields: []string{"model"}},
+	}
+	useConfigValues(t, map[string]interface{}{
+		"model": []interface{}{"tiny", "small"},
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotFields []string
+			for _, validationError := range conf.ValidateFields(tt.fields...) {
+				gotFields = append(gotFields, validationError.Field)
+			}
+			if !reflect.DeepEqual(gotFields, tt.wantFields) {
+				t.Errorf("ValidateFields() failed %v, want %v", gotFields, tt.wantFields)
+			}
+		})
+	}
+
+	var gotFields []string
+	for _, validationError := range conf.Validate() {
+		gotFields = append(gotFields, validationError.Field)
+	}
+	wantFields := []string{"model", "websocket_port", "max_speakers", "transcript_postprocessing"}
+	if !reflect.DeepEqual(gotFields, wantFields) {
+		t.Errorf("Validate() failed %v, want %v", gotFields, wantFields)
+	}
+}
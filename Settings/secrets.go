@@ -0,0 +1,169 @@
+package Settings
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"whispering-tiger-ui/Secrets"
+)
+
+// Secrets of the profiles (like API keys) are plugin settings, which are kept in the secrets store instead of the
+// profile. Plugin settings are secret if they are marked with "password": true or "secret": true.
+
+// IsSecretPluginSetting returns true if a plugin setting is marked as secret.
+func IsSecretPluginSetting(setting interface{}) bool {
+	settingMap, ok := setting.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	password, _ := settingMap["password"].(bool)
+	secret, _ := settingMap["secret"].(bool)
+	return password || secret
+}
+
+// secretID returns the id of a secret of a profile, like "default/plugin_settings/MyPlugin/api_key".
+func secretID(fileName string, path ...string) string {
+	profileName := strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+	return strings.Join(append([]string{profileName}, path...), "/")
+}
+
+// forEachSecret calls fn with the path and the current value of every (not empty) secret of the settings.
+// The returned value replaces the secret.
+func (c *Conf) forEachSecret(fn func(path []string, value string) (string, error)) error {
+	var errs []error
+	plugins, _ := c.Plugin_settings.(map[string]interface{})
+	for pluginName, pluginSettings := range plugins {
+		pluginSettingsMap, _ := pluginSettings.(map[string]interface{})
+		for settingName, setting := range pluginSettingsMap {
+			if !IsSecretPluginSetting(setting) {
+				continue
+			}
+			settingMap := setting.(map[string]interface{})
+			if text, ok := settingMap["value"].(string); ok && text != "" {
+				newValue, err := fn([]string{"plugin_settings", pluginName, settingName}, text)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("plugin_settings.%s.%s: %w", pluginName, settingName, err))
+					continue
+				}
+				settingMap["value"] = newValue
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// withSecretReferences returns a copy of the settings with all secrets saved to the secrets store
+// and replaced by references, so they are not written to the profile file.
+func (c *Conf) withSecretReferences(fileName string) (*Conf, error) {
+	conf := *c
+	conf.Plugin_settings = CopySettingValue(c.Plugin_settings)
+	err := conf.forEachSecret(func(path []string, value string) (string, error) {
+		if Secrets.IsReference(value) {
+			return value, nil
+		}
+		return Secrets.Save(secretID(fileName, path...), value)
+	})
+	return &conf, err
+}
+
+// ResolveSecrets replaces the references in the settings with the secrets and returns true if any was replaced.
+// References which can not be resolved (e.g. while the secrets file is locked) are kept.
+func (c *Conf) ResolveSecrets() bool {
+	resolved := false
+	_ = c.forEachSecret(func(path []string, value string) (string, error) {
+		if !Secrets.IsReference(value) {
+			return value, nil
+		}
+		secret, err := Secrets.Resolve(value)
+		if err != nil {
+			log.Printf("secret %s not resolved: %v", strings.Join(path, "."), err)
+			return value, nil
+		}
+		resolved = true
+		return secret, nil
+	})
+	return resolved
+}
+
+// removeSecretReferences clears the references which were not resolved, so the backend never gets a reference
+// instead of a secret.
+func (c *Conf) removeSecretReferences() {
+	c.Plugin_settings = CopySettingValue(c.Plugin_settings)
+	_ = c.forEachSecret(func(path []string, value string) (string, error) {
+		if Secrets.IsReference(value) {
+			return "", nil
+		}
+		return value, nil
+	})
+}
+
+// secretAt returns the value of the secret plugin setting at path (like returned by forEachSecret).
+func (c *Conf) secretAt(path []string) string {
+	if settingMap := c.secretSettingMap(path); settingMap != nil {
+		value, _ := settingMap["value"].(string)
+		return value
+	}
+	return ""
+}
+
+// setSecretAt sets the value of the secret plugin setting at path, if the setting exists.
+func (c *Conf) setSecretAt(path []string, value string) {
+	if settingMap := c.secretSettingMap(path); settingMap != nil {
+		settingMap["value"] = value
+	}
+}
+
+func (c *Conf) secretSettingMap(path []string) map[string]interface{} {
+	if len(path) != 3 || path[0] != "plugin_settings" {
+		return nil
+	}
+	plugins, _ := c.Plugin_settings.(map[string]interface{})
+	pluginSettings, _ := plugins[path[1]].(map[string]interface{})
+	if !IsSecretPluginSetting(pluginSettings[path[2]]) {
+		return nil
+	}
+	return pluginSettings[path[2]].(map[string]interface{})
+}
+
+// RemoveSecrets clears all secrets (e.g. before the settings are exported).
+func (c *Conf) RemoveSecrets() {
+	c.Plugin_settings = CopySettingValue(c.Plugin_settings)
+	_ = c.forEachSecret(func(path []string, value string) (string, error) {
+		return "", nil
+	})
+}
+
+// HasSecretReferences returns true if the settings contain references to stored secrets.
+func (c *Conf) HasSecretReferences() bool {
+	found := false
+	_ = c.forEachSecret(func(path []string, value string) (string, error) {
+		found = found || Secrets.IsReference(value)
+		return value, nil
+	})
+	return found
+}
+
+// MaskSecretValue returns the value of a setting for display, with all secrets masked.
+// key can also be an entry of plugin_settings, like "plugin_settings.MyPlugin".
+func MaskSecretValue(key string, value interface{}) interface{} {
+	if key != "plugin_settings" && !strings.HasPrefix(key, "plugin_settings.") {
+		return value
+	}
+
+	conf := Conf{}
+	if key == "plugin_settings" {
+		conf.Plugin_settings = value
+	} else {
+		conf.Plugin_settings = map[string]interface{}{strings.TrimPrefix(key, "plugin_settings."): value}
+	}
+	conf.Plugin_settings = CopySettingValue(conf.Plugin_settings)
+	_ = conf.forEachSecret(func(path []string, value string) (string, error) {
+		return Secrets.MaskedValue, nil
+	})
+	if key == "plugin_settings" {
+		return conf.Plugin_settings
+	}
+	return conf.Plugin_settings.(map[string]interface{})[strings.TrimPrefix(key, "plugin_settings.")]
+}
@@ -28,6 +28,8 @@ func GetConfProfileDir() string {
 type Conf struct {
 	// Internal Profile Settings
 	SettingsFilename string
+	Schema_version   int         `yaml:"schema_version,omitempty" json:"schema_version,omitempty"` // see Profiles.Migrations
+	Extends          string      `yaml:"extends,omitempty" json:"extends,omitempty"`               // profile file this profile inherits its settings from
 	Process_id       int         `yaml:"process_id" json:"process_id"`
 	Device_index     interface{} `yaml:"device_index,omitempty" json:"device_index,omitempty"`
 	Device_out_index interface{} `yaml:"device_out_index,omitempty" json:"device_out_index,omitempty"`
@@ -97,6 +99,9 @@ type Conf struct {
 	Transcription_auto_save_continous_text bool   `yaml:"transcription_auto_save_continous_text" json:"transcription_auto_save_continous_text"`
 	Transcription_save_audio_dir           string `yaml:"transcription_save_audio_dir" json:"transcription_save_audio_dir"`
 
+	// UI side transcript post-processing (find/replace rules, dictionaries, profanity masking)
+	Transcript_postprocessing PostProcessingConf `yaml:"transcript_postprocessing,omitempty" json:"transcript_postprocessing,omitempty"`
+
 	Silence_cutting_enabled   bool    `yaml:"silence_cutting_enabled" json:"silence_cutting_enabled"`
 	Silence_offset            float64 `yaml:"silence_offset" json:"silence_offset"`
 	Max_silence_length        float64 `yaml:"max_silence_length" json:"max_silence_length"`
@@ -207,6 +212,9 @@ var ExcludeConfigFields = []string{
 	"stt_enabled",
 	"ocr_txt_src_lang",
 	"ocr_txt_trg_lang",
+	"transcript_postprocessing",
+	"schema_version",
+	"extends",
 }
 
 var Config Conf
@@ -221,27 +229,23 @@ func FileExists(name string) bool {
 	return true
 }
 
-func confLoader(c interface{}, configFile string) interface{} {
-	if FileExists(configFile) == true {
-		yamlFile, err := os.ReadFile(configFile)
-		if err != nil {
-			log.Printf("yamlFile.Get err   #%v ", err)
-		}
-		err = yaml.Unmarshal(yamlFile, c)
-		if err != nil {
-			log.Fatalf("Unmarshal: %v", err)
-		}
-	} else {
-		err := fmt.Errorf("Config file %s not found", configFile)
+func confLoader(c *Conf, configFile string) error {
+	if !FileExists(configFile) {
+		err := &LoadError{File: configFile, Err: os.ErrNotExist}
 		log.Printf("Error: %v", err)
-		dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+		return err
 	}
-
-	return c
+	return c.LoadYamlSettings(configFile)
 }
 
-func (c *Conf) GetConf(configFile string) *Conf {
-	return confLoader(c, configFile).(*Conf)
+// GetConf loads the settings file into the Conf. Load errors are shown to the user.
+func (c *Conf) GetConf(configFile string) (*Conf, error) {
+	err := confLoader(c, configFile)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+	}
+	return c, err
 }
 
 func (c *Conf) GetOption(option string) (interface{}, error) {
@@ -253,84 +257,95 @@ func (c *Conf) GetOption(option string) (interface{}, error) {
 	return nil, fmt.Errorf("option %s not found", option)
 }
 
-func (c *Conf) SetOption(optionName string, value interface{}) {
-	switch value.(type) {
-	case string:
-		// if string value is an integer, convert it
-		intValue, err := strconv.Atoi(value.(string))
-		if err == nil {
-			value = intValue
-		}
-	}
-
+// SetOption converts the value to the type of the option and sets it.
+// Returns a *ValidationError if the value can not be converted.
+func (c *Conf) SetOption(optionName string, value interface{}) error {
 	values := reflect.ValueOf(c)
 	indirectValues := reflect.Indirect(values) // required to indirect the pointer
 	types := indirectValues.Type()
 	for i := 0; i < indirectValues.NumField(); i++ {
 		if strings.ToLower(types.Field(i).Name) == strings.ToLower(optionName) {
+			if types.Field(i).Type.Kind() == reflect.Interface {
+				// untyped fields (like device_index) keep integers as integers
+				if stringValue, ok := value.(string); ok {
+					if intValue, err := strconv.Atoi(stringValue); err == nil {
+						value = intValue
+					}
+				}
+			} else {
+				convertedValue, err := ConvertOption(optionName, value)
+				if err != nil {
+					return err
+				}
+				value = convertedValue
+			}
+
 			setValue := reflect.ValueOf(value)
 			if value == nil {
 				setValue = reflect.Zero(types.Field(i).Type)
 			}
-			switch types.Field(i).Type.Kind() {
-			// TODO: fix case where the value is a string and the field is a slice (like in tts_model)
-			case reflect.Slice:
-				switch value.(type) {
-				case string:
-					setValue = reflect.ValueOf([]string{value.(string)})
-				}
-			case reflect.String:
-				switch value.(type) {
-				case int:
-					setValue = reflect.ValueOf(strconv.Itoa(value.(int)))
-				}
-			case reflect.Int:
-				switch value.(type) {
-				case string:
-					tmpValue, _ := strconv.Atoi(value.(string))
-					setValue = reflect.ValueOf(tmpValue)
-				case float64:
-					tmpValue := int(value.(float64))
-					setValue = reflect.ValueOf(tmpValue)
-				}
-			case reflect.Float64:
-				switch value.(type) {
-				case string:
-					tmpValue, _ := strconv.ParseFloat(value.(string), 64)
-					setValue = reflect.ValueOf(tmpValue)
-				case int:
-					tmpValue := float64(value.(int))
-					setValue = reflect.ValueOf(tmpValue)
-				}
-
+			if !setValue.Type().AssignableTo(types.Field(i).Type) {
+				return newValidationError(strings.ToLower(optionName), value, ErrInvalidType, lang.L("has an invalid type"))
 			}
 			indirectValues.Field(i).Set(setValue)
-			return
+			return nil
 		}
 	}
+	return nil
 }
 
+// LoadYamlSettings loads a profile. If the profile extends other profiles, their settings are loaded first.
 func (c *Conf) LoadYamlSettings(fileName string) error {
-	yamlFile, err := os.ReadFile(fileName)
-	if err != nil {
-		log.Printf("yamlFile.Get err   #%v ", err)
+	if err := c.loadProfileLayers(fileName); err != nil {
 		return err
 	}
-	err = yaml.Unmarshal(yamlFile, &c)
+	c.ResolveSecrets()
+	return nil
+}
+
+// loadProfileLayers loads the settings of all layers of a profile without resolving the secrets.
+func (c *Conf) loadProfileLayers(fileName string) error {
+	layers, err := ProfileLayers(fileName)
 	if err != nil {
-		//log.Fatalf("Unmarshal: %v", err)
+		log.Printf("yamlFile.Get err   #%v ", err)
 		return err
 	}
+	for _, layer := range layers {
+		err = yaml.Unmarshal(layer.Raw, &c)
+		if err != nil {
+			return &LoadError{File: layer.File, Err: err}
+		}
+	}
 	return nil
 }
 
+// WriteYamlSettings saves the settings to the profile file. Secrets are saved to the secrets store
+// and the profile only contains references to them.
 func (c *Conf) WriteYamlSettings(fileName string) {
+	conf, err := c.withSecretReferences(fileName)
+	if err != nil {
+		// do not write the secrets to the profile
+		log.Printf("error: profile not saved, failed to store secrets: %v", err)
+		return
+	}
+	conf.writeYamlFile(fileName, 0644)
+}
+
+func (c *Conf) writeYamlFile(fileName string, perm os.FileMode) {
 	// marshal the struct to yaml and save as file
-	yamlFile, err := yaml.Marshal(c)
+	var yamlFile []byte
+	var err error
+	if c.Extends != "" {
+		// only save values which differ from the extended profile
+		yamlFile, err = c.marshalOverrides(fileName)
+	} else {
+		yamlFile, err = yaml.Marshal(c)
+	}
 	if err != nil {
 		log.Printf("error: %v", err)
+		return
 	}
-	err = os.WriteFile(fileName, yamlFile, 0644)
+	err = writeProfileFile(fileName, yamlFile, perm)
 	if err != nil {
 		log.Printf("error: %v", err)
 	}
@@ -391,6 +406,24 @@ func BuildSettingsForm(includeConfigFields []string, settingsFile string) fyne.C
 		MergedConfig = Config
 	}
 
+	// entries show field-level errors while typing
+	// the entries are used for settings without settings_values, so ErrNotAllowed is only reported for an Enum
+	newValidatedEntry := func(settingsName string) *widget.Entry {
+		entry := widget.NewEntry()
+		entry.Validator = func(text string) error {
+			if text == "None" {
+				return nil
+			}
+			_, err := MergedConfig.ValidateOption(settingsName, text)
+			var validationError *ValidationError
+			if errors.As(err, &validationError) {
+				return errors.New(validationError.Message)
+			}
+			return err
+		}
+		return entry
+	}
+
 	settingsFields := reflect.ValueOf(MergedConfig)
 
 	for i := 0; i < settingsFields.NumField(); i++ {
@@ -430,7 +463,7 @@ func BuildSettingsForm(includeConfigFields []string, settingsFile string) fyne.C
 						settingsForm.Append(settingsName, settingsWidget)
 					}
 				} else {
-					settingsWidget := widget.NewEntry()
+					settingsWidget := newValidatedEntry(settingsName)
 					settingsWidget.SetText(settingsValue.(string))
 					settingsForm.Append(settingsName, settingsWidget)
 				}
@@ -452,7 +485,7 @@ func BuildSettingsForm(includeConfigFields []string, settingsFile string) fyne.C
 
 					settingsForm.Append(settingsName, settingsWidget)
 				} else {
-					settingsWidget := widget.NewEntry()
+					settingsWidget := newValidatedEntry(settingsName)
 					settingsForm.Append(settingsName, settingsWidget)
 				}
 
@@ -472,7 +505,7 @@ func BuildSettingsForm(includeConfigFields []string, settingsFile string) fyne.C
 						settingsForm.Append(settingsName, settingsWidget)
 					}
 				} else {
-					settingsWidget := widget.NewEntry()
+					settingsWidget := newValidatedEntry(settingsName)
 					settingsWidget.SetText(strconv.Itoa(settingsValue.(int)))
 					settingsForm.Append(settingsName, settingsWidget)
 				}
@@ -489,7 +522,7 @@ func BuildSettingsForm(includeConfigFields []string, settingsFile string) fyne.C
 						settingsForm.Append(settingsName, settingsWidget)
 					}
 				} else {
-					settingsWidget := widget.NewEntry()
+					settingsWidget := newValidatedEntry(settingsName)
 					settingsWidget.SetText(strconv.FormatFloat(settingsValue.(float64), 'f', 2, 64))
 					settingsForm.Append(settingsName, settingsWidget)
 				}
@@ -507,7 +540,13 @@ func BuildSettingsForm(includeConfigFields []string, settingsFile string) fyne.C
 
 	if settingsFile != "" {
 		settingsForm.OnSubmit = func() {
-			needsSettingUpdate := false
+			// validate all changes on a copy first, so nothing is sent if any value is invalid
+			candidateConfig := MergedConfig
+			var changedItems []*widget.FormItem
+			var changedOldValues []interface{}
+			var changedValues []interface{}
+			var changedFields []string
+			var validationErrors ValidationErrors
 			for _, item := range settingsForm.Items {
 				var value interface{} = nil
 				switch item.Widget.(type) {
@@ -526,35 +565,68 @@ func BuildSettingsForm(includeConfigFields []string, settingsFile string) fyne.C
 				}
 
 				preChangeOption, err := MergedConfig.GetOption(item.Text)
-				sendValue := value
-				if err == nil {
-					switch preChangeOption.(type) {
-					case int:
-						switch value.(type) {
-						case string:
-							sendValue, _ = strconv.Atoi(value.(string))
-						}
-					case float64:
-						switch value.(type) {
-						case string:
-							sendValue, _ = strconv.ParseFloat(value.(string), 64)
-						}
+				if err != nil {
+					continue
+				}
+				sendValue, err := ConvertOption(item.Text, value)
+				if err != nil {
+					var validationError *ValidationError
+					if errors.As(err, &validationError) {
+						validationErrors = append(validationErrors, validationError)
 					}
-					if preChangeOption != sendValue {
-						needsSettingUpdate = true
-						sendMessage := Fields.SendMessageStruct{
-							Type:  "setting_change",
-							Name:  item.Text,
-							Value: sendValue,
-						}
-						sendMessage.SendMessage()
+					continue
+				}
+				if reflect.DeepEqual(preChangeOption, sendValue) || (sendValue == nil && isZeroValue(preChangeOption)) {
+					continue
+				}
+				if err = candidateConfig.SetOption(item.Text, value); err != nil {
+					var validationError *ValidationError
+					if errors.As(err, &validationError) {
+						validationErrors = append(validationErrors, validationError)
+					}
+					continue
+				}
+				changedItems = append(changedItems, item)
+				changedOldValues = append(changedOldValues, preChangeOption)
+				changedValues = append(changedValues, sendValue)
+				changedFields = append(changedFields, item.Text)
+			}
 
-						Config.SetOption(item.Text, value)
-						MergedConfig.SetOption(item.Text, value)
+			// only report errors of fields which are part of this form
+			for _, validationError := range candidateConfig.ValidateFields(changedFields...) {
+				for _, item := range settingsForm.Items {
+					if item.Text == validationError.Field && validationErrors.ByField(validationError.Field) == nil {
+						validationErrors = append(validationErrors, validationError)
 					}
 				}
 			}
-			if needsSettingUpdate {
+
+			for _, item := range settingsForm.Items {
+				item.HintText = ""
+				if validationError := validationErrors.ByField(item.Text); validationError != nil {
+					item.HintText = validationError.Message
+				}
+			}
+			settingsForm.Refresh()
+			if len(validationErrors) > 0 {
+				dialog.ShowError(validationErrors, fyne.CurrentApp().Driver().AllWindows()[0])
+				return
+			}
+
+			for i, item := range changedItems {
+				sendMessage := Fields.SendMessageStruct{
+					Type:  "setting_change",
+					Name:  item.Text,
+					Value: changedValues[i],
+				}
+				sendMessage.SendMessage()
+
+				_ = Config.SetOption(item.Text, changedValues[i])
+				_ = MergedConfig.SetOption(item.Text, changedValues[i])
+				newValue, _ := MergedConfig.GetOption(item.Text)
+				Journal.Record(item.Text, changedOldValues[i], newValue, "advanced settings")
+			}
+			if len(changedItems) > 0 {
 				sendMessage := Fields.SendMessageStruct{
 					Type: "setting_update_req",
 				}
@@ -0,0 +1,42 @@
+package UpdateUtility
+
+import (
+	"errors"
+	"fyne.io/fyne/v2"
+	"time"
+	"whispering-tiger-ui/Secrets"
+	"whispering-tiger-ui/Updater"
+)
+
+// ProxyPasswordSecret is the id of the proxy password in the secrets store.
+const ProxyPasswordSecret = "network_proxy_password"
+
+// NetworkTimeouts are the selectable network timeouts in seconds.
+var NetworkTimeouts = []int{10, 30, 60, 120}
+
+// NetworkPreferences returns the network settings of the preferences (the proxy password from the secrets store).
+func NetworkPreferences() (Updater.NetworkSettings, error) {
+	preferences := fyne.CurrentApp().Preferences()
+	settings := Updater.NetworkSettings{
+		ProxyUrl:      preferences.String("NetworkProxy"),
+		ProxyUsername: preferences.String("NetworkProxyUsername"),
+		CABundle:      preferences.String("NetworkCABundle"),
+		Timeout:       time.Duration(preferences.IntWithFallback("NetworkTimeout", int(Updater.DefaultNetworkTimeout.Seconds()))) * time.Second,
+		UserAgent:     preferences.String("NetworkUserAgent"),
+	}
+	var err error
+	if settings.ProxyUsername != "" {
+		settings.ProxyPassword, err = Secrets.CurrentStore().Get(ProxyPasswordSecret)
+		if errors.Is(err, Secrets.ErrNotFound) {
+			err = nil
+		}
+	}
+	return settings, err
+}
+
+// ApplyNetworkPreferences configures the HTTP client of all network operations with the preferences.
+// If the proxy password can not be read (e.g. locked secrets), the other settings are applied anyway.
+func ApplyNetworkPreferences() error {
+	settings, err := NetworkPreferences()
+	return errors.Join(err, Updater.ConfigureNetwork(settings))
+}
@@ -0,0 +1,70 @@
+package UpdateUtility
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/widget"
+	"strings"
+	"whispering-tiger-ui/ModelDownloader"
+	"whispering-tiger-ui/Updater"
+	"whispering-tiger-ui/Utilities"
+)
+
+// InstallFromOfflineSource installs the platform and the models which are in the offline source.
+// The platform is installed like an update from the offline update manifest, models which are not downloaded yet are
+// copied into the cache folder.
+func InstallFromOfflineSource(window fyne.Window) {
+	if !Updater.Offline.Enabled() {
+		dialog.ShowInformation(lang.L("Offline source"), lang.L("No offline source selected."), window)
+		return
+	}
+	checkDialog := dialog.NewCustomWithoutButtons(lang.L("Checking offline source..."), widget.NewProgressBarInfinite(), window)
+	checkDialog.Show()
+	go func() {
+		defer Utilities.PanicLogger()
+		var missingModels []ModelDownloader.ModelReference
+		for _, model := range ModelDownloader.OfflineModels() {
+			if !ModelDownloader.IsModelDownloaded(model) {
+				missingModels = append(missingModels, model)
+			}
+		}
+		checkDialog.Hide()
+
+		installPlatform := func() {
+			// the update manifest of the offline source belongs to its platform package
+			wasPreferred := Updater.Offline.Preferred()
+			Updater.Offline.SetPreferred(true)
+			defer Updater.Offline.SetPreferred(wasPreferred)
+			if !VersionCheck(window, true) {
+				dialog.ShowInformation(lang.L("Offline source"), lang.L("The platform is up to date."), window)
+			}
+		}
+		if len(missingModels) == 0 {
+			installPlatform()
+			return
+		}
+
+		var modelNames []string
+		for _, model := range missingModels {
+			modelNames = append(modelNames, model.String())
+		}
+		modelsLabel := widget.NewLabel(strings.Join(modelNames, "\n"))
+		confirmDialog := dialog.NewCustomConfirm(lang.L("Install models from the offline source?", map[string]interface{}{"Count": len(missingModels)}), lang.L("Install"), lang.L("Cancel"), container.NewVScroll(modelsLabel), func(b bool) {
+			if !b {
+				installPlatform()
+				return
+			}
+			go func() {
+				defer Utilities.PanicLogger()
+				for _, model := range missingModels {
+					_ = ModelDownloader.DownloadModel(model)
+				}
+				installPlatform()
+			}()
+		}, window)
+		confirmDialog.Resize(fyne.NewSize(500, 400))
+		confirmDialog.Show()
+	}()
+}
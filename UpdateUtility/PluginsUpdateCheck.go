@@ -10,10 +10,10 @@ import (
 	"image/color"
 	"io"
 	"log"
-	"net/http"
 	"os"
 	"regexp"
 	"strings"
+	"whispering-tiger-ui/Updater"
 	"whispering-tiger-ui/Utilities"
 )
 
@@ -40,7 +40,7 @@ type TableData struct {
 }
 
 func DownloadFile(url string) (string, error) {
-	resp, err := http.Get(url)
+	resp, err := Updater.HTTPClient().Get(url)
 	if err != nil {
 		return "", err
 	}
@@ -204,7 +204,7 @@ func FetchAndAnalyzePluginUrl(url string) (string, string, string, []byte) {
 	}
 	// Future extension: Add else if conditions for other domains like GitLab
 
-	resp, err := http.Get(url)
+	resp, err := Updater.HTTPClient().Get(url)
 	if err != nil {
 		fmt.Printf("Error fetching gist: %v\n", err)
 		return "err", "err", "", nil
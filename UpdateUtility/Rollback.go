@@ -0,0 +1,73 @@
+package UpdateUtility
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/widget"
+	"time"
+	"whispering-tiger-ui/RuntimeBackend"
+	"whispering-tiger-ui/Updater"
+)
+
+// PlatformRollback switches back to the previously installed platform version and restarts a running backend.
+func PlatformRollback(window fyne.Window, onFinished func()) {
+	info, err := Updater.LoadPlatformInfo()
+	if err != nil {
+		dialog.ShowError(err, window)
+		return
+	}
+	if !info.CanRollback() {
+		dialog.ShowInformation(lang.L("Rollback"), lang.L("No previous platform version available."), window)
+		return
+	}
+	dialog.ShowConfirm(lang.L("Rollback"), lang.L("Switch back to the previous platform version?", map[string]interface{}{"Version": info.PreviousVersion}), func(b bool) {
+		if !b {
+			return
+		}
+		restartBackend := false
+		if len(RuntimeBackend.BackendsList) > 0 && RuntimeBackend.BackendsList[0].IsRunning() {
+			RuntimeBackend.BackendsList[0].Stop()
+			time.Sleep(1 * time.Second)
+			restartBackend = true
+		}
+		if err := Updater.RollbackPlatform(); err != nil {
+			dialog.ShowError(err, window)
+		}
+		if restartBackend {
+			RuntimeBackend.BackendsList[0].Start()
+		}
+		if onFinished != nil {
+			onFinished()
+		}
+	}, window)
+}
+
+// ShowPlatformHistory shows the installations and rollbacks of the platform.
+func ShowPlatformHistory(window fyne.Window) {
+	info, err := Updater.LoadPlatformInfo()
+	if err != nil {
+		dialog.ShowError(err, window)
+		return
+	}
+	historyList := widget.NewList(
+		func() int {
+			return len(info.History)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, object fyne.CanvasObject) {
+			entry := info.History[id]
+			action := lang.L("Installed")
+			if entry.Action == "rollback" {
+				action = lang.L("Rollback")
+			}
+			object.(*widget.Label).SetText(entry.Installed.Format("2006-01-02 15:04") + "  " + action + "  " + entry.Version)
+		},
+	)
+	historyDialog := dialog.NewCustom(lang.L("Platform history"), lang.L("Close"), container.NewStack(historyList), window)
+	historyDialog.Resize(fyne.NewSize(500, 400))
+	historyDialog.Show()
+}
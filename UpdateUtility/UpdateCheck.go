@@ -1,17 +1,17 @@
 package UpdateUtility
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/lang"
 	"fyne.io/fyne/v2/widget"
 	"github.com/dustin/go-humanize"
-	"math/rand"
+	"log"
 	"net/url"
 	"os"
-	"path/filepath"
 	"time"
 	"whispering-tiger-ui/RuntimeBackend"
 	"whispering-tiger-ui/Updater"
@@ -20,7 +20,13 @@ import (
 
 var updateInfoUrl = "https://s3.libs.space:9000/projects/whispering/latest.yaml"
 
-func versionDownload(updater Updater.UpdatePackages, packageName, filename string, window fyne.Window, startBackend bool, progressTitle string, cleanUpFunc func()) error {
+func versionDownload(updater Updater.UpdatePackages, packageName, filename string, window fyne.Window, startBackend bool, progressTitle string) error {
+	checksum, err := updater.PackageChecksum(packageName)
+	if err != nil {
+		dialog.ShowError(err, window)
+		return err
+	}
+
 	statusBar := widget.NewProgressBar()
 	statusBarContainer := container.NewVBox(statusBar)
 	downloadDialog := dialog.NewCustom(progressTitle, lang.L("Hide (Download will continue)"), statusBarContainer, window)
@@ -32,117 +38,121 @@ func versionDownload(updater Updater.UpdatePackages, packageName, filename strin
 		reOpenAfterHide = true
 	})
 
-	hasEUServer := false
-	hasUSServer := false
 	var mergedUrls []string
 
-	// go through all url locations in the yaml slice and check if it exists
-	for langKey, locations := range updater.Packages[packageName].LocationUrls {
-		if langKey == "EU" && len(locations) > 0 {
-			hasEUServer = true
-		}
-		if langKey == "US" && len(locations) > 0 {
-			hasUSServer = true
-		}
+	// go through all url locations in the yaml slice. The download manager downloads from the fastest mirror.
+	for _, locations := range updater.Packages[packageName].LocationUrls {
 		if len(locations) > 0 {
 			mergedUrls = append(mergedUrls, locations...)
 		}
 	}
 
-	randomUrlIndex := rand.Int() % len(mergedUrls)
-	downloadUrl := mergedUrls[randomUrlIndex]
-
-	locationString := "DEFAULT"
-	// try to download from the closest server by checking the user's location
-	pcLang := Updater.GetLanguage()
-	print("lang: " + pcLang + "\n")
-	if hasUSServer && Updater.IsUSLocale(pcLang) {
-		locationString = "US"
-		// Download from US server
-		randomUrlIndex = rand.Int() % len(updater.Packages[packageName].LocationUrls["US"])
-		downloadUrl = updater.Packages[packageName].LocationUrls["US"][randomUrlIndex]
-	} else if hasEUServer && Updater.IsEULocale(pcLang) {
-		locationString = "EU"
-		// Download from EU server
-		randomUrlIndex = rand.Int() % len(updater.Packages[packageName].LocationUrls["EU"])
-		downloadUrl = updater.Packages[packageName].LocationUrls["EU"][randomUrlIndex]
-	}
-
-	downloader := Updater.Download{
-		Url:                 downloadUrl,
-		FallbackUrls:        mergedUrls,
-		Filepath:            filename,
-		ConcurrentDownloads: 4,
-		ChunkSize:           15 * 1024 * 1024, // 15 MB
-	}
-	downloader.WriteCounter.OnProgress = func(progress, total uint64, speed float64) {
-		if int64(total) == -1 {
-			statusBarContainer.Remove(statusBar)
-			statusBarContainer.Add(widget.NewProgressBarInfinite())
+	// only download the changed files if the package has a files manifest (and is not in the offline source)
+	_, offlinePackage := Updater.Offline.Find(mergedUrls, checksum)
+	if len(updater.Packages[packageName].FilesManifestUrls) > 0 && RuntimeBackend.BackendAvailable() && !offlinePackage {
+		filesManifest, err := updater.GetFilesManifest(packageName)
+		if err == nil {
+			// the single files can be paused and canceled in the downloads, this cancels the whole update
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			cancelButton := widget.NewButton(lang.L("Cancel update"), cancel)
+			statusBarContainer.Add(downloadingLabel)
+			statusBarContainer.Add(cancelButton)
 			statusBarContainer.Refresh()
-		} else {
-			statusBar.Max = float64(total)
-			statusBar.SetValue(float64(progress))
-
-			resumeStatusText := ""
-			if downloader.IsResuming() {
-				resumeStatusText = " (" + lang.L("Resuming") + ")"
-			}
-
-			speedStr := ""
-			if speed < 1024 {
-				speedStr = fmt.Sprintf("%.2f B/s", speed)
-			} else if speed < 1024*1024 {
-				speedStr = fmt.Sprintf("%.2f KiB/s", speed/1024)
-			} else {
-				speedStr = fmt.Sprintf("%.2f MiB/s", speed/(1024*1024))
+			err = Updater.InstallPlatformFiles(ctx, filesManifest, updater.Packages[packageName], func(progress Updater.PlatformFilesProgress) {
+				statusBar.Max = float64(progress.FilesTotal)
+				statusBar.SetValue(float64(progress.FilesDone))
+				downloadingLabel.SetText(lang.L("Updating changed files", map[string]interface{}{"FilesDone": progress.FilesDone, "FilesTotal": progress.FilesTotal, "Downloaded": humanize.Bytes(uint64(progress.BytesDownloaded))}))
+			})
+			statusBarContainer.Remove(cancelButton)
+			if err != nil {
+				switch {
+				case errors.Is(err, Updater.ErrDownloadPaused):
+					statusBarContainer.Add(widget.NewLabel(lang.L("Download paused. Start the update again to continue it.")))
+				case errors.Is(err, context.Canceled), errors.Is(err, Updater.ErrDownloadCanceled):
+					statusBarContainer.Add(widget.NewLabel(lang.L("Update canceled. The current version is kept.")))
+				default:
+					statusBarContainer.Add(widget.NewLabel(lang.L("Update failed. The current version is kept.")))
+					dialog.ShowError(err, window)
+				}
+				return err
 			}
-
-			downloadingLabel.SetText(lang.L("Downloading from location", map[string]interface{}{"Location": locationString, "TotalSize": humanize.Bytes(total), "Speed": speedStr}) + " " + resumeStatusText)
+			finishPlatformUpdate(statusBarContainer, downloadDialog, reOpenAfterHide, startBackend)
+			return nil
 		}
+		log.Printf("files manifest not available, downloading the full package: %v", err)
 	}
 
 	statusBarContainer.Add(downloadingLabel)
 	statusBarContainer.Refresh()
-	err := downloader.DownloadFile(3)
-	if err != nil {
-		dialog.ShowError(err, window)
-		return err
-	}
-	appExec, _ := os.Executable()
 
-	// check if the file has the correct hash
-	statusBarContainer.Add(widget.NewLabel(lang.L("Checking checksum...")))
-	if err := Updater.CheckFileHash(filename, updater.Packages[packageName].SHA256); err != nil {
-		fmt.Printf("Error: %s\n", err.Error())
-		dialog.ShowError(err, window)
-		checksumCheckFailLabel := widget.NewLabel(lang.L("Checksum check failed. Please delete temporary file and download again. If it still fails, please contact support."))
-		checksumCheckFailLabel.Wrapping = fyne.TextWrapWord
-		statusBarContainer.Add(checksumCheckFailLabel)
-		return err
-	}
+	progressBarInfinite := false
+	lastState := Updater.DownloadQueued
+	err = Updater.Downloads.Download(Updater.DownloadRequest{
+		Title:         progressTitle,
+		Urls:          mergedUrls,
+		Filepath:      filename,
+		Checksum:      checksum,
+		ExtractFormat: "none",
+		ExtractedSize: updater.Packages[packageName].ExtractedSize,
+		Priority:      Updater.PriorityHigh,
+	}, func(item Updater.DownloadItem) {
+		if item.State == Updater.DownloadVerifying && lastState != Updater.DownloadVerifying {
+			statusBarContainer.Add(widget.NewLabel(lang.L("Checking checksum...")))
+		}
+		lastState = item.State
+		if item.State != Updater.DownloadRunning {
+			return
+		}
+		if item.Total == -1 {
+			if !progressBarInfinite {
+				progressBarInfinite = true
+				statusBarContainer.Remove(statusBar)
+				statusBarContainer.Add(widget.NewProgressBarInfinite())
+				statusBarContainer.Refresh()
+			}
+			return
+		}
+		statusBar.Max = float64(item.Total)
+		statusBar.SetValue(float64(item.Progress))
 
-	// close running backend process
-	if len(RuntimeBackend.BackendsList) > 0 && RuntimeBackend.BackendsList[0].IsRunning() {
-		statusBarContainer.Add(widget.NewLabel(lang.L("Stopping Backend...")))
-		RuntimeBackend.BackendsList[0].Stop()
-		time.Sleep(1 * time.Second)
-	}
+		resumeStatusText := ""
+		if item.Resuming {
+			resumeStatusText = " (" + lang.L("Resuming") + ")"
+		}
 
-	// wait a bit before trying to extract
-	time.Sleep(2 * time.Second)
+		downloadingLabel.SetText(lang.L("Downloading from location", map[string]interface{}{"Location": item.Source, "TotalSize": humanize.Bytes(uint64(item.Total)), "Speed": item.SpeedString()}) + " " + resumeStatusText)
+	})
 
-	// clean up before extracting
-	if cleanUpFunc != nil {
-		statusBarContainer.Add(widget.NewLabel(lang.L("Removing old version...")))
-		cleanUpFunc()
+	if err != nil {
+		if lastState == Updater.DownloadVerifying {
+			// never keep a package which does not match the signed manifest
+			_ = os.Remove(filename)
+			checksumCheckFailLabel := widget.NewLabel(lang.L("Checksum check failed. The downloaded file does not match the signed update information and was removed."))
+			checksumCheckFailLabel.Wrapping = fyne.TextWrapWord
+			statusBarContainer.Add(checksumCheckFailLabel)
+		}
+		if errors.Is(err, Updater.ErrDownloadPaused) {
+			statusBarContainer.Add(widget.NewLabel(lang.L("Download paused. Resume it in the downloads to continue the update.")))
+		} else if !errors.Is(err, Updater.ErrDownloadCanceled) {
+			dialog.ShowError(err, window)
+		}
+		return err
 	}
-
-	// extract
-	statusBarContainer.Add(widget.NewLabel(lang.L("Extracting...")))
+	// extract into a new directory and switch to it when it is valid. The current version is kept for a rollback.
+	extractingLabel := widget.NewLabel(lang.L("Extracting..."))
+	statusBar.SetValue(0)
+	statusBarContainer.Add(extractingLabel)
 	statusBarContainer.Refresh()
-	err = Updater.Unzip(filename, filepath.Dir(appExec))
+	err = Updater.InstallPlatform(filename, updater.Packages[packageName], func(progress Updater.ExtractProgress) {
+		if progress.BytesTotal > 0 {
+			statusBar.Max = float64(progress.BytesTotal)
+			statusBar.SetValue(float64(progress.BytesDone))
+		}
+		extractingLabel.SetText(lang.L("Extracting files", map[string]interface{}{"FilesDone": progress.FilesDone, "Extracted": humanize.Bytes(uint64(progress.BytesDone))}))
+	})
 	if err != nil {
+		statusBarContainer.Add(widget.NewLabel(lang.L("Update failed. The current version is kept.")))
 		dialog.ShowError(err, window)
 		return err
 	}
@@ -152,13 +162,25 @@ func versionDownload(updater Updater.UpdatePackages, packageName, filename strin
 		return err
 	}
 
-	if err == nil {
-		statusBarContainer.Add(widget.NewLabel(lang.L("Finished.")))
-		downloadDialog.SetDismissText(lang.L("Close"))
-		downloadDialog.Refresh()
-		if reOpenAfterHide {
-			downloadDialog.Show()
-		}
+	finishPlatformUpdate(statusBarContainer, downloadDialog, reOpenAfterHide, startBackend)
+	return nil
+}
+
+// finishPlatformUpdate restarts the backend with the new platform version.
+func finishPlatformUpdate(statusBarContainer *fyne.Container, downloadDialog *dialog.CustomDialog, reOpenAfterHide bool, startBackend bool) {
+	// close running backend process, so it is restarted with the new version
+	if len(RuntimeBackend.BackendsList) > 0 && RuntimeBackend.BackendsList[0].IsRunning() {
+		statusBarContainer.Add(widget.NewLabel(lang.L("Stopping Backend...")))
+		RuntimeBackend.BackendsList[0].Stop()
+		time.Sleep(1 * time.Second)
+		startBackend = true
+	}
+
+	statusBarContainer.Add(widget.NewLabel(lang.L("Finished.")))
+	downloadDialog.SetDismissText(lang.L("Close"))
+	downloadDialog.Refresh()
+	if reOpenAfterHide {
+		downloadDialog.Show()
 	}
 
 	statusBarContainer.Refresh()
@@ -168,8 +190,6 @@ func versionDownload(updater Updater.UpdatePackages, packageName, filename strin
 		statusBarContainer.Add(widget.NewLabel(lang.L("Restarting Backend") + "..."))
 		RuntimeBackend.BackendsList[0].Start()
 	}
-
-	return nil
 }
 
 func VersionCheck(window fyne.Window, startBackend bool) bool {
@@ -178,28 +198,23 @@ func VersionCheck(window fyne.Window, startBackend bool) bool {
 	updater := Updater.UpdatePackages{}
 	err := updater.GetUpdateInfo(updateInfoUrl)
 	if err != nil {
+		log.Printf("failed to get update info: %v", err)
 		return false
 	}
 
 	// check platform version
-	platformFileWithoutVersion := !Utilities.FileExists(".current_platform.yaml") && (Utilities.FileExists("audioWhisper/audioWhisper.exe") || Utilities.FileExists("audioWhisper.py"))
+	platformFileWithoutVersion := !Utilities.FileExists(Updater.PlatformInfoFile) && RuntimeBackend.BackendAvailable()
 	platformRequiresUpdate := false
-	if Utilities.FileExists(".current_platform.yaml") {
-		currentPlatformVersion := Updater.UpdateInfo{}
-		data, err := os.ReadFile(".current_platform.yaml")
-		if err == nil {
-			_ = currentPlatformVersion.ReadYaml(data)
-			if currentPlatformVersion.Version != updater.Packages["ai_platform"].Version {
-				platformRequiresUpdate = true
-			}
-		}
+	currentPlatform, err := Updater.LoadPlatformInfo()
+	if err == nil && Utilities.FileExists(Updater.PlatformInfoFile) && currentPlatform.Version != updater.Packages["ai_platform"].Version {
+		platformRequiresUpdate = true
 	}
 
 	platformUpdateTitle := lang.L("Platform Update available")
 	platformUpdateText := lang.L("There is a new Update of the Platform available. Update to new version now?", map[string]interface{}{"Version": updater.Packages["ai_platform"].Version})
 	progressTitle := lang.L("Downloading Platform Update. (Please wait until this is finished!)")
 
-	if !Utilities.FileExists("audioWhisper/audioWhisper.exe") && !Utilities.FileExists("audioWhisper.py") {
+	if !RuntimeBackend.BackendAvailable() {
 		platformRequiresUpdate = true
 		platformUpdateTitle = lang.L("Platform not found")
 		platformUpdateText = lang.L("No required Platform file found. Download version now?", map[string]interface{}{"Version": updater.Packages["ai_platform"].Version})
@@ -211,24 +226,14 @@ func VersionCheck(window fyne.Window, startBackend bool) bool {
 		dialog.ShowConfirm(platformUpdateTitle, platformUpdateText, func(b bool) {
 			if b {
 				go func() {
-					cleanUpFunc := func() {
-						appExec, _ := os.Executable()
-						oldVersionDir := filepath.Join(filepath.Dir(appExec), "audioWhisper")
-						err := os.RemoveAll(oldVersionDir)
-						if err != nil {
-							dialog.ShowError(err, window)
-						}
-					}
-					err = versionDownload(updater, "ai_platform", "audioWhisper_platform.zip", window, startBackend, progressTitle, cleanUpFunc)
-					if err == nil {
-						packageInfo := updater.Packages["ai_platform"]
-						packageInfo.WriteYaml(".current_platform.yaml")
-					}
+					_ = versionDownload(updater, "ai_platform", "audioWhisper_platform.zip", window, startBackend, progressTitle)
 				}()
 			} else {
 				if platformFileWithoutVersion {
-					packageInfo := updater.Packages["ai_platform"]
-					packageInfo.WriteYaml(".current_platform.yaml")
+					currentPlatform.UpdateInfo = updater.Packages["ai_platform"]
+					if err := currentPlatform.Save(); err != nil {
+						log.Printf("failed to save platform version: %v", err)
+					}
 				}
 			}
 		}, window)
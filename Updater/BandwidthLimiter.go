@@ -0,0 +1,88 @@
+package Updater
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// BandwidthLimiter limits the combined speed of all downloads which share it (token bucket).
+type BandwidthLimiter struct {
+	mutex          sync.Mutex
+	bytesPerSecond int64
+	tokens         float64
+	lastRefill     time.Time
+}
+
+// SetLimit sets the limit in bytes per second. 0 disables the limit.
+func (l *BandwidthLimiter) SetLimit(bytesPerSecond int64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.bytesPerSecond = bytesPerSecond
+	l.tokens = 0
+	l.lastRefill = time.Now()
+}
+
+// Limit returns the limit in bytes per second (0 if there is none or the limiter is nil).
+func (l *BandwidthLimiter) Limit() int64 {
+	if l == nil {
+		return 0
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.bytesPerSecond
+}
+
+// WaitN blocks until n bytes may be transferred.
+func (l *BandwidthLimiter) WaitN(ctx context.Context, n int) error {
+	l.mutex.Lock()
+	if l.bytesPerSecond <= 0 {
+		l.mutex.Unlock()
+		return nil
+	}
+	now := time.Now()
+	l.tokens += now.Sub(l.lastRefill).Seconds() * float64(l.bytesPerSecond)
+	// allow bursts of at most one second
+	if l.tokens > float64(l.bytesPerSecond) {
+		l.tokens = float64(l.bytesPerSecond)
+	}
+	l.lastRefill = now
+	l.tokens -= float64(n)
+	wait := time.Duration(-l.tokens / float64(l.bytesPerSecond) * float64(time.Second))
+	l.mutex.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	select {
+	case <-time.After(wait):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Reader returns a reader which is limited by the limiter. A nil limiter returns the reader unchanged.
+func (l *BandwidthLimiter) Reader(ctx context.Context, reader io.Reader) io.Reader {
+	if l == nil {
+		return reader
+	}
+	return &limitedReader{ctx: ctx, reader: reader, limiter: l}
+}
+
+type limitedReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	limiter *BandwidthLimiter
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.WaitN(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,45 @@
+package Updater
+
+import (
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"os"
+	"path/filepath"
+)
+
+// NotEnoughSpaceError is returned if a download or extraction does not fit on the disk.
+type NotEnoughSpaceError struct {
+	Path      string
+	Required  uint64
+	Available uint64
+}
+
+func (e *NotEnoughSpaceError) Error() string {
+	return fmt.Sprintf("not enough disk space in %s: %s required, %s available", e.Path, humanize.Bytes(e.Required), humanize.Bytes(e.Available))
+}
+
+// CheckFreeSpace returns a NotEnoughSpaceError if less than required bytes are free on the disk of path.
+// path does not need to exist yet. If the free space can not be determined, no error is returned.
+func CheckFreeSpace(path string, required int64) error {
+	if required <= 0 {
+		return nil
+	}
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	for {
+		if _, err = os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	available, err := freeDiskSpace(dir)
+	if err != nil {
+		return nil
+	}
+	if available < uint64(required) {
+		return &NotEnoughSpaceError{Path: dir, Required: uint64(required), Available: available}
+	}
+	return nil
+}
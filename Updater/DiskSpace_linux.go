@@ -0,0 +1,16 @@
+//go:build linux
+
+package Updater
+
+import (
+	"syscall"
+)
+
+// freeDiskSpace returns the bytes available to the user on the disk of dir.
+func freeDiskSpace(dir string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
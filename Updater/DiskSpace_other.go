@@ -0,0 +1,12 @@
+//go:build !linux && !windows
+
+package Updater
+
+import (
+	"errors"
+)
+
+// freeDiskSpace is not supported on this platform, so CheckFreeSpace skips the check.
+func freeDiskSpace(dir string) (uint64, error) {
+	return 0, errors.New("free disk space is not supported on this platform")
+}
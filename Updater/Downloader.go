@@ -2,8 +2,8 @@ package Updater
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"github.com/hashicorp/go-cleanhttp"
 	"io"
 	"io/ioutil"
 	"math"
@@ -19,8 +19,6 @@ import (
 const DefaultChunkSize int64 = 20 * 1024 * 1024 // 20 MB
 const defaultConcurrentDownloads = 1
 
-var netClient = cleanhttp.DefaultPooledClient()
-
 type OnProgress func(bytesWritten, contentLength uint64, speed float64)
 
 type WriteCounter struct {
@@ -40,43 +38,71 @@ type Download struct {
 	ConcurrentDownloads    int
 	ChunkSize              int64 // in bytes
 	WriteCounter           WriteCounter
-	isResumed              bool
-	serverResumeSupport    bool
-	maxRetries             int
-	urlIndex               int
-	mu                     sync.Mutex
-	cond                   *sync.Cond
-	downloaded             map[int64][]byte
-	nextWrite              int64
-	remoteFileSize         int64
+	// Limiter limits the bandwidth of the download (can be shared by multiple downloads)
+	Limiter             *BandwidthLimiter
+	ctx                 context.Context
+	isResumed           bool
+	serverResumeSupport bool
+	maxRetries          int
+	urlIndex            int
+	urlMu               sync.Mutex
+	mu                  sync.Mutex
+	cond                *sync.Cond
+	downloaded          map[int64][]byte
+	nextWrite           int64
+	remoteFileSize      int64
+	// mirrorSwitches counts the switches to a faster mirror, which are limited to the number of urls
+	mirrorSwitches int
+	// etag of the remote file, which must match when switching the mirror in the middle of the download
+	etag       string
+	throughput throughputMeter
+	// Preflight is called with the size of the remote file (-1 if unknown) before the transfer starts.
+	// An error cancels the download (e.g. if there is not enough disk space).
+	Preflight func(remoteSize int64) error
 }
 
 func (d *Download) getUserAgent() string {
-	build := Utilities.AppBuild
-
-	return "Whispering_Tiger_DL/" + Utilities.AppVersion + " (" + build + ")"
+	return UserAgent()
 }
 
 func (d *Download) getRemoteFileSize() (int64, error) {
-	currentUrl := d.getCurrentUrl()
-
-	req, err := http.NewRequest("HEAD", currentUrl, nil)
+	size, etag, err := d.getRemoteFileInfo(d.getCurrentUrl())
 	if err != nil {
 		return 0, err
 	}
+	d.etag = etag
+	return size, nil
+}
+
+// getRemoteFileInfo returns the size (-1 if unknown) and the ETag of the file at url.
+func (d *Download) getRemoteFileInfo(url string) (int64, string, error) {
+	req, err := http.NewRequestWithContext(d.ctx, "HEAD", url, nil)
+	if err != nil {
+		return 0, "", err
+	}
 	req.Header.Set("User-Agent", d.getUserAgent())
 
-	resp, err := netClient.Do(req)
+	resp, err := HTTPClient().Do(req)
 	if err != nil {
-		return 0, err
+		return 0, "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return 0, "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	return resp.ContentLength, nil
+	return resp.ContentLength, resp.Header.Get("ETag"), nil
+}
+
+// isSameRemoteFile reports if the file at url has the size of the downloaded file and the same ETag (if both servers
+// send one), so switching the mirror in the middle of the download can not mix the parts of different files.
+func (d *Download) isSameRemoteFile(url string) bool {
+	size, etag, err := d.getRemoteFileInfo(url)
+	if err != nil || size < 0 || size != d.remoteFileSize {
+		return false
+	}
+	return d.etag == "" || etag == "" || etag == d.etag
 }
 
 func (d *Download) getRemoteFileSizeWithRetry(retries int) (int64, error) {
@@ -85,15 +111,18 @@ func (d *Download) getRemoteFileSizeWithRetry(retries int) (int64, error) {
 		if err == nil {
 			return remoteFileSize, nil
 		}
+		if d.ctx.Err() != nil {
+			return 0, err
+		}
 
 		if i < retries {
 			fmt.Printf("Error getting remote file size %s: %s. Retrying in 1 second...\n", d.Url, err.Error())
 			time.Sleep(1 * time.Second)
 		} else {
 			// Switch to the next fallback url if available
-			if d.urlIndex < len(d.FallbackUrls) {
+			Mirrors.RecordFailure(d.getCurrentUrl())
+			if d.nextUrl() {
 				fmt.Printf("All retries for URL %s have failed. Trying the next fallback URL...\n", d.getCurrentUrl())
-				d.urlIndex++
 				i = -1 // reset retry count for the next url
 				continue
 			} else {
@@ -131,7 +160,15 @@ func (d *Download) GetTotalDownloadedSize() int64 {
 }
 
 func (d *Download) DownloadFile(retries int) error {
-	progressCtx, progressCancel := context.WithCancel(context.Background())
+	return d.DownloadFileContext(context.Background(), retries)
+}
+
+// DownloadFileContext downloads the file until it is finished or ctx is canceled.
+// The partially downloaded file is kept, so the download can be resumed.
+func (d *Download) DownloadFileContext(ctx context.Context, retries int) error {
+	d.ctx = ctx
+	d.throughput.reset()
+	progressCtx, progressCancel := context.WithCancel(ctx)
 	defer progressCancel()
 
 	go func() {
@@ -163,6 +200,11 @@ func (d *Download) DownloadFile(retries int) error {
 	if err != nil {
 		return err
 	}
+	if d.Preflight != nil {
+		if err = d.Preflight(d.remoteFileSize); err != nil {
+			return err
+		}
+	}
 
 	// If remote file size is -1, proceed to download without knowing the file size
 	if d.remoteFileSize == -1 {
@@ -190,6 +232,8 @@ func (d *Download) DownloadFile(retries int) error {
 }
 
 func (d *Download) getCurrentUrl() string {
+	d.urlMu.Lock()
+	defer d.urlMu.Unlock()
 	currentUrl := d.Url
 	if d.urlIndex > 0 && d.urlIndex <= len(d.FallbackUrls) {
 		currentUrl = d.FallbackUrls[d.urlIndex-1]
@@ -197,17 +241,80 @@ func (d *Download) getCurrentUrl() string {
 	return currentUrl
 }
 
+// CurrentUrl returns the url which is currently downloaded from.
+func (d *Download) CurrentUrl() string {
+	return d.getCurrentUrl()
+}
+
+// nextUrl switches to the next fallback url. It returns false if there is none.
+func (d *Download) nextUrl() bool {
+	d.urlMu.Lock()
+	defer d.urlMu.Unlock()
+	if d.urlIndex < len(d.FallbackUrls) {
+		d.urlIndex++
+		return true
+	}
+	return false
+}
+
+// switchToFasterMirror switches to a considerably faster mirror (or any other one if the current one stalled).
+// Bandwidth limited downloads are not switched because of their speed, since the speed is not the mirrors one.
+func (d *Download) switchToFasterMirror(current string, stalled bool) bool {
+	if d.UseMultiServerDownload || len(d.FallbackUrls) == 0 || (!stalled && d.Limiter.Limit() > 0) {
+		return false
+	}
+	allUrls := append([]string{d.Url}, d.FallbackUrls...)
+	var target string
+	if stalled {
+		for _, rawUrl := range Mirrors.Rank(d.ctx, allUrls) {
+			if mirrorHost(rawUrl) != mirrorHost(current) {
+				target = rawUrl
+				break
+			}
+		}
+	} else if faster, ok := Mirrors.Faster(current, allUrls); ok {
+		target = faster
+	}
+	if target == "" || !d.isSameRemoteFile(target) {
+		return false
+	}
+
+	d.urlMu.Lock()
+	defer d.urlMu.Unlock()
+	if d.mirrorSwitches >= len(allUrls) {
+		return false
+	}
+	for index, rawUrl := range allUrls {
+		if rawUrl == target {
+			fmt.Printf("Switching download from %s to the faster mirror %s\n", current, target)
+			d.urlIndex = index
+			d.mirrorSwitches++
+			return true
+		}
+	}
+	return false
+}
+
 func (d *Download) retryAction(retries int, err error, progressCtx context.Context, contextCancel context.CancelFunc) error {
 	currentUrl := d.getCurrentUrl()
 
+	// do not retry paused or canceled downloads
+	if d.ctx.Err() != nil {
+		return d.ctx.Err()
+	}
+
 	if retries > 0 {
 		fmt.Printf("Error downloading %s: %s. Retrying in 1 seconds...\n", d.Url, err.Error())
-		time.Sleep(2 * time.Second)
+		select {
+		case <-time.After(2 * time.Second):
+		case <-d.ctx.Done():
+			return d.ctx.Err()
+		}
 		return d.downloadFileWithRetry(retries-1, progressCtx, contextCancel)
 	} else {
-		if d.urlIndex < len(d.FallbackUrls) {
+		Mirrors.RecordFailure(currentUrl)
+		if d.nextUrl() {
 			fmt.Printf("All retries for URL %s have failed. Trying the next fallback URL...\n", currentUrl)
-			d.urlIndex++
 			return d.downloadFileWithRetry(d.maxRetries, progressCtx, contextCancel)
 		} else {
 			fmt.Printf("All retries for URL %s and all fallback URLs have failed.\n", currentUrl)
@@ -232,18 +339,18 @@ func (d *Download) downloadFullFile(url string) error {
 	}
 	defer out.Close()
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(d.ctx, "GET", url, nil)
 	if err != nil {
 		return err
 	}
 	req.Header.Set("User-Agent", d.getUserAgent())
-	resp, err := netClient.Do(req)
+	resp, err := HTTPClient().Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	_, err = io.Copy(out, resp.Body)
+	_, err = io.Copy(out, d.Limiter.Reader(d.ctx, resp.Body))
 	if err != nil {
 		return err
 	}
@@ -287,6 +394,13 @@ func (d *Download) downloadFileWithRetry(retries int, progressCtx context.Contex
 		// Set ResumeSupport to true if the file download is resumed and the server supports resuming
 		d.isResumed = startBytes > 0 && d.serverResumeSupport
 
+		// nothing left to download
+		if startBytes == totalSize {
+			d.WriteCounter.Total = uint64(startBytes)
+			d.addBytes(0)
+			return nil
+		}
+
 		// Create the file without overwriting it
 		out, err := os.OpenFile(d.Filepath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
 		if err != nil {
@@ -325,12 +439,12 @@ func (d *Download) downloadFileWithRetry(retries int, progressCtx context.Contex
 					default:
 						chunkIndex := atomic.AddInt64(&startingChunk, 1) - 1
 						if chunkIndex >= int64(totalChunks) {
-							break
+							return
 						}
 
 						remaining := atomic.AddInt64(&remainingChunks, -1)
 						if remaining < 0 {
-							break
+							return
 						}
 
 						start := chunkIndex * d.ChunkSize // Updated start calculation
@@ -348,13 +462,25 @@ func (d *Download) downloadFileWithRetry(retries int, progressCtx context.Contex
 						}
 
 						chunk, downloaded, err := d.downloadChunk(currentUrl, start, end)
+						if errors.Is(err, errMirrorStalled) && d.switchToFasterMirror(currentUrl, true) {
+							// retry the chunk on another mirror
+							Mirrors.RecordFailure(currentUrl)
+							currentUrl = d.getCurrentUrl()
+							chunk, downloaded, err = d.downloadChunk(currentUrl, start, end)
+						} else if err == nil && !d.UseMultiServerDownload {
+							d.switchToFasterMirror(currentUrl, false)
+						}
 						if err != nil {
 							errorsChannel <- err
 							return
 						}
 
 						if downloaded {
-							chunksChannel <- *chunk
+							select {
+							case chunksChannel <- *chunk:
+							case <-progressCtx.Done():
+								return
+							}
 						}
 					}
 				}
@@ -364,6 +490,9 @@ func (d *Download) downloadFileWithRetry(retries int, progressCtx context.Contex
 	loop:
 		for {
 			select {
+			case <-d.ctx.Done():
+				wg.Wait()
+				return d.ctx.Err()
 			case err := <-errorsChannel:
 				return d.retryAction(retries, err, progressCtx, contextCancel)
 			case chunk := <-chunksChannel:
@@ -465,19 +594,31 @@ func (d *Download) CreateFinishedFile(fileExtension string, maxRetries int, retr
 	return nil
 }
 
+// downloadChunk downloads a range of the file and measures the throughput of the mirror.
+// If no data is received for mirrorStallTimeout, errMirrorStalled is returned.
 func (d *Download) downloadChunk(url string, start, end int64) (*Chunk, bool, error) {
-	req, err := http.NewRequest("GET", url, nil)
+	ctx, cancel := context.WithCancelCause(d.ctx)
+	defer cancel(nil)
+	stallTimer := time.AfterFunc(mirrorStallTimeout, func() { cancel(errMirrorStalled) })
+	defer stallTimer.Stop()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, false, err
 	}
 	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
 	req.Header.Set("User-Agent", d.getUserAgent())
 
-	resp, err := netClient.Do(req)
+	startTime := time.Now()
+	resp, err := HTTPClient().Do(req)
 	if err != nil {
+		if errors.Is(context.Cause(ctx), errMirrorStalled) {
+			return nil, false, errMirrorStalled
+		}
 		return nil, false, err
 	}
 	defer resp.Body.Close()
+	Mirrors.RecordLatency(url, time.Since(startTime))
 
 	if resp.StatusCode == http.StatusPartialContent {
 		d.serverResumeSupport = true
@@ -485,8 +626,18 @@ func (d *Download) downloadChunk(url string, start, end int64) (*Chunk, bool, er
 		return nil, false, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	data, err := ioutil.ReadAll(resp.Body)
+	// bandwidth limited downloads do not measure the speed of the mirror
+	var onRead func(n int)
+	if d.Limiter.Limit() <= 0 {
+		onRead = func(n int) {
+			d.throughput.add(url, n)
+		}
+	}
+	data, err := ioutil.ReadAll(d.Limiter.Reader(ctx, &stallReader{reader: resp.Body, timer: stallTimer, onRead: onRead}))
 	if err != nil {
+		if errors.Is(context.Cause(ctx), errMirrorStalled) {
+			return nil, false, errMirrorStalled
+		}
 		return nil, false, err
 	}
 
@@ -496,6 +647,24 @@ func (d *Download) downloadChunk(url string, start, end int64) (*Chunk, bool, er
 	}, true, nil
 }
 
+// stallReader restarts the stall timer whenever data is received and reports the received bytes to onRead (optional).
+type stallReader struct {
+	reader io.Reader
+	timer  *time.Timer
+	onRead func(n int)
+}
+
+func (r *stallReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.timer.Reset(mirrorStallTimeout)
+		if r.onRead != nil {
+			r.onRead(n)
+		}
+	}
+	return n, err
+}
+
 func (d *Download) getFileSize(filepath string) int64 {
 	file, err := os.Open(filepath)
 	if err != nil {
@@ -0,0 +1,153 @@
+package Updater
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/go-cleanhttp"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+	"whispering-tiger-ui/Utilities"
+)
+
+// All HTTP requests (updates, manifests, models and plugins) use the client of HTTPClient, so proxy, CA and timeout
+// settings apply everywhere. The websocket connection to the local backend does not use it.
+
+const DefaultNetworkTimeout = 30 * time.Second
+
+// NetworkSettings configure the HTTP client.
+type NetworkSettings struct {
+	// ProxyUrl is a http://, https:// or socks5:// proxy. If empty, the proxy of the environment is used.
+	ProxyUrl      string
+	ProxyUsername string
+	ProxyPassword string
+	// CABundle is a PEM file with additional trusted certificate authorities
+	CABundle string
+	// Timeout limits connecting and waiting for the response headers (not the transfer of the body)
+	Timeout time.Duration
+	// UserAgent replaces the default user agent
+	UserAgent string
+}
+
+var (
+	networkMutex    sync.RWMutex
+	netClient       = &http.Client{Transport: &userAgentTransport{transport: cleanhttp.DefaultPooledTransport()}}
+	networkSettings NetworkSettings
+)
+
+// DefaultUserAgent returns the user agent which is sent if none is configured.
+func DefaultUserAgent() string {
+	return "Whispering_Tiger_DL/" + Utilities.AppVersion + " (" + Utilities.AppBuild + ")"
+}
+
+// UserAgent returns the user agent of all requests.
+func UserAgent() string {
+	networkMutex.RLock()
+	defer networkMutex.RUnlock()
+	if networkSettings.UserAgent != "" {
+		return networkSettings.UserAgent
+	}
+	return DefaultUserAgent()
+}
+
+// HTTPClient returns the configured client.
+func HTTPClient() *http.Client {
+	networkMutex.RLock()
+	defer networkMutex.RUnlock()
+	return netClient
+}
+
+// userAgentTransport sets the user agent of requests which have none.
+type userAgentTransport struct {
+	transport http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Header.Get("User-Agent") == "" {
+		request = request.Clone(request.Context())
+		request.Header.Set("User-Agent", UserAgent())
+	}
+	return t.transport.RoundTrip(request)
+}
+
+// proxyFunc returns the proxy of the settings (with the credentials) or the proxy of the environment.
+func (s NetworkSettings) proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	if s.ProxyUrl == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	proxyUrl, err := url.Parse(s.ProxyUrl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy url: %w", err)
+	}
+	switch proxyUrl.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q (use http, https or socks5)", proxyUrl.Scheme)
+	}
+	if proxyUrl.Host == "" {
+		return nil, errors.New("proxy url has no host")
+	}
+	if s.ProxyUsername != "" {
+		proxyUrl.User = url.UserPassword(s.ProxyUsername, s.ProxyPassword)
+	}
+	return http.ProxyURL(proxyUrl), nil
+}
+
+// rootCAs returns the system certificate authorities with the ones of the CA bundle added.
+func (s NetworkSettings) rootCAs() (*x509.CertPool, error) {
+	if s.CABundle == "" {
+		return nil, nil
+	}
+	pem, err := os.ReadFile(s.CABundle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("CA bundle contains no PEM certificates")
+	}
+	return pool, nil
+}
+
+// ConfigureNetwork replaces the HTTP client with one using the settings. If the settings are invalid, the client is
+// not changed.
+func ConfigureNetwork(settings NetworkSettings) error {
+	if settings.Timeout <= 0 {
+		settings.Timeout = DefaultNetworkTimeout
+	}
+	proxy, err := settings.proxyFunc()
+	if err != nil {
+		return err
+	}
+	rootCAs, err := settings.rootCAs()
+	if err != nil {
+		return err
+	}
+
+	transport := cleanhttp.DefaultPooledTransport()
+	transport.Proxy = proxy
+	transport.DialContext = (&net.Dialer{
+		Timeout:   settings.Timeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = settings.Timeout
+	transport.ResponseHeaderTimeout = settings.Timeout
+	if rootCAs != nil {
+		transport.TLSClientConfig = &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12}
+	}
+
+	networkMutex.Lock()
+	defer networkMutex.Unlock()
+	netClient.CloseIdleConnections()
+	netClient = &http.Client{Transport: &userAgentTransport{transport: transport}}
+	networkSettings = settings
+	return nil
+}
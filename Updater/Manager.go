@@ -0,0 +1,714 @@
+package Updater
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// All downloads (models, backend requested files and updates) run through the download manager,
+// which queues them by priority, limits how many run at the same time and their bandwidth
+// and keeps its state in a file, so unfinished downloads can be resumed after a restart.
+
+type DownloadState string
+
+const (
+	DownloadQueued     DownloadState = "queued"
+	DownloadRunning    DownloadState = "running"
+	DownloadVerifying  DownloadState = "verifying"
+	DownloadExtracting DownloadState = "extracting"
+	DownloadPaused     DownloadState = "paused"
+	DownloadFinished   DownloadState = "finished"
+	DownloadFailed     DownloadState = "failed"
+	DownloadCanceled   DownloadState = "canceled"
+)
+
+// download priorities, higher priorities are downloaded first
+const (
+	PriorityLow    = 0
+	PriorityNormal = 10
+	PriorityHigh   = 20
+)
+
+const (
+	defaultMaxConcurrentDownloads = 2
+	// number of finished, failed and canceled downloads which are kept in the list
+	maxDownloadHistory = 50
+)
+
+var ErrDownloadCanceled = errors.New("download canceled")
+var ErrDownloadPaused = errors.New("download paused")
+
+// DownloadRequest describes a file to download.
+type DownloadRequest struct {
+	Title string `json:"title"`
+	// Url is downloaded first (optional, the fastest mirror of Urls otherwise). Urls are used as fallback.
+	Url      string   `json:"url,omitempty"`
+	Urls     []string `json:"urls"`
+	Filepath string   `json:"filepath"`
+	Checksum string   `json:"checksum,omitempty"`
+	// ExtractFormat is "zip", "tar.gz", "tar.zst" or "7z". If empty, it is detected by the file name. "none" does not extract.
+	ExtractFormat string `json:"extract_format,omitempty"`
+	// ExtractedSize is the uncompressed size of the archive (from the manifest) used to check the free disk space
+	// before downloading. If it is unknown, the size of the archive is assumed.
+	ExtractedSize int64 `json:"extracted_size,omitempty"`
+	// FinishedMarker creates a file with the ".finished" extension after the download is complete
+	FinishedMarker bool `json:"finished_marker,omitempty"`
+	Priority       int  `json:"priority"`
+}
+
+// ExtractType returns how the download is extracted ("" if it is not extracted).
+func (r DownloadRequest) ExtractType() string {
+	switch r.ExtractFormat {
+	case "none":
+		return ""
+	case "":
+		if format := ArchiveFormat(r.Filepath); format != "" {
+			return format
+		}
+		return ArchiveFormat(r.firstUrl())
+	}
+	return r.ExtractFormat
+}
+
+func (r DownloadRequest) firstUrl() string {
+	if r.Url != "" {
+		return r.Url
+	}
+	if len(r.Urls) > 0 {
+		return r.Urls[0]
+	}
+	return ""
+}
+
+// DownloadItem is a download of the manager.
+type DownloadItem struct {
+	DownloadRequest
+	ID       string        `json:"id"`
+	State    DownloadState `json:"state"`
+	Error    string        `json:"error,omitempty"`
+	Progress uint64        `json:"progress"`
+	// Total is -1 while the size is unknown. While extracting, Progress and Total are the extracted bytes.
+	Total    int64     `json:"total"`
+	Added    time.Time `json:"added"`
+	Finished time.Time `json:"finished"`
+
+	Speed    float64 `json:"-"`
+	Resuming bool    `json:"-"`
+	// Source is the host the file is downloaded from
+	Source string `json:"-"`
+
+	sequence int64
+	cancel   context.CancelCauseFunc
+	done     chan struct{}
+	// paused is closed while the download is paused
+	paused   chan struct{}
+	err      error
+	onChange func(item DownloadItem)
+}
+
+// IsActive returns true if the download is running (including verifying and extracting).
+func (i DownloadItem) IsActive() bool {
+	return i.State == DownloadRunning || i.State == DownloadVerifying || i.State == DownloadExtracting
+}
+
+// IsDone returns true if the download does not continue anymore.
+func (i DownloadItem) IsDone() bool {
+	return i.State == DownloadFinished || i.State == DownloadFailed || i.State == DownloadCanceled
+}
+
+// SpeedString formats the download speed.
+func (i DownloadItem) SpeedString() string {
+	if i.Speed < 1024 {
+		return fmt.Sprintf("%.2f B/s", i.Speed)
+	} else if i.Speed < 1024*1024 {
+		return fmt.Sprintf("%.2f KiB/s", i.Speed/1024)
+	}
+	return fmt.Sprintf("%.2f MiB/s", i.Speed/(1024*1024))
+}
+
+type DownloadManager struct {
+	mutex         sync.Mutex
+	items         []*DownloadItem
+	sequence      int64
+	maxConcurrent int
+	Limiter       BandwidthLimiter
+	stateFile     string
+	listeners     map[string]func()
+	// OnEnqueued is called when a new download was added (e.g. to show the downloads)
+	OnEnqueued func(item DownloadItem)
+}
+
+var Downloads = &DownloadManager{
+	maxConcurrent: defaultMaxConcurrentDownloads,
+}
+
+// SetMaxConcurrent sets how many downloads run at the same time.
+func (m *DownloadManager) SetMaxConcurrent(maxConcurrent int) {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	m.mutex.Lock()
+	m.maxConcurrent = maxConcurrent
+	m.scheduleLocked()
+	m.mutex.Unlock()
+}
+
+func (m *DownloadManager) MaxConcurrent() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.maxConcurrent
+}
+
+// OnChanged registers a listener which is called whenever a download changed. The key replaces earlier listeners with the same key.
+func (m *DownloadManager) OnChanged(key string, listener func()) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.listeners == nil {
+		m.listeners = map[string]func(){}
+	}
+	if listener == nil {
+		delete(m.listeners, key)
+		return
+	}
+	m.listeners[key] = listener
+}
+
+// notify calls the listeners. Must not be called while the mutex is locked.
+func (m *DownloadManager) notify(item *DownloadItem) {
+	m.mutex.Lock()
+	listeners := make([]func(), 0, len(m.listeners))
+	for _, listener := range m.listeners {
+		listeners = append(listeners, listener)
+	}
+	var onChange func(item DownloadItem)
+	var itemCopy DownloadItem
+	if item != nil {
+		onChange = item.onChange
+		itemCopy = *item
+	}
+	m.mutex.Unlock()
+
+	if onChange != nil {
+		onChange(itemCopy)
+	}
+	for _, listener := range listeners {
+		listener()
+	}
+}
+
+// Items returns a copy of all downloads, ordered by the time they were added.
+func (m *DownloadManager) Items() []DownloadItem {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	items := make([]DownloadItem, 0, len(m.items))
+	for _, item := range m.items {
+		items = append(items, *item)
+	}
+	return items
+}
+
+func (m *DownloadManager) findLocked(id string) *DownloadItem {
+	for _, item := range m.items {
+		if item.ID == id {
+			return item
+		}
+	}
+	return nil
+}
+
+// Enqueue adds a download to the queue and returns its id.
+// If the same file is already downloading, the existing download is returned instead.
+// onChange is called whenever the state or progress of the download changed (can be nil).
+func (m *DownloadManager) Enqueue(request DownloadRequest, onChange func(item DownloadItem)) string {
+	m.mutex.Lock()
+	for index, item := range m.items {
+		if item.Filepath != request.Filepath {
+			continue
+		}
+		if !item.IsDone() {
+			if request.Priority > item.Priority {
+				item.Priority = request.Priority
+			}
+			if onChange != nil {
+				item.onChange = onChange
+			}
+			id := item.ID
+			m.mutex.Unlock()
+			return id
+		}
+		// replace the old download of the file
+		m.items = append(m.items[:index], m.items[index+1:]...)
+		break
+	}
+
+	m.sequence++
+	item := &DownloadItem{
+		DownloadRequest: request,
+		ID:              strconv.FormatInt(time.Now().UnixNano(), 36) + strconv.FormatInt(m.sequence, 36),
+		State:           DownloadQueued,
+		Total:           -1,
+		Added:           time.Now(),
+		sequence:        m.sequence,
+		done:            make(chan struct{}),
+		paused:          make(chan struct{}),
+		onChange:        onChange,
+	}
+	m.items = append(m.items, item)
+	m.scheduleLocked()
+	m.saveLocked()
+	itemCopy := *item
+	onEnqueued := m.OnEnqueued
+	m.mutex.Unlock()
+
+	if onEnqueued != nil {
+		onEnqueued(itemCopy)
+	}
+	m.notify(item)
+	return item.ID
+}
+
+// Wait blocks until the download is finished, failed, canceled or paused. ErrDownloadPaused is returned if the
+// download was paused (it can be resumed and waited for again).
+func (m *DownloadManager) Wait(id string) error {
+	m.mutex.Lock()
+	item := m.findLocked(id)
+	if item == nil {
+		m.mutex.Unlock()
+		return fmt.Errorf("unknown download %s", id)
+	}
+	done, paused := item.done, item.paused
+	m.mutex.Unlock()
+
+	select {
+	case <-done:
+	case <-paused:
+		return ErrDownloadPaused
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return item.err
+}
+
+// Download enqueues the request and waits until it is done.
+func (m *DownloadManager) Download(request DownloadRequest, onChange func(item DownloadItem)) error {
+	return m.Wait(m.Enqueue(request, onChange))
+}
+
+// Pause stops a queued or running download. The downloaded part is kept.
+func (m *DownloadManager) Pause(id string) {
+	m.mutex.Lock()
+	item := m.findLocked(id)
+	if item == nil || item.IsDone() || item.State == DownloadPaused {
+		m.mutex.Unlock()
+		return
+	}
+	if item.cancel != nil {
+		// the running download is set to paused when it stopped
+		item.cancel(ErrDownloadPaused)
+	} else {
+		m.pauseLocked(item)
+		m.saveLocked()
+	}
+	m.mutex.Unlock()
+	m.notify(item)
+}
+
+// Resume queues a paused download again. Failed downloads are retried.
+func (m *DownloadManager) Resume(id string) {
+	m.mutex.Lock()
+	item := m.findLocked(id)
+	if item == nil || (item.State != DownloadPaused && item.State != DownloadFailed) {
+		m.mutex.Unlock()
+		return
+	}
+	if item.State == DownloadFailed {
+		item.done = make(chan struct{})
+		item.err = nil
+		item.Error = ""
+	}
+	item.paused = make(chan struct{})
+	item.State = DownloadQueued
+	m.scheduleLocked()
+	m.saveLocked()
+	m.mutex.Unlock()
+	m.notify(item)
+}
+
+// Cancel stops a download and removes the downloaded part.
+func (m *DownloadManager) Cancel(id string) {
+	m.mutex.Lock()
+	item := m.findLocked(id)
+	if item == nil || item.IsDone() {
+		m.mutex.Unlock()
+		return
+	}
+	if item.cancel != nil {
+		// the running download is set to canceled when it stopped
+		item.cancel(ErrDownloadCanceled)
+		m.mutex.Unlock()
+		return
+	}
+	m.finishLocked(item, DownloadCanceled, ErrDownloadCanceled)
+	m.mutex.Unlock()
+	removePartialDownload(item.Filepath)
+	m.notify(item)
+}
+
+// SetPriority changes the priority of a download.
+func (m *DownloadManager) SetPriority(id string, priority int) {
+	m.mutex.Lock()
+	if item := m.findLocked(id); item != nil {
+		item.Priority = priority
+		m.saveLocked()
+	}
+	m.mutex.Unlock()
+	m.notify(nil)
+}
+
+// MoveToFront gives the download a higher priority than all other downloads.
+func (m *DownloadManager) MoveToFront(id string) {
+	m.mutex.Lock()
+	priority := PriorityHigh
+	for _, item := range m.items {
+		if item.ID != id && item.Priority >= priority {
+			priority = item.Priority + 1
+		}
+	}
+	m.mutex.Unlock()
+	m.SetPriority(id, priority)
+}
+
+// Remove removes a finished, failed or canceled download from the list.
+func (m *DownloadManager) Remove(id string) {
+	m.mutex.Lock()
+	for index, item := range m.items {
+		if item.ID == id && item.IsDone() {
+			m.items = append(m.items[:index], m.items[index+1:]...)
+			m.saveLocked()
+			break
+		}
+	}
+	m.mutex.Unlock()
+	m.notify(nil)
+}
+
+// ClearDone removes all finished, failed and canceled downloads from the list.
+func (m *DownloadManager) ClearDone() {
+	m.mutex.Lock()
+	var items []*DownloadItem
+	for _, item := range m.items {
+		if !item.IsDone() {
+			items = append(items, item)
+		}
+	}
+	m.items = items
+	m.saveLocked()
+	m.mutex.Unlock()
+	m.notify(nil)
+}
+
+// scheduleLocked starts the queued downloads with the highest priority while there are free download slots.
+func (m *DownloadManager) scheduleLocked() {
+	running := 0
+	var queued []*DownloadItem
+	for _, item := range m.items {
+		if item.IsActive() {
+			running++
+		} else if item.State == DownloadQueued {
+			queued = append(queued, item)
+		}
+	}
+	sort.SliceStable(queued, func(i, j int) bool {
+		if queued[i].Priority != queued[j].Priority {
+			return queued[i].Priority > queued[j].Priority
+		}
+		return queued[i].sequence < queued[j].sequence
+	})
+	for _, item := range queued {
+		if running >= m.maxConcurrent {
+			return
+		}
+		running++
+		ctx, cancel := context.WithCancelCause(context.Background())
+		item.cancel = cancel
+		item.State = DownloadRunning
+		go m.run(ctx, item)
+	}
+}
+
+// finishLocked sets the final state of a download and wakes up everyone waiting for it.
+func (m *DownloadManager) finishLocked(item *DownloadItem, state DownloadState, err error) {
+	item.State = state
+	item.err = err
+	item.Error = ""
+	if err != nil {
+		item.Error = err.Error()
+	}
+	item.Finished = time.Now()
+	item.cancel = nil
+	close(item.done)
+	m.trimHistoryLocked()
+	m.saveLocked()
+}
+
+func (m *DownloadManager) trimHistoryLocked() {
+	doneCount := 0
+	for _, item := range m.items {
+		if item.IsDone() {
+			doneCount++
+		}
+	}
+	var items []*DownloadItem
+	for _, item := range m.items {
+		if item.IsDone() && doneCount > maxDownloadHistory {
+			doneCount--
+			continue
+		}
+		items = append(items, item)
+	}
+	m.items = items
+}
+
+// pauseLocked sets the download to paused and wakes up everyone waiting for it.
+func (m *DownloadManager) pauseLocked(item *DownloadItem) {
+	item.State = DownloadPaused
+	select {
+	case <-item.paused:
+	default:
+		close(item.paused)
+	}
+}
+
+func (m *DownloadManager) setState(item *DownloadItem, state DownloadState) {
+	m.mutex.Lock()
+	item.State = state
+	m.mutex.Unlock()
+	m.notify(item)
+}
+
+func (m *DownloadManager) run(ctx context.Context, item *DownloadItem) {
+	m.mutex.Lock()
+	request := item.DownloadRequest
+	m.mutex.Unlock()
+	m.notify(item)
+
+	err := m.transfer(ctx, item, request)
+
+	removePartial := false
+	m.mutex.Lock()
+	switch {
+	case err == nil:
+		m.finishLocked(item, DownloadFinished, nil)
+	case errors.Is(context.Cause(ctx), ErrDownloadPaused):
+		m.pauseLocked(item)
+		item.cancel = nil
+		item.Speed = 0
+		m.saveLocked()
+	case errors.Is(context.Cause(ctx), ErrDownloadCanceled):
+		m.finishLocked(item, DownloadCanceled, ErrDownloadCanceled)
+		removePartial = true
+	default:
+		log.Printf("download of %s failed: %v", request.Title, err)
+		m.finishLocked(item, DownloadFailed, err)
+	}
+	m.scheduleLocked()
+	m.mutex.Unlock()
+	if removePartial {
+		removePartialDownload(request.Filepath)
+	}
+	m.notify(item)
+}
+
+// transfer downloads, verifies and extracts the file of a download.
+func (m *DownloadManager) transfer(ctx context.Context, item *DownloadItem, request DownloadRequest) error {
+	if len(request.Urls) == 0 && request.Url == "" {
+		return errors.New("no download url")
+	}
+	downloadTargetDir := filepath.Dir(request.Filepath)
+	if err := os.MkdirAll(downloadTargetDir, 0755); err != nil {
+		return err
+	}
+
+	// files of the offline source are copied instead of downloaded
+	if offlineFile, ok := Offline.Find(append([]string{request.Url}, request.Urls...), request.Checksum); ok {
+		log.Printf("using %s from the offline source", offlineFile)
+		var size int64
+		if stat, err := os.Stat(offlineFile); err == nil {
+			size = stat.Size()
+		}
+		m.mutex.Lock()
+		item.Source = "offline"
+		item.Total = size
+		m.mutex.Unlock()
+		m.notify(item)
+		if err := CheckFreeSpace(downloadTargetDir, request.requiredSpace(size, 0)); err != nil {
+			return err
+		}
+		if err := copyOfflineFile(offlineFile, request.Filepath); err != nil {
+			return err
+		}
+		m.mutex.Lock()
+		item.Progress = uint64(size)
+		m.mutex.Unlock()
+		return m.finishTransfer(item, request, &Download{Filepath: request.Filepath})
+	}
+
+	// the fastest mirror is downloaded from first, the others are the fallback
+	downloadUrl := request.Url
+	fallbackUrls := request.Urls
+	if downloadUrl == "" {
+		rankedUrls := Mirrors.Rank(ctx, request.Urls)
+		downloadUrl, fallbackUrls = rankedUrls[0], rankedUrls[1:]
+	}
+
+	downloader := Download{
+		Url:                 downloadUrl,
+		FallbackUrls:        fallbackUrls,
+		Filepath:            request.Filepath,
+		ConcurrentDownloads: 4,
+		ChunkSize:           15 * 1024 * 1024, // 15 MB
+		Limiter:             &m.Limiter,
+	}
+	downloader.Preflight = func(remoteSize int64) error {
+		return CheckFreeSpace(downloadTargetDir, request.requiredSpace(remoteSize, downloader.getFileSize(request.Filepath)))
+	}
+	downloader.WriteCounter.OnProgress = func(progress, total uint64, speed float64) {
+		m.mutex.Lock()
+		item.Progress = progress
+		item.Total = int64(total)
+		item.Speed = speed
+		item.Resuming = downloader.IsResuming()
+		item.Source = mirrorHost(downloader.CurrentUrl())
+		m.mutex.Unlock()
+		m.notify(item)
+	}
+	if err := downloader.DownloadFileContext(ctx, 3); err != nil {
+		return err
+	}
+	return m.finishTransfer(item, request, &downloader)
+}
+
+// requiredSpace returns the disk space needed to finish the download of an archive with archiveSize bytes
+// (-1 if unknown) when downloaded bytes of it are already on the disk.
+func (r DownloadRequest) requiredSpace(archiveSize, downloaded int64) int64 {
+	if archiveSize < 0 {
+		return r.ExtractedSize
+	}
+	required := archiveSize - max(downloaded, 0)
+	if r.ExtractedSize > 0 {
+		required += r.ExtractedSize
+	} else if r.ExtractType() != "" {
+		required += archiveSize
+	}
+	return required
+}
+
+// finishTransfer verifies and extracts the downloaded file of a download.
+func (m *DownloadManager) finishTransfer(item *DownloadItem, request DownloadRequest, downloader *Download) error {
+	downloadTargetDir := filepath.Dir(request.Filepath)
+
+	// check if the file has the correct hash
+	if request.Checksum != "" {
+		m.setState(item, DownloadVerifying)
+		if err := CheckFileHash(request.Filepath, request.Checksum); err != nil {
+			return fmt.Errorf("%w. Please delete the downloaded file and download again. If it still fails, please contact support", err)
+		}
+	}
+
+	if extractType := request.ExtractType(); extractType != "" {
+		m.mutex.Lock()
+		item.Progress, item.Total = 0, 0
+		m.mutex.Unlock()
+		m.setState(item, DownloadExtracting)
+		// wait a bit before trying to extract
+		time.Sleep(1 * time.Second)
+		err := Extract(request.Filepath, downloadTargetDir, extractType, request.ExtractedSize, func(progress ExtractProgress) {
+			m.mutex.Lock()
+			item.Progress = uint64(progress.BytesDone)
+			item.Total = progress.BytesTotal
+			m.mutex.Unlock()
+			m.notify(item)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if request.FinishedMarker {
+		return downloader.CreateFinishedFile(".finished", 5, 3*time.Second)
+	}
+	return nil
+}
+
+func removePartialDownload(fileName string) {
+	if err := os.Remove(fileName); err != nil && !os.IsNotExist(err) {
+		log.Printf("failed to remove canceled download %s: %v", fileName, err)
+	}
+}
+
+// saveLocked writes the state of all downloads to the state file (if it was restored from one).
+func (m *DownloadManager) saveLocked() {
+	if m.stateFile == "" {
+		return
+	}
+	data, err := json.MarshalIndent(m.items, "", "  ")
+	if err != nil {
+		log.Printf("failed to save download state: %v", err)
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(m.stateFile), 0755); err == nil {
+		err = os.WriteFile(m.stateFile, data, 0644)
+	}
+	if err != nil {
+		log.Printf("failed to save download state: %v", err)
+	}
+}
+
+// Restore loads the downloads of the last run from the state file. Unfinished downloads are restored as paused,
+// since nobody waits for them anymore to use the file (e.g. to install an update). They can be resumed by the user.
+// Changes are saved to the state file from now on.
+func (m *DownloadManager) Restore(stateFile string) error {
+	m.mutex.Lock()
+	defer m.notify(nil)
+	defer m.mutex.Unlock()
+
+	m.stateFile = stateFile
+	data, err := os.ReadFile(stateFile)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var items []*DownloadItem
+	if err = json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if m.findLocked(item.ID) != nil {
+			continue
+		}
+		m.sequence++
+		item.sequence = m.sequence
+		item.done = make(chan struct{})
+		item.paused = make(chan struct{})
+		if item.IsDone() {
+			close(item.done)
+			if item.Error != "" {
+				item.err = errors.New(item.Error)
+			}
+		} else {
+			m.pauseLocked(item)
+		}
+		m.items = append(m.items, item)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package Updater
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownloadRequestRequiredSpace(t *testing.T) {
+	tests := []struct {
+		name        string
+		request     DownloadRequest
+		archiveSize int64
+		downloaded  int64
+		want        int64
+	}{
+		{name: "file", request: DownloadRequest{Filepath: "model.bin"}, archiveSize: 100, want: 100},
+		{name: "resumed file", request: DownloadRequest{Filepath: "model.bin"}, archiveSize: 100, downloaded: 40, want: 60},
+		{name: "archive of unknown extracted size", request: DownloadRequest{Filepath: "model.zip"}, archiveSize: 100, downloaded: 40, want: 160},
+		{name: "archive of known extracted size", request: DownloadRequest{Filepath: "model.zip", ExtractedSize: 300}, archiveSize: 100, want: 400},
+		{name: "archive detected by url", request: DownloadRequest{Filepath: "model", Urls: []string{"https://example.com/model.tar.gz"}}, archiveSize: 100, want: 200},
+		{name: "archive not extracted", request: DownloadRequest{Filepath: "model.zip", ExtractFormat: "none"}, archiveSize: 100, want: 100},
+		{name: "unknown archive size", request: DownloadRequest{Filepath: "model.zip", ExtractedSize: 300}, archiveSize: -1, downloaded: 40, want: 300},
+		{name: "no downloaded file", request: DownloadRequest{Filepath: "model.bin"}, archiveSize: 100, downloaded: -1, want: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.request.requiredSpace(tt.archiveSize, tt.downloaded); got != tt.want {
+				t.Errorf("requiredSpace() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloadManagerRestore(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "downloads.json")
+	items := []*DownloadItem{
+		{ID: "queued", State: DownloadQueued},
+		{ID: "running", State: DownloadRunning},
+		{ID: "extracting", State: DownloadExtracting},
+		{ID: "finished", State: DownloadFinished},
+		{ID: "failed", State: DownloadFailed, Error: "connection lost"},
+	}
+	data, err := json.Marshal(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(stateFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := &DownloadManager{maxConcurrent: defaultMaxConcurrentDownloads}
+	if err = manager.Restore(stateFile); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	// restoring again must not add the downloads twice
+	if err = manager.Restore(stateFile); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if got := len(manager.Items()); got != len(items) {
+		t.Fatalf("Restore() restored %d downloads, want %d", got, len(items))
+	}
+
+	tests := []struct {
+		id        string
+		wantState DownloadState
+		wantErr   error
+		wantError string
+	}{
+		{id: "queued", wantState: DownloadPaused, wantErr: ErrDownloadPaused},
+		{id: "running", wantState: DownloadPaused, wantErr: ErrDownloadPaused},
+		{id: "extracting", wantState: DownloadPaused, wantErr: ErrDownloadPaused},
+		{id: "finished", wantState: DownloadFinished},
+		{id: "failed", wantState: DownloadFailed, wantError: "connection lost"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			manager.mutex.Lock()
+			state := manager.findLocked(tt.id).State
+			manager.mutex.Unlock()
+			if state != tt.wantState {
+				t.Errorf("state = %s, want %s", state, tt.wantState)
+			}
+			err := manager.Wait(tt.id)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Wait() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantError != "":
+				if err == nil || err.Error() != tt.wantError {
+					t.Errorf("Wait() error = %v, want %q", err, tt.wantError)
+				}
+			case err != nil:
+				t.Errorf("Wait() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestDownloadManagerRestoreMissingFile(t *testing.T) {
+	manager := &DownloadManager{maxConcurrent: defaultMaxConcurrentDownloads}
+	if err := manager.Restore(filepath.Join(t.TempDir(), "downloads.json")); err != nil {
+		t.Errorf("Restore() error = %v", err)
+	}
+	if items := manager.Items(); len(items) != 0 {
+		t.Errorf("Restore() restored %d downloads from a missing file", len(items))
+	}
+}
@@ -0,0 +1,337 @@
+package Updater
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Mirrors are ranked by their measured latency and throughput. The measurements of every host are updated by probes
+// (HEAD request and a small range request) and by the downloaded chunks and are remembered across sessions.
+
+// mirrorProbeSize is the size of the range request of a probe
+const mirrorProbeSize = 256 * 1024
+
+const mirrorProbeTimeout = 5 * time.Second
+
+// mirrorProbeInterval is the time after which a host is probed again
+const mirrorProbeInterval = 6 * time.Hour
+
+// mirrorFailurePenalty is the time a failed host is ranked last
+const mirrorFailurePenalty = 10 * time.Minute
+
+// slowMirrorFactor switches to another mirror if the current one is slower than this factor of the other one
+const slowMirrorFactor = 0.33
+
+// mirrorStallTimeout cancels a chunk if no data was received for this time
+const mirrorStallTimeout = 30 * time.Second
+
+// mirrorThroughputWindow is the time over which the received bytes of all connections to a host are measured
+const mirrorThroughputWindow = 2 * time.Second
+
+// mirrorSaveDelay collects the measurements of this time into one write of the state file
+const mirrorSaveDelay = 5 * time.Second
+
+var errMirrorStalled = fmt.Errorf("no data received for %s", mirrorStallTimeout)
+
+// MirrorStat is the measured performance of a host.
+type MirrorStat struct {
+	// Latency is the moving average of the response time
+	Latency time.Duration `json:"latency"`
+	// Throughput is the moving average in bytes per second
+	Throughput  float64   `json:"throughput"`
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"last_failure,omitempty"`
+	LastProbe   time.Time `json:"last_probe,omitempty"`
+}
+
+type MirrorStats struct {
+	mutex     sync.Mutex
+	hosts     map[string]*MirrorStat
+	stateFile string
+	saveTimer *time.Timer
+	// saveMutex serializes the writes of the state file
+	saveMutex sync.Mutex
+}
+
+var Mirrors = &MirrorStats{hosts: map[string]*MirrorStat{}}
+
+func mirrorHost(rawUrl string) string {
+	parsedUrl, err := url.Parse(rawUrl)
+	if err != nil {
+		return rawUrl
+	}
+	return parsedUrl.Host
+}
+
+// movingAverage weights the new value with a quarter.
+func movingAverage(old, value float64) float64 {
+	if old <= 0 {
+		return value
+	}
+	return old*0.75 + value*0.25
+}
+
+func (s *MirrorStats) statLocked(host string) *MirrorStat {
+	stat, ok := s.hosts[host]
+	if !ok {
+		stat = &MirrorStat{}
+		s.hosts[host] = stat
+	}
+	return stat
+}
+
+// Stat returns the measured performance of the host of rawUrl.
+func (s *MirrorStats) Stat(rawUrl string) (MirrorStat, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	stat, ok := s.hosts[mirrorHost(rawUrl)]
+	if !ok {
+		return MirrorStat{}, false
+	}
+	return *stat, true
+}
+
+// RecordLatency updates the latency of the host of rawUrl.
+func (s *MirrorStats) RecordLatency(rawUrl string, latency time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	stat := s.statLocked(mirrorHost(rawUrl))
+	stat.Latency = time.Duration(movingAverage(float64(stat.Latency), float64(latency)))
+}
+
+// RecordTransfer updates the throughput of the host of rawUrl with the bytes received (over all connections) in
+// duration. Small transfers are ignored, since they mostly measure the latency.
+func (s *MirrorStats) RecordTransfer(rawUrl string, bytes int64, duration time.Duration) {
+	if bytes < mirrorProbeSize/2 || duration <= 0 {
+		return
+	}
+	s.mutex.Lock()
+	stat := s.statLocked(mirrorHost(rawUrl))
+	stat.Throughput = movingAverage(stat.Throughput, float64(bytes)/duration.Seconds())
+	stat.Failures = 0
+	s.mutex.Unlock()
+	s.scheduleSave()
+}
+
+// RecordFailure marks the host of rawUrl as failed, so it is ranked last for a while.
+func (s *MirrorStats) RecordFailure(rawUrl string) {
+	s.mutex.Lock()
+	stat := s.statLocked(mirrorHost(rawUrl))
+	stat.Failures++
+	stat.LastFailure = time.Now()
+	s.mutex.Unlock()
+	s.scheduleSave()
+}
+
+// lessLocked reports if host a is ranked before host b.
+func (s *MirrorStats) lessLocked(a, b string) bool {
+	statA, statB := s.statLocked(a), s.statLocked(b)
+	failedA := time.Since(statA.LastFailure) < mirrorFailurePenalty
+	failedB := time.Since(statB.LastFailure) < mirrorFailurePenalty
+	if failedA != failedB {
+		return failedB
+	}
+	if statA.Throughput != statB.Throughput {
+		return statA.Throughput > statB.Throughput
+	}
+	if statA.Latency > 0 && statB.Latency > 0 {
+		return statA.Latency < statB.Latency
+	}
+	return statA.Latency > 0
+}
+
+// probe measures the latency (HEAD request) and throughput (small range request) of a url.
+func (s *MirrorStats) probe(ctx context.Context, rawUrl string) {
+	ctx, cancel := context.WithTimeout(ctx, mirrorProbeTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, "HEAD", rawUrl, nil)
+	if err != nil {
+		return
+	}
+	startTime := time.Now()
+	response, err := HTTPClient().Do(request)
+	if err != nil {
+		if ctx.Err() == nil || ctx.Err() == context.DeadlineExceeded {
+			s.RecordFailure(rawUrl)
+		}
+		return
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		s.RecordFailure(rawUrl)
+		return
+	}
+	s.RecordLatency(rawUrl, time.Since(startTime))
+
+	request, err = http.NewRequestWithContext(ctx, "GET", rawUrl, nil)
+	if err != nil {
+		return
+	}
+	request.Header.Set("Range", fmt.Sprintf("bytes=0-%d", mirrorProbeSize-1))
+	startTime = time.Now()
+	response, err = HTTPClient().Do(request)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+	n, _ := io.Copy(io.Discard, io.LimitReader(response.Body, mirrorProbeSize))
+	s.RecordTransfer(rawUrl, n, time.Since(startTime))
+}
+
+// Rank returns the urls ordered from the fastest to the slowest host. Hosts without recent measurements are
+// probed first (in parallel).
+func (s *MirrorStats) Rank(ctx context.Context, urls []string) []string {
+	if len(urls) < 2 {
+		return urls
+	}
+	var wg sync.WaitGroup
+	probed := map[string]bool{}
+	s.mutex.Lock()
+	for _, rawUrl := range urls {
+		host := mirrorHost(rawUrl)
+		stat := s.statLocked(host)
+		if probed[host] || time.Since(stat.LastProbe) < mirrorProbeInterval {
+			continue
+		}
+		probed[host] = true
+		stat.LastProbe = time.Now()
+		wg.Add(1)
+		go func(rawUrl string) {
+			defer wg.Done()
+			s.probe(ctx, rawUrl)
+		}(rawUrl)
+	}
+	s.mutex.Unlock()
+	wg.Wait()
+	if len(probed) > 0 {
+		s.scheduleSave()
+	}
+
+	ranked := append([]string{}, urls...)
+	s.mutex.Lock()
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return s.lessLocked(mirrorHost(ranked[i]), mirrorHost(ranked[j]))
+	})
+	s.mutex.Unlock()
+	return ranked
+}
+
+// Faster returns the url of urls which is considerably faster than current, if there is one.
+func (s *MirrorStats) Faster(current string, urls []string) (string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	currentHost := mirrorHost(current)
+	currentStat := s.statLocked(currentHost)
+	best, bestThroughput := "", currentStat.Throughput/slowMirrorFactor
+	for _, rawUrl := range urls {
+		host := mirrorHost(rawUrl)
+		stat := s.statLocked(host)
+		if host == currentHost || time.Since(stat.LastFailure) < mirrorFailurePenalty {
+			continue
+		}
+		if stat.Throughput > bestThroughput {
+			best, bestThroughput = rawUrl, stat.Throughput
+		}
+	}
+	return best, best != "" && currentStat.Throughput > 0
+}
+
+// scheduleSave writes the state file after mirrorSaveDelay, so the measurements of many chunks are written at once.
+func (s *MirrorStats) scheduleSave() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.stateFile == "" || s.saveTimer != nil {
+		return
+	}
+	s.saveTimer = time.AfterFunc(mirrorSaveDelay, s.Save)
+}
+
+// Save writes the measurements to the state file (if it was restored from one).
+func (s *MirrorStats) Save() {
+	s.saveMutex.Lock()
+	defer s.saveMutex.Unlock()
+
+	s.mutex.Lock()
+	if s.saveTimer != nil {
+		s.saveTimer.Stop()
+		s.saveTimer = nil
+	}
+	stateFile := s.stateFile
+	data, err := json.MarshalIndent(s.hosts, "", "  ")
+	s.mutex.Unlock()
+	if stateFile == "" || err != nil {
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(stateFile), 0755); err != nil {
+		return
+	}
+	tmpFile := stateFile + ".tmp"
+	if err = os.WriteFile(tmpFile, data, 0644); err == nil {
+		err = os.Rename(tmpFile, stateFile)
+	}
+	if err != nil {
+		log.Printf("failed to save mirror measurements: %v", err)
+	}
+}
+
+// throughputMeter measures the throughput of a host over all concurrent connections of a download.
+type throughputMeter struct {
+	mutex   sync.Mutex
+	rawUrl  string
+	bytes   int64
+	started time.Time
+}
+
+// add counts n received bytes from rawUrl and records the throughput of the host every mirrorThroughputWindow.
+func (m *throughputMeter) add(rawUrl string, n int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	now := time.Now()
+	if mirrorHost(rawUrl) != mirrorHost(m.rawUrl) || m.started.IsZero() {
+		m.rawUrl, m.bytes, m.started = rawUrl, 0, now
+	}
+	m.bytes += int64(n)
+	if elapsed := now.Sub(m.started); elapsed >= mirrorThroughputWindow {
+		Mirrors.RecordTransfer(m.rawUrl, m.bytes, elapsed)
+		m.bytes, m.started = 0, now
+	}
+}
+
+// reset starts a new measurement (e.g. when the download was paused).
+func (m *throughputMeter) reset() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.rawUrl, m.bytes, m.started = "", 0, time.Time{}
+}
+
+// Restore loads the measurements of the last sessions from stateFile, which is also used to save them.
+func (s *MirrorStats) Restore(stateFile string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.stateFile = stateFile
+	data, err := os.ReadFile(stateFile)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	hosts := map[string]*MirrorStat{}
+	if err = json.Unmarshal(data, &hosts); err != nil {
+		return err
+	}
+	for host, stat := range hosts {
+		s.hosts[host] = stat
+	}
+	return nil
+}
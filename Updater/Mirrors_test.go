@@ -0,0 +1,137 @@
+package Updater
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestMirrors returns mirror stats with the measurements by host. The hosts count as recently probed.
+func newTestMirrors(stats map[string]MirrorStat) *MirrorStats {
+	mirrors := &MirrorStats{hosts: map[string]*MirrorStat{}}
+	for host, stat := range stats {
+		stat.LastProbe = time.Now()
+		mirrors.hosts[host] = &stat
+	}
+	return mirrors
+}
+
+func TestMirrorStatsRank(t *testing.T) {
+	urls := []string{"https://a.example/file.zip", "https://b.example/file.zip", "https://c.example/file.zip"}
+
+	tests := []struct {
+		name  string
+		stats map[string]MirrorStat
+		want  []string
+	}{
+		{
+			name:  "throughput",
+			stats: map[string]MirrorStat{"a.example": {Throughput: 10}, "b.example": {Throughput: 30}, "c.example": {Throughput: 20}},
+			want:  []string{urls[1], urls[2], urls[0]},
+		},
+		{
+			name:  "latency if the throughput is equal",
+			stats: map[string]MirrorStat{"a.example": {Latency: 300}, "b.example": {Latency: 100}, "c.example": {Latency: 200}},
+			want:  []string{urls[1], urls[2], urls[0]},
+		},
+		{
+			name:  "measured latency before unknown latency",
+			stats: map[string]MirrorStat{"a.example": {}, "b.example": {}, "c.example": {Latency: 200}},
+			want:  []string{urls[2], urls[0], urls[1]},
+		},
+		{
+			name:  "recent failure last",
+			stats: map[string]MirrorStat{"a.example": {Throughput: 30, LastFailure: time.Now()}, "b.example": {Throughput: 10}, "c.example": {Throughput: 20}},
+			want:  []string{urls[2], urls[1], urls[0]},
+		},
+		{
+			name:  "old failure ignored",
+			stats: map[string]MirrorStat{"a.example": {Throughput: 30, LastFailure: time.Now().Add(-2 * mirrorFailurePenalty)}, "b.example": {Throughput: 10}, "c.example": {Throughput: 20}},
+			want:  []string{urls[0], urls[2], urls[1]},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newTestMirrors(tt.stats).Rank(context.Background(), urls)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Rank() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMirrorStatsFaster(t *testing.T) {
+	current := "https://a.example/file.zip"
+	urls := []string{current, "https://b.example/file.zip"}
+
+	tests := []struct {
+		name   string
+		stats  map[string]MirrorStat
+		want   string
+		wantOk bool
+	}{
+		{name: "considerably faster", stats: map[string]MirrorStat{"a.example": {Throughput: 10}, "b.example": {Throughput: 50}}, want: urls[1], wantOk: true},
+		{name: "slightly faster", stats: map[string]MirrorStat{"a.example": {Throughput: 10}, "b.example": {Throughput: 20}}},
+		{name: "current not measured", stats: map[string]MirrorStat{"b.example": {Throughput: 50}}, want: urls[1]},
+		{name: "faster but failed", stats: map[string]MirrorStat{"a.example": {Throughput: 10}, "b.example": {Throughput: 50, LastFailure: time.Now()}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := newTestMirrors(tt.stats).Faster(current, urls)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("Faster() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestMirrorStatsRecord(t *testing.T) {
+	rawUrl := "https://a.example/file.zip"
+	mirrors := newTestMirrors(nil)
+
+	mirrors.RecordLatency(rawUrl, 100*time.Millisecond)
+	mirrors.RecordLatency(rawUrl, 500*time.Millisecond)
+	// transfers smaller than half of a probe only measure the latency
+	mirrors.RecordTransfer(rawUrl, 1024, time.Second)
+	mirrors.RecordFailure(rawUrl)
+	mirrors.RecordTransfer(rawUrl, 1024*1024, time.Second)
+
+	stat, ok := mirrors.Stat("https://a.example/other.zip")
+	if !ok {
+		t.Fatal("Stat() found no measurements of the host")
+	}
+	if stat.Latency != 200*time.Millisecond {
+		t.Errorf("Latency = %v, want %v", stat.Latency, 200*time.Millisecond)
+	}
+	if stat.Throughput != 1024*1024 {
+		t.Errorf("Throughput = %v, want %v", stat.Throughput, 1024*1024)
+	}
+	if stat.Failures != 0 {
+		t.Errorf("Failures = %d, want 0 after a successful transfer", stat.Failures)
+	}
+	if _, ok = mirrors.Stat("https://b.example/file.zip"); ok {
+		t.Error("Stat() found measurements of an unknown host")
+	}
+}
+
+func TestMirrorStatsSaveRestore(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state", "mirrors.json")
+	mirrors := newTestMirrors(nil)
+	if err := mirrors.Restore(stateFile); err != nil {
+		t.Fatalf("Restore() of a missing file error = %v", err)
+	}
+	mirrors.RecordLatency("https://a.example/file.zip", time.Second)
+	mirrors.RecordFailure("https://a.example/file.zip")
+	mirrors.Save()
+
+	restored := newTestMirrors(nil)
+	if err := restored.Restore(stateFile); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	stat, ok := restored.Stat("https://a.example/file.zip")
+	if !ok || stat.Latency != time.Second || stat.Failures != 1 {
+		t.Errorf("restored stat = %+v, %v, want latency %v and 1 failure", stat, ok, time.Second)
+	}
+}
@@ -0,0 +1,195 @@
+package Updater
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// An offline source is a directory (or zip, tar.gz, tar.zst or 7z archive, e.g. on a USB drive) containing the files of the update manifest,
+// platform packages and models. Files are found by the file name of their download url and are only used if they
+// match the expected checksum. Manifests (like latest.yaml and its signature) are found by their file name and are
+// only used if they can not be downloaded, unless the source is preferred (like for an explicit offline install), since
+// a remembered bundle gets outdated.
+//
+//	bundle/latest.yaml
+//	bundle/latest.yaml.sig
+//	bundle/whispering-tiger_1.0.0.4_win.zip
+//	bundle/models/medium-ct2-fp16.zip
+
+// offlineArchiveDir is where an offline archive is extracted to
+var offlineArchiveDir = filepath.Join(".cache", "offline_bundle")
+
+type OfflineSource struct {
+	mutex  sync.Mutex
+	source string
+	dir    string
+	// files maps the lower case file names to their paths
+	files map[string][]string
+	// hashes caches the SHA256 of checked files
+	hashes map[string]string
+	// preferred sources are used for manifests before downloading them
+	preferred bool
+}
+
+var Offline = &OfflineSource{}
+
+// SetSource uses the directory or archive at source for downloads. An empty source disables it.
+func (o *OfflineSource) SetSource(source string) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.source, o.dir, o.files, o.hashes = "", "", nil, nil
+	if source == "" {
+		return nil
+	}
+	stat, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	dir := source
+	if !stat.IsDir() {
+		format := ArchiveFormat(source)
+		if format == "" {
+			return errors.New("offline source must be a directory or archive")
+		}
+		// the archive is only extracted again if it changed
+		marker := filepath.Join(offlineArchiveDir, ".offline_source")
+		sourceId := fmt.Sprintf("%s|%d|%d", source, stat.Size(), stat.ModTime().Unix())
+		if data, err := os.ReadFile(marker); err != nil || string(data) != sourceId {
+			if err = os.RemoveAll(offlineArchiveDir); err != nil {
+				return err
+			}
+			// the size of tar archives is unknown before extracting them, their compressed size is the minimum
+			if err = Extract(source, offlineArchiveDir, format, stat.Size(), nil); err != nil {
+				_ = os.RemoveAll(offlineArchiveDir)
+				return err
+			}
+			if err = os.WriteFile(marker, []byte(sourceId), 0644); err != nil {
+				return err
+			}
+		}
+		dir = offlineArchiveDir
+	}
+
+	files := map[string][]string{}
+	err = filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			name := strings.ToLower(entry.Name())
+			files[name] = append(files[name], filePath)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// prefer files closer to the root of the source
+	for _, paths := range files {
+		sort.SliceStable(paths, func(i, j int) bool {
+			return strings.Count(paths[i], string(os.PathSeparator)) < strings.Count(paths[j], string(os.PathSeparator))
+		})
+	}
+	o.source, o.dir, o.files, o.hashes = source, dir, files, map[string]string{}
+	return nil
+}
+
+// Source returns the configured directory or archive ("" if none).
+func (o *OfflineSource) Source() string {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.source
+}
+
+func (o *OfflineSource) Enabled() bool {
+	return o.Source() != ""
+}
+
+// SetPreferred uses the manifests of the offline source before downloading them.
+func (o *OfflineSource) SetPreferred(preferred bool) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.preferred = preferred
+}
+
+func (o *OfflineSource) Preferred() bool {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.preferred && o.source != ""
+}
+
+// urlFileName returns the unescaped file name of a download url.
+func urlFileName(rawUrl string) string {
+	urlPath := rawUrl
+	if parsedUrl, err := url.Parse(rawUrl); err == nil {
+		urlPath = parsedUrl.Path
+	}
+	return strings.ToLower(path.Base(urlPath))
+}
+
+func (o *OfflineSource) candidates(rawUrl string) []string {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return append([]string{}, o.files[urlFileName(rawUrl)]...)
+}
+
+// Find returns the file of the offline source for one of the urls which matches the checksum.
+// Files without a known checksum are never taken from the offline source.
+func (o *OfflineSource) Find(urls []string, checksum string) (string, bool) {
+	if !o.Enabled() || !IsValidChecksum(checksum) {
+		return "", false
+	}
+	for _, rawUrl := range urls {
+		for _, filePath := range o.candidates(rawUrl) {
+			o.mutex.Lock()
+			hash, ok := o.hashes[filePath]
+			o.mutex.Unlock()
+			if !ok {
+				hash = fileSHA256(filePath, -1)
+				o.mutex.Lock()
+				if o.hashes != nil {
+					o.hashes[filePath] = hash
+				}
+				o.mutex.Unlock()
+			}
+			if hash != "" && strings.EqualFold(hash, checksum) {
+				return filePath, true
+			}
+		}
+	}
+	return "", false
+}
+
+// ReadFile returns the content of the file with the file name of the url (used for manifests and signatures).
+func (o *OfflineSource) ReadFile(rawUrl string) ([]byte, bool) {
+	candidates := o.candidates(rawUrl)
+	if len(candidates) == 0 {
+		return nil, false
+	}
+	data, err := os.ReadFile(candidates[0])
+	return data, err == nil
+}
+
+// copyOfflineFile copies a file of the offline source to target (replacing a partial download).
+func copyOfflineFile(source, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	tmpFile := target + ".offline"
+	if err := copyFile(source, tmpFile); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpFile, target); err != nil {
+		_ = os.Remove(tmpFile)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package Updater
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Binary diffs of platform files are gzip compressed streams of operations, which build the new file from ranges of
+// the old file and new data:
+//
+//	"WTPATCH1"
+//	0x01 offset(uint64) length(uint64)  copy length bytes from offset of the old file
+//	0x02 length(uint64) data            write data
+//	0x00                                end of patch
+//
+// All numbers are big endian.
+
+const patchMagic = "WTPATCH1"
+
+const (
+	patchOpEnd  byte = 0x00
+	patchOpCopy byte = 0x01
+	patchOpData byte = 0x02
+)
+
+var ErrInvalidPatch = errors.New("invalid patch file")
+
+// ApplyPatch creates newFile from oldFile and the patch file.
+func ApplyPatch(oldFile, patchFile, newFile string) error {
+	oldReader, err := os.Open(oldFile)
+	if err != nil {
+		return err
+	}
+	defer oldReader.Close()
+
+	patchReader, err := os.Open(patchFile)
+	if err != nil {
+		return err
+	}
+	defer patchReader.Close()
+	patchStream, err := gzip.NewReader(patchReader)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	defer patchStream.Close()
+	patch := bufio.NewReader(patchStream)
+
+	magic := make([]byte, len(patchMagic))
+	if _, err = io.ReadFull(patch, magic); err != nil || string(magic) != patchMagic {
+		return ErrInvalidPatch
+	}
+
+	out, err := os.Create(newFile)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(out)
+	err = applyPatchOperations(oldReader, patch, writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(newFile)
+	}
+	return err
+}
+
+func applyPatchOperations(old io.ReaderAt, patch *bufio.Reader, writer io.Writer) error {
+	for {
+		op, err := patch.ReadByte()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		switch op {
+		case patchOpEnd:
+			return nil
+		case patchOpCopy:
+			var header [2]uint64
+			if err = binary.Read(patch, binary.BigEndian, &header); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+			}
+			if _, err = io.Copy(writer, io.NewSectionReader(old, int64(header[0]), int64(header[1]))); err != nil {
+				return err
+			}
+		case patchOpData:
+			var length uint64
+			if err = binary.Read(patch, binary.BigEndian, &length); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+			}
+			if written, err := io.CopyN(writer, patch, int64(length)); err != nil || written != int64(length) {
+				return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+			}
+		default:
+			return fmt.Errorf("%w: unknown operation %d", ErrInvalidPatch, op)
+		}
+	}
+}
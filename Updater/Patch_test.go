@@ -0,0 +1,111 @@
+package Updater
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// patchCopy and patchData build the operations of a patch.
+func patchCopy(offset, length uint64) []byte {
+	op := []byte{patchOpCopy}
+	op = binary.BigEndian.AppendUint64(op, offset)
+	return binary.BigEndian.AppendUint64(op, length)
+}
+
+func patchData(data string) []byte {
+	op := binary.BigEndian.AppendUint64([]byte{patchOpData}, uint64(len(data)))
+	return append(op, data...)
+}
+
+// writeTestPatch writes the gzip compressed patch content into a file.
+func writeTestPatch(t *testing.T, content ...[]byte) string {
+	t.Helper()
+	var buffer bytes.Buffer
+	gzw := gzip.NewWriter(&buffer)
+	for _, part := range content {
+		if _, err := gzw.Write(part); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "file.patch")
+	if err := os.WriteFile(file, buffer.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestApplyPatch(t *testing.T) {
+	const oldContent = "Hello old world!"
+	magic := []byte(patchMagic)
+	end := []byte{patchOpEnd}
+
+	tests := []struct {
+		name    string
+		patch   [][]byte
+		want    string
+		wantErr error
+	}{
+		{name: "copy and data", patch: [][]byte{magic, patchCopy(0, 6), patchData("new"), patchCopy(9, 7), end}, want: "Hello new world!"},
+		{name: "data only", patch: [][]byte{magic, patchData("replaced"), end}, want: "replaced"},
+		{name: "empty", patch: [][]byte{magic, end}, want: ""},
+		{name: "wrong magic", patch: [][]byte{[]byte("WTPATCH0"), end}, wantErr: ErrInvalidPatch},
+		{name: "missing end", patch: [][]byte{magic, patchData("new")}, wantErr: ErrInvalidPatch},
+		{name: "truncated data", patch: [][]byte{magic, patchData("new")[:10]}, wantErr: ErrInvalidPatch},
+		{name: "truncated copy", patch: [][]byte{magic, patchCopy(0, 6)[:5]}, wantErr: ErrInvalidPatch},
+		{name: "unknown operation", patch: [][]byte{magic, {0x7f}, end}, wantErr: ErrInvalidPatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			oldFile := filepath.Join(dir, "old")
+			newFile := filepath.Join(dir, "new")
+			if err := os.WriteFile(oldFile, []byte(oldContent), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := ApplyPatch(oldFile, writeTestPatch(t, tt.patch...), newFile)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ApplyPatch() error = %v, want %v", err, tt.wantErr)
+				}
+				if _, err = os.Stat(newFile); !os.IsNotExist(err) {
+					t.Error("new file was not removed after a failed patch")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyPatch() error = %v", err)
+			}
+			got, err := os.ReadFile(newFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("ApplyPatch() wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPatchNotGzip(t *testing.T) {
+	dir := t.TempDir()
+	oldFile := filepath.Join(dir, "old")
+	patchFile := filepath.Join(dir, "file.patch")
+	if err := os.WriteFile(oldFile, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(patchFile, []byte(patchMagic), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyPatch(oldFile, patchFile, filepath.Join(dir, "new")); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("ApplyPatch() error = %v, want %v", err, ErrInvalidPatch)
+	}
+}
@@ -0,0 +1,272 @@
+package Updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"whispering-tiger-ui/Utilities"
+)
+
+// Platform versions are extracted into their own directory below PlatformsDir. The active version is the
+// pointer (Path) in PlatformInfoFile, which is replaced atomically, so a failed update never breaks the install.
+//
+//	platforms/1.0.0.3/audioWhisper/audioWhisper.exe
+//	platforms/1.0.0.4/audioWhisper/audioWhisper.exe
+//	.current_platform.yaml (path: platforms/1.0.0.4)
+//
+// An empty Path is the old layout with the platform in the application directory (audioWhisper/).
+
+const PlatformInfoFile = ".current_platform.yaml"
+const PlatformsDir = "platforms"
+
+// platformHistoryLength is the number of installations which are kept in the history
+const platformHistoryLength = 10
+
+const platformSmokeTestTimeout = 2 * time.Minute
+
+type PlatformHistoryEntry struct {
+	Version   string    `yaml:"version"`
+	Path      string    `yaml:"path"`
+	SHA256    string    `yaml:"SHA256,omitempty"`
+	Installed time.Time `yaml:"installed"`
+	// Action is "install" or "rollback"
+	Action string `yaml:"action"`
+}
+
+// PlatformInfo is the content of PlatformInfoFile.
+type PlatformInfo struct {
+	UpdateInfo `yaml:",inline"`
+	// Path is the directory of the active platform (containing the audioWhisper directory)
+	Path string `yaml:"path,omitempty"`
+	// Previous is the directory of the previously active platform, which is kept for a rollback
+	PreviousPath    string `yaml:"previousPath,omitempty"`
+	PreviousVersion string `yaml:"previousVersion,omitempty"`
+	// History lists the installations and rollbacks (newest first)
+	History []PlatformHistoryEntry `yaml:"history,omitempty"`
+}
+
+// LoadPlatformInfo reads PlatformInfoFile. If it does not exist, an empty PlatformInfo is returned.
+func LoadPlatformInfo() (*PlatformInfo, error) {
+	info := &PlatformInfo{}
+	data, err := os.ReadFile(PlatformInfoFile)
+	if os.IsNotExist(err) {
+		return info, nil
+	} else if err != nil {
+		return info, err
+	}
+	if err = yaml.Unmarshal(data, info); err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+// Save replaces PlatformInfoFile atomically (written to a temporary file and renamed).
+func (p *PlatformInfo) Save() error {
+	data, err := yaml.Marshal(p)
+	if err != nil {
+		return err
+	}
+	tmpFile := PlatformInfoFile + ".tmp"
+	if err = os.WriteFile(tmpFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, PlatformInfoFile)
+}
+
+// CanRollback reports if a previous platform version is available.
+func (p *PlatformInfo) CanRollback() bool {
+	return p.PreviousVersion != "" && PlatformExists(p.PreviousPath)
+}
+
+func (p *PlatformInfo) addHistory(action string) {
+	p.History = append([]PlatformHistoryEntry{{
+		Version:   p.Version,
+		Path:      p.Path,
+		SHA256:    p.SHA256,
+		Installed: time.Now(),
+		Action:    action,
+	}}, p.History...)
+	if len(p.History) > platformHistoryLength {
+		p.History = p.History[:platformHistoryLength]
+	}
+}
+
+// ActivePlatformDir returns the directory of the active platform ("." for the application directory).
+func ActivePlatformDir() string {
+	info, err := LoadPlatformInfo()
+	if err != nil || info.Path == "" {
+		return "."
+	}
+	return info.Path
+}
+
+// PlatformCommand returns the executable and arguments to run the platform in dir and the virtual environment
+// it needs (if any).
+func PlatformCommand(dir string, arguments []string) (name string, args []string, virtualEnv string, err error) {
+	if dir == "" {
+		dir = "."
+	}
+	platformDir := filepath.Join(dir, "audioWhisper")
+	venvDir := filepath.Join(platformDir, "venv")
+	if Utilities.FileExists(filepath.Join(platformDir, "audioWhisper.exe")) {
+		return filepath.Join(platformDir, "audioWhisper.exe"), arguments, "", nil
+	} else if Utilities.FileExists(filepath.Join(platformDir, "audioWhisper")) { // Linux variant without file extension
+		return filepath.Join(platformDir, "audioWhisper"), arguments, "", nil
+	} else if Utilities.FileExists(filepath.Join(platformDir, "audioWhisper.py")) && Utilities.FileExists(filepath.Join(venvDir, "Scripts", "python.exe")) {
+		return filepath.Join(venvDir, "Scripts", "python.exe"), append([]string{"-u", filepath.Join(platformDir, "audioWhisper.py")}, arguments...), venvDir + string(os.PathSeparator), nil
+	} else if Utilities.FileExists(filepath.Join(platformDir, "audioWhisper.py")) && Utilities.FileExists(filepath.Join(venvDir, "Scripts", "python")) { // Linux variant without file extension
+		return filepath.Join(venvDir, "Scripts", "python"), append([]string{"-u", filepath.Join(platformDir, "audioWhisper.py")}, arguments...), venvDir + string(os.PathSeparator), nil
+	}
+	return "", nil, "", fmt.Errorf("no platform found in %s", platformDir)
+}
+
+// PlatformExists reports if dir contains a platform.
+func PlatformExists(dir string) bool {
+	_, _, _, err := PlatformCommand(dir, nil)
+	return err == nil
+}
+
+// validatePlatform checks that the expected files exist and that the platform runs (--version).
+func validatePlatform(dir string) error {
+	name, arguments, virtualEnv, err := PlatformCommand(dir, []string{"--version"})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), platformSmokeTestTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, name, arguments...)
+	Utilities.ProcessHideWindowAttr(cmd)
+	cmd.Env = append(os.Environ(), "PYTHONIOENCODING=UTF-8", "PYTHONUTF8=1")
+	if virtualEnv != "" {
+		cmd.Env = append(cmd.Env, "VIRTUAL_ENV="+virtualEnv)
+	}
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("platform smoke test failed: %w\n%s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+var unsafePathCharacters = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// newPlatformDir returns a directory below PlatformsDir for the version which is not in use.
+func newPlatformDir(version string, info *PlatformInfo) string {
+	name := unsafePathCharacters.ReplaceAllString(version, "_")
+	if name == "" || name == "." || name == ".." {
+		name = "platform"
+	}
+	dir := filepath.Join(PlatformsDir, name)
+	if dir == info.Path || dir == info.PreviousPath {
+		dir += "_" + strconv.FormatInt(time.Now().Unix(), 10)
+	}
+	return dir
+}
+
+// InstallPlatform extracts the platform archive into a new versioned directory, validates it and switches to it.
+// The previously active platform is kept for a rollback, older ones are removed.
+// If anything fails, the active platform is not changed. onProgress (optional) is called during the extraction.
+func InstallPlatform(archive string, packageInfo UpdateInfo, onProgress func(ExtractProgress)) error {
+	info, err := LoadPlatformInfo()
+	if err != nil {
+		return err
+	}
+
+	targetDir := newPlatformDir(packageInfo.Version, info)
+	stagingDir := targetDir + ".staging"
+	if err = os.RemoveAll(stagingDir); err != nil {
+		return err
+	}
+	if err = Extract(archive, stagingDir, packageInfo.ArchiveFormat(), packageInfo.ExtractedSize, onProgress); err != nil {
+		_ = os.RemoveAll(stagingDir)
+		return fmt.Errorf("failed to extract platform: %w", err)
+	}
+	return activatePlatform(info, stagingDir, targetDir, packageInfo)
+}
+
+// activatePlatform validates the platform in stagingDir, moves it to targetDir and switches to it.
+// The previously active platform is kept for a rollback, older ones are removed.
+func activatePlatform(info *PlatformInfo, stagingDir, targetDir string, packageInfo UpdateInfo) error {
+	err := validatePlatform(stagingDir)
+	if err != nil {
+		_ = os.RemoveAll(stagingDir)
+		return err
+	}
+	if err = os.RemoveAll(targetDir); err != nil {
+		_ = os.RemoveAll(stagingDir)
+		return err
+	}
+	if err = os.Rename(stagingDir, targetDir); err != nil {
+		_ = os.RemoveAll(stagingDir)
+		return err
+	}
+
+	// switch to the new version
+	oldPath, oldVersion := info.PreviousPath, info.PreviousVersion
+	if PlatformExists(info.Path) {
+		info.PreviousPath, info.PreviousVersion = info.Path, info.Version
+		if info.PreviousVersion == "" {
+			// platform of the old layout which was installed without version file
+			info.PreviousVersion = "unknown"
+		}
+	}
+	info.UpdateInfo = packageInfo
+	info.Path = targetDir
+	info.addHistory("install")
+	if err = info.Save(); err != nil {
+		_ = os.RemoveAll(targetDir)
+		return err
+	}
+
+	// only the previous version is kept
+	if oldVersion != "" && oldPath != info.PreviousPath && oldPath != info.Path {
+		removePlatform(oldPath)
+	}
+	return nil
+}
+
+// RollbackPlatform switches back to the previously active platform.
+func RollbackPlatform() error {
+	info, err := LoadPlatformInfo()
+	if err != nil {
+		return err
+	}
+	if !info.CanRollback() {
+		return errors.New("no previous platform version available")
+	}
+	var previousInfo UpdateInfo
+	for _, entry := range info.History {
+		if entry.Path == info.PreviousPath && entry.Version == info.PreviousVersion {
+			previousInfo = UpdateInfo{Version: entry.Version, SHA256: entry.SHA256}
+			break
+		}
+	}
+	if previousInfo.Version == "" {
+		previousInfo.Version = info.PreviousVersion
+	}
+
+	info.Path, info.PreviousPath = info.PreviousPath, info.Path
+	info.Version, info.PreviousVersion = previousInfo.Version, info.Version
+	info.SHA256 = previousInfo.SHA256
+	info.LocationUrls = nil
+	info.addHistory("rollback")
+	return info.Save()
+}
+
+// removePlatform removes a platform directory (the old layout is removed from the application directory).
+func removePlatform(dir string) {
+	if dir == "" || dir == "." {
+		dir = "audioWhisper"
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		log.Printf("failed to remove old platform %s: %v", dir, err)
+	}
+}
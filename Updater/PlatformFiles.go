@@ -0,0 +1,416 @@
+package Updater
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"whispering-tiger-ui/Utilities"
+)
+
+/* example files manifest
+version: 1.0.0.4
+baseUrls:
+  - https://eu2.someurl.com/ai_platform/1.0.0.4/
+files:
+  - path: audioWhisper/audioWhisper.exe
+    size: 12345678
+    SHA256: 0
+    mode: 0755
+    patches:
+      - fromSHA256: 0
+        path: patches/audioWhisper.exe.1.0.0.3.patch
+        SHA256: 0
+*/
+
+// platformFileWorkers is the number of files which are installed at the same time. The downloads are limited by the
+// download manager.
+const platformFileWorkers = 4
+
+// PlatformFilesManifest lists all files of a platform version with their hashes, so only changed files
+// have to be downloaded. Files and patches are downloaded from the BaseUrls.
+type PlatformFilesManifest struct {
+	Version  string         `yaml:"version"`
+	BaseUrls []string       `yaml:"baseUrls"`
+	Files    []PlatformFile `yaml:"files"`
+}
+
+type PlatformFile struct {
+	// Path is relative to the platform directory (using "/")
+	Path   string `yaml:"path"`
+	Size   int64  `yaml:"size"`
+	SHA256 string `yaml:"SHA256"`
+	Mode   uint32 `yaml:"mode,omitempty"`
+	// Patches are binary diffs to create the file from an older version of it
+	Patches []PlatformFilePatch `yaml:"patches,omitempty"`
+}
+
+type PlatformFilePatch struct {
+	FromSHA256 string `yaml:"fromSHA256"`
+	Path       string `yaml:"path"`
+	SHA256     string `yaml:"SHA256"`
+}
+
+// PlatformFilesProgress is the state of InstallPlatformFiles.
+type PlatformFilesProgress struct {
+	FilesDone  int
+	FilesTotal int
+	// BytesDownloaded counts the downloaded bytes of finished files (without patches and reused files)
+	BytesDownloaded int64
+	// Current is the file which was last started
+	Current string
+}
+
+func validPlatformFilePath(filePath string) bool {
+	if filePath == "" || strings.Contains(filePath, "\\") || path.IsAbs(filePath) {
+		return false
+	}
+	cleanPath := path.Clean(filePath)
+	return cleanPath == filePath && cleanPath != "." && cleanPath != ".." && !strings.HasPrefix(cleanPath, "../")
+}
+
+func (m *PlatformFilesManifest) validate() error {
+	if len(m.BaseUrls) == 0 {
+		return errors.New("files manifest has no base urls")
+	}
+	if len(m.Files) == 0 {
+		return errors.New("files manifest has no files")
+	}
+	for _, file := range m.Files {
+		if !validPlatformFilePath(file.Path) {
+			return fmt.Errorf("illegal file path in files manifest: %s", file.Path)
+		}
+		if !IsValidChecksum(file.SHA256) {
+			return fmt.Errorf("file %s has no valid SHA256 in files manifest", file.Path)
+		}
+		for _, patch := range file.Patches {
+			if !validPlatformFilePath(patch.Path) || !IsValidChecksum(patch.SHA256) || !IsValidChecksum(patch.FromSHA256) {
+				return fmt.Errorf("invalid patch for file %s in files manifest", file.Path)
+			}
+		}
+	}
+	return nil
+}
+
+// urls returns the download urls of a file (or patch) path for all base urls.
+func (m *PlatformFilesManifest) urls(filePath string) []string {
+	segments := strings.Split(filePath, "/")
+	for i := range segments {
+		segments[i] = url.PathEscape(segments[i])
+	}
+	escapedPath := strings.Join(segments, "/")
+	var urls []string
+	for _, baseUrl := range m.BaseUrls {
+		urls = append(urls, strings.TrimSuffix(baseUrl, "/")+"/"+escapedPath)
+	}
+	return urls
+}
+
+// GetFilesManifest downloads the files manifest of the package. Its hash is checked against the signed update
+// manifest.
+func (u *UpdatePackages) GetFilesManifest(packageName string) (*PlatformFilesManifest, error) {
+	packageInfo, ok := u.Packages[packageName]
+	if !ok || len(packageInfo.FilesManifestUrls) == 0 {
+		return nil, fmt.Errorf("package %s has no files manifest", packageName)
+	}
+	if !IsValidChecksum(packageInfo.FilesManifestSHA256) {
+		return nil, fmt.Errorf("package %s has no valid files manifest SHA256 in the signed update manifest", packageName)
+	}
+
+	var lastErr error
+	for _, manifestUrl := range packageInfo.FilesManifestUrls {
+		data, err := u.getYaml(manifestUrl)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		hash, _ := Utilities.FileHash(bytes.NewReader(data))
+		if !strings.EqualFold(hash, packageInfo.FilesManifestSHA256) {
+			lastErr = fmt.Errorf("files manifest hash does not match expected hash (calculated: %s, expected: %s)", hash, packageInfo.FilesManifestSHA256)
+			continue
+		}
+		manifest := &PlatformFilesManifest{}
+		if err = yaml.Unmarshal(data, manifest); err != nil {
+			return nil, err
+		}
+		if err = manifest.validate(); err != nil {
+			return nil, err
+		}
+		return manifest, nil
+	}
+	return nil, lastErr
+}
+
+// fileSHA256 returns the hash of the file or "" if it does not exist or has a different size.
+func fileSHA256(filePath string, size int64) string {
+	stat, err := os.Stat(filePath)
+	if err != nil || stat.IsDir() || (size >= 0 && stat.Size() != size) {
+		return ""
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	hash, err := Utilities.FileHash(file)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(hash)
+}
+
+// copyFile copies the file. It is not hard linked, since changing the mode of the copy would change the original.
+func copyFile(src, dst string) error {
+	_ = os.Remove(dst)
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		_ = os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
+
+// downloadPlatformFile downloads a file of the manifest with the download manager (resuming a partial download),
+// which also checks its hash. The download is paused or canceled when ctx is canceled with ErrDownloadPaused or
+// any other cause.
+func downloadPlatformFile(ctx context.Context, manifest *PlatformFilesManifest, filePath, checksum, target string) error {
+	id := Downloads.Enqueue(DownloadRequest{
+		Title:         path.Base(filePath) + " (" + manifest.Version + ")",
+		Urls:          manifest.urls(filePath),
+		Filepath:      target,
+		Checksum:      checksum,
+		ExtractFormat: "none",
+		Priority:      PriorityHigh,
+	}, nil)
+	stop := context.AfterFunc(ctx, func() {
+		if errors.Is(context.Cause(ctx), ErrDownloadPaused) {
+			Downloads.Pause(id)
+		} else {
+			Downloads.Cancel(id)
+		}
+	})
+	defer stop()
+	if err := Downloads.Wait(id); err != nil {
+		if ctx.Err() != nil && !errors.Is(err, ErrDownloadPaused) {
+			return context.Cause(ctx)
+		}
+		if !errors.Is(err, ErrDownloadPaused) {
+			// a file with a wrong hash would be resumed as complete by the next attempt
+			_ = os.Remove(target)
+		}
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+	return nil
+}
+
+// installPlatformFile puts one file of the manifest into the staging directory. It is reused from the active platform
+// if it did not change, patched if a binary diff for the active version exists and downloaded otherwise.
+// It returns the number of downloaded bytes of the full file.
+func installPlatformFile(ctx context.Context, manifest *PlatformFilesManifest, file PlatformFile, activeDir, stagingDir string) (int64, error) {
+	target := filepath.Join(stagingDir, filepath.FromSlash(file.Path))
+	checksum := strings.ToLower(file.SHA256)
+
+	// already staged by an interrupted update
+	if fileSHA256(target, file.Size) == checksum {
+		return 0, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return 0, err
+	}
+
+	activeFile := filepath.Join(activeDir, filepath.FromSlash(file.Path))
+	activeChecksum := fileSHA256(activeFile, -1)
+	if activeChecksum == checksum {
+		return 0, copyFile(activeFile, target)
+	}
+
+	if activeChecksum != "" {
+		for _, patch := range file.Patches {
+			if !strings.EqualFold(patch.FromSHA256, activeChecksum) {
+				continue
+			}
+			patchFile := target + ".patch"
+			err := downloadPlatformFile(ctx, manifest, patch.Path, patch.SHA256, patchFile)
+			if err == nil {
+				err = ApplyPatch(activeFile, patchFile, target)
+			}
+			_ = os.Remove(patchFile)
+			if err == nil && fileSHA256(target, file.Size) == checksum {
+				return 0, nil
+			}
+			if ctx.Err() != nil {
+				return 0, context.Cause(ctx)
+			}
+			if errors.Is(err, ErrDownloadPaused) {
+				return 0, err
+			}
+			// download the full file instead
+			log.Printf("failed to patch %s: %v", file.Path, err)
+			_ = os.Remove(target)
+			break
+		}
+	}
+
+	if file.Size == 0 {
+		// empty files are not written by the downloader
+		if err := os.WriteFile(target, nil, 0644); err != nil {
+			return 0, err
+		}
+		return 0, CheckFileHash(target, checksum)
+	}
+	if err := downloadPlatformFile(ctx, manifest, file.Path, checksum, target); err != nil {
+		return 0, err
+	}
+	return file.Size, nil
+}
+
+// cleanStagingDir removes all files and directories from the staging directory which are not part of the manifest,
+// so no stale files of an earlier attempt end up in the installed version. Listed files are kept, since their
+// hash is checked before they are reused and partial downloads are continued.
+func cleanStagingDir(stagingDir string, manifest *PlatformFilesManifest) error {
+	keep := map[string]bool{}
+	for _, file := range manifest.Files {
+		for filePath := file.Path; filePath != "."; filePath = path.Dir(filePath) {
+			keep[filePath] = true
+		}
+	}
+	var stale []string
+	err := filepath.WalkDir(stagingDir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && filePath == stagingDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		relativePath, err := filepath.Rel(stagingDir, filePath)
+		if err != nil || relativePath == "." {
+			return err
+		}
+		relativePath = filepath.ToSlash(relativePath)
+		// a listed directory which is a file now (or the other way round) is stale as well
+		if !keep[relativePath] || (entry.IsDir() && manifest.hasFile(relativePath)) {
+			stale = append(stale, filePath)
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, filePath := range stale {
+		if err = os.RemoveAll(filePath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *PlatformFilesManifest) hasFile(filePath string) bool {
+	for _, file := range m.Files {
+		if file.Path == filePath {
+			return true
+		}
+	}
+	return false
+}
+
+// InstallPlatformFiles installs the platform version of the files manifest. Unchanged files are taken from the active
+// platform, changed files are patched or downloaded (with the download manager) into a staging directory, which is
+// kept if the update is interrupted, so it can be continued. After all files are verified, the version is validated
+// and switched to like with InstallPlatform. If one of the downloads is paused, the others are paused as well and
+// ErrDownloadPaused is returned. Canceling ctx cancels the downloads.
+func InstallPlatformFiles(ctx context.Context, manifest *PlatformFilesManifest, packageInfo UpdateInfo, onProgress func(PlatformFilesProgress)) error {
+	info, err := LoadPlatformInfo()
+	if err != nil {
+		return err
+	}
+	activeDir := ActivePlatformDir()
+	if !PlatformExists(activeDir) {
+		activeDir = "."
+	}
+	targetDir := newPlatformDir(packageInfo.Version, info)
+	stagingDir := targetDir + ".staging"
+	if err = cleanStagingDir(stagingDir, manifest); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var mutex sync.Mutex
+	progress := PlatformFilesProgress{FilesTotal: len(manifest.Files)}
+	var firstErr error
+	files := make(chan PlatformFile)
+	var wg sync.WaitGroup
+	for i := 0; i < platformFileWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range files {
+				downloaded, err := installPlatformFile(ctx, manifest, file, activeDir, stagingDir)
+				if err == nil && file.Mode != 0 {
+					err = os.Chmod(filepath.Join(stagingDir, filepath.FromSlash(file.Path)), os.FileMode(file.Mode))
+				}
+				mutex.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = fmt.Errorf("failed to install %s: %w", file.Path, err)
+					if errors.Is(err, ErrDownloadPaused) {
+						cancel(ErrDownloadPaused)
+					} else {
+						cancel(err)
+					}
+				}
+				progress.FilesDone++
+				progress.BytesDownloaded += downloaded
+				currentProgress := progress
+				mutex.Unlock()
+				if onProgress != nil {
+					onProgress(currentProgress)
+				}
+			}
+		}()
+	}
+	for _, file := range manifest.Files {
+		select {
+		case files <- file:
+			mutex.Lock()
+			progress.Current = file.Path
+			mutex.Unlock()
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(files)
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+
+	return activatePlatform(info, stagingDir, targetDir, packageInfo)
+}
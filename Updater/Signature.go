@@ -0,0 +1,90 @@
+package Updater
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// UpdatePublicKeys are the base64 encoded ed25519 public keys of the release signing key (comma separated, to allow
+// key rotation). The update manifest and the model catalog are only accepted with a valid signature of one of them.
+// The keys are embedded at build time (build.bat and the release pipeline refuse to build without them):
+//
+//	go build -ldflags "-X whispering-tiger-ui/Updater.UpdatePublicKeys=<key>"
+//	GOFLAGS="-ldflags=-X=whispering-tiger-ui/Updater.UpdatePublicKeys=<key>" fyne package --release
+//
+// Builds without a key (like development builds) can not verify manifests and do not install updates.
+var UpdatePublicKeys = ""
+
+// SignatureExtension is appended to the manifest url to get its detached signature.
+const SignatureExtension = ".sig"
+
+var (
+	ErrInvalidSignature = errors.New("update manifest signature is invalid")
+	ErrNoUpdateKey      = errors.New("no update public key in this build, manifests can not be verified")
+)
+
+// TrustedUpdateKeys returns the parsed UpdatePublicKeys. Without a key, ErrNoUpdateKey is returned.
+func TrustedUpdateKeys() ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for _, encodedKey := range strings.Split(UpdatePublicKeys, ",") {
+		encodedKey = strings.TrimSpace(encodedKey)
+		if encodedKey == "" {
+			continue
+		}
+		key, err := ParsePublicKey(encodedKey)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, ErrNoUpdateKey
+	}
+	return keys, nil
+}
+
+func ParsePublicKey(encodedKey string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key length %d", len(key))
+	}
+	return key, nil
+}
+
+// SignManifest returns the base64 encoded detached signature of the manifest data.
+func SignManifest(privateKey ed25519.PrivateKey, data []byte) []byte {
+	return []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, data)))
+}
+
+// VerifyManifest checks the base64 encoded detached signature of the manifest data against the keys.
+func VerifyManifest(keys []ed25519.PublicKey, data, signature []byte) error {
+	decodedSignature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil || len(decodedSignature) != ed25519.SignatureSize {
+		return ErrInvalidSignature
+	}
+	for _, key := range keys {
+		if ed25519.Verify(key, data, decodedSignature) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// IsValidChecksum reports if the checksum is a SHA256 hex string.
+func IsValidChecksum(checksum string) bool {
+	if len(checksum) != 64 {
+		return false
+	}
+	for _, c := range strings.ToLower(checksum) {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
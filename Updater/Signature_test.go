@@ -0,0 +1,177 @@
+package Updater
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testManifest = `packages:
+  ai_platform:
+    version: 1.0.0.1
+    locationUrls:
+      DEFAULT:
+        - https://example.com/platform.zip
+    SHA256: 3a7bd3e2360a3d29eea436fcfb7e44c735d117c42d1c1835420b6b9942dd4f1b
+  app:
+    version: 1.0.0.2
+    locationUrls: {}
+    SHA256: ""
+`
+
+// useTestKeys trusts the public keys for the duration of the test.
+func useTestKeys(t *testing.T, keys ...ed25519.PublicKey) {
+	t.Helper()
+	var encodedKeys []string
+	for _, key := range keys {
+		encodedKeys = append(encodedKeys, base64.StdEncoding.EncodeToString(key))
+	}
+	previous := UpdatePublicKeys
+	UpdatePublicKeys = strings.Join(encodedKeys, ",")
+	t.Cleanup(func() { UpdatePublicKeys = previous })
+}
+
+func newTestKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return publicKey, privateKey
+}
+
+// serveFiles serves the files by their path. Missing files are answered with 404.
+func serveFiles(t *testing.T, files map[string][]byte) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGetUpdateInfo(t *testing.T) {
+	publicKey, privateKey := newTestKey(t)
+	otherPublicKey, otherPrivateKey := newTestKey(t)
+	manifest := []byte(testManifest)
+	tampered := []byte(strings.Replace(testManifest, "3a7bd3e2", "00000000", 1))
+
+	tests := []struct {
+		name        string
+		trusted     []ed25519.PublicKey
+		manifest    []byte
+		signature   []byte
+		wantErr     error
+		wantAnyErr  bool
+		wantVersion string
+	}{
+		{name: "valid", trusted: []ed25519.PublicKey{publicKey}, manifest: manifest, signature: SignManifest(privateKey, manifest), wantVersion: "1.0.0.1"},
+		{name: "rotated key", trusted: []ed25519.PublicKey{otherPublicKey, publicKey}, manifest: manifest, signature: SignManifest(privateKey, manifest), wantVersion: "1.0.0.1"},
+		{name: "tampered", trusted: []ed25519.PublicKey{publicKey}, manifest: tampered, signature: SignManifest(privateKey, manifest), wantErr: ErrInvalidSignature},
+		{name: "untrusted key", trusted: []ed25519.PublicKey{publicKey}, manifest: manifest, signature: SignManifest(otherPrivateKey, manifest), wantErr: ErrInvalidSignature},
+		{name: "garbage signature", trusted: []ed25519.PublicKey{publicKey}, manifest: manifest, signature: []byte("not a signature"), wantErr: ErrInvalidSignature},
+		{name: "unsigned", trusted: []ed25519.PublicKey{publicKey}, manifest: manifest, wantAnyErr: true},
+		{name: "no trusted key", manifest: manifest, signature: SignManifest(privateKey, manifest), wantErr: ErrNoUpdateKey},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useTestKeys(t, test.trusted...)
+			files := map[string][]byte{"/latest.yaml": test.manifest}
+			if test.signature != nil {
+				files["/latest.yaml"+SignatureExtension] = test.signature
+			}
+			server := serveFiles(t, files)
+
+			updater := UpdatePackages{}
+			err := updater.GetUpdateInfo(server.URL + "/latest.yaml")
+			switch {
+			case test.wantErr != nil:
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("expected %v, got %v", test.wantErr, err)
+				}
+			case test.wantAnyErr:
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+			default:
+				if err != nil {
+					t.Fatal(err)
+				}
+				if version := updater.Packages["ai_platform"].Version; version != test.wantVersion {
+					t.Errorf("expected version %s, got %s", test.wantVersion, version)
+				}
+			}
+			if err != nil && len(updater.Packages) > 0 {
+				t.Error("packages of a refused manifest were parsed")
+			}
+		})
+	}
+}
+
+func TestPackageChecksum(t *testing.T) {
+	publicKey, privateKey := newTestKey(t)
+	useTestKeys(t, publicKey)
+	manifest := []byte(testManifest)
+	server := serveFiles(t, map[string][]byte{
+		"/latest.yaml":                      manifest,
+		"/latest.yaml" + SignatureExtension: SignManifest(privateKey, manifest),
+	})
+	updater := UpdatePackages{}
+	if err := updater.GetUpdateInfo(server.URL + "/latest.yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		packageName string
+		wantErr     bool
+	}{
+		{packageName: "ai_platform"},
+		{packageName: "app", wantErr: true},
+		{packageName: "missing", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.packageName, func(t *testing.T) {
+			checksum, err := updater.PackageChecksum(test.packageName)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got checksum %q", checksum)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !IsValidChecksum(checksum) {
+				t.Errorf("invalid checksum %q", checksum)
+			}
+		})
+	}
+}
+
+func TestIsValidChecksum(t *testing.T) {
+	tests := []struct {
+		checksum string
+		want     bool
+	}{
+		{"3a7bd3e2360a3d29eea436fcfb7e44c735d117c42d1c1835420b6b9942dd4f1b", true},
+		{"3A7BD3E2360A3D29EEA436FCFB7E44C735D117C42D1C1835420B6B9942DD4F1B", true},
+		{"", false},
+		{"0", false},
+		{"3a7bd3e2360a3d29eea436fcfb7e44c735d117c42d1c1835420b6b9942dd4f1", false},
+		{"xa7bd3e2360a3d29eea436fcfb7e44c735d117c42d1c1835420b6b9942dd4f1b", false},
+	}
+	for _, test := range tests {
+		if got := IsValidChecksum(test.checksum); got != test.want {
+			t.Errorf("IsValidChecksum(%q) = %v, expected %v", test.checksum, got, test.want)
+		}
+	}
+}
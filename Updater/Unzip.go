@@ -5,138 +5,313 @@ import (
 	"archive/zip"
 	"compress/gzip"
 	"fmt"
+	"github.com/bodgit/sevenzip"
+	"github.com/klauspost/compress/zstd"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
-func Unzip(src, dest string) error {
-	r, err := zip.OpenReader(src)
-	if err != nil {
-		return err
+// ExtractProgress is the progress of an extraction. BytesTotal is 0 if the uncompressed size is unknown.
+type ExtractProgress struct {
+	BytesDone  int64
+	BytesTotal int64
+	FilesDone  int
+	Current    string
+}
+
+// ArchiveFormat returns the archive format of a file name or url ("" if it is no supported archive).
+func ArchiveFormat(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return "zip"
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(name, ".tar.zst"), strings.HasSuffix(name, ".tzst"):
+		return "tar.zst"
+	case strings.HasSuffix(name, ".7z"):
+		return "7z"
 	}
-	defer func() {
-		if err := r.Close(); err != nil {
-			panic(err)
-		}
-	}()
+	return ""
+}
 
-	os.MkdirAll(dest, 0755)
+// archiveEntry is a file or directory of an archive. open is only valid during the walk callback.
+type archiveEntry struct {
+	name  string
+	mode  fs.FileMode
+	isDir bool
+	size  int64
+	open  func() (io.ReadCloser, error)
+}
 
-	// Closure to address file descriptors issue with all the deferred .Close() methods
-	extractAndWriteFile := func(f *zip.File) error {
-		rc, err := f.Open()
+// walkArchive calls fn for the directories and regular files of the archive in the archive order.
+// Other entries (like symlinks) are skipped.
+func walkArchive(src, format string, fn func(entry archiveEntry) error) error {
+	switch format {
+	case "zip":
+		r, err := zip.OpenReader(src)
 		if err != nil {
 			return err
 		}
-		defer func() {
-			if err := rc.Close(); err != nil {
-				panic(err)
+		defer r.Close()
+		for _, f := range r.File {
+			info := f.FileInfo()
+			if !info.IsDir() && !info.Mode().IsRegular() {
+				continue
+			}
+			if err = fn(archiveEntry{name: f.Name, mode: info.Mode(), isDir: info.IsDir(), size: int64(f.UncompressedSize64), open: f.Open}); err != nil {
+				return err
 			}
-		}()
-
-		path := filepath.Join(dest, f.Name)
-
-		// Check for ZipSlip (Directory traversal)
-		if !strings.HasPrefix(path, filepath.Clean(dest)+string(os.PathSeparator)) {
-			return fmt.Errorf("illegal file path: %s", path)
 		}
-
-		if f.FileInfo().IsDir() {
-			os.MkdirAll(path, f.Mode())
+		return nil
+	case "7z":
+		r, err := sevenzip.OpenReader(src)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		for _, f := range r.File {
+			info := f.FileInfo()
+			if !info.IsDir() && !info.Mode().IsRegular() {
+				continue
+			}
+			if err = fn(archiveEntry{name: f.Name, mode: info.Mode(), isDir: info.IsDir(), size: int64(f.UncompressedSize), open: f.Open}); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "tar.gz", "tar.zst":
+		r, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		var decompressed io.Reader
+		if format == "tar.gz" {
+			gzr, err := gzip.NewReader(r)
+			if err != nil {
+				return err
+			}
+			defer gzr.Close()
+			decompressed = gzr
 		} else {
-			os.MkdirAll(filepath.Dir(path), f.Mode())
-			f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
+			zr, err := zstd.NewReader(r)
 			if err != nil {
 				return err
 			}
-			defer func() {
-				if err := f.Close(); err != nil {
-					panic(err)
-				}
-			}()
-
-			_, err = io.Copy(f, rc)
+			defer zr.Close()
+			decompressed = zr
+		}
+		tr := tar.NewReader(decompressed)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				return nil
+			}
 			if err != nil {
 				return err
 			}
+			if hdr.Typeflag != tar.TypeDir && hdr.Typeflag != tar.TypeReg {
+				continue
+			}
+			entry := archiveEntry{name: hdr.Name, mode: hdr.FileInfo().Mode(), isDir: hdr.Typeflag == tar.TypeDir, size: hdr.Size, open: func() (io.ReadCloser, error) {
+				return io.NopCloser(tr), nil
+			}}
+			if err = fn(entry); err != nil {
+				return err
+			}
 		}
-		return nil
 	}
+	return fmt.Errorf("unsupported archive format %q", format)
+}
 
-	for _, f := range r.File {
-		err := extractAndWriteFile(f)
-		if err != nil {
-			return err
+// ArchiveUncompressedSize returns the size of all files of a zip or 7z archive from its directory.
+// The size of tar archives is only known after decompressing them, so -1 is returned for them.
+func ArchiveUncompressedSize(src, format string) (int64, error) {
+	if format != "zip" && format != "7z" {
+		return -1, nil
+	}
+	var size int64
+	err := walkArchive(src, format, func(entry archiveEntry) error {
+		size += entry.size
+		return nil
+	})
+	return size, err
+}
+
+// createdPaths remembers the files and directories created by an extraction, so they can be removed on failure.
+type createdPaths []string
+
+// add remembers path if it does not exist yet (only the topmost created directory is needed).
+func (c *createdPaths) add(path string) {
+	for {
+		parent := filepath.Dir(path)
+		if _, err := os.Stat(parent); err == nil || parent == path {
+			break
 		}
+		path = parent
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		*c = append(*c, path)
 	}
+}
 
-	return nil
+func (c *createdPaths) mkdirAll(path string) error {
+	c.add(path)
+	return os.MkdirAll(path, 0755)
 }
 
-func Untar(src, dest string) error {
-	r, err := os.Open(src)
-	if err != nil {
-		return err
+func (c createdPaths) remove() {
+	for i := len(c) - 1; i >= 0; i-- {
+		_ = os.RemoveAll(c[i])
 	}
-	defer func() {
-		if err := r.Close(); err != nil {
-			panic(err)
-		}
-	}()
+}
+
+// extractWriter counts the extracted bytes and reports the progress at most every 250ms.
+type extractWriter struct {
+	writer     io.Writer
+	progress   *ExtractProgress
+	onProgress func(ExtractProgress)
+	lastUpdate time.Time
+}
 
-	gzr, err := gzip.NewReader(r)
+func (w *extractWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.progress.BytesDone += int64(n)
+	w.report(false)
+	return n, err
+}
+
+func (w *extractWriter) report(force bool) {
+	if w.onProgress != nil && (force || time.Since(w.lastUpdate) >= 250*time.Millisecond) {
+		w.lastUpdate = time.Now()
+		w.onProgress(*w.progress)
+	}
+}
+
+// Extract streams the files of a zip, tar.gz, tar.zst or 7z archive into dest.
+// Before extracting, the free disk space is compared to the uncompressed size (from the archive directory or
+// expectedSize for tar archives, 0 if unknown). If the extraction fails, all files and directories it created are
+// removed again (existing files which were overwritten are not restored).
+func Extract(src, dest, format string, expectedSize int64, onProgress func(ExtractProgress)) (err error) {
+	size, err := ArchiveUncompressedSize(src, format)
 	if err != nil {
 		return err
 	}
+	if size < 0 {
+		size = expectedSize
+	}
+	if err = CheckFreeSpace(dest, size); err != nil {
+		return err
+	}
+
+	var created createdPaths
 	defer func() {
-		if err := gzr.Close(); err != nil {
-			panic(err)
+		if err != nil {
+			created.remove()
 		}
 	}()
+	if err = created.mkdirAll(dest); err != nil {
+		return err
+	}
 
-	tr := tar.NewReader(gzr)
+	progress := ExtractProgress{BytesTotal: max(size, 0)}
+	writer := &extractWriter{progress: &progress, onProgress: onProgress}
+	cleanDest := filepath.Clean(dest) + string(os.PathSeparator)
 
-	// Ensure the destination directory exists
-	os.MkdirAll(dest, 0755)
+	extractFile := func(entry archiveEntry, path string) error {
+		rc, err := entry.open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
 
-	// Iterate over files in .tar.gz
-	for {
-		hdr, err := tr.Next()
-		if err == io.EOF {
-			break
+		if err := created.mkdirAll(filepath.Dir(path)); err != nil {
+			return err
+		}
+		created.add(path)
+		mode := entry.mode.Perm()
+		if mode == 0 {
+			mode = 0644
 		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
 		if err != nil {
 			return err
 		}
+		writer.writer = f
+		if _, err = io.Copy(writer, rc); err != nil {
+			_ = f.Close()
+			return err
+		}
+		return f.Close()
+	}
 
-		path := filepath.Join(dest, hdr.Name)
+	err = walkArchive(src, format, func(entry archiveEntry) error {
+		path := filepath.Join(dest, entry.name)
 
-		// Check for TarSlip (Directory traversal)
-		if !strings.HasPrefix(path, filepath.Clean(dest)+string(os.PathSeparator)) {
+		// Check for ZipSlip (Directory traversal)
+		if path != filepath.Clean(dest) && !strings.HasPrefix(path, cleanDest) {
 			return fmt.Errorf("illegal file path: %s", path)
 		}
+		progress.Current = entry.name
+		if entry.isDir {
+			return created.mkdirAll(path)
+		}
+		if err := extractFile(entry, path); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", entry.name, err)
+		}
+		progress.FilesDone++
+		writer.report(false)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	progress.Current = ""
+	writer.report(true)
+	return nil
+}
 
-		// Check if it's a dir or a file
-		if hdr.Typeflag == tar.TypeDir {
-			os.MkdirAll(path, 0755)
-		} else if hdr.Typeflag == tar.TypeReg {
-			os.MkdirAll(filepath.Dir(path), 0755)
-			f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(hdr.Mode))
-			if err != nil {
-				return err
-			}
-			defer func() {
-				if err := f.Close(); err != nil {
-					panic(err)
-				}
-			}()
+// Unzip extracts a zip archive into dest.
+func Unzip(src, dest string) error {
+	return Extract(src, dest, "zip", 0, nil)
+}
 
-			if _, err := io.Copy(f, tr); err != nil {
-				return err
+// Untar extracts a tar.gz archive into dest.
+func Untar(src, dest string) error {
+	return Extract(src, dest, "tar.gz", 0, nil)
+}
+
+// ArchiveTopLevelEntries returns the names of the files and directories in the root of an archive.
+func ArchiveTopLevelEntries(src, format string) ([]string, error) {
+	var names []string
+	addName := func(name string) {
+		name = strings.TrimPrefix(filepath.ToSlash(name), "./")
+		if index := strings.Index(name, "/"); index >= 0 {
+			name = name[:index]
+		}
+		// entries like "../x" would point outside of the archive directory
+		if !filepath.IsLocal(name) {
+			return
+		}
+		for _, existing := range names {
+			if existing == name {
+				return
 			}
 		}
+		names = append(names, name)
 	}
-	return nil
+
+	err := walkArchive(src, format, func(entry archiveEntry) error {
+		addName(entry.name)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
 }
@@ -0,0 +1,153 @@
+package Updater
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// writeTestZip creates a zip archive with the files by their name.
+func writeTestZip(t *testing.T, files map[string]string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "test.zip")
+	out, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	writer := zip.NewWriter(out)
+	for _, name := range sortedKeys(files) {
+		w, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Write([]byte(files[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// writeTestTarGz creates a tar.gz archive with the files by their name.
+func writeTestTarGz(t *testing.T, files map[string]string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "test.tar.gz")
+	out, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	gzw := gzip.NewWriter(out)
+	writer := tar.NewWriter(gzw)
+	for _, name := range sortedKeys(files) {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name])), Typeflag: tar.TypeReg}
+		if err = writer.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = writer.Write([]byte(files[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func sortedKeys(files map[string]string) []string {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		files   map[string]string
+		wantErr bool
+	}{
+		{name: "zip", format: "zip", files: map[string]string{"a.txt": "a", "dir/b.txt": "b"}},
+		{name: "tar.gz", format: "tar.gz", files: map[string]string{"a.txt": "a", "dir/b.txt": "b"}},
+		{name: "zip parent traversal", format: "zip", files: map[string]string{"a.txt": "a", "../evil.txt": "evil"}, wantErr: true},
+		{name: "zip nested traversal", format: "zip", files: map[string]string{"dir/../../evil.txt": "evil"}, wantErr: true},
+		{name: "tar.gz parent traversal", format: "tar.gz", files: map[string]string{"../evil.txt": "evil"}, wantErr: true},
+		{name: "zip sibling prefix", format: "zip", files: map[string]string{"../dest-evil/evil.txt": "evil"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var src string
+			if tt.format == "zip" {
+				src = writeTestZip(t, tt.files)
+			} else {
+				src = writeTestTarGz(t, tt.files)
+			}
+			root := t.TempDir()
+			dest := filepath.Join(root, "dest")
+
+			err := Extract(src, dest, tt.format, 0, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Extract() succeeded, want error")
+				}
+				// nothing may be written outside of dest and the created files are removed again
+				entries, _ := os.ReadDir(root)
+				if len(entries) != 0 {
+					t.Errorf("files left after failed extraction: %v", entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			for name, content := range tt.files {
+				data, err := os.ReadFile(filepath.Join(dest, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != content {
+					t.Errorf("%s = %q, want %q", name, data, content)
+				}
+			}
+		})
+	}
+}
+
+func TestArchiveTopLevelEntries(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{name: "files and directories", files: map[string]string{"a.txt": "", "dir/b.txt": "", "dir/c.txt": ""}, want: []string{"a.txt", "dir"}},
+		{name: "dot prefix", files: map[string]string{"./dir/a.txt": ""}, want: []string{"dir"}},
+		{name: "parent traversal", files: map[string]string{"../evil.txt": "", "a.txt": ""}, want: []string{"a.txt"}},
+		{name: "absolute path", files: map[string]string{"/etc/evil": "", "a.txt": ""}, want: []string{"a.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ArchiveTopLevelEntries(writeTestZip(t, tt.files), "zip")
+			if err != nil {
+				t.Fatalf("ArchiveTopLevelEntries() error = %v", err)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ArchiveTopLevelEntries() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -26,12 +26,32 @@ packages:
       US:
         - https://usc1.someurl.com/data1.0.0.3_win.zip
     SHA256: 0
+    filesManifestUrls:
+      - https://eu2.someurl.com/data1.0.0.3_files.yaml
+    filesManifestSHA256: 0
 */
 
 type UpdateInfo struct {
 	Version      string              `yaml:"version"`
 	LocationUrls map[string][]string `yaml:"locationUrls"`
 	SHA256       string              `yaml:"SHA256"`
+	// FilesManifestUrls point to the PlatformFilesManifest of the version, so only changed files are downloaded (optional)
+	FilesManifestUrls   []string `yaml:"filesManifestUrls,omitempty"`
+	FilesManifestSHA256 string   `yaml:"filesManifestSHA256,omitempty"`
+	// ExtractedSize is the uncompressed size of the package, used to check the free disk space before downloading (optional)
+	ExtractedSize int64 `yaml:"extractedSize,omitempty"`
+}
+
+// ArchiveFormat returns the archive format of the package by its location urls ("zip" if unknown).
+func (i UpdateInfo) ArchiveFormat() string {
+	for _, locations := range i.LocationUrls {
+		for _, location := range locations {
+			if format := ArchiveFormat(location); format != "" {
+				return format
+			}
+		}
+	}
+	return "zip"
 }
 
 func (i *UpdateInfo) WriteYaml(fileName string) {
@@ -71,7 +91,29 @@ func (u *UpdatePackages) parsePackagesFromYaml(data []byte) error {
 }
 
 func (u *UpdatePackages) getYaml(url string) ([]byte, error) {
-	resp, err := http.Get(url)
+	return FetchFile(url)
+}
+
+// FetchFile downloads a small file (like a manifest) into memory. A file with the same name in the offline source is
+// used if the download fails, or first if the offline source is preferred (explicit offline installs).
+func FetchFile(url string) ([]byte, error) {
+	if Offline.Preferred() {
+		if data, ok := Offline.ReadFile(url); ok {
+			return data, nil
+		}
+	}
+	data, err := fetchRemoteFile(url)
+	if err != nil {
+		if offlineData, ok := Offline.ReadFile(url); ok {
+			log.Printf("using %s from the offline source: %v", url, err)
+			return offlineData, nil
+		}
+	}
+	return data, err
+}
+
+func fetchRemoteFile(url string) ([]byte, error) {
+	resp, err := HTTPClient().Get(url)
 	if err != nil {
 		return []byte{}, fmt.Errorf("GET error: %v", err)
 	}
@@ -101,10 +143,42 @@ func (u *UpdatePackages) WriteYaml(fileName string) {
 	}
 }
 
+// GetUpdateInfo downloads the update manifest and verifies its detached signature (url + SignatureExtension).
+// Unsigned manifests are refused.
 func (u *UpdatePackages) GetUpdateInfo(url string) error {
 	data, err := u.getYaml(url)
 	if err != nil {
 		return err
 	}
+	if err = VerifyDetachedSignature(url, data); err != nil {
+		return err
+	}
 	return u.parsePackagesFromYaml(data)
 }
+
+// VerifyDetachedSignature downloads the signature of the file at url (url + SignatureExtension) and verifies data
+// with the UpdatePublicKeys. A missing signature is an error.
+func VerifyDetachedSignature(url string, data []byte) error {
+	keys, err := TrustedUpdateKeys()
+	if err != nil {
+		return err
+	}
+	signature, err := FetchFile(url + SignatureExtension)
+	if err != nil {
+		return fmt.Errorf("failed to get signature: %w", err)
+	}
+	return VerifyManifest(keys, data, signature)
+}
+
+// PackageChecksum returns the SHA256 of the package. A package without a valid checksum is refused, since it could
+// not be verified against the signed manifest.
+func (u *UpdatePackages) PackageChecksum(packageName string) (string, error) {
+	packageInfo, ok := u.Packages[packageName]
+	if !ok {
+		return "", fmt.Errorf("package %s not found in update manifest", packageName)
+	}
+	if !IsValidChecksum(packageInfo.SHA256) {
+		return "", fmt.Errorf("package %s has no valid SHA256 in the signed update manifest", packageName)
+	}
+	return packageInfo.SHA256, nil
+}
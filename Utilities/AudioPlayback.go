@@ -0,0 +1,105 @@
+package Utilities
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gen2brain/malgo"
+	"github.com/youpy/go-wav"
+	"io"
+	"os"
+	"sync"
+)
+
+const wavFormatFloat = 3
+
+// PlayWavFile plays a wav file on the output device with the given name (or the default device if not found).
+// It blocks until the file is played or stop is closed.
+func PlayWavFile(fileName string, audioApi malgo.Backend, outputDeviceName string, stop <-chan struct{}) error {
+	defer PanicLogger()
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	wavReader := wav.NewReader(file)
+	wavFormat, err := wavReader.Format()
+	if err != nil {
+		return err
+	}
+
+	var sampleFormat malgo.FormatType
+	switch wavFormat.BitsPerSample {
+	case 8:
+		sampleFormat = malgo.FormatU8
+	case 16:
+		sampleFormat = malgo.FormatS16
+	case 24:
+		sampleFormat = malgo.FormatS24
+	case 32:
+		sampleFormat = malgo.FormatS32
+		if wavFormat.AudioFormat == wavFormatFloat {
+			sampleFormat = malgo.FormatF32
+		}
+	default:
+		return fmt.Errorf("unsupported wav format with %d bits per sample", wavFormat.BitsPerSample)
+	}
+
+	ctx, err := InitMalgo(audioApi)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = ctx.Uninit()
+		ctx.Free()
+	}()
+
+	deviceConfig := malgo.DefaultDeviceConfig(malgo.Playback)
+	deviceConfig.Playback.Format = sampleFormat
+	deviceConfig.Playback.Channels = uint32(wavFormat.NumChannels)
+	deviceConfig.SampleRate = wavFormat.SampleRate
+	deviceConfig.Alsa.NoMMap = 1
+
+	playbackDevices, err := ctx.Devices(malgo.Playback)
+	if err == nil {
+		for _, deviceInfo := range playbackDevices {
+			if deviceInfo.Name() == outputDeviceName {
+				deviceConfig.Playback.DeviceID = deviceInfo.ID.Pointer()
+				break
+			}
+		}
+	}
+
+	finished := make(chan struct{})
+	var finishOnce sync.Once
+	onSamples := func(pOutputSample, pInputSamples []byte, framecount uint32) {
+		readBytes, readErr := io.ReadFull(wavReader, pOutputSample)
+		// fill the rest of the buffer with silence
+		for i := readBytes; i < len(pOutputSample); i++ {
+			pOutputSample[i] = 0
+		}
+		if readErr != nil {
+			finishOnce.Do(func() { close(finished) })
+		}
+	}
+
+	device, err := malgo.InitDevice(ctx.Context, deviceConfig, malgo.DeviceCallbacks{Data: onSamples})
+	if err != nil {
+		return err
+	}
+	defer device.Uninit()
+
+	if err = device.Start(); err != nil {
+		return err
+	}
+
+	select {
+	case <-finished:
+	case <-stop:
+	}
+	if err = device.Stop(); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
@@ -1,69 +1,29 @@
 package Hardwareinfo
 
+import "sync"
+
 type AIModel struct {
 	Name                        string
 	Float32PrecisionMemoryUsage float64
 }
 
-// Models is a list of AIModel which is a struct that contains the name of the model and the memory usage in MB for Float32
-var Models = []AIModel{
-	// Official Whisper models
-	{"WhisperO_tiny", 1676.0},
-	{"WhisperO_base", 1932.0},
-	{"WhisperO_small", 3432.0},
-	{"WhisperO_medium", 7634.0},
-	{"WhisperO_large", 13702.0},
-	// WhisperCT2 models
-	{"WhisperCT2_tiny", 1054.0},
-	{"WhisperCT2_base", 1185.0},
-	{"WhisperCT2_small", 1873.0},
-	{"WhisperCT2_medium", 3905.0},
-	{"WhisperCT2_large", 6985.0},
-	{"WhisperCT2_medium-distilled", 1898.0},
-	{"WhisperCT2_large-distilled", 3339.0},
-	// Speech T5
-	{"Whispert5_tiny", 927.0},
-	{"Whispert5_base", 927.0},
-	{"Whispert5_small", 927.0},
-	{"Whispert5_medium", 927.0},
-	{"Whispert5_large", 927.0},
-	// Seamless M4T
-	{"Whisperm4t_medium", 6250.0},
-	{"Whisperm4t_large", 10518.0},
-	// wav2vec bert2.0 models
-	{"Whisperwav2vec-bert_tiny", 2989.0},
-	{"Whisperwav2vec-bert_base", 2989.0},
-	{"Whisperwav2vec-bert_small", 2989.0},
-	{"Whisperwav2vec-bert_medium", 2989.0},
-	{"Whisperwav2vec-bert_large", 2989.0},
-	// NeMo Canary models
-	{"Whispernemo-canary_tiny", 4509.0},
-	{"Whispernemo-canary_base", 4509.0},
-	{"Whispernemo-canary_small", 4509.0},
-	{"Whispernemo-canary_medium", 4509.0},
-	{"Whispernemo-canary_large", 4509.0},
-	// MMS models
-	{"Whispermms_1b-all", 4646.0},
-	{"Whispermms_mms-1b-fl102", 4544.0},
-	{"Whispermms_mms-1b-l1107", 4623.0},
-	// NLLB200CT2 models
-	{"TxtTranslatorNLLB200_CT2_small", 3087.0},
-	{"TxtTranslatorNLLB200_CT2_medium", 6069.0},
-	{"TxtTranslatorNLLB200_CT2_large", 13803.0},
-	// NLLB200 models
-	{"TxtTranslatorNLLB200_small", 3657.0},
-	{"TxtTranslatorNLLB200_medium", 6620.0},
-	{"TxtTranslatorNLLB200_large", 14837.0},
-	// M2M100 models
-	{"TxtTranslatorM2M100_small", 2197.0},
-	{"TxtTranslatorM2M100_large", 5211.0},
-	// Seamless M4T models
-	{"TxtTranslatorSeamless_M4T_medium", 6250.0},
-	{"TxtTranslatorSeamless_M4T_large", 10518.0},
-	{"TxtTranslatorSeamless_M4T_large-v2", 10518.0},
-	// TTS types
-	{"ttsType-silero_", 1533.0},
-	{"ttsType-f5_e2_", 1200.0},
+// models contains the name of the models and their memory usage in MB for Float32.
+// They are set from the estimates of the model catalog (see ModelDownloader).
+var models []AIModel
+var modelsMutex sync.RWMutex
+
+// SetModels replaces the memory estimates of the models.
+func SetModels(newModels []AIModel) {
+	modelsMutex.Lock()
+	defer modelsMutex.Unlock()
+	models = newModels
+}
+
+// Models returns the memory estimates of the models.
+func Models() []AIModel {
+	modelsMutex.RLock()
+	defer modelsMutex.RUnlock()
+	return models
 }
 
 const (
@@ -80,3 +40,32 @@ const (
 func EstimateMemoryUsage(float32MemoryUsage float64, targetType float64) float64 {
 	return (float32MemoryUsage / float64(Float32)) * float64(targetType)
 }
+
+// PrecisionFactor returns the bytes per parameter of a precision setting (like "float16" or "int8_float16").
+func PrecisionFactor(precision string) float64 {
+	switch precision {
+	case "float16", "bfloat16":
+		return Float16
+	case "int32":
+		return Int32
+	case "int16":
+		return Int16
+	case "int8", "int8_float16", "int8_bfloat16":
+		return Int8
+	case "8bit":
+		return Bit8
+	case "4bit":
+		return Bit4
+	}
+	return Float32
+}
+
+// ModelMemoryUsage returns the estimated memory usage in MB of a model in Models with the given precision.
+func ModelMemoryUsage(name string, precision string) (float64, bool) {
+	for _, model := range Models() {
+		if model.Name == name {
+			return EstimateMemoryUsage(model.Float32PrecisionMemoryUsage, PrecisionFactor(precision)), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,57 @@
+package Hardwareinfo
+
+import (
+	"strings"
+)
+
+// HardwareProbe is the detected hardware which is relevant for choosing AI models.
+type HardwareProbe struct {
+	CPUMemoryMB int64
+	// GPUMemoryMB is 0 if no dedicated GPU (or its memory) was found
+	GPUMemoryMB int64
+	// GPUVendor is "nvidia", "amd", "intel" or empty if no dedicated GPU was found
+	GPUVendor         string
+	GPUName           string
+	ComputeCapability float32
+}
+
+// ProbeHardware detects the CPU memory, the dedicated GPU and its memory.
+func ProbeHardware() HardwareProbe {
+	probe := HardwareProbe{
+		CPUMemoryMB: GetCPUMemory(),
+	}
+
+	if HasNVIDIACard() {
+		probe.GPUVendor = "nvidia"
+		_, probe.GPUMemoryMB = GetGPUMemory()
+		if probe.GPUMemoryMB <= 0 {
+			// fall back to registry reading of Video Memory
+			foundGPU, _ := FindDedicatedGPUByVendor([]string{"nvidia"})
+			if len(foundGPU) > 0 {
+				probe.GPUMemoryMB = foundGPU[0].MemoryMB
+				probe.GPUName = foundGPU[0].AdapterName
+			}
+		}
+		probe.ComputeCapability = GetGPUComputeCapability()
+		return probe
+	}
+
+	foundGPU, _ := FindDedicatedGPUByVendor([]string{"nvidia", "amd", "intel"})
+	if len(foundGPU) > 0 {
+		probe.GPUMemoryMB = foundGPU[0].MemoryMB
+		probe.GPUName = foundGPU[0].AdapterName
+		vendorName := strings.ToLower(foundGPU[0].VendorName + " " + foundGPU[0].AdapterName)
+		for _, vendor := range []string{"nvidia", "amd", "intel"} {
+			if strings.Contains(vendorName, vendor) {
+				probe.GPUVendor = vendor
+				break
+			}
+		}
+	}
+	return probe
+}
+
+// HasDedicatedGPU returns true if a GPU with known memory was found.
+func (p HardwareProbe) HasDedicatedGPU() bool {
+	return p.GPUVendor != "" && p.GPUMemoryMB > 0
+}
@@ -1,26 +1,80 @@
 package Messages
 
-import "whispering-tiger-ui/Fields"
+import (
+	"whispering-tiger-ui/Fields"
+	"whispering-tiger-ui/Sessions"
+)
+
+type WhisperSegment struct {
+	Start   float64 `json:"start"`
+	End     float64 `json:"end"`
+	Text    string  `json:"text"`
+	Speaker string  `json:"speaker,omitempty"`
+}
+
+type WhisperWord struct {
+	Word        string  `json:"word"`
+	Start       float64 `json:"start"`
+	End         float64 `json:"end"`
+	Probability float64 `json:"probability"`
+}
 
 type WhisperResult struct {
-	Text                 string `json:"text"`
-	Language             string `json:"language"`
-	TxtTranslation       string `json:"txt_translation,omitempty"`
-	TxtTranslationTarget string `json:"txt_translation_target,omitempty"`
+	Text                 string           `json:"text"`
+	Language             string           `json:"language"`
+	TxtTranslation       string           `json:"txt_translation,omitempty"`
+	TxtTranslationTarget string           `json:"txt_translation_target,omitempty"`
+	Speaker              string           `json:"speaker,omitempty"`
+	Start                float64          `json:"start,omitempty"`
+	End                  float64          `json:"end,omitempty"`
+	Segments             []WhisperSegment `json:"segments,omitempty"`
+	Words                []WhisperWord    `json:"words,omitempty"`
 }
 
 func (res WhisperResult) String() string {
 	return res.Text
 }
+
+// Update shows the transcript in the result list and records it in the current session.
 func (res WhisperResult) Update() {
+	Sessions.Current.AddResult(res.UpdateResultList())
+}
+
+// UpdateResultList shows the result in the result list without recording it in the session (e.g. for LLM answers).
+func (res WhisperResult) UpdateResultList() Fields.WhisperResult {
 	FieldsWhisperResultData := Fields.WhisperResult{
 		Text:                 res.Text,
 		Language:             res.Language,
 		TxtTranslation:       res.TxtTranslation,
 		TxtTranslationTarget: res.TxtTranslationTarget,
+		Speaker:              res.Speaker,
+		Start:                res.Start,
+		End:                  res.End,
+	}
+	for _, segment := range res.Segments {
+		FieldsWhisperResultData.Segments = append(FieldsWhisperResultData.Segments, Fields.WhisperResultSegment{
+			Start:   segment.Start,
+			End:     segment.End,
+			Text:    segment.Text,
+			Speaker: segment.Speaker,
+		})
+	}
+	for _, word := range res.Words {
+		FieldsWhisperResultData.Words = append(FieldsWhisperResultData.Words, Fields.WhisperResultWord{
+			Word:        word.Word,
+			Start:       word.Start,
+			End:         word.End,
+			Probability: word.Probability,
+		})
+	}
+
+	// register speakers, so they get a stable color and can be renamed
+	for _, speaker := range FieldsWhisperResultData.ResultSpeakers() {
+		Fields.RegisterSpeaker(speaker)
 	}
 
 	// prepend to slice Fields.DataBindings.WhisperResultsData
 	Fields.DataBindings.WhisperResultsData = append([]Fields.WhisperResult{FieldsWhisperResultData}, Fields.DataBindings.WhisperResultsData...)
 	Fields.Field.WhisperResultList.Refresh()
+	return FieldsWhisperResultData
 }
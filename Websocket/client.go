@@ -3,7 +3,6 @@ package Websocket
 import (
 	"bytes"
 	"encoding/json"
-	"flag"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
@@ -68,7 +67,6 @@ func (c *Client) Start() {
 	go realtimeLabelHideTimer()
 	go ProcessReceiveMessageChannel()
 
-	flag.Parse()
 	log.SetFlags(0)
 
 	//interrupt := make(chan os.Signal, 1)
@@ -99,6 +97,10 @@ func (c *Client) Start() {
 
 	connectingStateDialog.Hide()
 	previouslyConnected = true
+	if runBackend {
+		// the backend loaded its settings when it accepts connections
+		Settings.RemoveBackendSettingsFile()
+	}
 
 	defer c.Conn.Close()
 	//c.Conn.SetReadLimit(maxMessageSize)
@@ -138,6 +140,7 @@ func (c *Client) Start() {
 					connectingStateDialog.Hide()
 				}
 				if runBackend {
+					Settings.RemoveBackendSettingsFile()
 					log.Println("send ui_connected")
 					// send info that backend is running locally
 					sendMessage := Fields.SendMessageStruct{
@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 	"whispering-tiger-ui/Fields"
+	"whispering-tiger-ui/Sessions"
 	"whispering-tiger-ui/Settings"
 	"whispering-tiger-ui/Utilities"
 	"whispering-tiger-ui/Websocket/Messages"
@@ -33,6 +34,13 @@ type MessageStruct struct {
 	TxtTranslationSource string `json:"txt_translation_source,omitempty"`
 	TxtTranslationTarget string `json:"txt_translation_target,omitempty"`
 
+	// only in case of whisper message with speaker diarization or timestamps
+	Speaker  string                    `json:"speaker,omitempty"`
+	Start    float64                   `json:"start,omitempty"`
+	End      float64                   `json:"end,omitempty"`
+	Segments []Messages.WhisperSegment `json:"segments,omitempty"`
+	Words    []Messages.WhisperWord    `json:"words,omitempty"`
+
 	// only in case of text translate message
 	TranslateResult string `json:"translate_result,omitempty"`
 	OriginalText    string `json:"original_text,omitempty"`
@@ -256,11 +264,28 @@ func (c *MessageStruct) HandleReceiveMessage() {
 	case "transcript":
 		c.Text = strings.TrimSpace(c.Text)
 		c.TxtTranslation = strings.TrimSpace(c.TxtTranslation)
+
+		// apply user defined post-processing (replacement rules, dictionaries, profanity masking)
+		postProcessing := Settings.Config.Transcript_postprocessing
+		if postProcessing.Enabled {
+			rawText := c.Text
+			c.Text = postProcessing.Apply(c.Text, c.Language)
+			c.TxtTranslation = postProcessing.Apply(c.TxtTranslation, c.TxtTranslationTarget)
+			for i := range c.Segments {
+				c.Segments[i].Text = postProcessing.Apply(c.Segments[i].Text, c.Language)
+			}
+			c.Words = postProcessWords(postProcessing, c.Words, rawText, c.Text, c.Language)
+		}
 		whisperResultMessage := Messages.WhisperResult{
 			Text:                 c.Text,
 			Language:             c.Language,
 			TxtTranslation:       c.TxtTranslation,
 			TxtTranslationTarget: c.TxtTranslationTarget,
+			Speaker:              c.Speaker,
+			Start:                c.Start,
+			End:                  c.End,
+			Segments:             c.Segments,
+			Words:                c.Words,
 		}
 
 		//go func() {
@@ -297,6 +322,10 @@ func (c *MessageStruct) HandleReceiveMessage() {
 		default:
 		}
 	case "translate_result":
+		// translations of session entries are stored in the session instead of the Text-Translate tab
+		if Sessions.HandleTranslateResult(c.OriginalText, c.TranslateResult) {
+			return
+		}
 		//Messages.LastTranslationResult = c.TranslateResult
 		Fields.Field.TranscriptionTranslationInput.SetText(c.TranslateResult)
 		if c.OriginalText != "" {
@@ -341,6 +370,10 @@ func (c *MessageStruct) HandleReceiveMessage() {
 
 	// special case for LLM plugin
 	case "llm_answer":
+		// answers to session summary requests are not added to the result list
+		if Sessions.HandleLlmAnswer(c.Text, c.LlmAnswer) {
+			return
+		}
 		c.Text = strings.TrimSpace(c.Text)
 		c.TxtTranslation = strings.TrimSpace(c.LlmAnswer)
 		whisperResultMessage := Messages.WhisperResult{
@@ -354,7 +387,8 @@ func (c *MessageStruct) HandleReceiveMessage() {
 			resultListMutex.Lock()
 			defer resultListMutex.Unlock()
 
-			resultMsg_.Update()
+			// LLM answers are not part of the session transcript
+			resultMsg_.UpdateResultList()
 
 			// stop processing status
 			Fields.Field.ProcessingStatus.Stop()
@@ -476,11 +510,41 @@ func (c *MessageStruct) HandleReceiveMessage() {
 
 }
 
+// postProcessWords applies the post-processing to the words of a transcription, so the confidence highlighting and the
+// word correction show the processed text. If the processed words do not match the processed text anymore (e.g. a rule
+// matched across words), the words are removed, so the correction does not undo the post-processing.
+func postProcessWords(postProcessing Settings.PostProcessingConf, words []Messages.WhisperWord, rawText string, processedText string, language string) []Messages.WhisperWord {
+	if len(words) == 0 {
+		return words
+	}
+	wordTexts := make([]string, len(words))
+	for i, word := range words {
+		wordTexts[i] = word.Word
+	}
+	wordsMatchedText := strings.TrimSpace(strings.Join(wordTexts, "")) == rawText
+	wordTexts = postProcessing.ApplyWords(wordTexts, language)
+	if wordsMatchedText && strings.TrimSpace(strings.Join(wordTexts, "")) != processedText {
+		return nil
+	}
+	processedWords := make([]Messages.WhisperWord, len(words))
+	copy(processedWords, words)
+	for i := range processedWords {
+		processedWords[i].Word = wordTexts[i]
+	}
+	return processedWords
+}
+
 func HandleSendMessage(sendMessage *Fields.SendMessageStruct) {
 	defer Utilities.PanicLogger()
 
 	switch sendMessage.Type {
 	case "setting_change":
+		// values are only checked against the settings_values which were received from the backend
+		if _, err := Settings.Config.ValidateOption(sendMessage.Name, sendMessage.Value); err != nil {
+			log.Printf("setting_change not sent: %v", err)
+			sendMessage.Value = SkipMessage
+			return
+		}
 		switch sendMessage.Name {
 		case "src_lang", "ocr_txt_src_lang":
 			langCode := Messages.InstalledLanguages.GetCodeByName(sendMessage.Value.(string))
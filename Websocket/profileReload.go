@@ -0,0 +1,75 @@
+package Websocket
+
+import (
+	"log"
+	"whispering-tiger-ui/Fields"
+	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/Utilities"
+	"whispering-tiger-ui/Websocket/Messages"
+)
+
+// ApplyProfileReload sends the changed settings of a reloaded profile as setting_change messages to the backend
+// and refreshes the pages. Settings which require a backend restart are not applied.
+func ApplyProfileReload(reload *Settings.ProfileReload) {
+	defer Utilities.PanicLogger()
+	if reload.Err != nil || reload.Conf == nil {
+		return
+	}
+
+	for _, key := range reload.ChangedKeys() {
+		SendSettingChange(key, reload.Changed[key])
+	}
+
+	copiedKeys := append(reload.ChangedKeys(), reload.UiChanged...)
+	if err := Settings.CopySettings(reload.Conf, &Settings.Config, copiedKeys); err != nil {
+		log.Printf("failed to apply reloaded profile: %v", err)
+		return
+	}
+	RefreshSettingsWidgets()
+}
+
+// SendSettingChange sends a setting value as it is stored in the settings as setting_change message to the backend.
+func SendSettingChange(name string, value interface{}) {
+	// HandleSendMessage expects the values the way the widgets set them
+	switch name {
+	case "current_language", "target_language":
+		if languageCode, ok := value.(string); ok {
+			if languageName := Messages.TranslateSettings.GetWhisperLanguageNameByCode(languageCode); languageName != "" {
+				value = languageName
+			}
+		}
+	case "tts_model":
+		models, ok := value.([]string)
+		if !ok || len(models) < 2 {
+			log.Printf("tts_model not applied: %v", value)
+			return
+		}
+		value = models[1]
+	}
+	sendMessage := Fields.SendMessageStruct{
+		Type:  "setting_change",
+		Name:  name,
+		Value: value,
+	}
+	sendMessage.SendMessage()
+}
+
+// settingsPageRefreshers rebuild the settings pages which are not bound to the translate settings (like the plugin settings)
+var settingsPageRefreshers = map[string]func(){}
+
+// OnRefreshSettingsWidgets sets a function which rebuilds a settings page when the settings changed.
+// Setting a function with the same key again replaces it.
+func OnRefreshSettingsWidgets(key string, refresh func()) {
+	settingsPageRefreshers[key] = refresh
+}
+
+// RefreshSettingsWidgets updates the widgets bound to the translate settings with the current settings
+// and rebuilds the other settings pages.
+func RefreshSettingsWidgets() {
+	Messages.TranslateSettings.Conf = Settings.Config
+	Messages.TranslateSettings.OscAutoProcessingEnabled = Settings.Config.Osc_auto_processing_enabled
+	Messages.TranslateSettings.Update()
+	for _, refresh := range settingsPageRefreshers {
+		refresh()
+	}
+}
@@ -1 +1,8 @@
+@echo off
+rem release builds need the update public keys, otherwise they can not verify and install updates
+if "%UPDATE_PUBLIC_KEYS%"=="" (
+    echo UPDATE_PUBLIC_KEYS is not set, set it to the base64 encoded update public keys ^(comma separated^)
+    exit /b 1
+)
+set GOFLAGS=-ldflags=-X=whispering-tiger-ui/Updater.UpdatePublicKeys=%UPDATE_PUBLIC_KEYS%
 fyne package --release
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/lang"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
@@ -16,13 +19,19 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"whispering-tiger-ui/Cli"
 	"whispering-tiger-ui/Fields"
+	"whispering-tiger-ui/ModelDownloader"
 	"whispering-tiger-ui/Pages"
 	"whispering-tiger-ui/Pages/Advanced"
+	"whispering-tiger-ui/Profiles"
 	"whispering-tiger-ui/Resources"
 	"whispering-tiger-ui/RuntimeBackend"
+	"whispering-tiger-ui/Secrets"
+	"whispering-tiger-ui/Sessions"
 	"whispering-tiger-ui/Settings"
 	"whispering-tiger-ui/UpdateUtility"
+	"whispering-tiger-ui/Updater"
 	"whispering-tiger-ui/Utilities"
 	"whispering-tiger-ui/Websocket"
 )
@@ -74,12 +83,63 @@ func determineWindowsFont(fontsDir string) string {
 func main() {
 	defer Utilities.PanicLogger()
 
-	// main application
+	// command line arguments
+	if len(os.Args) > 1 {
+		Cli.AttachParentConsole()
+	}
+	options, err := Cli.Parse(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		Cli.Usage(os.Stdout)
+		return
+	}
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "error:", err)
+		Cli.Usage(os.Stderr)
+		os.Exit(2)
+	}
+
 	val, ok := os.LookupEnv("WT_SCALE")
 	if ok {
 		_ = os.Setenv("FYNE_SCALE", val)
 	}
 
+	// the app is created before running commands, so they use the same preferences (e.g. the network settings)
+	a := app.NewWithID("io.github.whispering-tiger")
+	Utilities.AppVersion = a.Metadata().Version
+	Utilities.AppBuild = strconv.Itoa(a.Metadata().Build)
+
+	// proxy and certificate settings of all network operations
+	if err := UpdateUtility.ApplyNetworkPreferences(); err != nil {
+		log.Printf("failed to apply network settings: %v", err)
+	}
+
+	if options.Offline != "" {
+		if err = Updater.Offline.SetSource(options.Offline); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		// explicitly selected for this run, so its manifests are used before downloading them
+		Updater.Offline.SetPreferred(true)
+	}
+	if err = ModelDownloader.LoadCatalog(); err != nil {
+		log.Printf("failed to load model catalog: %v", err)
+	}
+	if options.Command != "" {
+		os.Exit(Cli.Run(options))
+	}
+	if options.Headless {
+		conf, err := Cli.LoadProfile(options.Profile)
+		if err == nil {
+			err = Cli.RunHeadless(conf)
+		}
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// main application
 	lang.SetLanguageOrder([]string{"en"})
 	langVal, langOk := os.LookupEnv("PREFERRED_LANGUAGE")
 	if langOk && langVal != "" {
@@ -90,14 +150,10 @@ func main() {
 	// initialize global fields (so they can use initialized languages)
 	Fields.InitializeGlobalFields()
 
-	a := app.NewWithID("io.github.whispering-tiger")
 	a.SetIcon(Resources.ResourceAppIconPng)
 
 	a.Settings().SetTheme(&AppTheme{})
 
-	Utilities.AppVersion = a.Metadata().Version
-	Utilities.AppBuild = strconv.Itoa(a.Metadata().Build)
-
 	w := a.NewWindow("Whispering Tiger")
 	w.SetMaster()
 	w.CenterOnScreen()
@@ -116,12 +172,22 @@ func main() {
 
 	profileWindow := a.NewWindow(lang.L("Whispering Tiger Profiles"))
 
+	// the profile given with --profile is loaded without showing the profile window
+	var startupProfile *Settings.Conf
+	if options.Profile != "" {
+		startupProfile, err = Cli.LoadProfile(options.Profile)
+		if err != nil {
+			log.Printf("failed to load profile: %v", err)
+		}
+	}
+
 	onProfileClose := func() {
+		Sessions.StartSession(Settings.Config.SettingsFilename, Settings.Config.Transcription_save_audio_dir)
 
 		RuntimeBackend.BackendsList = append(RuntimeBackend.BackendsList, RuntimeBackend.NewWhisperProcess())
 		RuntimeBackend.BackendsList[0].DeviceIndex = strconv.Itoa(Settings.Config.Device_index.(int))
 		RuntimeBackend.BackendsList[0].DeviceOutIndex = strconv.Itoa(Settings.Config.Device_out_index.(int))
-		RuntimeBackend.BackendsList[0].SettingsFile = filepath.Join(Settings.GetConfProfileDir(), Settings.Config.SettingsFilename)
+		RuntimeBackend.BackendsList[0].SettingsFile = Settings.PrepareBackendSettingsFile(filepath.Join(Settings.GetConfProfileDir(), Settings.Config.SettingsFilename))
 		// Setting this to use UTF-8 encoding for Python does not work when build using PyInstaller
 		if fyne.CurrentApp().Preferences().BoolWithFallback("RunWithUTF8", true) {
 			log.Printf("Running with UTF-8 encoding")
@@ -147,6 +213,7 @@ func main() {
 			if !Settings.Config.Run_backend_reconnect {
 				RuntimeBackend.BackendsList[0].Start()
 			}
+			go ModelDownloader.MarkModelsUsed(Profiles.RequiredModels(&Settings.Config))
 		}
 
 		// initialize status bar
@@ -163,6 +230,7 @@ func main() {
 			container.NewTabItemWithIcon(lang.L("Text-Translate"), theme.NewThemedResource(Resources.ResourceTranslateIconSvg), Pages.CreateTextTranslateWindow()),
 			container.NewTabItemWithIcon(lang.L("Text-to-Speech"), theme.NewThemedResource(Resources.ResourceTextToSpeechIconSvg), Pages.CreateTextToSpeechWindow()),
 			container.NewTabItemWithIcon(lang.L("Image-to-Text"), theme.NewThemedResource(Resources.ResourceImageRecognitionIconSvg), Pages.CreateOcrWindow()),
+			container.NewTabItemWithIcon(lang.L("Sessions"), theme.HistoryIcon(), Pages.CreateSessionsWindow()),
 			container.NewTabItemWithIcon(lang.L("Plugins"), theme.NewThemedResource(Resources.ResourcePluginsIconSvg), Advanced.CreatePluginSettingsPage()),
 			container.NewTabItemWithIcon(lang.L("Settings"), theme.SettingsIcon(), Pages.CreateSettingsWindow()),
 			container.NewTabItemWithIcon(lang.L("Advanced"), theme.MoreVerticalIcon(), Pages.CreateAdvancedWindow()),
@@ -179,6 +247,10 @@ func main() {
 				tab.Content = Pages.CreateSettingsWindow()
 				tab.Content.Refresh()
 			}
+			if tab.Text == lang.L("Sessions") {
+				tab.Content = Pages.CreateSessionsWindow()
+				tab.Content.Refresh()
+			}
 			if tab.Text == lang.L("Plugins") {
 				tab.Content.(*container.Scroll).Content = Advanced.CreatePluginSettingsPage()
 				tab.Content.(*container.Scroll).Content.Refresh()
@@ -214,6 +286,31 @@ func main() {
 
 		w.Resize(fyne.NewSize(float32(mainWindowWidth), float32(mainWindowHeight)))
 
+		// undo and redo setting changes
+		Settings.Journal.SendValue = Websocket.SendSettingChange
+		Settings.Journal.OnChanged("widgets", func(applied bool) {
+			if applied {
+				Websocket.RefreshSettingsWidgets()
+			}
+		})
+		Websocket.OnRefreshSettingsWidgets("tabs", func() {
+			// rebuild the visible settings page, the other pages are rebuilt when they are selected
+			switch appTabs.Selected().Text {
+			case lang.L("Settings"), lang.L("Plugins"), lang.L("Advanced"):
+				appTabs.OnSelected(appTabs.Selected())
+			}
+		})
+		// shortcuts are called on the UI thread, so the widgets are refreshed there
+		undoShortcut := func(shortcut fyne.Shortcut) {
+			Settings.Journal.Undo()
+		}
+		redoShortcut := func(shortcut fyne.Shortcut) {
+			Settings.Journal.Redo()
+		}
+		w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyZ, Modifier: fyne.KeyModifierShortcutDefault}, undoShortcut)
+		w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyY, Modifier: fyne.KeyModifierShortcutDefault}, redoShortcut)
+		w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyZ, Modifier: fyne.KeyModifierShortcutDefault | fyne.KeyModifierShift}, redoShortcut)
+
 		// show main window
 		w.Show()
 
@@ -222,32 +319,97 @@ func main() {
 
 		go WebsocketClient.Start()
 
-		fyne.CurrentApp().Preferences().SetFloat("ProfileWindowWidth", float64(profileWindow.Canvas().Size().Width))
-		fyne.CurrentApp().Preferences().SetFloat("ProfileWindowHeight", float64(profileWindow.Canvas().Size().Height))
+		// apply changes of the profile file made outside the application
+		Pages.WatchActiveProfile(filepath.Join(Settings.GetConfProfileDir(), Settings.Config.SettingsFilename))
+
+		if startupProfile == nil {
+			fyne.CurrentApp().Preferences().SetFloat("ProfileWindowWidth", float64(profileWindow.Canvas().Size().Width))
+			fyne.CurrentApp().Preferences().SetFloat("ProfileWindowHeight", float64(profileWindow.Canvas().Size().Height))
+		}
 
 		// close profile window
 		profileWindow.Close()
 	}
 
-	profilePage := Pages.CreateProfileWindow(onProfileClose)
-	profileWindow.SetContent(profilePage)
+	// secrets are kept in an encrypted file if no OS keyring is available
+	onSecretsUnlocked := func() {
+		// the proxy password can be read now
+		if err := UpdateUtility.ApplyNetworkPreferences(); err != nil {
+			log.Printf("failed to apply network settings: %v", err)
+		}
+		// the backend got empty values for the secrets of the profile while they were locked
+		if Settings.Config.HasSecretReferences() && Settings.Config.ResolveSecrets() && len(RuntimeBackend.BackendsList) > 0 && RuntimeBackend.BackendsList[0].IsRunning() {
+			sendMessage := Fields.SendMessageStruct{
+				Type:  "setting_change",
+				Name:  "plugin_settings",
+				Value: Settings.Config.Plugin_settings,
+			}
+			sendMessage.SendMessage()
+		}
+	}
+	Secrets.FileStore.OnPassphraseRequired = func() {
+		windows := fyne.CurrentApp().Driver().AllWindows()
+		Pages.ShowSecretsUnlockDialog(windows[len(windows)-1], onSecretsUnlocked)
+	}
 
-	// set profile window size
-	profileWindowWidth := fyne.CurrentApp().Preferences().FloatWithFallback("ProfileWindowWidth", 1400)
-	profileWindowHeight := fyne.CurrentApp().Preferences().FloatWithFallback("ProfileWindowHeight", 600)
-	profileWindow.Resize(fyne.NewSize(float32(profileWindowWidth), float32(profileWindowHeight)))
+	// downloads of models and updates are shared by all windows
+	Pages.ApplyDownloadPreferences()
+	if err := Updater.Downloads.Restore(filepath.Join(".cache", "downloads.json")); err != nil {
+		log.Printf("failed to restore downloads: %v", err)
+	}
+	if offlineSource := a.Preferences().String("OfflineSource"); options.Offline == "" && offlineSource != "" {
+		if err := Updater.Offline.SetSource(offlineSource); err != nil {
+			log.Printf("failed to use offline source %s: %v", offlineSource, err)
+		}
+	}
+	if err := Updater.Mirrors.Restore(filepath.Join(".cache", "mirrors.json")); err != nil {
+		log.Printf("failed to restore mirror measurements: %v", err)
+	}
+	Updater.Downloads.OnEnqueued = func(item Updater.DownloadItem) {
+		Pages.ShowDownloadsWindow()
+	}
 
-	profileWindow.CenterOnScreen()
-	profileWindow.Show()
+	// the secrets are unlocked and the updates are checked in the first window, which is the main window if the
+	// profile was selected with --profile. startBackend starts the loaded profile's backend after the platform download.
+	runStartupChecks := func(window fyne.Window, startBackend bool) {
+		if Secrets.CurrentStore() == Secrets.Store(Secrets.FileStore) && Secrets.FileStore.Exists() && Secrets.FileStore.IsLocked() {
+			Pages.ShowSecretsUnlockDialog(window, onSecretsUnlocked)
+		}
+		if fyne.CurrentApp().Preferences().BoolWithFallback("CheckForUpdateAtStartup", true) || !RuntimeBackend.BackendAvailable() {
+			go UpdateUtility.VersionCheck(window, startBackend)
+		}
+	}
 
-	// check for updates
-	if fyne.CurrentApp().Preferences().BoolWithFallback("CheckForUpdateAtStartup", true) || (!Utilities.FileExists("audioWhisper/audioWhisper.exe") && !Utilities.FileExists("audioWhisper.py")) {
-		go func() {
-			if len(fyne.CurrentApp().Driver().AllWindows()) == 2 {
-				UpdateUtility.VersionCheck(fyne.CurrentApp().Driver().AllWindows()[1], false)
-			}
-		}()
+	if startupProfile != nil {
+		// profile selected with --profile, skip the profile window
+		Settings.Config = *startupProfile
+		websocketAddr := Settings.Config.Websocket_ip + ":" + strconv.Itoa(Settings.Config.Websocket_port)
+		if Settings.Config.Run_backend && Utilities.CheckPortInUse(websocketAddr) {
+			log.Printf("websocket port %s is in use, reconnecting to the running backend", websocketAddr)
+			Settings.Config.Run_backend_reconnect = true
+		}
+		onProfileClose()
+		runStartupChecks(w, Settings.Config.Run_backend && !Settings.Config.Run_backend_reconnect)
+	} else {
+		profilePage := Pages.CreateProfileWindow(onProfileClose)
+		profileWindow.SetContent(profilePage)
+
+		// set profile window size
+		profileWindowWidth := fyne.CurrentApp().Preferences().FloatWithFallback("ProfileWindowWidth", 1400)
+		profileWindowHeight := fyne.CurrentApp().Preferences().FloatWithFallback("ProfileWindowHeight", 600)
+		profileWindow.Resize(fyne.NewSize(float32(profileWindowWidth), float32(profileWindowHeight)))
+
+		profileWindow.CenterOnScreen()
+		profileWindow.Show()
+		runStartupChecks(profileWindow, false)
 	}
+
+	// the model catalog can list new models and mirrors without a new release
+	go func() {
+		if err := ModelDownloader.UpdateCatalog(); err != nil {
+			log.Printf("failed to update model catalog: %v", err)
+		}
+	}()
 	if fyne.CurrentApp().Preferences().BoolWithFallback("CheckForPluginUpdatesAtStartup", true) {
 		go func() {
 			lastCheckTimestamp := fyne.CurrentApp().Preferences().IntWithFallback("CheckForPluginUpdatesAtStartupLastTime", 0)
@@ -263,7 +425,7 @@ func main() {
 						if b {
 							Advanced.CreatePluginListWindow(nil, false)
 						}
-					}, fyne.CurrentApp().Driver().AllWindows()[1])
+					}, fyne.CurrentApp().Driver().AllWindows()[len(fyne.CurrentApp().Driver().AllWindows())-1])
 				}
 			}
 		}()
@@ -276,6 +438,11 @@ func main() {
 			RuntimeBackend.BackendsList[0].WriterBackend.Close()
 			RuntimeBackend.BackendsList[0].ReaderBackend.Close()
 		}
+		Settings.RemoveBackendSettingsFile()
+		// write the entries which are not saved yet
+		if Sessions.Current != nil {
+			_ = Sessions.Current.Save()
+		}
 	})
 
 	a.Run()